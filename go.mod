@@ -8,9 +8,10 @@ require (
 	github.com/k8stopologyawareschedwg/noderesourcetopology-api v0.0.10
 	github.com/onsi/ginkgo v1.16.4
 	github.com/onsi/gomega v1.13.0
-	github.com/openshift/api v0.0.0-20210713130143-be21c6cb1bea // indirect
+	github.com/openshift/api v0.0.0-20210713130143-be21c6cb1bea
 	github.com/openshift/client-go v0.0.0-20200320143156-e7fa42a1261e
 	github.com/spf13/cobra v1.1.1
+	github.com/spf13/pflag v1.0.5
 	k8s.io/api v0.21.2
 	k8s.io/apiextensions-apiserver v0.21.2
 	k8s.io/apimachinery v0.21.2
@@ -20,6 +21,7 @@ require (
 	k8s.io/kubernetes v1.21.0 // indirect
 	sigs.k8s.io/controller-runtime v0.9.2
 	sigs.k8s.io/scheduler-plugins v0.19.9
+	sigs.k8s.io/yaml v1.2.0
 )
 
 replace (
@@ -19,6 +19,8 @@ package clientutil
 import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
@@ -35,9 +37,35 @@ func init() {
 	apiextensionsv1.AddToScheme(scheme.Scheme)
 }
 
+// Kubeconfig, if not empty, is the path of the kubeconfig all the clients use, overriding the
+// KUBECONFIG environment variable and the in-cluster configuration.
+var Kubeconfig string
+
+// Impersonate, if its UserName is not empty, is the identity all the clients act as,
+// like the `--as` and `--as-group` options of kubectl.
+var Impersonate rest.ImpersonationConfig
+
+func getConfig() (*rest.Config, error) {
+	cfg, err := getBaseConfig()
+	if err != nil {
+		return nil, err
+	}
+	if Impersonate.UserName != "" {
+		cfg.Impersonate = Impersonate
+	}
+	return cfg, nil
+}
+
+func getBaseConfig() (*rest.Config, error) {
+	if Kubeconfig == "" {
+		return config.GetConfig()
+	}
+	return clientcmd.BuildConfigFromFlags("", Kubeconfig)
+}
+
 // New returns a controller-runtime client.
 func New() (client.Client, error) {
-	cfg, err := config.GetConfig()
+	cfg, err := getConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -48,7 +76,7 @@ func New() (client.Client, error) {
 
 // NewK8s returns a kubernetes clientset
 func NewK8s() (*kubernetes.Clientset, error) {
-	cfg, err := config.GetConfig()
+	cfg, err := getConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -61,7 +89,7 @@ func NewK8s() (*kubernetes.Clientset, error) {
 }
 
 func NewK8sExt() (*apiextension.Clientset, error) {
-	cfg, err := config.GetConfig()
+	cfg, err := getConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -74,7 +102,7 @@ func NewK8sExt() (*apiextension.Clientset, error) {
 }
 
 func NewTopologyClient() (*topologyclientset.Clientset, error) {
-	cfg, err := config.GetConfig()
+	cfg, err := getConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -91,7 +119,7 @@ type OCPClientSet struct {
 }
 
 func NewOCPClientSet() (*OCPClientSet, error) {
-	cfg, err := config.GetConfig()
+	cfg, err := getConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -22,6 +22,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/k8stopologyawareschedwg/deployer/pkg/clientutil"
@@ -52,3 +53,55 @@ func GetBySelector(selector labels.Selector) ([]corev1.Node, error) {
 	}
 	return nodes.Items, nil
 }
+
+// GetSchedulable returns the nodes which can run the pods with the given spec, considering
+// the cordoned nodes, the node selector and the scheduling taints. Node affinity is not considered.
+func GetSchedulable(nodes []corev1.Node, podSpec *corev1.PodSpec) []corev1.Node {
+	sel := labels.SelectorFromSet(podSpec.NodeSelector)
+	var ret []corev1.Node
+	for _, node := range nodes {
+		if node.Spec.Unschedulable || !sel.Matches(labels.Set(node.Labels)) {
+			continue
+		}
+		if !toleratesTaints(podSpec.Tolerations, node.Spec.Taints) {
+			continue
+		}
+		ret = append(ret, node)
+	}
+	return ret
+}
+
+func toleratesTaints(tolerations []corev1.Toleration, taints []corev1.Taint) bool {
+	for idx := range taints {
+		taint := &taints[idx]
+		if taint.Effect == corev1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		tolerated := false
+		for _, tol := range tolerations {
+			if tol.ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+	return true
+}
+
+// GetArchitectures returns the sorted, unique CPU architectures of the given nodes.
+func GetArchitectures(nodes []corev1.Node) []string {
+	arches := sets.NewString()
+	for _, node := range nodes {
+		arch := node.Status.NodeInfo.Architecture
+		if arch == "" {
+			arch = node.Labels[corev1.LabelArchStable]
+		}
+		if arch != "" {
+			arches.Insert(arch)
+		}
+	}
+	return arches.List()
+}
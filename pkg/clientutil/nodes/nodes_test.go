@@ -0,0 +1,136 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package nodes
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetArchitectures(t *testing.T) {
+	type testCase struct {
+		name     string
+		nodes    []corev1.Node
+		expected []string
+	}
+
+	testCases := []testCase{
+		{
+			name:     "no nodes",
+			expected: []string{},
+		},
+		{
+			name: "from node info",
+			nodes: []corev1.Node{
+				{Status: corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{Architecture: "arm64"}}},
+				{Status: corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{Architecture: "amd64"}}},
+				{Status: corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{Architecture: "amd64"}}},
+			},
+			expected: []string{"amd64", "arm64"},
+		},
+		{
+			name: "fallback to label",
+			nodes: []corev1.Node{
+				{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{corev1.LabelArchStable: "arm64"}}},
+				{},
+			},
+			expected: []string{"arm64"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := GetArchitectures(tc.nodes)
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("got %v expected %v", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestGetSchedulable(t *testing.T) {
+	nodes := []corev1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker-0", Labels: map[string]string{"pool": "a"}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker-1", Labels: map[string]string{"pool": "b"}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "cordoned", Labels: map[string]string{"pool": "a"}},
+			Spec:       corev1.NodeSpec{Unschedulable: true},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "control-plane", Labels: map[string]string{"pool": "a"}},
+			Spec: corev1.NodeSpec{
+				Taints: []corev1.Taint{
+					{Key: "node-role.kubernetes.io/control-plane", Effect: corev1.TaintEffectNoSchedule},
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "preferred", Labels: map[string]string{"pool": "a"}},
+			Spec: corev1.NodeSpec{
+				Taints: []corev1.Taint{
+					{Key: "example.com/busy", Effect: corev1.TaintEffectPreferNoSchedule},
+				},
+			},
+		},
+	}
+
+	type testCase struct {
+		name     string
+		podSpec  corev1.PodSpec
+		expected []string
+	}
+
+	testCases := []testCase{
+		{
+			name:     "no constraints",
+			expected: []string{"worker-0", "worker-1", "preferred"},
+		},
+		{
+			name:     "node selector",
+			podSpec:  corev1.PodSpec{NodeSelector: map[string]string{"pool": "a"}},
+			expected: []string{"worker-0", "preferred"},
+		},
+		{
+			name: "tolerations",
+			podSpec: corev1.PodSpec{
+				Tolerations: []corev1.Toleration{
+					{Key: "node-role.kubernetes.io/control-plane", Operator: corev1.TolerationOpExists},
+				},
+			},
+			expected: []string{"worker-0", "worker-1", "control-plane", "preferred"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := []string{}
+			for _, node := range GetSchedulable(nodes, &tc.podSpec) {
+				got = append(got, node.Name)
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("got %v expected %v", got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/objects"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests"
+)
+
+type applyOptions struct {
+	path           string
+	waitCompletion bool
+}
+
+func NewApplyCommand(commonOpts *CommonOptions) *cobra.Command {
+	opts := &applyOptions{}
+	apply := &cobra.Command{
+		Use:   "apply",
+		Short: "deploy previously rendered manifests",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.path == "" {
+				return fmt.Errorf("must provide the manifests using -f")
+			}
+			objs, err := readObjects(opts.path)
+			if err != nil {
+				return err
+			}
+			la := newLogAdapter(commonOpts, commonOpts.Log, commonOpts.DebugLog)
+			_, err = objects.Deploy(cmd.Context(), la, objs, objects.Options{
+				WaitCompletion: opts.waitCompletion,
+				WaitTimeout:    commonOpts.WaitTimeout,
+				PollInterval:   commonOpts.PollInterval,
+			})
+			return err
+		},
+		Args: cobra.NoArgs,
+	}
+	apply.Flags().StringVarP(&opts.path, "filename", "f", "", "file or directory containing the rendered manifests.")
+	apply.Flags().BoolVarP(&opts.waitCompletion, "wait", "W", false, "wait for deployment to be all completed.")
+	return apply
+}
+
+// readObjects reads the objects from path, which can be a file or a directory.
+// Directories are not traversed recursively; files are read in lexical order.
+func readObjects(path string) ([]client.Object, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return readObjectsFromFile(path)
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var fileNames []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		switch filepath.Ext(entry.Name()) {
+		case ".yaml", ".yml", ".json":
+			fileNames = append(fileNames, entry.Name())
+		}
+	}
+	sort.Strings(fileNames)
+
+	var objs []client.Object
+	for _, fileName := range fileNames {
+		fileObjs, err := readObjectsFromFile(filepath.Join(path, fileName))
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, fileObjs...)
+	}
+	return objs, nil
+}
+
+func readObjectsFromFile(path string) ([]client.Object, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	objs, err := manifests.DecodeObjects(src)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode %q: %w", path, err)
+	}
+	return objs, nil
+}
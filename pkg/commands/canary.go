@@ -0,0 +1,89 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/rte"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/tlog"
+)
+
+type canaryOptions struct {
+	nodeSelector   map[string]string
+	waitCompletion bool
+}
+
+func NewCanaryCommand(commonOpts *CommonOptions) *cobra.Command {
+	opts := &canaryOptions{}
+	canary := &cobra.Command{
+		Use:   "canary",
+		Short: "try out a new topology updater configuration on a subset of the nodes",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+		Args: cobra.NoArgs,
+	}
+	canary.PersistentFlags().StringToStringVar(&opts.nodeSelector, "node-selector", nil, "comma-separated key=value labels of the canary nodes.")
+	canary.PersistentFlags().BoolVarP(&opts.waitCompletion, "wait", "W", false, "wait for the canary to be running.")
+	canary.AddCommand(
+		newCanaryCommand(commonOpts, opts, "deploy", "run the new configuration on the canary nodes, alongside the stable topology updater", true, rte.DeployCanary),
+		newCanaryCommand(commonOpts, opts, "promote", "roll out the canary configuration on all the nodes and remove the canary", true, rte.PromoteCanary),
+		newCanaryCommand(commonOpts, opts, "remove", "remove the canary, restoring the stable topology updater on the canary nodes", false, rte.RemoveCanary),
+	)
+	return canary
+}
+
+func newCanaryCommand(commonOpts *CommonOptions, opts *canaryOptions, use, short string, needsConfig bool, run func(log tlog.Logger, opts rte.Options) error) *cobra.Command {
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if needsConfig && commonOpts.RTEConfigData == "" {
+				return fmt.Errorf("must provide the canary configuration using --rte-config-file")
+			}
+			la := newLogAdapter(commonOpts, commonOpts.Log, commonOpts.DebugLog)
+			platDetect := detectPlatform(commonOpts.DebugLog, commonOpts.UserPlatform, commonOpts.DetectTimeout)
+			if err := platDetect.Err(); err != nil {
+				return err
+			}
+			return run(la, rte.Options{
+				Platform:           platDetect.Discovered,
+				ManifestsSource:    commonOpts.ManifestsSource,
+				WaitCompletion:     opts.waitCompletion,
+				WaitTimeout:        commonOpts.WaitTimeout,
+				PollInterval:       commonOpts.PollInterval,
+				RTEConfigData:      commonOpts.RTEConfigData,
+				ImmutableConfig:    commonOpts.RTEImmutableConfig,
+				ConfigMapName:      commonOpts.RTEConfigMapName,
+				ExtraLabels:        commonOpts.ExtraLabels,
+				ExtraAnnotations:   commonOpts.ExtraAnnotations,
+				PullIfNotPresent:   commonOpts.PullIfNotPresent,
+				ImagePullSecrets:   commonOpts.ImagePullSecrets,
+				AllNodes:           commonOpts.AllNodes,
+				NodeSelector:       commonOpts.RTENodeSelector,
+				Tolerations:        commonOpts.RTETolerations,
+				APIGroup:           commonOpts.APIGroup,
+				Namespace:          commonOpts.UpdaterNamespace,
+				CanaryNodeSelector: opts.nodeSelector,
+			})
+		},
+		Args: cobra.NoArgs,
+	}
+}
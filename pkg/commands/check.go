@@ -0,0 +1,157 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/validator"
+)
+
+// the outcomes of the checks. Only the failed ones are blocking.
+const (
+	checkPassed  = "passed"
+	checkFailed  = "failed"
+	checkWarning = "warning"
+)
+
+type checkOptions struct {
+	jsonOutput bool
+}
+
+// checkResult is the outcome of one check of the cluster prerequisites.
+type checkResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Hint tells how to fix the issues, if any.
+	Hint   string                       `json:"hint,omitempty"`
+	Issues []validator.ValidationResult `json:"issues,omitempty"`
+}
+
+// checkReport tells if the cluster is ready for a deployment.
+type checkReport struct {
+	Platform platform.Platform `json:"platform"`
+	Ready    bool              `json:"ready"`
+	Checks   []checkResult     `json:"checks"`
+}
+
+func NewCheckCommand(commonOpts *CommonOptions) *cobra.Command {
+	opts := &checkOptions{}
+	check := &cobra.Command{
+		Use:   "check",
+		Short: "check the cluster prerequisites of topology-aware-scheduling, before deploying",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			platDetect := detectPlatform(commonOpts.DebugLog, commonOpts.UserPlatform, commonOpts.DetectTimeout)
+			if err := platDetect.Err(); err != nil {
+				return err
+			}
+			report, err := checkCluster(commonOpts, platDetect.Discovered)
+			if err != nil {
+				return err
+			}
+			if opts.jsonOutput {
+				if err := json.NewEncoder(os.Stdout).Encode(report); err != nil {
+					return err
+				}
+			} else {
+				printCheckReport(report)
+			}
+			if !report.Ready {
+				return fmt.Errorf("the cluster is not ready for topology-aware-scheduling")
+			}
+			return nil
+		},
+		Args: cobra.NoArgs,
+	}
+	check.Flags().BoolVarP(&opts.jsonOutput, "json", "J", false, "output JSON, not text.")
+	return check
+}
+
+func checkCluster(commonOpts *CommonOptions, plat platform.Platform) (checkReport, error) {
+	vd := validator.Validator{
+		Log: commonOpts.DebugLog,
+	}
+	report := checkReport{
+		Platform: plat,
+		Ready:    true,
+	}
+
+	items, err := validateKubeletConfig(vd, plat)
+	if err != nil {
+		return report, err
+	}
+	kubeletHint := fmt.Sprintf("configure the kubelet of the worker nodes with the %q CPU manager policy and the %q topology manager policy",
+		validator.ExpectedCPUManagerPolicy, validator.ExpectedTopologyManagerPolicy)
+	if plat == platform.OpenShift {
+		kubeletHint += ", using a KubeletConfig object"
+	}
+	report.add(newCheckResult("kubelet configuration", checkFailed, kubeletHint, items))
+
+	items, err = vd.ValidateAPIServerFeatureGates()
+	if err != nil {
+		return report, err
+	}
+	// the apiserver state of the kubelet gates is just a hint, the kubelet configuration check is the real one
+	report.add(newCheckResult("feature gates", checkWarning, "enable the feature gates in the cluster components, the kubelet ones on the worker nodes", items))
+
+	items, err = vd.ValidateNRTCRD(commonOpts.APIGroup)
+	if err != nil {
+		return report, err
+	}
+	report.add(newCheckResult("NodeResourceTopology API", checkWarning, "deploy the API too, e.g. using \"deploy\" or \"deploy api\"", items))
+
+	return report, nil
+}
+
+// newCheckResult returns the check result having the given issues, which have the given status if any.
+func newCheckResult(name, status, hint string, items []validator.ValidationResult) checkResult {
+	if len(items) == 0 {
+		return checkResult{Name: name, Status: checkPassed}
+	}
+	return checkResult{Name: name, Status: status, Hint: hint, Issues: items}
+}
+
+func (report *checkReport) add(res checkResult) {
+	report.Checks = append(report.Checks, res)
+	if res.Status == checkFailed {
+		report.Ready = false
+	}
+}
+
+// like the validate output, undecorated
+func printCheckReport(report checkReport) {
+	fmt.Printf("platform: %s\n", report.Platform)
+	for _, res := range report.Checks {
+		fmt.Printf("%-8s %s\n", res.Status, res.Name)
+		for _, item := range res.Issues {
+			fmt.Printf("  - %s\n", item.String())
+		}
+		if res.Hint != "" {
+			fmt.Printf("  hint: %s\n", res.Hint)
+		}
+	}
+	if report.Ready {
+		fmt.Printf("READY>>: the cluster meets the prerequisites\n")
+	} else {
+		fmt.Printf("NOT READY>>: fix the failed checks before deploying\n")
+	}
+}
@@ -0,0 +1,202 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/images"
+	rtemanifests "github.com/k8stopologyawareschedwg/deployer/pkg/manifests/rte"
+	schedmanifests "github.com/k8stopologyawareschedwg/deployer/pkg/manifests/sched"
+)
+
+// the origins of the settings
+const (
+	sourceDefault = "default"
+	sourceEnv     = "env"
+	sourceFlag    = "flag"
+	sourceFile    = "file"
+	sourceInline  = "inline"
+	sourceNone    = "none"
+)
+
+type configShowOptions struct {
+	format string
+}
+
+func NewConfigCommand(commonOpts *CommonOptions) *cobra.Command {
+	config := &cobra.Command{
+		Use:   "config",
+		Short: "inspect the deployer configuration",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return ShowHelp(cmd, args)
+		},
+		Args: cobra.NoArgs,
+	}
+	config.AddCommand(NewConfigShowCommand(commonOpts))
+	return config
+}
+
+func NewConfigShowCommand(commonOpts *CommonOptions) *cobra.Command {
+	opts := &configShowOptions{}
+	show := &cobra.Command{
+		Use:   "show",
+		Short: "show the effective configuration, resolving the flags, the environment variables and the defaults. Does not access the cluster.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.format != formatYAML && opts.format != formatJSON {
+				return fmt.Errorf("unsupported output format: %q", opts.format)
+			}
+			cfo := newConfigOutput(commonOpts)
+			if opts.format == formatJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(cfo)
+			}
+			data, err := yaml.Marshal(cfo)
+			if err != nil {
+				return err
+			}
+			_, err = os.Stdout.Write(data)
+			return err
+		},
+		Args: cobra.NoArgs,
+	}
+	show.Flags().StringVar(&opts.format, "output-format", formatYAML, "format of the configuration. One of: \"yaml\", \"json\".")
+	return show
+}
+
+type configOutput struct {
+	// Platform is empty if it is going to be autodetected, which requires the cluster.
+	Platform           string                `json:"platform,omitempty"`
+	PlatformAutodetect bool                  `json:"platform_autodetect"`
+	ManifestsVersion   string                `json:"manifests_version,omitempty"`
+	Replicas           int                   `json:"replicas"`
+	Updater            configUpdaterOutput   `json:"updater"`
+	Scheduler          configSchedulerOutput `json:"scheduler"`
+	Images             []configImageOutput   `json:"images"`
+}
+
+type configUpdaterOutput struct {
+	Type string `json:"type"`
+	// Namespace is empty if it is the platform default.
+	Namespace string `json:"namespace,omitempty"`
+	// ConfigSource is where the configuration data comes from: sourceFile, sourceInline or sourceNone.
+	ConfigSource    string            `json:"config_source"`
+	ConfigFile      string            `json:"config_file,omitempty"`
+	ConfigMapName   string            `json:"config_map_name"`
+	ImmutableConfig bool              `json:"immutable_config"`
+	AllNodes        bool              `json:"all_nodes"`
+	NodeSelector    map[string]string `json:"node_selector,omitempty"`
+	MaxUnavailable  string            `json:"max_unavailable,omitempty"`
+	HostNetwork     *bool             `json:"host_network,omitempty"`
+	DNSPolicy       string            `json:"dns_policy,omitempty"`
+	ServiceMonitor  bool              `json:"service_monitor"`
+}
+
+type configSchedulerOutput struct {
+	Mode            string          `json:"mode"`
+	Name            string          `json:"name"`
+	LeaderElect     bool            `json:"leader_elect"`
+	OnControlPlane  bool            `json:"on_control_plane"`
+	SpreadReplicas  string          `json:"spread_replicas,omitempty"`
+	ScoringStrategy string          `json:"scoring_strategy,omitempty"`
+	FeatureGates    map[string]bool `json:"feature_gates,omitempty"`
+}
+
+type configImageOutput struct {
+	Component string `json:"component"`
+	Image     string `json:"image"`
+	// Source is sourceDefault, sourceEnv (the TAS_*_IMAGE variables) or sourceFlag (--image).
+	Source string `json:"source"`
+}
+
+func newConfigOutput(commonOpts *CommonOptions) configOutput {
+	cfo := configOutput{
+		PlatformAutodetect: commonOpts.UserPlatform == platform.Unknown,
+		ManifestsVersion:   commonOpts.ManifestsVersion,
+		Replicas:           commonOpts.Replicas,
+		Updater: configUpdaterOutput{
+			Type:            rtemanifests.ComponentName,
+			Namespace:       commonOpts.UpdaterNamespace,
+			ConfigSource:    sourceNone,
+			ConfigMapName:   commonOpts.RTEConfigMapName,
+			ImmutableConfig: commonOpts.RTEImmutableConfig,
+			AllNodes:        commonOpts.AllNodes,
+			NodeSelector:    commonOpts.RTENodeSelector,
+			ServiceMonitor:  commonOpts.ServiceMonitor,
+			HostNetwork:     commonOpts.RTEHostNetwork,
+			DNSPolicy:       string(commonOpts.RTEDNSPolicy),
+		},
+		Scheduler: configSchedulerOutput{
+			Mode:            commonOpts.SchedulerMode,
+			Name:            commonOpts.SchedulerName,
+			LeaderElect:     commonOpts.SchedulerLeaderElect,
+			OnControlPlane:  commonOpts.SchedulerOnControlPlane,
+			SpreadReplicas:  commonOpts.SchedulerSpreadReplicas,
+			ScoringStrategy: commonOpts.SchedulerScoringStrategy,
+			FeatureGates:    commonOpts.SchedulerFeatureGates,
+		},
+		Images: []configImageOutput{
+			newConfigImageOutput(commonOpts, images.ComponentTopologyUpdater, images.ResourceTopologyExporterImage, images.EnvResourceTopologyExporterImage),
+			newConfigImageOutput(commonOpts, images.ComponentSchedulerPlugin, images.SchedulerPluginSchedulerImage, images.EnvSchedulerPluginImage),
+			newConfigImageOutput(commonOpts, images.ComponentSchedulerController, images.SchedulerPluginControllerImage, images.EnvSchedulerPluginControllerImage),
+		},
+	}
+	if !cfo.PlatformAutodetect {
+		cfo.Platform = commonOpts.UserPlatform.String()
+	}
+
+	if configFile := rteConfigFilePath(commonOpts); configFile != "" {
+		cfo.Updater.ConfigSource = sourceFile
+		cfo.Updater.ConfigFile = configFile
+	} else if commonOpts.RTEConfigData != "" {
+		cfo.Updater.ConfigSource = sourceInline
+	}
+	if cfo.Updater.ConfigMapName == "" {
+		cfo.Updater.ConfigMapName = rtemanifests.DefaultConfigMapName
+	}
+	if commonOpts.RTEMaxUnavailable != nil {
+		cfo.Updater.MaxUnavailable = commonOpts.RTEMaxUnavailable.String()
+	}
+
+	if cfo.Scheduler.Mode == schedmanifests.ModeReplaceDefault {
+		cfo.Scheduler.Name = schedmanifests.DefaultSchedulerName
+	} else if cfo.Scheduler.Name == "" {
+		cfo.Scheduler.Name = schedmanifests.SchedulerName
+	}
+	return cfo
+}
+
+func newConfigImageOutput(commonOpts *CommonOptions, component, image, envVar string) configImageOutput {
+	source := sourceDefault
+	if _, ok := commonOpts.Images[component]; ok {
+		source = sourceFlag
+	} else if _, ok := os.LookupEnv(envVar); ok {
+		source = sourceEnv
+	}
+	return configImageOutput{
+		Component: component,
+		Image:     image,
+		Source:    source,
+	}
+}
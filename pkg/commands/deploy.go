@@ -17,13 +17,25 @@
 package commands
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
+	"os"
 
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer"
 	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/api"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/objects"
 	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
 	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/rte"
 	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/sched"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests"
+	schedmanifests "github.com/k8stopologyawareschedwg/deployer/pkg/manifests/sched"
 	"github.com/k8stopologyawareschedwg/deployer/pkg/tlog"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/validator"
+	deployerversion "github.com/k8stopologyawareschedwg/deployer/pkg/version"
 
 	"github.com/spf13/cobra"
 )
@@ -31,6 +43,22 @@ import (
 type deployOptions struct {
 	clusterPlatform platform.Platform
 	waitCompletion  bool
+	output          string
+	events          *eventStream
+	dryRun          bool
+	// producerOnly, checkFeatureGates, strict and prune are used only by the top-level deploy command
+	producerOnly      bool
+	checkFeatureGates bool
+	strict            bool
+	prune             bool
+	// forceRemoveFinalizers, force and ignoreNotFound are used only by the remove commands, byLabel only by the top-level one
+	forceRemoveFinalizers bool
+	byLabel               bool
+	force                 bool
+	ignoreNotFound        bool
+	// reportFile, if not empty, is where the report of the objects acted on is written, collected in report
+	reportFile string
+	report     deployer.Result
 }
 
 func NewDeployCommand(commonOpts *CommonOptions) *cobra.Command {
@@ -38,12 +66,19 @@ func NewDeployCommand(commonOpts *CommonOptions) *cobra.Command {
 	deploy := &cobra.Command{
 		Use:   "deploy",
 		Short: "deploy the components and configurations needed for topology-aware-scheduling",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			return deployOnCluster(commonOpts, opts)
-		},
+		RunE: opts.withReport(opts.withSummary(func(cmd *cobra.Command, args []string) error {
+			return deployOnCluster(cmd.Context(), commonOpts, opts)
+		})),
 		Args: cobra.NoArgs,
 	}
 	deploy.PersistentFlags().BoolVarP(&opts.waitCompletion, "wait", "W", false, "wait for deployment to be all completed.")
+	deploy.PersistentFlags().StringVarP(&opts.output, "output", "o", "", "output format. One of: \"\" (full log), \"name\" (created objects only, log on stderr), \"events\" (NDJSON progress events, log on stderr).")
+	deploy.Flags().BoolVar(&opts.checkFeatureGates, "check-feature-gates", false, "check the feature gates required by the components are enabled in the cluster, where discoverable.")
+	deploy.Flags().BoolVar(&opts.strict, "strict", false, "fail if the preflight checks report any issue, instead of just warning.")
+	deploy.PersistentFlags().StringVar(&opts.reportFile, "report-file", "", "write in this file the JSON report of the objects acted on, also on failure.")
+	deploy.PersistentFlags().BoolVar(&opts.dryRun, "dry-run", false, "report the objects which would be created or updated, without changing the cluster. Fails if anything would change.")
+	deploy.Flags().BoolVar(&opts.prune, "prune", false, "after deploying, delete the objects created by the previous deployments which are not deployed anymore. Honors --wait.")
+	deploy.Flags().BoolVar(&opts.producerOnly, "producer-only", false, "deploy only the API and the topology updater, for clusters whose scheduler already consumes the NodeResourceTopology objects.")
 	deploy.AddCommand(NewDeployAPICommand(commonOpts, opts))
 	deploy.AddCommand(NewDeploySchedulerPluginCommand(commonOpts, opts))
 	deploy.AddCommand(NewDeployTopologyUpdaterCommand(commonOpts, opts))
@@ -55,47 +90,84 @@ func NewRemoveCommand(commonOpts *CommonOptions) *cobra.Command {
 	remove := &cobra.Command{
 		Use:   "remove",
 		Short: "remove the components and configurations needed for topology-aware-scheduling",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			la := tlog.NewLogAdapter(commonOpts.Log, commonOpts.DebugLog)
-			platDetect := detectPlatform(commonOpts.DebugLog, commonOpts.UserPlatform)
+		RunE: opts.withReport(func(cmd *cobra.Command, args []string) error {
+			la := newLogAdapter(commonOpts, commonOpts.Log, commonOpts.DebugLog)
+			if opts.byLabel {
+				return removeByLabel(cmd.Context(), la, commonOpts, opts)
+			}
+			platDetect := detectPlatform(commonOpts.DebugLog, commonOpts.UserPlatform, commonOpts.DetectTimeout)
 			opts.clusterPlatform = platDetect.Discovered
-			if opts.clusterPlatform == platform.Unknown {
-				return fmt.Errorf("cannot autodetect the platform, and no platform given")
+			if err := platDetect.Err(); err != nil {
+				return err
 			}
 
 			var err error
-			err = sched.Remove(la, sched.Options{
-				Platform:         opts.clusterPlatform,
-				WaitCompletion:   opts.waitCompletion,
-				RTEConfigData:    commonOpts.RTEConfigData,
-				PullIfNotPresent: commonOpts.PullIfNotPresent,
-			})
+			err = opts.recordResult(sched.Remove(cmd.Context(), la, sched.Options{
+				Platform:               opts.clusterPlatform,
+				ManifestsSource:        commonOpts.ManifestsSource,
+				WaitCompletion:         opts.waitCompletion,
+				WaitTimeout:            commonOpts.WaitTimeout,
+				PollInterval:           commonOpts.PollInterval,
+				RTEConfigData:          commonOpts.RTEConfigData,
+				PullIfNotPresent:       commonOpts.PullIfNotPresent,
+				Mode:                   commonOpts.SchedulerMode,
+				SchedulerName:          commonOpts.SchedulerName,
+				EnforcedPodSelector:    commonOpts.SchedulerEnforcedPodSelector,
+				NodeResourcesNamespace: commonOpts.UpdaterNamespace,
+				Force:                  opts.force,
+				IgnoreNotFound:         opts.ignoreNotFound,
+			}))
 			if err != nil {
 				// intentionally keep going to remove as much as possible
 				la.Printf("error removing: %v", err)
 			}
-			err = rte.Remove(la, rte.Options{
-				Platform:         opts.clusterPlatform,
-				WaitCompletion:   opts.waitCompletion,
-				RTEConfigData:    commonOpts.RTEConfigData,
-				PullIfNotPresent: commonOpts.PullIfNotPresent,
-			})
+			if commonOpts.SchedulerName != "" {
+				la.Printf("kept the topology updater and the API, shared with the other scheduler plugin instances")
+				return nil
+			}
+			err = opts.recordResult(rte.Remove(cmd.Context(), la, rte.Options{
+				Platform:              opts.clusterPlatform,
+				ManifestsSource:       commonOpts.ManifestsSource,
+				WaitCompletion:        opts.waitCompletion,
+				WaitTimeout:           commonOpts.WaitTimeout,
+				PollInterval:          commonOpts.PollInterval,
+				RTEConfigData:         commonOpts.RTEConfigData,
+				ImmutableConfig:       commonOpts.RTEImmutableConfig,
+				ConfigMapName:         commonOpts.RTEConfigMapName,
+				PullIfNotPresent:      commonOpts.PullIfNotPresent,
+				ForceRemoveFinalizers: opts.forceRemoveFinalizers,
+				Force:                 opts.force,
+				IgnoreNotFound:        opts.ignoreNotFound,
+				Namespace:             commonOpts.UpdaterNamespace,
+				SkipNamespace:         commonOpts.UpdaterSkipNamespace,
+				WithNetworkPolicy:     commonOpts.NetworkPolicy,
+				WithServiceMonitor:    commonOpts.ServiceMonitor,
+			}))
 			if err != nil {
 				// intentionally keep going to remove as much as possible
 				la.Printf("error removing: %v", err)
 			}
-			err = api.Remove(la, api.Options{
-				Platform: opts.clusterPlatform,
-			})
+			err = opts.recordResult(api.Remove(cmd.Context(), la, api.Options{
+				Platform:        opts.clusterPlatform,
+				ManifestsSource: commonOpts.ManifestsSource,
+				APIGroup:        commonOpts.APIGroup,
+				Force:           opts.force,
+				IgnoreNotFound:  opts.ignoreNotFound,
+			}))
 			if err != nil {
 				// intentionally keep going to remove as much as possible
 				la.Printf("error removing: %v", err)
 			}
 			return nil
-		},
+		}),
 		Args: cobra.NoArgs,
 	}
 	remove.PersistentFlags().BoolVarP(&opts.waitCompletion, "wait", "W", false, "wait for removal to be all completed.")
+	remove.PersistentFlags().BoolVar(&opts.forceRemoveFinalizers, "force-remove-finalizers", false, "clear the topology updater finalizers instead of waiting for the external controllers to do it.")
+	remove.PersistentFlags().BoolVar(&opts.force, "force", false, fmt.Sprintf("clear the finalizers of the objects created by the deployer still there %v after their deletion, like a CRD stuck terminating.", deployer.DefaultForceGracePeriod))
+	remove.PersistentFlags().BoolVar(&opts.ignoreNotFound, "ignore-not-found", false, "do not report the objects already gone, e.g. never deployed, keeping idempotent teardowns quiet. Other failures are still reported.")
+	remove.PersistentFlags().StringVar(&opts.reportFile, "report-file", "", "write in this file the JSON report of the objects acted on, also on failure.")
+	remove.Flags().BoolVar(&opts.byLabel, "by-label", false, "remove all the objects labeled as created by the deployer, instead of the ones in the manifests of this version.")
 	remove.AddCommand(NewRemoveAPICommand(commonOpts, opts))
 	remove.AddCommand(NewRemoveSchedulerPluginCommand(commonOpts, opts))
 	remove.AddCommand(NewRemoveTopologyUpdaterCommand(commonOpts, opts))
@@ -106,18 +178,37 @@ func NewDeployAPICommand(commonOpts *CommonOptions, opts *deployOptions) *cobra.
 	deploy := &cobra.Command{
 		Use:   "api",
 		Short: "deploy the APIs needed for topology-aware-scheduling",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			la := tlog.NewLogAdapter(commonOpts.Log, commonOpts.DebugLog)
-			platDetect := detectPlatform(commonOpts.DebugLog, commonOpts.UserPlatform)
+		RunE: opts.withReport(opts.withSummary(func(cmd *cobra.Command, args []string) error {
+			la, err := newDeployLogAdapter(commonOpts, opts)
+			if err != nil {
+				return err
+			}
+			platDetect := detectPlatform(commonOpts.DebugLog, commonOpts.UserPlatform, commonOpts.DetectTimeout)
 			opts.clusterPlatform = platDetect.Discovered
-			if opts.clusterPlatform == platform.Unknown {
-				return fmt.Errorf("cannot autodetect the platform, and no platform given")
+			if err := platDetect.Err(); err != nil {
+				return err
 			}
-			if err := api.Deploy(la, api.Options{Platform: opts.clusterPlatform}); err != nil {
+			if err := opts.recordResult(api.Deploy(cmd.Context(), la, api.Options{
+				Platform:         opts.clusterPlatform,
+				ManifestsSource:  commonOpts.ManifestsSource,
+				ServedVersions:   commonOpts.APIServedVersions,
+				StorageVersion:   commonOpts.APIStorageVersion,
+				Categories:       commonOpts.APICategories,
+				ShortNames:       commonOpts.APIShortNames,
+				APIGroup:         commonOpts.APIGroup,
+				DryRun:           opts.dryRun,
+				OnCreate:         opts.onCreate(),
+				ExtraLabels:      deployLabels(commonOpts),
+				ExtraAnnotations: deployAnnotations(commonOpts),
+				WaitCompletion:   opts.waitCompletion,
+				WaitTimeout:      commonOpts.WaitTimeout,
+				PollInterval:     commonOpts.PollInterval,
+				OnReady:          opts.onReady(),
+			})); err != nil {
 				return err
 			}
 			return nil
-		},
+		})),
 		Args: cobra.NoArgs,
 	}
 	return deploy
@@ -127,20 +218,50 @@ func NewDeploySchedulerPluginCommand(commonOpts *CommonOptions, opts *deployOpti
 	deploy := &cobra.Command{
 		Use:   "scheduler-plugin",
 		Short: "deploy the scheduler plugin needed for topology-aware-scheduling",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			la := tlog.NewLogAdapter(commonOpts.Log, commonOpts.DebugLog)
-			platDetect := detectPlatform(commonOpts.DebugLog, commonOpts.UserPlatform)
+		RunE: opts.withReport(opts.withSummary(func(cmd *cobra.Command, args []string) error {
+			la, err := newDeployLogAdapter(commonOpts, opts)
+			if err != nil {
+				return err
+			}
+			platDetect := detectPlatform(commonOpts.DebugLog, commonOpts.UserPlatform, commonOpts.DetectTimeout)
 			opts.clusterPlatform = platDetect.Discovered
-			if opts.clusterPlatform == platform.Unknown {
-				return fmt.Errorf("cannot autodetect the platform, and no platform given")
+			if err := platDetect.Err(); err != nil {
+				return err
 			}
-			return sched.Deploy(la, sched.Options{
-				Platform:         opts.clusterPlatform,
-				WaitCompletion:   opts.waitCompletion,
-				RTEConfigData:    commonOpts.RTEConfigData,
-				PullIfNotPresent: commonOpts.PullIfNotPresent,
-			})
-		},
+			return opts.recordResult(sched.Deploy(cmd.Context(), la, sched.Options{
+				Platform:               opts.clusterPlatform,
+				ManifestsSource:        commonOpts.ManifestsSource,
+				WaitCompletion:         opts.waitCompletion,
+				WaitTimeout:            commonOpts.WaitTimeout,
+				PollInterval:           commonOpts.PollInterval,
+				Replicas:               int32(commonOpts.Replicas),
+				RTEConfigData:          commonOpts.RTEConfigData,
+				PullIfNotPresent:       commonOpts.PullIfNotPresent,
+				ImagePullSecrets:       commonOpts.ImagePullSecrets,
+				PriorityClassName:      commonOpts.PriorityClassName,
+				WithNetworkPolicy:      commonOpts.NetworkPolicy,
+				SpreadReplicas:         commonOpts.SchedulerSpreadReplicas,
+				Tolerations:            commonOpts.SchedulerTolerations,
+				PodNodeAffinity:        commonOpts.SchedulerPodNodeAffinity,
+				LeaderElect:            commonOpts.SchedulerLeaderElect,
+				ScoringStrategy:        commonOpts.SchedulerScoringStrategy,
+				Mode:                   commonOpts.SchedulerMode,
+				NodeSelector:           commonOpts.SchedulerNodeSelector,
+				FeatureGates:           commonOpts.SchedulerFeatureGates,
+				PodSchedulerName:       commonOpts.SchedulerPodSchedulerName,
+				SchedulerName:          commonOpts.SchedulerName,
+				APIGroup:               commonOpts.APIGroup,
+				TokenExpirationSeconds: commonOpts.SchedulerTokenExpirationSeconds,
+				TokenAudience:          commonOpts.SchedulerTokenAudience,
+				EnforcedPodSelector:    commonOpts.SchedulerEnforcedPodSelector,
+				NodeResourcesNamespace: commonOpts.UpdaterNamespace,
+				DryRun:                 opts.dryRun,
+				OnCreate:               opts.onCreate(),
+				ExtraLabels:            deployLabels(commonOpts),
+				ExtraAnnotations:       deployAnnotations(commonOpts),
+				OnReady:                opts.onReady(),
+			}))
+		})),
 		Args: cobra.NoArgs,
 	}
 	return deploy
@@ -150,20 +271,50 @@ func NewDeployTopologyUpdaterCommand(commonOpts *CommonOptions, opts *deployOpti
 	deploy := &cobra.Command{
 		Use:   "topology-updater",
 		Short: "deploy the topology updater needed for topology-aware-scheduling",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			la := tlog.NewLogAdapter(commonOpts.Log, commonOpts.DebugLog)
-			platDetect := detectPlatform(commonOpts.DebugLog, commonOpts.UserPlatform)
+		RunE: opts.withReport(opts.withSummary(func(cmd *cobra.Command, args []string) error {
+			la, err := newDeployLogAdapter(commonOpts, opts)
+			if err != nil {
+				return err
+			}
+			platDetect := detectPlatform(commonOpts.DebugLog, commonOpts.UserPlatform, commonOpts.DetectTimeout)
 			opts.clusterPlatform = platDetect.Discovered
-			if opts.clusterPlatform == platform.Unknown {
-				return fmt.Errorf("cannot autodetect the platform, and no platform given")
+			if err := platDetect.Err(); err != nil {
+				return err
 			}
-			return rte.Deploy(la, rte.Options{
-				Platform:         opts.clusterPlatform,
-				WaitCompletion:   opts.waitCompletion,
-				RTEConfigData:    commonOpts.RTEConfigData,
-				PullIfNotPresent: commonOpts.PullIfNotPresent,
-			})
-		},
+			return opts.recordResult(rte.Deploy(cmd.Context(), la, rte.Options{
+				Platform:                     opts.clusterPlatform,
+				ManifestsSource:              commonOpts.ManifestsSource,
+				WaitCompletion:               opts.waitCompletion,
+				WaitTimeout:                  commonOpts.WaitTimeout,
+				PollInterval:                 commonOpts.PollInterval,
+				RTEConfigData:                commonOpts.RTEConfigData,
+				ImmutableConfig:              commonOpts.RTEImmutableConfig,
+				ConfigMapName:                commonOpts.RTEConfigMapName,
+				PullIfNotPresent:             commonOpts.PullIfNotPresent,
+				ImagePullSecrets:             commonOpts.ImagePullSecrets,
+				PriorityClassName:            commonOpts.PriorityClassName,
+				WithNetworkPolicy:            commonOpts.NetworkPolicy,
+				WithServiceMonitor:           commonOpts.ServiceMonitor,
+				AllNodes:                     commonOpts.AllNodes,
+				NodeSelector:                 commonOpts.RTENodeSelector,
+				Tolerations:                  commonOpts.RTETolerations,
+				StartupProbeFailureThreshold: commonOpts.RTEStartupProbeFailureThreshold,
+				StartupProbePeriodSeconds:    commonOpts.RTEStartupProbePeriodSeconds,
+				Finalizers:                   commonOpts.RTEFinalizers,
+				MaxUnavailable:               commonOpts.RTEMaxUnavailable,
+				HostNetwork:                  commonOpts.RTEHostNetwork,
+				DNSPolicy:                    commonOpts.RTEDNSPolicy,
+				PodSchedulerName:             commonOpts.RTEPodSchedulerName,
+				Namespace:                    commonOpts.UpdaterNamespace,
+				SkipNamespace:                commonOpts.UpdaterSkipNamespace,
+				APIGroup:                     commonOpts.APIGroup,
+				DryRun:                       opts.dryRun,
+				OnCreate:                     opts.onCreate(),
+				ExtraLabels:                  deployLabels(commonOpts),
+				ExtraAnnotations:             deployAnnotations(commonOpts),
+				OnReady:                      opts.onReady(),
+			}))
+		})),
 		Args: cobra.NoArgs,
 	}
 	return deploy
@@ -173,19 +324,25 @@ func NewRemoveAPICommand(commonOpts *CommonOptions, opts *deployOptions) *cobra.
 	remove := &cobra.Command{
 		Use:   "api",
 		Short: "remove the APIs needed for topology-aware-scheduling",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			la := tlog.NewLogAdapter(commonOpts.Log, commonOpts.DebugLog)
-			platDetect := detectPlatform(commonOpts.DebugLog, commonOpts.UserPlatform)
+		RunE: opts.withReport(func(cmd *cobra.Command, args []string) error {
+			la := newLogAdapter(commonOpts, commonOpts.Log, commonOpts.DebugLog)
+			platDetect := detectPlatform(commonOpts.DebugLog, commonOpts.UserPlatform, commonOpts.DetectTimeout)
 			opts.clusterPlatform = platDetect.Discovered
-			if opts.clusterPlatform == platform.Unknown {
-				return fmt.Errorf("cannot autodetect the platform, and no platform given")
+			if err := platDetect.Err(); err != nil {
+				return err
 			}
 
-			if err := api.Remove(la, api.Options{Platform: opts.clusterPlatform}); err != nil {
+			if err := opts.recordResult(api.Remove(cmd.Context(), la, api.Options{
+				Platform:        opts.clusterPlatform,
+				ManifestsSource: commonOpts.ManifestsSource,
+				APIGroup:        commonOpts.APIGroup,
+				Force:           opts.force,
+				IgnoreNotFound:  opts.ignoreNotFound,
+			})); err != nil {
 				return err
 			}
 			return nil
-		},
+		}),
 		Args: cobra.NoArgs,
 	}
 	return remove
@@ -195,20 +352,29 @@ func NewRemoveSchedulerPluginCommand(commonOpts *CommonOptions, opts *deployOpti
 	remove := &cobra.Command{
 		Use:   "scheduler-plugin",
 		Short: "remove the scheduler plugin needed for topology-aware-scheduling",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			la := tlog.NewLogAdapter(commonOpts.Log, commonOpts.DebugLog)
-			platDetect := detectPlatform(commonOpts.DebugLog, commonOpts.UserPlatform)
+		RunE: opts.withReport(func(cmd *cobra.Command, args []string) error {
+			la := newLogAdapter(commonOpts, commonOpts.Log, commonOpts.DebugLog)
+			platDetect := detectPlatform(commonOpts.DebugLog, commonOpts.UserPlatform, commonOpts.DetectTimeout)
 			opts.clusterPlatform = platDetect.Discovered
-			if opts.clusterPlatform == platform.Unknown {
-				return fmt.Errorf("cannot autodetect the platform, and no platform given")
+			if err := platDetect.Err(); err != nil {
+				return err
 			}
-			return sched.Remove(la, sched.Options{
-				Platform:         opts.clusterPlatform,
-				WaitCompletion:   opts.waitCompletion,
-				RTEConfigData:    commonOpts.RTEConfigData,
-				PullIfNotPresent: commonOpts.PullIfNotPresent,
-			})
-		},
+			return opts.recordResult(sched.Remove(cmd.Context(), la, sched.Options{
+				Platform:               opts.clusterPlatform,
+				ManifestsSource:        commonOpts.ManifestsSource,
+				WaitCompletion:         opts.waitCompletion,
+				WaitTimeout:            commonOpts.WaitTimeout,
+				PollInterval:           commonOpts.PollInterval,
+				RTEConfigData:          commonOpts.RTEConfigData,
+				PullIfNotPresent:       commonOpts.PullIfNotPresent,
+				Mode:                   commonOpts.SchedulerMode,
+				SchedulerName:          commonOpts.SchedulerName,
+				EnforcedPodSelector:    commonOpts.SchedulerEnforcedPodSelector,
+				NodeResourcesNamespace: commonOpts.UpdaterNamespace,
+				Force:                  opts.force,
+				IgnoreNotFound:         opts.ignoreNotFound,
+			}))
+		}),
 		Args: cobra.NoArgs,
 	}
 	return remove
@@ -218,52 +384,332 @@ func NewRemoveTopologyUpdaterCommand(commonOpts *CommonOptions, opts *deployOpti
 	remove := &cobra.Command{
 		Use:   "topology-updater",
 		Short: "remove the topology updater needed for topology-aware-scheduling",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			la := tlog.NewLogAdapter(commonOpts.Log, commonOpts.DebugLog)
-			platDetect := detectPlatform(commonOpts.DebugLog, commonOpts.UserPlatform)
+		RunE: opts.withReport(func(cmd *cobra.Command, args []string) error {
+			la := newLogAdapter(commonOpts, commonOpts.Log, commonOpts.DebugLog)
+			platDetect := detectPlatform(commonOpts.DebugLog, commonOpts.UserPlatform, commonOpts.DetectTimeout)
 			opts.clusterPlatform = platDetect.Discovered
-			if opts.clusterPlatform == platform.Unknown {
-				return fmt.Errorf("cannot autodetect the platform, and no platform given")
+			if err := platDetect.Err(); err != nil {
+				return err
 			}
-			return rte.Remove(la, rte.Options{
-				Platform:         opts.clusterPlatform,
-				WaitCompletion:   opts.waitCompletion,
-				RTEConfigData:    commonOpts.RTEConfigData,
-				PullIfNotPresent: commonOpts.PullIfNotPresent,
-			})
-		},
+			return opts.recordResult(rte.Remove(cmd.Context(), la, rte.Options{
+				Platform:              opts.clusterPlatform,
+				ManifestsSource:       commonOpts.ManifestsSource,
+				WaitCompletion:        opts.waitCompletion,
+				WaitTimeout:           commonOpts.WaitTimeout,
+				PollInterval:          commonOpts.PollInterval,
+				RTEConfigData:         commonOpts.RTEConfigData,
+				ImmutableConfig:       commonOpts.RTEImmutableConfig,
+				ConfigMapName:         commonOpts.RTEConfigMapName,
+				PullIfNotPresent:      commonOpts.PullIfNotPresent,
+				ForceRemoveFinalizers: opts.forceRemoveFinalizers,
+				Force:                 opts.force,
+				IgnoreNotFound:        opts.ignoreNotFound,
+				Namespace:             commonOpts.UpdaterNamespace,
+				SkipNamespace:         commonOpts.UpdaterSkipNamespace,
+				WithNetworkPolicy:     commonOpts.NetworkPolicy,
+				WithServiceMonitor:    commonOpts.ServiceMonitor,
+			}))
+		}),
 		Args: cobra.NoArgs,
 	}
 	return remove
 }
 
-func deployOnCluster(commonOpts *CommonOptions, opts *deployOptions) error {
-	la := tlog.NewLogAdapter(commonOpts.Log, commonOpts.DebugLog)
-	platDetect := detectPlatform(commonOpts.DebugLog, commonOpts.UserPlatform)
-	opts.clusterPlatform = platDetect.Discovered
-	if opts.clusterPlatform == platform.Unknown {
-		return fmt.Errorf("cannot autodetect the platform, and no platform given")
+func deployOnCluster(ctx context.Context, commonOpts *CommonOptions, opts *deployOptions) error {
+	la, err := newDeployLogAdapter(commonOpts, opts)
+	if err != nil {
+		return err
 	}
-	if err := api.Deploy(la, api.Options{
-		Platform: opts.clusterPlatform,
-	}); err != nil {
+	platDetect := detectPlatform(commonOpts.DebugLog, commonOpts.UserPlatform, commonOpts.DetectTimeout)
+	opts.clusterPlatform = platDetect.Discovered
+	if err := platDetect.Err(); err != nil {
 		return err
 	}
-	if err := rte.Deploy(la, rte.Options{
+	if opts.checkFeatureGates {
+		if err := checkFeatureGates(la, commonOpts, opts); err != nil {
+			return err
+		}
+	}
+	// in dry-run mode all the components are checked, even if some would change
+	dryRunChanged := false
+	if err := foldDryRun(opts.recordResult(api.Deploy(ctx, la, api.Options{
 		Platform:         opts.clusterPlatform,
+		ManifestsSource:  commonOpts.ManifestsSource,
+		ServedVersions:   commonOpts.APIServedVersions,
+		StorageVersion:   commonOpts.APIStorageVersion,
+		Categories:       commonOpts.APICategories,
+		ShortNames:       commonOpts.APIShortNames,
+		APIGroup:         commonOpts.APIGroup,
+		DryRun:           opts.dryRun,
+		OnCreate:         opts.onCreate(),
+		ExtraLabels:      deployLabels(commonOpts),
+		ExtraAnnotations: deployAnnotations(commonOpts),
 		WaitCompletion:   opts.waitCompletion,
-		RTEConfigData:    commonOpts.RTEConfigData,
-		PullIfNotPresent: commonOpts.PullIfNotPresent,
-	}); err != nil {
+		WaitTimeout:      commonOpts.WaitTimeout,
+		PollInterval:     commonOpts.PollInterval,
+		OnReady:          opts.onReady(),
+	})), &dryRunChanged); err != nil {
 		return err
 	}
-	if err := sched.Deploy(la, sched.Options{
-		Platform:         opts.clusterPlatform,
-		WaitCompletion:   opts.waitCompletion,
-		RTEConfigData:    commonOpts.RTEConfigData,
-		PullIfNotPresent: commonOpts.PullIfNotPresent,
-	}); err != nil {
+	if err := foldDryRun(opts.recordResult(rte.Deploy(ctx, la, rte.Options{
+		Platform:                     opts.clusterPlatform,
+		ManifestsSource:              commonOpts.ManifestsSource,
+		WaitCompletion:               opts.waitCompletion,
+		WaitTimeout:                  commonOpts.WaitTimeout,
+		PollInterval:                 commonOpts.PollInterval,
+		RTEConfigData:                commonOpts.RTEConfigData,
+		ImmutableConfig:              commonOpts.RTEImmutableConfig,
+		ConfigMapName:                commonOpts.RTEConfigMapName,
+		PullIfNotPresent:             commonOpts.PullIfNotPresent,
+		ImagePullSecrets:             commonOpts.ImagePullSecrets,
+		PriorityClassName:            commonOpts.PriorityClassName,
+		WithNetworkPolicy:            commonOpts.NetworkPolicy,
+		WithServiceMonitor:           commonOpts.ServiceMonitor,
+		AllNodes:                     commonOpts.AllNodes,
+		NodeSelector:                 commonOpts.RTENodeSelector,
+		Tolerations:                  commonOpts.RTETolerations,
+		StartupProbeFailureThreshold: commonOpts.RTEStartupProbeFailureThreshold,
+		StartupProbePeriodSeconds:    commonOpts.RTEStartupProbePeriodSeconds,
+		Finalizers:                   commonOpts.RTEFinalizers,
+		MaxUnavailable:               commonOpts.RTEMaxUnavailable,
+		HostNetwork:                  commonOpts.RTEHostNetwork,
+		DNSPolicy:                    commonOpts.RTEDNSPolicy,
+		PodSchedulerName:             commonOpts.RTEPodSchedulerName,
+		Namespace:                    commonOpts.UpdaterNamespace,
+		SkipNamespace:                commonOpts.UpdaterSkipNamespace,
+		APIGroup:                     commonOpts.APIGroup,
+		DryRun:                       opts.dryRun,
+		OnCreate:                     opts.onCreate(),
+		ExtraLabels:                  deployLabels(commonOpts),
+		ExtraAnnotations:             deployAnnotations(commonOpts),
+		OnReady:                      opts.onReady(),
+	})), &dryRunChanged); err != nil {
 		return err
 	}
+	if opts.producerOnly {
+		la.Printf("producer-only mode: skipped the scheduler plugin, the NodeResourceTopology objects are left to the cluster scheduler")
+	} else if err := foldDryRun(opts.recordResult(sched.Deploy(ctx, la, sched.Options{
+		Platform:               opts.clusterPlatform,
+		ManifestsSource:        commonOpts.ManifestsSource,
+		WaitCompletion:         opts.waitCompletion,
+		WaitTimeout:            commonOpts.WaitTimeout,
+		PollInterval:           commonOpts.PollInterval,
+		Replicas:               int32(commonOpts.Replicas),
+		RTEConfigData:          commonOpts.RTEConfigData,
+		PullIfNotPresent:       commonOpts.PullIfNotPresent,
+		ImagePullSecrets:       commonOpts.ImagePullSecrets,
+		PriorityClassName:      commonOpts.PriorityClassName,
+		WithNetworkPolicy:      commonOpts.NetworkPolicy,
+		SpreadReplicas:         commonOpts.SchedulerSpreadReplicas,
+		Tolerations:            commonOpts.SchedulerTolerations,
+		PodNodeAffinity:        commonOpts.SchedulerPodNodeAffinity,
+		LeaderElect:            commonOpts.SchedulerLeaderElect,
+		ScoringStrategy:        commonOpts.SchedulerScoringStrategy,
+		Mode:                   commonOpts.SchedulerMode,
+		NodeSelector:           commonOpts.SchedulerNodeSelector,
+		FeatureGates:           commonOpts.SchedulerFeatureGates,
+		PodSchedulerName:       commonOpts.SchedulerPodSchedulerName,
+		SchedulerName:          commonOpts.SchedulerName,
+		APIGroup:               commonOpts.APIGroup,
+		TokenExpirationSeconds: commonOpts.SchedulerTokenExpirationSeconds,
+		TokenAudience:          commonOpts.SchedulerTokenAudience,
+		EnforcedPodSelector:    commonOpts.SchedulerEnforcedPodSelector,
+		NodeResourcesNamespace: commonOpts.UpdaterNamespace,
+		DryRun:                 opts.dryRun,
+		OnCreate:               opts.onCreate(),
+		ExtraLabels:            deployLabels(commonOpts),
+		ExtraAnnotations:       deployAnnotations(commonOpts),
+		OnReady:                opts.onReady(),
+	})), &dryRunChanged); err != nil {
+		return err
+	}
+	if opts.prune {
+		if err := foldDryRun(pruneObjects(ctx, la, commonOpts, opts), &dryRunChanged); err != nil {
+			return err
+		}
+	}
+	if dryRunChanged {
+		return deployer.ErrDryRunChanges
+	}
+	return nil
+}
+
+// deployLabels returns the labels of the deployed objects: the user-supplied ones, and the ones marking
+// the objects as created by the deployer for the scheduler plugin instance, which must win to let the
+// later deployments of the same instance prune them.
+func deployLabels(commonOpts *CommonOptions) map[string]string {
+	instanceLabels := manifests.InstanceLabels(deployInstance(commonOpts))
+	ret := make(map[string]string, len(commonOpts.ExtraLabels)+len(instanceLabels))
+	for key, val := range commonOpts.ExtraLabels {
+		ret[key] = val
+	}
+	for key, val := range instanceLabels {
+		ret[key] = val
+	}
+	return ret
+}
+
+// deployInstance returns the name of the scheduler plugin instance the deployment is for.
+func deployInstance(commonOpts *CommonOptions) string {
+	if commonOpts.SchedulerName != "" {
+		return commonOpts.SchedulerName
+	}
+	return schedmanifests.SchedulerName
+}
+
+// deployAnnotations returns the annotations to add to the deployed objects: the user-supplied ones
+// and the deployer version, read back by upgrade.
+func deployAnnotations(commonOpts *CommonOptions) map[string]string {
+	ret := make(map[string]string, len(commonOpts.ExtraAnnotations)+1)
+	for key, val := range commonOpts.ExtraAnnotations {
+		ret[key] = val
+	}
+	ret[manifests.AnnotationDeployerVersion] = deployerversion.GitVersion
+	return ret
+}
+
+// pruneObjects deletes the objects created by the previous deployments which are not deployed anymore.
+// In dry-run mode, reports them instead.
+func pruneObjects(ctx context.Context, la tlog.Logger, commonOpts *CommonOptions, opts *deployOptions) error {
+	clusterOpts := *commonOpts
+	clusterOpts.UserPlatform = opts.clusterPlatform
+	// a namespace created by a previous deployment is not stale because it is not managed anymore
+	clusterOpts.UpdaterSkipNamespace = false
+	comps, err := makeComponentObjects(&clusterOpts, nil)
+	if err != nil {
+		return err
+	}
+	if opts.producerOnly {
+		comps, err = selectComponentObjects(comps, []string{componentAPI, componentTopologyUpdater})
+		if err != nil {
+			return err
+		}
+	}
+
+	// the other scheduler plugin instances are deployed separately, leave their objects alone
+	stale, err := objects.Stale(ctx, la, flattenComponentObjects(comps), manifests.InstanceLabels(deployInstance(commonOpts)))
+	if err != nil {
+		return fmt.Errorf("cannot find the objects to prune: %w", err)
+	}
+	if len(stale) == 0 {
+		la.Printf("no objects to prune")
+		return nil
+	}
+	if opts.dryRun {
+		for _, obj := range stale {
+			desc := manifests.ObjectName(obj)
+			if obj.GetNamespace() != "" {
+				desc += " in namespace " + obj.GetNamespace()
+			}
+			la.Printf("would prune %s", desc)
+		}
+		return deployer.ErrDryRunChanges
+	}
+	return opts.recordResult(objects.Remove(ctx, la, stale, objects.Options{
+		WaitCompletion: opts.waitCompletion,
+		WaitTimeout:    commonOpts.WaitTimeout,
+		PollInterval:   commonOpts.PollInterval,
+	}))
+}
+
+// removeByLabel deletes all the objects labeled as created by the deployer, without rendering the
+// manifests, so the removal works even if they changed since the objects were deployed.
+func removeByLabel(ctx context.Context, la tlog.Logger, commonOpts *CommonOptions, opts *deployOptions) error {
+	if opts.forceRemoveFinalizers {
+		return fmt.Errorf("cannot force the removal of the finalizers when removing by label")
+	}
+	objs, err := objects.Labeled(ctx, la, map[string]string{
+		manifests.LabelManagedBy: manifests.ManagedByDeployer,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot find the objects to remove: %w", err)
+	}
+	if commonOpts.UpdaterSkipNamespace {
+		objs = skipUpdaterNamespace(la, commonOpts, objs)
+	}
+	return opts.recordResult(objects.Remove(ctx, la, objs, objects.Options{
+		WaitCompletion: opts.waitCompletion,
+		WaitTimeout:    commonOpts.WaitTimeout,
+		PollInterval:   commonOpts.PollInterval,
+		Force:          opts.force,
+		IgnoreNotFound: opts.ignoreNotFound,
+	}))
+}
+
+// skipUpdaterNamespace filters out the topology updater namespace, managed by others.
+func skipUpdaterNamespace(la tlog.Logger, commonOpts *CommonOptions, objs []client.Object) []client.Object {
+	namespace := commonOpts.UpdaterNamespace
+	if namespace == "" {
+		_, namespace, _ = rte.SetupNamespace(platform.Kubernetes)
+	}
+	var ret []client.Object
+	for _, obj := range objs {
+		if obj.GetObjectKind().GroupVersionKind().Kind == "Namespace" && obj.GetName() == namespace {
+			la.Printf("skipping the namespace %q, not managed by the deployer", namespace)
+			continue
+		}
+		ret = append(ret, obj)
+	}
+	return ret
+}
+
+// foldDryRun returns err, unless it only reports the dry-run changes, which are recorded in changed.
+func foldDryRun(err error, changed *bool) error {
+	if errors.Is(err, deployer.ErrDryRunChanges) {
+		*changed = true
+		return nil
+	}
+	return err
+}
+
+// newDeployLogAdapter returns the logger for the deploy flows. When only the
+// object names or the events are requested, the log is moved to stderr so
+// stdout can be consumed by other tools.
+func newDeployLogAdapter(commonOpts *CommonOptions, opts *deployOptions) (tlog.Logger, error) {
+	if opts.output != outputEvents {
+		if err := validateOutput(opts.output); err != nil {
+			return nil, err
+		}
+	}
+	if opts.output == outputName || opts.output == outputEvents {
+		return newLogAdapter(commonOpts, log.New(os.Stderr, "", log.LstdFlags), commonOpts.DebugLog), nil
+	}
+	return newLogAdapter(commonOpts, commonOpts.Log, commonOpts.DebugLog), nil
+}
+
+func (opts *deployOptions) onCreate() deployer.ObjectFunc {
+	if opts.events != nil {
+		return opts.events.objectCreated
+	}
+	if opts.output != outputName {
+		return nil
+	}
+	return func(obj client.Object) {
+		fmt.Println(manifests.ObjectName(obj))
+	}
+}
+
+func (opts *deployOptions) onReady() deployer.ObjectFunc {
+	if opts.events == nil {
+		return nil
+	}
+	return opts.events.objectReady
+}
+
+// checkFeatureGates warns about the required feature gates found disabled, failing in strict mode.
+func checkFeatureGates(la tlog.Logger, commonOpts *CommonOptions, opts *deployOptions) error {
+	vd := validator.Validator{
+		Log: commonOpts.DebugLog,
+	}
+	items, err := vd.ValidateAPIServerFeatureGates()
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		la.Printf("WARNING: required feature gate %q is disabled", item.Setting)
+	}
+	if opts.strict && len(items) > 0 {
+		return fmt.Errorf("%d required feature gates disabled", len(items))
+	}
 	return nil
 }
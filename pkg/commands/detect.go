@@ -17,10 +17,13 @@
 package commands
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -38,7 +41,7 @@ func NewDetectCommand(commonOpts *CommonOptions) *cobra.Command {
 		Use:   "detect",
 		Short: "detect the cluster platform (kubernetes, openshift...)",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			platDetect := detectPlatform(commonOpts.DebugLog, commonOpts.UserPlatform)
+			platDetect := detectPlatform(commonOpts.DebugLog, commonOpts.UserPlatform, commonOpts.DetectTimeout)
 			if opts.jsonOutput {
 				json.NewEncoder(os.Stdout).Encode(platDetect)
 			} else {
@@ -56,9 +59,28 @@ type detectionOutput struct {
 	AutoDetected platform.Platform `json:"auto_detected"`
 	UserSupplied platform.Platform `json:"user_supplied"`
 	Discovered   platform.Platform `json:"discovered"`
+	// Version is the version of the discovered platform, empty if unknown.
+	// Detected only if the platform is autodetected.
+	Version platform.Version `json:"version,omitempty"`
+	// Reason is why the autodetection failed, if it did.
+	Reason string `json:"reason,omitempty"`
+	err    error
 }
 
-func detectPlatform(debugLog *log.Logger, userSupplied platform.Platform) detectionOutput {
+// Err returns nil if the platform was discovered, or an error telling why it was not.
+func (do detectionOutput) Err() error {
+	if do.Discovered != platform.Unknown {
+		return nil
+	}
+	if do.err != nil {
+		return fmt.Errorf("cannot autodetect the platform, and no platform given: %w", do.err)
+	}
+	return errors.New("cannot autodetect the platform, and no platform given")
+}
+
+// detectPlatform discovers the platform, unless supplied by the user. The detection requests are bounded by the
+// timeout: zero means detect.DefaultTimeout, negative waits indefinitely.
+func detectPlatform(debugLog *log.Logger, userSupplied platform.Platform, timeout time.Duration) detectionOutput {
 	do := detectionOutput{
 		AutoDetected: platform.Unknown,
 		UserSupplied: userSupplied,
@@ -71,14 +93,38 @@ func detectPlatform(debugLog *log.Logger, userSupplied platform.Platform) detect
 		return do
 	}
 
-	dp, err := detect.Detect()
+	if timeout == 0 {
+		timeout = detect.DefaultTimeout
+	}
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	dp, err := detect.DetectContext(ctx)
 	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("the cluster did not answer within %v, check it is reachable or raise --detect-timeout: %w", timeout, err)
+		}
 		debugLog.Printf("failed to detect the platform: %v", err)
+		do.Reason = err.Error()
+		do.err = err
 		return do
 	}
 
 	debugLog.Printf("auto-detected platform: %q", dp)
 	do.AutoDetected = dp
 	do.Discovered = do.AutoDetected
+
+	ver, err := detect.VersionContext(ctx, dp)
+	if err != nil {
+		// not fatal: the version only refines the platform
+		debugLog.Printf("failed to detect the platform version: %v", err)
+		return do
+	}
+	debugLog.Printf("auto-detected platform version: %q", ver)
+	do.Version = ver
 	return do
 }
@@ -0,0 +1,208 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/diff"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests"
+)
+
+const (
+	outputJSON    = "json"
+	outputUnified = "unified"
+
+	// diffContext is the count of unchanged lines shown around the changes in the unified diffs
+	diffContext = 3
+)
+
+// errDiffFound makes the diff command exit with failure if the cluster objects differ from the manifests.
+var errDiffFound = errors.New("the cluster objects differ from the manifests")
+
+// objectDrift is how an object differs from its cluster counterpart, which is nil if missing.
+type objectDrift struct {
+	diff.ObjectDiff
+	desired client.Object
+	live    *unstructured.Unstructured
+}
+
+type diffOptions struct {
+	output string
+}
+
+func NewDiffCommand(commonOpts *CommonOptions) *cobra.Command {
+	opts := &diffOptions{}
+	diffCmd := &cobra.Command{
+		Use:   "diff",
+		Short: "show the differences between the manifests and the objects on the cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.output != "" && opts.output != outputJSON && opts.output != outputUnified {
+				return fmt.Errorf("unsupported output format: %q", opts.output)
+			}
+			la := newLogAdapter(commonOpts, commonOpts.Log, commonOpts.DebugLog)
+			platDetect := detectPlatform(commonOpts.DebugLog, commonOpts.UserPlatform, commonOpts.DetectTimeout)
+			if err := platDetect.Err(); err != nil {
+				return err
+			}
+			clusterOpts := *commonOpts
+			clusterOpts.UserPlatform = platDetect.Discovered
+			objs, err := makeObjects(&clusterOpts, nil)
+			if err != nil {
+				return err
+			}
+			addExtraMetadata(commonOpts, objs)
+
+			hp, err := deployer.NewHelper("DIF", la)
+			if err != nil {
+				return err
+			}
+			drifts, err := diffObjects(hp, objs)
+			if err != nil {
+				return err
+			}
+
+			switch opts.output {
+			case outputJSON:
+				diffs := []diff.ObjectDiff{}
+				for _, od := range drifts {
+					diffs = append(diffs, od.ObjectDiff)
+				}
+				err = json.NewEncoder(os.Stdout).Encode(diffs)
+			case outputUnified:
+				err = writeDiffUnified(os.Stdout, drifts)
+			default:
+				writeDiffText(os.Stdout, drifts)
+			}
+			if err != nil {
+				return err
+			}
+			if len(drifts) > 0 {
+				return errDiffFound
+			}
+			return nil
+		},
+		Args: cobra.NoArgs,
+	}
+	diffCmd.Flags().StringVarP(&opts.output, "output", "o", "", "output format. One of: \"\" (changed paths), \"json\", \"unified\" (unified diff of the fields set in the manifests). Exits with failure if anything differs.")
+	return diffCmd
+}
+
+// diffObjects compares the objects with their cluster counterparts, returning only the objects which differ.
+func diffObjects(hp *deployer.Helper, objs []client.Object) ([]objectDrift, error) {
+	var drifts []objectDrift
+	for _, obj := range objs {
+		live := &unstructured.Unstructured{}
+		live.SetGroupVersionKind(obj.GetObjectKind().GroupVersionKind())
+		od := objectDrift{
+			ObjectDiff: diff.ObjectDiff{
+				Object: manifests.ObjectName(obj),
+			},
+			desired: obj,
+		}
+
+		err := hp.GetObject(client.ObjectKeyFromObject(obj), live)
+		if err != nil {
+			if !k8serrors.IsNotFound(err) {
+				return nil, err
+			}
+			od.Missing = true
+		} else {
+			od.live = live
+			od.Changes, err = diff.Compare(obj, live)
+			if err != nil {
+				return nil, fmt.Errorf("cannot compare %s: %w", od.Object, err)
+			}
+		}
+
+		if !od.IsEmpty() {
+			drifts = append(drifts, od)
+		}
+	}
+	return drifts, nil
+}
+
+func writeDiffText(w io.Writer, drifts []objectDrift) {
+	for _, od := range drifts {
+		if od.Missing {
+			fmt.Fprintf(w, "%s: missing\n", od.Object)
+			continue
+		}
+		fmt.Fprintf(w, "%s:\n", od.Object)
+		for _, change := range od.Changes {
+			fmt.Fprintf(w, "  ~ %s: %s -> %s\n", change.Path, diffValue(change.Old), diffValue(change.New))
+		}
+	}
+}
+
+// writeDiffUnified writes the unified diffs of the fields set in the manifests, from the cluster objects
+// to the manifests. The missing objects show as entirely added.
+func writeDiffUnified(w io.Writer, drifts []objectDrift) error {
+	for _, od := range drifts {
+		var live interface{}
+		liveName := "live/" + od.Object
+		if od.live != nil {
+			live = od.live
+		} else {
+			liveName = "/dev/null"
+		}
+		desiredFields, liveFields, err := diff.Relevant(od.desired, live)
+		if err != nil {
+			return fmt.Errorf("cannot compare %s: %w", od.Object, err)
+		}
+		desiredText, err := diffText(desiredFields)
+		if err != nil {
+			return err
+		}
+		liveText, err := diffText(liveFields)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(w, diff.Unified(liveName, "manifests/"+od.Object, liveText, desiredText, diffContext))
+	}
+	return nil
+}
+
+// diffText renders the fields one per line, with sorted keys, so the same fields always yield the same text.
+func diffText(fields map[string]interface{}) (string, error) {
+	if fields == nil {
+		return "", nil
+	}
+	data, err := json.MarshalIndent(fields, "", "  ")
+	return string(data) + "\n", err
+}
+
+func diffValue(val interface{}) string {
+	if val == nil {
+		return "<unset>"
+	}
+	data, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Sprintf("%v", val)
+	}
+	return string(data)
+}
@@ -0,0 +1,120 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package commands
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests"
+)
+
+const (
+	eventCreated = "created"
+	eventReady   = "ready"
+	eventSummary = "summary"
+)
+
+// deployEvent is a line of the deploy event stream. The stream always ends with a summary event.
+type deployEvent struct {
+	Type      string    `json:"type"`
+	Time      time.Time `json:"time"`
+	Object    string    `json:"object,omitempty"`
+	Namespace string    `json:"namespace,omitempty"`
+	// summary only
+	Success *bool  `json:"success,omitempty"`
+	Created int    `json:"created,omitempty"`
+	Ready   int    `json:"ready,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// eventStream writes the deploy progress as newline-delimited JSON, for machine consumption.
+type eventStream struct {
+	mu      sync.Mutex
+	enc     *json.Encoder
+	created int
+	ready   int
+}
+
+func newEventStream(w io.Writer) *eventStream {
+	return &eventStream{
+		enc: json.NewEncoder(w),
+	}
+}
+
+func (es *eventStream) objectCreated(obj client.Object) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	es.created++
+	es.emit(objectEvent(eventCreated, obj))
+}
+
+func (es *eventStream) objectReady(obj client.Object) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	es.ready++
+	es.emit(objectEvent(eventReady, obj))
+}
+
+func (es *eventStream) summary(err error) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	success := (err == nil)
+	ev := deployEvent{
+		Type:    eventSummary,
+		Time:    time.Now(),
+		Success: &success,
+		Created: es.created,
+		Ready:   es.ready,
+	}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	es.emit(ev)
+}
+
+func (es *eventStream) emit(ev deployEvent) {
+	// nothing sensible to do if the consumer went away
+	es.enc.Encode(ev)
+}
+
+func objectEvent(evType string, obj client.Object) deployEvent {
+	return deployEvent{
+		Type:      evType,
+		Time:      time.Now(),
+		Object:    manifests.ObjectName(obj),
+		Namespace: obj.GetNamespace(),
+	}
+}
+
+// withSummary makes the deploy command terminate the event stream, if requested, with the summary of the outcome.
+func (opts *deployOptions) withSummary(run func(cmd *cobra.Command, args []string) error) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if opts.output != outputEvents {
+			return run(cmd, args)
+		}
+		opts.events = newEventStream(cmd.OutOrStdout())
+		err := run(cmd, args)
+		opts.events.summary(err)
+		return err
+	}
+}
@@ -0,0 +1,64 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/rte"
+)
+
+type missingOptions struct {
+	jsonOutput bool
+}
+
+func NewMissingRTECommand(commonOpts *CommonOptions) *cobra.Command {
+	opts := &missingOptions{}
+	missing := &cobra.Command{
+		Use:   "missing-rte",
+		Short: "list the nodes which don't run the topology updater, with the likely reason",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			la := newLogAdapter(commonOpts, commonOpts.DebugLog, commonOpts.DebugLog)
+			platDetect := detectPlatform(commonOpts.DebugLog, commonOpts.UserPlatform, commonOpts.DetectTimeout)
+			if err := platDetect.Err(); err != nil {
+				return err
+			}
+			missingNodes, err := rte.MissingNodes(la, rte.Options{
+				Platform:        platDetect.Discovered,
+				ManifestsSource: commonOpts.ManifestsSource,
+				Namespace:       commonOpts.UpdaterNamespace,
+			})
+			if err != nil {
+				return err
+			}
+			if opts.jsonOutput {
+				return json.NewEncoder(os.Stdout).Encode(missingNodes)
+			}
+			for _, mn := range missingNodes {
+				fmt.Printf("%s: %s\n", mn.Name, mn.Reason)
+			}
+			return nil
+		},
+		Args: cobra.NoArgs,
+	}
+	missing.Flags().BoolVarP(&opts.jsonOutput, "json", "J", false, "output JSON, not text.")
+	return missing
+}
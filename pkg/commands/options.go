@@ -0,0 +1,108 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package commands
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
+	schedmanifests "github.com/k8stopologyawareschedwg/deployer/pkg/manifests/sched"
+)
+
+// CommonOption sets a field of CommonOptions. Meant for the library users not going through the command line.
+type CommonOption func(*CommonOptions)
+
+// DefaultCommonOptions returns CommonOptions with the same defaults of the command line flags,
+// then applies the given options in order.
+func DefaultCommonOptions(opts ...CommonOption) *CommonOptions {
+	commonOpts := &CommonOptions{
+		UserPlatform:          platform.Unknown,
+		Log:                   log.New(os.Stdout, "", log.LstdFlags),
+		DebugLog:              log.New(ioutil.Discard, "", 0),
+		Replicas:              1,
+		Retries:               deployer.Retries,
+		SchedulerMode:         schedmanifests.ModeSecondary,
+		SchedulerFeatureGates: make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(commonOpts)
+	}
+	return commonOpts
+}
+
+func WithPlatform(plat platform.Platform) CommonOption {
+	return func(commonOpts *CommonOptions) {
+		commonOpts.UserPlatform = plat
+	}
+}
+
+func WithReplicas(replicas int) CommonOption {
+	return func(commonOpts *CommonOptions) {
+		commonOpts.Replicas = replicas
+	}
+}
+
+// WithLog sets the loggers. A nil debugLog disables the debug log.
+func WithLog(logger, debugLog *log.Logger) CommonOption {
+	return func(commonOpts *CommonOptions) {
+		commonOpts.Log = logger
+		if debugLog == nil {
+			debugLog = log.New(ioutil.Discard, "", 0)
+		}
+		commonOpts.DebugLog = debugLog
+		commonOpts.Debug = (debugLog.Writer() != ioutil.Discard)
+	}
+}
+
+func WithPullIfNotPresent(pullIfNotPresent bool) CommonOption {
+	return func(commonOpts *CommonOptions) {
+		commonOpts.PullIfNotPresent = pullIfNotPresent
+	}
+}
+
+func WithRTEConfigData(data string) CommonOption {
+	return func(commonOpts *CommonOptions) {
+		commonOpts.RTEConfigData = data
+	}
+}
+
+func WithSchedulerMode(mode string) CommonOption {
+	return func(commonOpts *CommonOptions) {
+		commonOpts.SchedulerMode = mode
+	}
+}
+
+func WithSchedulerFeatureGates(featureGates map[string]bool) CommonOption {
+	return func(commonOpts *CommonOptions) {
+		commonOpts.SchedulerFeatureGates = featureGates
+	}
+}
+
+func WithAllNodes(allNodes bool) CommonOption {
+	return func(commonOpts *CommonOptions) {
+		commonOpts.AllNodes = allNodes
+	}
+}
+
+func WithAPIGroup(group string) CommonOption {
+	return func(commonOpts *CommonOptions) {
+		commonOpts.APIGroup = group
+	}
+}
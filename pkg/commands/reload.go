@@ -0,0 +1,57 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/rte"
+)
+
+func NewReloadConfigCommand(commonOpts *CommonOptions) *cobra.Command {
+	reload := &cobra.Command{
+		Use:   "reload-config",
+		Short: "roll out a new topology updater configuration, rolling back on failure",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if commonOpts.RTEConfigData == "" {
+				return fmt.Errorf("must provide the new configuration using --rte-config-file")
+			}
+			la := newLogAdapter(commonOpts, commonOpts.Log, commonOpts.DebugLog)
+			platDetect := detectPlatform(commonOpts.DebugLog, commonOpts.UserPlatform, commonOpts.DetectTimeout)
+			if err := platDetect.Err(); err != nil {
+				return err
+			}
+			return rte.ReloadConfig(la, rte.Options{
+				Platform:         platDetect.Discovered,
+				ManifestsSource:  commonOpts.ManifestsSource,
+				WaitTimeout:      commonOpts.WaitTimeout,
+				PollInterval:     commonOpts.PollInterval,
+				RTEConfigData:    commonOpts.RTEConfigData,
+				ImmutableConfig:  commonOpts.RTEImmutableConfig,
+				ConfigMapName:    commonOpts.RTEConfigMapName,
+				ExtraLabels:      deployLabels(commonOpts),
+				ExtraAnnotations: commonOpts.ExtraAnnotations,
+				PullIfNotPresent: commonOpts.PullIfNotPresent,
+				Namespace:        commonOpts.UpdaterNamespace,
+			})
+		},
+		Args: cobra.NoArgs,
+	}
+	return reload
+}
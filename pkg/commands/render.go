@@ -17,10 +17,16 @@
 package commands
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
@@ -29,10 +35,28 @@ import (
 	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests/api"
 	rtemanifests "github.com/k8stopologyawareschedwg/deployer/pkg/manifests/rte"
 	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests/sched"
-	"github.com/k8stopologyawareschedwg/deployer/pkg/tlog"
 )
 
-type renderOptions struct{}
+const (
+	formatYAML = "yaml"
+	formatJSON = "json"
+	// formatKustomize writes YAML files along with the kustomization.yaml listing them, only in a directory
+	formatKustomize = "kustomize"
+)
+
+type renderOptions struct {
+	output      string
+	format      string
+	outputFile  string
+	tee         bool
+	kubeVersion string
+	namespaces  []string
+	outputDir   string
+	force       bool
+	components  []string
+	// rbacAPIVersion is the version of the rendered RBAC objects. Empty means the manifests one, v1.
+	rbacAPIVersion string
+}
 
 func NewRenderCommand(commonOpts *CommonOptions) *cobra.Command {
 	opts := &renderOptions{}
@@ -47,6 +71,16 @@ func NewRenderCommand(commonOpts *CommonOptions) *cobra.Command {
 		},
 		Args: cobra.NoArgs,
 	}
+	render.PersistentFlags().StringVar(&opts.outputFile, "output-file", "", "write the manifests to this file instead of stdout.")
+	render.PersistentFlags().StringVar(&opts.kubeVersion, "kube-version", "", "fail if any manifest uses API versions deprecated on this kubernetes version.")
+	render.PersistentFlags().StringSliceVar(&opts.namespaces, "namespaces", nil, "comma-separated list of namespaces to render the topology updater into, once per namespace. Only on kubernetes.")
+	render.PersistentFlags().BoolVar(&opts.tee, "tee", false, "write the manifests to stdout too. Requires --output-file.")
+	render.PersistentFlags().StringVarP(&opts.output, "output", "o", "", "output format. One of: \"\" (full manifests), \"name\".")
+	render.PersistentFlags().StringVar(&opts.format, "output-format", formatYAML, "format of the manifests. One of: \"yaml\" (documents separated by ---), \"json\" (array of objects), \"kustomize\" (yaml files and kustomization.yaml, requires --output-dir).")
+	render.PersistentFlags().StringVar(&opts.outputDir, "output-dir", "", "write the manifests in this directory, one file per object. Rendering all the components, use one subdirectory per component.")
+	render.PersistentFlags().StringVar(&opts.rbacAPIVersion, "rbac-api-version", manifests.RBACAPIVersionV1, "version of the rbac.authorization.k8s.io objects. One of: \"v1\", \"v1beta1\" (for kubernetes older than 1.8).")
+	render.PersistentFlags().BoolVar(&opts.force, "force", false, "write in the --output-dir directory even if not empty, replacing its content.")
+	render.Flags().StringSliceVar(&opts.components, "components", nil, "comma-separated list of the components to render, among \"api\", \"topology-updater\" and \"scheduler-plugin\". All the components if empty.")
 	render.AddCommand(NewRenderAPICommand(commonOpts, opts))
 	render.AddCommand(NewRenderSchedulerPluginCommand(commonOpts, opts))
 	render.AddCommand(NewRenderTopologyUpdaterCommand(commonOpts, opts))
@@ -61,11 +95,21 @@ func NewRenderAPICommand(commonOpts *CommonOptions, opts *renderOptions) *cobra.
 			if commonOpts.UserPlatform == platform.Unknown {
 				return fmt.Errorf("must explicitely select a cluster platform")
 			}
-			apiManifests, err := api.GetManifests(commonOpts.UserPlatform)
+			apiManifests, err := api.GetManifestsFromSource(commonOpts.ManifestsSource, commonOpts.UserPlatform)
+			if err != nil {
+				return err
+			}
+			apiManifests, err = apiManifests.Update(api.UpdateOptions{
+				ServedVersions: commonOpts.APIServedVersions,
+				StorageVersion: commonOpts.APIStorageVersion,
+				Categories:     commonOpts.APICategories,
+				ShortNames:     commonOpts.APIShortNames,
+				APIGroup:       commonOpts.APIGroup,
+			})
 			if err != nil {
 				return err
 			}
-			return renderObjects(apiManifests.Update().ToObjects())
+			return renderObjects(commonOpts, opts, apiManifests.ToObjects())
 		},
 		Args: cobra.NoArgs,
 	}
@@ -85,8 +129,39 @@ func NewRenderSchedulerPluginCommand(commonOpts *CommonOptions, opts *renderOpti
 			if err != nil {
 				return err
 			}
+			if commonOpts.UpdaterNamespace != "" {
+				if err := rtemanifests.ValidateNamespace(commonOpts.UserPlatform, commonOpts.UpdaterNamespace); err != nil {
+					return err
+				}
+				rteNamespace = commonOpts.UpdaterNamespace
+			}
+
+			if err := sched.ValidateMode(commonOpts.UserPlatform, commonOpts.SchedulerMode); err != nil {
+				return err
+			}
+			if err := sched.ValidateNodeSelector(commonOpts.SchedulerNodeSelector); err != nil {
+				return err
+			}
+			if err := sched.ValidateFeatureGates(commonOpts.SchedulerFeatureGates); err != nil {
+				return err
+			}
+			if err := sched.ValidatePodSchedulerName(commonOpts.SchedulerMode, commonOpts.SchedulerPodSchedulerName); err != nil {
+				return err
+			}
+			if err := sched.ValidateSchedulerName(commonOpts.SchedulerMode, commonOpts.SchedulerName, commonOpts.SchedulerPodSchedulerName); err != nil {
+				return err
+			}
+			if err := sched.ValidateTokenExpiration(commonOpts.SchedulerTokenExpirationSeconds); err != nil {
+				return err
+			}
+			if err := sched.ValidateEnforcedPodSelector(commonOpts.SchedulerEnforcedPodSelector); err != nil {
+				return err
+			}
+			if err := sched.ValidateReplicas(int32(commonOpts.Replicas)); err != nil {
+				return err
+			}
 
-			schedManifests, err := sched.GetManifests(commonOpts.UserPlatform)
+			schedManifests, err := sched.GetManifestsFromSource(commonOpts.ManifestsSource, commonOpts.UserPlatform)
 			if err != nil {
 				return err
 			}
@@ -95,9 +170,26 @@ func NewRenderSchedulerPluginCommand(commonOpts *CommonOptions, opts *renderOpti
 				Replicas:               int32(commonOpts.Replicas),
 				NodeResourcesNamespace: rteNamespace,
 				PullIfNotPresent:       commonOpts.PullIfNotPresent,
+				ImagePullSecrets:       commonOpts.ImagePullSecrets,
+				PriorityClassName:      commonOpts.PriorityClassName,
+				WithNetworkPolicy:      commonOpts.NetworkPolicy,
+				SpreadReplicas:         commonOpts.SchedulerSpreadReplicas,
+				Tolerations:            commonOpts.SchedulerTolerations,
+				PodNodeAffinity:        commonOpts.SchedulerPodNodeAffinity,
+				LeaderElect:            commonOpts.SchedulerLeaderElect,
+				ScoringStrategy:        commonOpts.SchedulerScoringStrategy,
+				Mode:                   commonOpts.SchedulerMode,
+				NodeSelector:           commonOpts.SchedulerNodeSelector,
+				FeatureGates:           commonOpts.SchedulerFeatureGates,
+				PodSchedulerName:       commonOpts.SchedulerPodSchedulerName,
+				SchedulerName:          commonOpts.SchedulerName,
+				APIGroup:               commonOpts.APIGroup,
+				TokenExpirationSeconds: commonOpts.SchedulerTokenExpirationSeconds,
+				TokenAudience:          commonOpts.SchedulerTokenAudience,
+				EnforcedPodSelector:    commonOpts.SchedulerEnforcedPodSelector,
 			}
-			la := tlog.NewLogAdapter(commonOpts.Log, commonOpts.DebugLog)
-			return renderObjects(schedManifests.Update(la, updateOpts).ToObjects())
+			la := newLogAdapter(commonOpts, commonOpts.Log, commonOpts.DebugLog)
+			return renderObjects(commonOpts, opts, schedManifests.Update(la, updateOpts).ToObjects())
 		},
 		Args: cobra.NoArgs,
 	}
@@ -112,79 +204,431 @@ func NewRenderTopologyUpdaterCommand(commonOpts *CommonOptions, opts *renderOpti
 			if commonOpts.UserPlatform == platform.Unknown {
 				return fmt.Errorf("must explicitely select a cluster platform")
 			}
-			objs, _, err := makeRTEObjects(commonOpts)
+			objs, _, err := makeRTEObjects(commonOpts, opts.namespaces)
 			if err != nil {
 				return err
 			}
-			return renderObjects(objs)
+			return renderObjects(commonOpts, opts, objs)
 		},
 		Args: cobra.NoArgs,
 	}
 	return render
 }
 
-func makeRTEObjects(commonOpts *CommonOptions) ([]client.Object, string, error) {
-	ns, namespace, err := rtedeploy.SetupNamespace(commonOpts.UserPlatform)
+// makeRTEObjects builds the topology updater objects once per given namespace, or in the updater
+// namespace if none is given. Returns the namespace of the first set of objects.
+func makeRTEObjects(commonOpts *CommonOptions, namespaces []string) ([]client.Object, string, error) {
+	if len(namespaces) == 0 {
+		return makeRTEObjectsForNamespace(commonOpts, commonOpts.UpdaterNamespace)
+	}
+	if commonOpts.UpdaterNamespace != "" {
+		return nil, "", fmt.Errorf("--namespaces and --updater-namespace are mutually exclusive")
+	}
+	if commonOpts.UserPlatform != platform.Kubernetes {
+		return nil, "", fmt.Errorf("rendering into multiple namespaces is supported only on %s", platform.Kubernetes)
+	}
+
+	var objs []client.Object
+	for _, namespace := range namespaces {
+		nsObjs, _, err := makeRTEObjectsForNamespace(commonOpts, namespace)
+		if err != nil {
+			return nil, namespace, err
+		}
+		objs = append(objs, nsObjs...)
+	}
+	return objs, namespaces[0], nil
+}
+
+// makeRTEObjectsForNamespace builds the topology updater objects in the given namespace, or in the
+// platform default namespace if empty.
+func makeRTEObjectsForNamespace(commonOpts *CommonOptions, namespace string) ([]client.Object, string, error) {
+	ns, defaultNamespace, err := rtedeploy.SetupNamespace(commonOpts.UserPlatform)
 	if err != nil {
+		return nil, defaultNamespace, err
+	}
+	if err := rtemanifests.ValidateNamespace(commonOpts.UserPlatform, namespace); err != nil {
 		return nil, namespace, err
 	}
+	if namespace == "" {
+		namespace = defaultNamespace
+	} else if ns != nil {
+		ns.Name = namespace
+	}
+	if ns == nil && commonOpts.UpdaterIncludeNamespace {
+		ns, err = manifests.Namespace(manifests.ComponentResourceTopologyExporter)
+		if err != nil {
+			return nil, namespace, err
+		}
+		ns.Name = namespace
+	}
 
-	mf, err := rtemanifests.GetManifests(commonOpts.UserPlatform)
+	mf, err := rtemanifests.GetManifestsForNamespaceFromSource(commonOpts.ManifestsSource, commonOpts.UserPlatform, namespace)
 	if err != nil {
 		return nil, namespace, err
 	}
 	mf = mf.Update(rtemanifests.UpdateOptions{
-		ConfigData:       commonOpts.RTEConfigData,
-		PullIfNotPresent: commonOpts.PullIfNotPresent,
-		Namespace:        namespace,
+		ConfigData:                   commonOpts.RTEConfigData,
+		ImmutableConfig:              commonOpts.RTEImmutableConfig,
+		ConfigMapName:                commonOpts.RTEConfigMapName,
+		PullIfNotPresent:             commonOpts.PullIfNotPresent,
+		ImagePullSecrets:             commonOpts.ImagePullSecrets,
+		PriorityClassName:            commonOpts.PriorityClassName,
+		WithNetworkPolicy:            commonOpts.NetworkPolicy,
+		WithServiceMonitor:           commonOpts.ServiceMonitor,
+		Namespace:                    namespace,
+		AllNodes:                     commonOpts.AllNodes,
+		NodeSelector:                 commonOpts.RTENodeSelector,
+		Tolerations:                  commonOpts.RTETolerations,
+		StartupProbeFailureThreshold: commonOpts.RTEStartupProbeFailureThreshold,
+		StartupProbePeriodSeconds:    commonOpts.RTEStartupProbePeriodSeconds,
+		Finalizers:                   commonOpts.RTEFinalizers,
+		MaxUnavailable:               commonOpts.RTEMaxUnavailable,
+		HostNetwork:                  commonOpts.RTEHostNetwork,
+		DNSPolicy:                    commonOpts.RTEDNSPolicy,
+		PodSchedulerName:             commonOpts.RTEPodSchedulerName,
+		APIGroup:                     commonOpts.APIGroup,
 	})
+	if commonOpts.AllNodes {
+		if err := rtemanifests.ValidateAllNodes(mf.DaemonSet); err != nil {
+			return nil, namespace, err
+		}
+	}
 
 	rteObjs := mf.ToObjects()
-	if commonOpts.UserPlatform == platform.Kubernetes {
+	if (commonOpts.UserPlatform == platform.Kubernetes || commonOpts.UpdaterIncludeNamespace) && !commonOpts.UpdaterSkipNamespace {
 		return append([]client.Object{ns}, rteObjs...), namespace, nil
 	}
 	return rteObjs, namespace, nil
 }
 
 func renderManifests(cmd *cobra.Command, commonOpts *CommonOptions, opts *renderOptions, args []string) error {
-	var objs []client.Object
-
-	apiManifests, err := api.GetManifests(commonOpts.UserPlatform)
+	if opts.outputDir != "" {
+		comps, err := makeComponentObjects(commonOpts, opts.namespaces)
+		if err != nil {
+			return err
+		}
+		comps, err = selectComponentObjects(comps, opts.components)
+		if err != nil {
+			return err
+		}
+		return renderComponentsDir(commonOpts, opts, comps)
+	}
+	if opts.tee && opts.outputFile == "" {
+		return fmt.Errorf("--tee requires --output-file")
+	}
+	var buf bytes.Buffer
+	err := RenderAll(&buf, commonOpts.UserPlatform, RenderOptions{
+		CommonOptions:  *commonOpts,
+		Namespaces:     opts.namespaces,
+		Components:     opts.components,
+		Output:         opts.output,
+		Format:         opts.format,
+		KubeVersion:    opts.kubeVersion,
+		RBACAPIVersion: opts.rbacAPIVersion,
+	})
 	if err != nil {
 		return err
 	}
-	objs = append(objs, apiManifests.Update().ToObjects()...)
+	return writeOutput(opts, buf.Bytes())
+}
+
+// RenderOptions are the options of RenderAll.
+type RenderOptions struct {
+	// CommonOptions are the options shared by all the commands. Their UserPlatform is ignored.
+	CommonOptions
+	// Namespaces, if not empty, are the namespaces to render the topology updater into, once per namespace.
+	// Only on kubernetes.
+	Namespaces []string
+	// Components, if not empty, are the components to render, among "api", "topology-updater" and "scheduler-plugin".
+	Components []string
+	// Output is one of "" (full manifests) or "name".
+	Output string
+	// Format is one of "yaml" (default if empty) or "json".
+	Format string
+	// KubeVersion, if not empty, makes RenderAll fail if any manifest uses API versions deprecated on this kubernetes version.
+	KubeVersion string
+	// RBACAPIVersion is the version of the rendered RBAC objects, "v1" (default if empty) or "v1beta1".
+	RBACAPIVersion string
+}
+
+// RenderAll writes to w the manifests of all the components for the given platform,
+// exactly like the render command does.
+func RenderAll(w io.Writer, plat platform.Platform, opts RenderOptions) error {
+	if plat == platform.Unknown {
+		return fmt.Errorf("must explicitely select a cluster platform")
+	}
+	commonOpts := opts.CommonOptions
+	commonOpts.UserPlatform = plat
+	if commonOpts.Log == nil {
+		commonOpts.Log = log.New(ioutil.Discard, "", 0)
+	}
+	if commonOpts.DebugLog == nil {
+		commonOpts.DebugLog = log.New(ioutil.Discard, "", 0)
+	}
+	renderOpts := &renderOptions{
+		output:         opts.Output,
+		format:         opts.Format,
+		kubeVersion:    opts.KubeVersion,
+		rbacAPIVersion: opts.RBACAPIVersion,
+	}
+	if renderOpts.format == "" {
+		renderOpts.format = formatYAML
+	}
 
-	rteObjs, rteNs, err := makeRTEObjects(commonOpts)
+	comps, err := makeComponentObjects(&commonOpts, opts.Namespaces)
 	if err != nil {
 		return err
 	}
-	objs = append(objs, rteObjs...)
-
-	schedManifests, err := sched.GetManifests(commonOpts.UserPlatform)
+	comps, err = selectComponentObjects(comps, opts.Components)
 	if err != nil {
 		return err
 	}
+	objs := flattenComponentObjects(comps)
+	if err := prepareObjects(&commonOpts, renderOpts, objs); err != nil {
+		return err
+	}
+	return writeObjects(w, renderOpts, objs)
+}
+
+// componentObjects are the objects of a component, in creation order.
+type componentObjects struct {
+	name string
+	objs []client.Object
+}
+
+// makeObjects builds all the objects for all the components, in creation order.
+// The topology updater objects are built once per namespace in rteNamespaces, if any.
+func makeObjects(commonOpts *CommonOptions, rteNamespaces []string) ([]client.Object, error) {
+	comps, err := makeComponentObjects(commonOpts, rteNamespaces)
+	if err != nil {
+		return nil, err
+	}
+	return flattenComponentObjects(comps), nil
+}
+
+// addExtraMetadata adds the user-supplied labels and annotations to the objects.
+func addExtraMetadata(commonOpts *CommonOptions, objs []client.Object) {
+	for _, obj := range objs {
+		manifests.UpdateMetadata(obj, commonOpts.ExtraLabels, commonOpts.ExtraAnnotations)
+	}
+}
+
+// selectComponentObjects returns the objects of the given components only, keeping the creation order.
+// Returns all the components if none is given.
+func selectComponentObjects(comps []componentObjects, names []string) ([]componentObjects, error) {
+	if len(names) == 0 {
+		return comps, nil
+	}
+	wanted := sets.NewString(names...)
+	var ret []componentObjects
+	for _, comp := range comps {
+		if wanted.Has(comp.name) {
+			ret = append(ret, comp)
+			wanted.Delete(comp.name)
+		}
+	}
+	if wanted.Len() > 0 {
+		return nil, fmt.Errorf("unknown components: %s", strings.Join(wanted.List(), ", "))
+	}
+	return ret, nil
+}
+
+func flattenComponentObjects(comps []componentObjects) []client.Object {
+	var objs []client.Object
+	for _, comp := range comps {
+		objs = append(objs, comp.objs...)
+	}
+	return objs
+}
+
+// makeComponentObjects builds all the objects grouped by component, components in creation order.
+func makeComponentObjects(commonOpts *CommonOptions, rteNamespaces []string) ([]componentObjects, error) {
+	var comps []componentObjects
+
+	apiManifests, err := api.GetManifestsFromSource(commonOpts.ManifestsSource, commonOpts.UserPlatform)
+	if err != nil {
+		return nil, err
+	}
+	apiManifests, err = apiManifests.Update(api.UpdateOptions{
+		ServedVersions: commonOpts.APIServedVersions,
+		StorageVersion: commonOpts.APIStorageVersion,
+		Categories:     commonOpts.APICategories,
+		ShortNames:     commonOpts.APIShortNames,
+		APIGroup:       commonOpts.APIGroup,
+	})
+	if err != nil {
+		return nil, err
+	}
+	comps = append(comps, componentObjects{name: componentAPI, objs: apiManifests.ToObjects()})
+
+	rteObjs, rteNs, err := makeRTEObjects(commonOpts, rteNamespaces)
+	if err != nil {
+		return nil, err
+	}
+	comps = append(comps, componentObjects{name: componentTopologyUpdater, objs: rteObjs})
+
+	if err := sched.ValidateMode(commonOpts.UserPlatform, commonOpts.SchedulerMode); err != nil {
+		return nil, err
+	}
+	if err := sched.ValidateNodeSelector(commonOpts.SchedulerNodeSelector); err != nil {
+		return nil, err
+	}
+	if err := sched.ValidateFeatureGates(commonOpts.SchedulerFeatureGates); err != nil {
+		return nil, err
+	}
+	if err := sched.ValidatePodSchedulerName(commonOpts.SchedulerMode, commonOpts.SchedulerPodSchedulerName); err != nil {
+		return nil, err
+	}
+	if err := sched.ValidateSchedulerName(commonOpts.SchedulerMode, commonOpts.SchedulerName, commonOpts.SchedulerPodSchedulerName); err != nil {
+		return nil, err
+	}
+	if err := sched.ValidateTokenExpiration(commonOpts.SchedulerTokenExpirationSeconds); err != nil {
+		return nil, err
+	}
+	if err := sched.ValidateEnforcedPodSelector(commonOpts.SchedulerEnforcedPodSelector); err != nil {
+		return nil, err
+	}
+	if err := sched.ValidateReplicas(int32(commonOpts.Replicas)); err != nil {
+		return nil, err
+	}
+
+	schedManifests, err := sched.GetManifestsFromSource(commonOpts.ManifestsSource, commonOpts.UserPlatform)
+	if err != nil {
+		return nil, err
+	}
 
 	schedUpdateOpts := sched.UpdateOptions{
 		Replicas:               int32(commonOpts.Replicas),
 		NodeResourcesNamespace: rteNs,
 		PullIfNotPresent:       commonOpts.PullIfNotPresent,
+		ImagePullSecrets:       commonOpts.ImagePullSecrets,
+		PriorityClassName:      commonOpts.PriorityClassName,
+		WithNetworkPolicy:      commonOpts.NetworkPolicy,
+		SpreadReplicas:         commonOpts.SchedulerSpreadReplicas,
+		Tolerations:            commonOpts.SchedulerTolerations,
+		PodNodeAffinity:        commonOpts.SchedulerPodNodeAffinity,
+		LeaderElect:            commonOpts.SchedulerLeaderElect,
+		ScoringStrategy:        commonOpts.SchedulerScoringStrategy,
+		Mode:                   commonOpts.SchedulerMode,
+		NodeSelector:           commonOpts.SchedulerNodeSelector,
+		FeatureGates:           commonOpts.SchedulerFeatureGates,
+		PodSchedulerName:       commonOpts.SchedulerPodSchedulerName,
+		SchedulerName:          commonOpts.SchedulerName,
+		APIGroup:               commonOpts.APIGroup,
+		TokenExpirationSeconds: commonOpts.SchedulerTokenExpirationSeconds,
+		TokenAudience:          commonOpts.SchedulerTokenAudience,
+		EnforcedPodSelector:    commonOpts.SchedulerEnforcedPodSelector,
 	}
 
-	la := tlog.NewLogAdapter(commonOpts.Log, commonOpts.DebugLog)
-	objs = append(objs, schedManifests.Update(la, schedUpdateOpts).ToObjects()...)
+	la := newLogAdapter(commonOpts, commonOpts.Log, commonOpts.DebugLog)
+	comps = append(comps, componentObjects{name: componentSchedulerPlugin, objs: schedManifests.Update(la, schedUpdateOpts).ToObjects()})
+	return comps, nil
+}
 
-	return renderObjects(objs)
+func renderObjects(commonOpts *CommonOptions, opts *renderOptions, objs []client.Object) error {
+	if opts.tee && opts.outputFile == "" {
+		return fmt.Errorf("--tee requires --output-file")
+	}
+	if err := prepareObjects(commonOpts, opts, objs); err != nil {
+		return err
+	}
+	if opts.outputDir != "" {
+		return renderObjectsDir(opts, objs)
+	}
+
+	var buf bytes.Buffer
+	if err := writeObjects(&buf, opts, objs); err != nil {
+		return err
+	}
+	return writeOutput(opts, buf.Bytes())
+}
+
+// prepareObjects validates the render options and the objects, adding the user-supplied metadata.
+// The objects are sorted in the canonical order, so the output does not depend on how they were built.
+func prepareObjects(commonOpts *CommonOptions, opts *renderOptions, objs []client.Object) error {
+	if err := validateOutput(opts.output); err != nil {
+		return err
+	}
+	addExtraMetadata(commonOpts, objs)
+	if err := validateFormat(opts.format); err != nil {
+		return err
+	}
+	if err := manifests.ConvertRBACObjects(objs, opts.rbacAPIVersion); err != nil {
+		return err
+	}
+	if err := validateObjects(opts, objs); err != nil {
+		return err
+	}
+	manifests.SortObjects(objs)
+	return nil
 }
 
-func renderObjects(objs []client.Object) error {
+func writeObjects(out io.Writer, opts *renderOptions, objs []client.Object) error {
+	if opts.output == outputName {
+		for _, obj := range objs {
+			fmt.Fprintln(out, manifests.ObjectName(obj))
+		}
+		return nil
+	}
+
+	if opts.format == formatJSON {
+		return manifests.SerializeObjectsJSON(objs, out)
+	}
+
 	for _, obj := range objs {
-		fmt.Printf("---\n")
-		if err := manifests.SerializeObject(obj, os.Stdout); err != nil {
+		fmt.Fprintf(out, "---\n")
+		if err := manifests.SerializeObject(obj, out); err != nil {
 			return err
 		}
 	}
 
 	return nil
 }
+
+// writeOutput writes the rendered data to the output file, to stdout or to both.
+// The data is fully rendered beforehand, so failures never leave a partial output file.
+func writeOutput(opts *renderOptions, data []byte) error {
+	if opts.outputFile == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	dst, err := os.Create(opts.outputFile)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	var out io.Writer = dst
+	if opts.tee {
+		out = io.MultiWriter(dst, os.Stdout)
+	}
+	_, err = out.Write(data)
+	return err
+}
+
+func validateFormat(format string) error {
+	if format == formatKustomize {
+		return fmt.Errorf("output format %q requires --output-dir", format)
+	}
+	if format != formatYAML && format != formatJSON {
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+	return nil
+}
+
+// validateObjects checks the objects can be rendered together, setting their TypeMeta.
+func validateObjects(opts *renderOptions, objs []client.Object) error {
+	for _, obj := range objs {
+		if err := manifests.EnsureTypeMeta(obj); err != nil {
+			return err
+		}
+	}
+	if err := manifests.ValidateUniqueObjects(objs); err != nil {
+		return err
+	}
+	if opts.kubeVersion != "" {
+		if err := manifests.ValidateAPIVersions(objs, opts.kubeVersion); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests"
+)
+
+// the component subdirectories of the render output directory, named like the subcommands
+const (
+	componentAPI             = "api"
+	componentTopologyUpdater = "topology-updater"
+	componentSchedulerPlugin = "scheduler-plugin"
+)
+
+const kustomizationFileName = "kustomization.yaml"
+
+// renderComponentsDir writes the objects in a layout suitable for the git directory generator
+// of the ArgoCD ApplicationSets: <dir>/<component>/<kind>-<namespace>-<name>.yaml, one object per file.
+// With --force, the component subdirectories are replaced, so the objects no longer rendered
+// do not linger. In kustomize format, <dir> is a kustomize base listing the component directories,
+// each one listing its files.
+func renderComponentsDir(commonOpts *CommonOptions, opts *renderOptions, comps []componentObjects) error {
+	if err := validateOutputDir(opts); err != nil {
+		return err
+	}
+	addExtraMetadata(commonOpts, flattenComponentObjects(comps))
+	for _, comp := range comps {
+		if err := manifests.ConvertRBACObjects(comp.objs, opts.rbacAPIVersion); err != nil {
+			return err
+		}
+	}
+	if err := validateObjects(opts, flattenComponentObjects(comps)); err != nil {
+		return err
+	}
+	if err := prepareOutputDir(opts); err != nil {
+		return err
+	}
+	for _, comp := range comps {
+		compDir := filepath.Join(opts.outputDir, comp.name)
+		if err := os.RemoveAll(compDir); err != nil {
+			return err
+		}
+		if err := writeObjectsDir(compDir, opts.format, comp.objs); err != nil {
+			return err
+		}
+	}
+	if opts.format != formatKustomize {
+		return nil
+	}
+	var compNames []string
+	for _, comp := range comps {
+		compNames = append(compNames, comp.name)
+	}
+	return writeKustomization(opts.outputDir, compNames)
+}
+
+// renderObjectsDir writes the objects in the output directory, one object per file.
+func renderObjectsDir(opts *renderOptions, objs []client.Object) error {
+	if err := validateOutputDir(opts); err != nil {
+		return err
+	}
+	if err := prepareOutputDir(opts); err != nil {
+		return err
+	}
+	return writeObjectsDir(opts.outputDir, opts.format, objs)
+}
+
+func validateOutputDir(opts *renderOptions) error {
+	if opts.outputFile != "" || opts.tee || opts.output != "" {
+		return fmt.Errorf("--output-dir is incompatible with --output-file, --tee and --output")
+	}
+	if opts.format == formatKustomize {
+		return nil
+	}
+	return validateFormat(opts.format)
+}
+
+// prepareOutputDir creates the output directory if needed. An existing directory must be empty,
+// unless forced.
+func prepareOutputDir(opts *renderOptions) error {
+	entries, err := os.ReadDir(opts.outputDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(entries) > 0 && !opts.force {
+		return fmt.Errorf("output directory %q is not empty, use --force to write in it anyway", opts.outputDir)
+	}
+	return os.MkdirAll(opts.outputDir, 0755)
+}
+
+func writeObjectsDir(dir, format string, objs []client.Object) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	written := make(map[string]string)
+	for _, obj := range objs {
+		fileName := objectFileName(obj, format)
+		if prev, ok := written[fileName]; ok {
+			return fmt.Errorf("objects %s and %s would be written in the same file %q", prev, manifests.ObjectName(obj), fileName)
+		}
+		written[fileName] = manifests.ObjectName(obj)
+		if err := writeObjectFile(filepath.Join(dir, fileName), format, obj); err != nil {
+			return err
+		}
+	}
+	if format != formatKustomize {
+		return nil
+	}
+	// list the files in the canonical order, regardless of how the objects were built
+	sorted := append([]client.Object{}, objs...)
+	manifests.SortObjects(sorted)
+	var fileNames []string
+	for _, obj := range sorted {
+		fileNames = append(fileNames, objectFileName(obj, format))
+	}
+	return writeKustomization(dir, fileNames)
+}
+
+// writeKustomization writes in the directory the kustomization.yaml listing the resources, in the given order.
+func writeKustomization(dir string, resources []string) error {
+	var buf bytes.Buffer
+	buf.WriteString("apiVersion: kustomize.config.k8s.io/v1beta1\n")
+	buf.WriteString("kind: Kustomization\n")
+	buf.WriteString("resources:\n")
+	for _, res := range resources {
+		fmt.Fprintf(&buf, "- %s\n", res)
+	}
+	return os.WriteFile(filepath.Join(dir, kustomizationFileName), buf.Bytes(), 0644)
+}
+
+// objectFileName returns the file name of the object, <kind>-<namespace>-<name>.<extension>,
+// or <kind>-<name>.<extension> for the cluster-scoped objects. The namespace keeps apart
+// the same object rendered in many namespaces.
+func objectFileName(obj client.Object, format string) string {
+	kind := strings.ToLower(obj.GetObjectKind().GroupVersionKind().Kind)
+	ext := format
+	if format == formatKustomize {
+		ext = formatYAML
+	}
+	if ns := obj.GetNamespace(); ns != "" {
+		return fmt.Sprintf("%s-%s-%s.%s", kind, ns, obj.GetName(), ext)
+	}
+	return fmt.Sprintf("%s-%s.%s", kind, obj.GetName(), ext)
+}
+
+func writeObjectFile(path, format string, obj client.Object) error {
+	dst, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	serialize := manifests.SerializeObject
+	if format == formatJSON {
+		serialize = manifests.SerializeObjectJSON
+	}
+	if err := serialize(obj, dst); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
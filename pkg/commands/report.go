@@ -0,0 +1,76 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer"
+)
+
+// deployReport is the machine-readable outcome of a deploy or remove command.
+type deployReport struct {
+	Success bool                    `json:"success"`
+	Error   string                  `json:"error,omitempty"`
+	Objects []deployer.ObjectResult `json:"objects"`
+}
+
+// recordResult adds the objects acted on to the report, passing err through.
+func (opts *deployOptions) recordResult(res *deployer.Result, err error) error {
+	opts.report.Merge(res)
+	return err
+}
+
+// withReport makes the command write the report of the objects acted on, if requested, whatever the outcome.
+func (opts *deployOptions) withReport(run func(cmd *cobra.Command, args []string) error) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if opts.reportFile == "" {
+			return run(cmd, args)
+		}
+		opts.report = deployer.Result{}
+		err := run(cmd, args)
+		if wErr := writeReport(opts.reportFile, opts.report, err); wErr != nil {
+			if err != nil {
+				return fmt.Errorf("%w (and cannot write the report: %v)", err, wErr)
+			}
+			return fmt.Errorf("cannot write the report: %w", wErr)
+		}
+		return err
+	}
+}
+
+func writeReport(path string, res deployer.Result, err error) error {
+	rep := deployReport{
+		Success: (err == nil),
+		Objects: res.Objects,
+	}
+	if err != nil {
+		rep.Error = err.Error()
+	}
+	if rep.Objects == nil {
+		rep.Objects = []deployer.ObjectResult{}
+	}
+	data, mErr := json.MarshalIndent(rep, "", "  ")
+	if mErr != nil {
+		return mErr
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
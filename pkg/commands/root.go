@@ -21,22 +21,173 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
+	"github.com/k8stopologyawareschedwg/deployer/pkg/clientutil"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer"
 	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform/detect"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/wait"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/images"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests"
+	rtemanifests "github.com/k8stopologyawareschedwg/deployer/pkg/manifests/rte"
+	schedmanifests "github.com/k8stopologyawareschedwg/deployer/pkg/manifests/sched"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/metrics"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/tlog"
+)
+
+// envVarPrefix is the prefix of the environment variables which provide
+// the defaults for the global flags, e.g. DEPLOYER_PLATFORM for --platform.
+const envVarPrefix = "DEPLOYER_"
+
+// outputName makes the commands print only the object identities, like `kubectl -o name`.
+// outputEvents makes the deploy commands stream their progress as newline-delimited JSON.
+const (
+	outputName   = "name"
+	outputEvents = "events"
+)
+
+// logFormatText is the human-oriented log, logFormatJSON emits a JSON record per message.
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
 )
 
 type CommonOptions struct {
-	Debug            bool
-	UserPlatform     platform.Platform
-	Log              *log.Logger
-	DebugLog         *log.Logger
-	Replicas         int
-	RTEConfigData    string
-	PullIfNotPresent bool
-	rteConfigFile    string
-	plat             string
+	Debug bool
+	// LogFormat is logFormatText, the default if empty, or logFormatJSON.
+	LogFormat          string
+	UserPlatform       platform.Platform
+	Log                *log.Logger
+	DebugLog           *log.Logger
+	Replicas           int
+	RTEConfigData      string
+	RTEImmutableConfig bool
+	RTEConfigMapName   string
+	PullIfNotPresent   bool
+	ImagePullSecrets   []string
+	PriorityClassName  string
+	// ServiceMonitor adds a ServiceMonitor scraping the topology updater metrics. Requires the prometheus operator.
+	ServiceMonitor bool
+	// NetworkPolicy adds the network policies letting the topology updater and scheduler plugin pods work
+	// in clusters denying the traffic by default.
+	NetworkPolicy                   bool
+	SchedulerMode                   string
+	SchedulerNodeSelector           map[string]string
+	SchedulerFeatureGates           map[string]bool
+	SchedulerPodSchedulerName       string
+	SchedulerName                   string
+	SchedulerTokenExpirationSeconds int64
+	SchedulerTokenAudience          string
+	SchedulerEnforcedPodSelector    map[string]string
+	SchedulerSpreadReplicas         string
+	// SchedulerTolerations and SchedulerPodNodeAffinity place the scheduler plugin pods.
+	SchedulerTolerations     []corev1.Toleration
+	SchedulerPodNodeAffinity *corev1.NodeAffinity
+	// SchedulerOnControlPlane makes the scheduler plugin pods run on the control-plane nodes,
+	// adding the needed tolerations and node affinity.
+	SchedulerOnControlPlane bool
+	// SchedulerScoringStrategy makes the scheduler plugin score the nodes using this strategy.
+	SchedulerScoringStrategy string
+	// SchedulerLeaderElect makes the scheduler plugin replicas elect a leader.
+	SchedulerLeaderElect bool
+	RTEPodSchedulerName  string
+	RTENodeSelector      map[string]string
+	RTETolerations       []corev1.Toleration
+	UpdaterNamespace     string
+	// UpdaterSkipNamespace leaves the topology updater namespace to be managed by others: it is not rendered,
+	// created or deleted.
+	UpdaterSkipNamespace bool
+	// UpdaterIncludeNamespace renders the topology updater namespace on all the platforms, not only on kubernetes,
+	// so the manifests are self-contained. Mutually exclusive with UpdaterSkipNamespace.
+	UpdaterIncludeNamespace bool
+	// ExtraLabels and ExtraAnnotations are added to all the rendered or created objects.
+	ExtraLabels      map[string]string
+	ExtraAnnotations map[string]string
+	Images           map[string]string
+	// ManifestsVersion selects the embedded manifests to deploy or render. Empty means the default ones.
+	// ManifestsSource loads them.
+	ManifestsVersion string
+	ManifestsSource  manifests.Source
+	WaitJitter       float64
+	// PollInterval, if not zero, overrides how often all the waits poll the cluster.
+	PollInterval time.Duration
+	// PollBackoff grows the poll intervals by this factor after each unsatisfied poll. Zero disables the backoff.
+	PollBackoff float64
+	// Retries is how many times the requests failing with transient errors are retried.
+	Retries int
+	// WaitTimeout bounds the waits on the objects. Zero means the default timeout, negative waits indefinitely.
+	WaitTimeout time.Duration
+	// DetectTimeout bounds the platform detection. Zero means detect.DefaultTimeout, negative waits indefinitely.
+	DetectTimeout                   time.Duration
+	APIServedVersions               []string
+	APIStorageVersion               string
+	APICategories                   []string
+	APIShortNames                   []string
+	APIGroup                        string
+	AllNodes                        bool
+	RTEStartupProbeFailureThreshold int32
+	RTEStartupProbePeriodSeconds    int32
+	RTEFinalizers                   []string
+	RTEMaxUnavailable               *intstr.IntOrString
+	RTEHostNetwork                  *bool
+	RTEDNSPolicy                    corev1.DNSPolicy
+	MetricsAddr                     string
+	stopMetrics                     func() error
+	rteConfigFile                   string
+	kubeconfig                      string
+	impersonateUser                 string
+	impersonateGroups               []string
+	rteTolerations                  []string
+	rteMaxUnavailable               string
+	rteHostNetwork                  bool
+	updaterConfigFile               string
+	schedFeatureGates               map[string]string
+	waitTimeout                     time.Duration
+	detectTimeout                   time.Duration
+	plat                            string
+}
+
+// rteConfigFilePath returns the file the RTE config is read from, empty if none.
+func rteConfigFilePath(commonOpts *CommonOptions) string {
+	if commonOpts.rteConfigFile != "" {
+		return commonOpts.rteConfigFile
+	}
+	return commonOpts.updaterConfigFile
+}
+
+func validateOutput(output string) error {
+	if output != "" && output != outputName {
+		return fmt.Errorf("unsupported output format: %q", output)
+	}
+	return nil
+}
+
+// setFlagsFromEnv sets the flags not given on the command line from the
+// matching environment variables, if any. Explicit flags always win.
+func setFlagsFromEnv(flags *pflag.FlagSet) error {
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed {
+			return
+		}
+		name := envVarPrefix + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		val, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+		if setErr := flags.Set(f.Name, val); setErr != nil {
+			err = fmt.Errorf("invalid value %q from %s: %w", val, name, setErr)
+		}
+	})
+	return err
 }
 
 func ShowHelp(cmd *cobra.Command, args []string) error {
@@ -46,15 +197,27 @@ func ShowHelp(cmd *cobra.Command, args []string) error {
 
 type NewCommandFunc func(ko *CommonOptions) *cobra.Command
 
+// newLogAdapter returns the adapter for the given loggers, in the log format selected by the user.
+func newLogAdapter(commonOpts *CommonOptions, logger, debugLog *log.Logger) tlog.Logger {
+	if commonOpts.LogFormat == logFormatJSON {
+		return tlog.NewJSONLogAdapter(logger, debugLog)
+	}
+	return tlog.NewLogAdapter(logger, debugLog)
+}
+
 // NewRootCommand returns entrypoint command to interact with all other commands
 func NewRootCommand(extraCmds ...NewCommandFunc) *cobra.Command {
-	commonOpts := &CommonOptions{}
+	commonOpts := DefaultCommonOptions()
 
 	root := &cobra.Command{
 		Use:   "deployer",
 		Short: "deployer helps setting up all the topology-aware-scheduling components on a kubernetes cluster",
 
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := setFlagsFromEnv(cmd.Root().PersistentFlags()); err != nil {
+				return err
+			}
+
 			if commonOpts.Debug {
 				commonOpts.DebugLog = log.New(os.Stderr, "", log.LstdFlags)
 			} else {
@@ -62,17 +225,180 @@ func NewRootCommand(extraCmds ...NewCommandFunc) *cobra.Command {
 			}
 			// we abuse the logger to have a common interface and the timestamps
 			commonOpts.Log = log.New(os.Stdout, "", log.LstdFlags)
+			switch commonOpts.LogFormat {
+			case logFormatText:
+			case logFormatJSON:
+				// the records carry their own timestamps
+				commonOpts.Log = log.New(tlog.NewJSONWriter(os.Stdout, tlog.LevelInfo), "", 0)
+				commonOpts.DebugLog = log.New(tlog.NewJSONWriter(commonOpts.DebugLog.Writer(), tlog.LevelDebug), "", 0)
+			default:
+				return fmt.Errorf("invalid log format %q: must be %q or %q", commonOpts.LogFormat, logFormatText, logFormatJSON)
+			}
+
+			if commonOpts.WaitJitter < 0 {
+				return fmt.Errorf("invalid wait jitter %v: must be >= 0", commonOpts.WaitJitter)
+			}
+			wait.PollJitter = commonOpts.WaitJitter
+			if commonOpts.PollInterval < 0 {
+				return fmt.Errorf("invalid poll interval %v: must be >= 0", commonOpts.PollInterval)
+			}
+			if commonOpts.PollBackoff != 0 && commonOpts.PollBackoff < 1 {
+				return fmt.Errorf("invalid poll backoff %v: must be 0 or >= 1", commonOpts.PollBackoff)
+			}
+			wait.PollBackoff = commonOpts.PollBackoff
+			if commonOpts.Retries < 0 {
+				return fmt.Errorf("invalid retries %d: must be >= 0", commonOpts.Retries)
+			}
+			deployer.Retries = commonOpts.Retries
+
+			if commonOpts.waitTimeout < 0 {
+				return fmt.Errorf("invalid wait timeout %v: must be >= 0", commonOpts.waitTimeout)
+			}
+			commonOpts.WaitTimeout = commonOpts.waitTimeout
+			if commonOpts.WaitTimeout == 0 {
+				commonOpts.WaitTimeout = deployer.NoWaitTimeout
+			}
+			if commonOpts.detectTimeout < 0 {
+				return fmt.Errorf("invalid detect timeout %v: must be >= 0", commonOpts.detectTimeout)
+			}
+			commonOpts.DetectTimeout = commonOpts.detectTimeout
+			if commonOpts.DetectTimeout == 0 {
+				commonOpts.DetectTimeout = detect.NoTimeout
+			}
+
+			if err := images.Override(commonOpts.Images); err != nil {
+				return err
+			}
+
+			if commonOpts.kubeconfig != "" {
+				if _, err := os.Stat(commonOpts.kubeconfig); err != nil {
+					return fmt.Errorf("cannot use the kubeconfig: %w", err)
+				}
+				clientutil.Kubeconfig = commonOpts.kubeconfig
+			}
+
+			if len(commonOpts.impersonateGroups) > 0 && commonOpts.impersonateUser == "" {
+				return fmt.Errorf("--as-group requires --as")
+			}
+			clientutil.Impersonate.UserName = commonOpts.impersonateUser
+			clientutil.Impersonate.Groups = commonOpts.impersonateGroups
+
+			commonOpts.SchedulerFeatureGates = make(map[string]bool)
+			for name, val := range commonOpts.schedFeatureGates {
+				enabled, err := strconv.ParseBool(val)
+				if err != nil {
+					return fmt.Errorf("invalid value %q for feature gate %q: %w", val, name, err)
+				}
+				commonOpts.SchedulerFeatureGates[name] = enabled
+			}
+
+			manifestsSource, err := manifests.NewSource(commonOpts.ManifestsVersion)
+			if err != nil {
+				return err
+			}
+			commonOpts.ManifestsSource = manifestsSource
+			if commonOpts.UpdaterSkipNamespace && commonOpts.UpdaterIncludeNamespace {
+				return fmt.Errorf("--updater-skip-namespace and --updater-include-namespace are mutually exclusive")
+			}
+			if err := rtemanifests.ValidateConfigMapName(commonOpts.RTEConfigMapName); err != nil {
+				return err
+			}
+			if err := schedmanifests.ValidateSpreadReplicas(commonOpts.SchedulerSpreadReplicas); err != nil {
+				return err
+			}
+			if err := schedmanifests.ValidateScoringStrategy(commonOpts.SchedulerScoringStrategy, images.SchedulerPluginSchedulerImage); err != nil {
+				return err
+			}
+
+			if err := manifests.ValidateMetadata(commonOpts.ExtraLabels, commonOpts.ExtraAnnotations); err != nil {
+				return err
+			}
+			if err := manifests.ValidateImagePullSecrets(commonOpts.ImagePullSecrets); err != nil {
+				return err
+			}
+			if err := manifests.ValidatePriorityClassName(commonOpts.PriorityClassName); err != nil {
+				return err
+			}
+
+			if err := rtemanifests.ValidateNodeSelector(commonOpts.RTENodeSelector); err != nil {
+				return err
+			}
+			commonOpts.RTETolerations = nil
+			for _, spec := range commonOpts.rteTolerations {
+				tol, err := manifests.ParseToleration(spec)
+				if err != nil {
+					return err
+				}
+				commonOpts.RTETolerations = append(commonOpts.RTETolerations, tol)
+			}
+
+			commonOpts.RTEMaxUnavailable = nil
+			if commonOpts.rteMaxUnavailable != "" {
+				val := intstr.Parse(commonOpts.rteMaxUnavailable)
+				if err := manifests.ValidateMaxUnavailable(val); err != nil {
+					return err
+				}
+				commonOpts.RTEMaxUnavailable = &val
+			}
+
+			commonOpts.RTEHostNetwork = nil
+			if cmd.Root().PersistentFlags().Changed("rte-host-network") {
+				hostNetwork := commonOpts.rteHostNetwork
+				commonOpts.RTEHostNetwork = &hostNetwork
+			}
+			if err := manifests.ValidateDNSPolicy(commonOpts.RTEDNSPolicy); err != nil {
+				return err
+			}
+
+			if commonOpts.SchedulerOnControlPlane {
+				commonOpts.SchedulerTolerations = manifests.ControlPlaneTolerations()
+				commonOpts.SchedulerPodNodeAffinity = manifests.ControlPlaneNodeAffinity()
+			}
+
+			if commonOpts.APIGroup != "" {
+				if err := manifests.ValidateAPIGroup(commonOpts.APIGroup); err != nil {
+					return err
+				}
+			}
+
+			if commonOpts.MetricsAddr != "" {
+				stop, err := metrics.Serve(commonOpts.MetricsAddr)
+				if err != nil {
+					return fmt.Errorf("cannot serve the metrics on %q: %w", commonOpts.MetricsAddr, err)
+				}
+				commonOpts.stopMetrics = stop
+				commonOpts.DebugLog.Printf("serving metrics on %q", commonOpts.MetricsAddr)
+			}
 
 			// if it is unknown, it's fine
 			commonOpts.UserPlatform, _ = platform.FromString(commonOpts.plat)
 
-			if commonOpts.rteConfigFile != "" {
-				data, err := os.ReadFile(commonOpts.rteConfigFile)
+			if commonOpts.rteConfigFile != "" && commonOpts.updaterConfigFile != "" {
+				return fmt.Errorf("--rte-config-file and --updater-config-file are mutually exclusive")
+			}
+			if configFile := rteConfigFilePath(commonOpts); configFile != "" {
+				if commonOpts.RTEConfigData != "" {
+					return fmt.Errorf("the RTE config is given both inline and from the file %q", configFile)
+				}
+				data, err := os.ReadFile(configFile)
 				if err != nil {
 					return err
 				}
+				if err := rtemanifests.ValidateConfigData(string(data)); err != nil {
+					return fmt.Errorf("invalid RTE config in %q: %w", configFile, err)
+				}
 				commonOpts.RTEConfigData = string(data)
 				commonOpts.DebugLog.Printf("RTE config: read %d bytes", len(commonOpts.RTEConfigData))
+			} else if commonOpts.RTEConfigData != "" {
+				if err := rtemanifests.ValidateConfigData(commonOpts.RTEConfigData); err != nil {
+					return fmt.Errorf("invalid RTE config: %w", err)
+				}
+			}
+			return nil
+		},
+		PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+			if commonOpts.stopMetrics != nil {
+				return commonOpts.stopMetrics()
 			}
 			return nil
 		},
@@ -84,20 +410,81 @@ func NewRootCommand(extraCmds ...NewCommandFunc) *cobra.Command {
 	}
 
 	root.PersistentFlags().BoolVarP(&commonOpts.Debug, "debug", "D", false, "enable debug log")
+	root.PersistentFlags().StringVar(&commonOpts.LogFormat, "log-format", logFormatText, "format of the log: 'text' or 'json', one record per line.")
 	root.PersistentFlags().StringVarP(&commonOpts.plat, "platform", "P", "", "platform to deploy on")
-	root.PersistentFlags().IntVarP(&commonOpts.Replicas, "replicas", "R", 1, "set the replica value - where relevant.")
+	root.PersistentFlags().IntVarP(&commonOpts.Replicas, "replicas", "R", 1, "set the replica value - where relevant. 0 means the default.")
 	root.PersistentFlags().BoolVar(&commonOpts.PullIfNotPresent, "pull-if-not-present", false, "force pull policies to IfNotPresent.")
+	root.PersistentFlags().StringSliceVar(&commonOpts.ImagePullSecrets, "image-pull-secrets", nil, "comma-separated list of the secrets the topology updater and scheduler plugin pods use to pull their images.")
+	root.PersistentFlags().BoolVar(&commonOpts.ServiceMonitor, "rte-service-monitor", false, "add a ServiceMonitor, and its Service, making the prometheus operator scrape the topology updater metrics. Requires the prometheus operator CRDs.")
+	root.PersistentFlags().BoolVar(&commonOpts.NetworkPolicy, "network-policy", false, "add network policies letting the topology updater and scheduler plugin pods reach the API server, and the topology updater metrics be scraped.")
+	root.PersistentFlags().StringVar(&commonOpts.PriorityClassName, "priority-class-name", "", "priority class of the topology updater and scheduler plugin pods. The priority class must exist.")
+	root.PersistentFlags().StringVar(&commonOpts.SchedulerMode, "scheduler-mode", schedmanifests.ModeSecondary, "scheduler plugin mode: \"secondary\" or \"replace-default\".")
+	root.PersistentFlags().StringToStringVar(&commonOpts.SchedulerNodeSelector, "scheduler-node-selector", nil, "comma-separated key=value node labels the scheduler plugin restricts its scheduling to.")
+	root.PersistentFlags().StringToStringVar(&commonOpts.schedFeatureGates, "scheduler-feature-gates", nil, "comma-separated name=true|false feature gates to set on the scheduler plugin.")
+	root.PersistentFlags().StringVar(&commonOpts.SchedulerName, "scheduler-name", "", "name of the scheduler plugin profile, also naming its deployment, configuration and RBAC, to run alongside other instances. Only in \"secondary\" mode. Default is \""+schedmanifests.SchedulerName+"\".")
+	root.PersistentFlags().StringVar(&commonOpts.SchedulerPodSchedulerName, "scheduler-pods-scheduler-name", "", "scheduler of the scheduler plugin pods. Default is the cluster default. Required in \"replace-default\" mode.")
+	root.PersistentFlags().Int64Var(&commonOpts.SchedulerTokenExpirationSeconds, "scheduler-token-expiration-seconds", 0, "make the scheduler plugin use a projected service account token expiring after these seconds. 0 keeps the auto-mounted token.")
+	root.PersistentFlags().StringVar(&commonOpts.SchedulerTokenAudience, "scheduler-token-audience", "", "audience of an extra scheduler plugin projected service account token, for consumers other than the apiserver. Used only with --scheduler-token-expiration-seconds.")
+	root.PersistentFlags().BoolVar(&commonOpts.SchedulerOnControlPlane, "scheduler-on-control-plane", false, "run the scheduler plugin pods on the control-plane nodes, tolerating their taints.")
+	root.PersistentFlags().BoolVar(&commonOpts.SchedulerLeaderElect, "scheduler-leader-elect", false, "make the scheduler plugin replicas elect a leader. Needed to run more than one replica.")
+	root.PersistentFlags().StringVar(&commonOpts.SchedulerScoringStrategy, "scheduler-scoring-strategy", "", "make the scheduler plugin score the nodes: \"MostAllocated\", \"BalancedAllocation\" or \"LeastAllocated\". Requires a scheduler plugin image built from kubernetes "+schedmanifests.ScoringKubeVersion+" or newer. Default is filtering only.")
+	root.PersistentFlags().StringVar(&commonOpts.SchedulerSpreadReplicas, "scheduler-spread-replicas", "", "spread the scheduler plugin replicas across the nodes: \"preferred\" or \"required\". Default is no spreading.")
+	root.PersistentFlags().StringToStringVar(&commonOpts.SchedulerEnforcedPodSelector, "scheduler-enforce-pod-selector", nil, "comma-separated key=value pod labels: reject the pods matching them not using the scheduler plugin. Requires kubernetes 1.30+.")
+	root.PersistentFlags().StringToStringVar(&commonOpts.RTENodeSelector, "rte-node-selector", nil, "comma-separated key=value node labels the topology updater runs on, in addition to the manifest ones.")
+	root.PersistentFlags().StringSliceVar(&commonOpts.rteTolerations, "rte-tolerations", nil, "comma-separated key[=value][:effect] taints the topology updater tolerates, in addition to the manifest ones.")
+	root.PersistentFlags().StringVar(&commonOpts.RTEPodSchedulerName, "rte-pods-scheduler-name", "", "scheduler of the topology updater pods. Default is the cluster default.")
+	root.PersistentFlags().BoolVar(&commonOpts.UpdaterSkipNamespace, "updater-skip-namespace", false, "do not render, create or delete the topology updater namespace, which must exist. Only on kubernetes.")
+	root.PersistentFlags().BoolVar(&commonOpts.UpdaterIncludeNamespace, "updater-include-namespace", false, "render the topology updater namespace on all the platforms, e.g. openshift-monitoring on openshift. Only when rendering.")
+	root.PersistentFlags().StringVar(&commonOpts.UpdaterNamespace, "updater-namespace", "", "namespace of the topology updater objects. Default is the platform default. Supported only on kubernetes.")
+	root.PersistentFlags().StringVar(&commonOpts.ManifestsVersion, "manifests-version", "", fmt.Sprintf("version of the embedded manifests to use. Available: %s.", strings.Join(manifests.Versions(), ", ")))
+	root.PersistentFlags().StringToStringVar(&commonOpts.Images, "image", nil, "component=image overrides of the container images, e.g. to use a mirror registry. Can be repeated. Components: topology-updater, scheduler-plugin, scheduler-controller.")
+	root.PersistentFlags().DurationVar(&commonOpts.waitTimeout, "wait-timeout", deployer.DefaultWaitTimeout, "how long to wait for the objects to be ready or gone, when waiting. 0 waits indefinitely.")
+	root.PersistentFlags().DurationVar(&commonOpts.detectTimeout, "detect-timeout", detect.DefaultTimeout, "how long to wait for the cluster to answer the platform detection requests. 0 waits indefinitely.")
+	root.PersistentFlags().Float64Var(&commonOpts.WaitJitter, "wait-jitter", 0, "randomly extend wait poll intervals up to this factor. 0 disables jitter.")
+	root.PersistentFlags().DurationVar(&commonOpts.PollInterval, "poll-interval", 0, "how often the waits poll the cluster. 0 keeps the default of each wait (1s to 10s).")
+	root.PersistentFlags().Float64Var(&commonOpts.PollBackoff, "poll-backoff", 0, "grow the wait poll intervals by this factor after each poll, up to 30s. 0 disables the backoff.")
+	root.PersistentFlags().IntVar(&commonOpts.Retries, "retries", deployer.Retries, "retry the requests failing with transient errors (conflicts, server errors, connection resets) up to this many times, with exponential backoff. 0 disables the retries.")
+	root.PersistentFlags().StringSliceVar(&commonOpts.APIServedVersions, "api-served-versions", nil, "comma-separated list of the API versions to serve. Default is to use the manifest settings.")
+	root.PersistentFlags().StringVar(&commonOpts.APIStorageVersion, "api-storage-version", "", "API version to be used as storage version. Default is to use the manifest settings.")
+	root.PersistentFlags().StringSliceVar(&commonOpts.APICategories, "api-categories", nil, "comma-separated list of categories of the API CRD. Default is to use the manifest settings.")
+	root.PersistentFlags().StringSliceVar(&commonOpts.APIShortNames, "api-short-names", nil, "comma-separated list of short names of the API CRD. Default is to use the manifest settings.")
+	root.PersistentFlags().StringVar(&commonOpts.APIGroup, "api-group", "", "API group of the NodeResourceTopology CRD, also used in the RBAC rules. Default is the upstream group.")
+	root.PersistentFlags().BoolVar(&commonOpts.AllNodes, "all-nodes", false, "run the topology updater on all the nodes, control-plane included.")
+	root.PersistentFlags().Int32Var(&commonOpts.RTEStartupProbeFailureThreshold, "rte-startup-failure-threshold", 0, "failure threshold of the topology updater startup probe, checking the metrics port is served. 0 means kubernetes default.")
+	root.PersistentFlags().Int32Var(&commonOpts.RTEStartupProbePeriodSeconds, "rte-startup-period-seconds", 0, "period of the topology updater startup probe. 0 means kubernetes default.")
+	root.PersistentFlags().StringVar(&commonOpts.rteMaxUnavailable, "rte-max-unavailable", "", "roll the topology updater pods out gradually, replacing at most this number or percentage (e.g. 10%) of them at a time. Default is the cluster default, one at a time.")
+	root.PersistentFlags().BoolVar(&commonOpts.rteHostNetwork, "rte-host-network", false, "make the topology updater pods use the host network (or not, with =false), e.g. if the CNI prevents them to reach the kubelet. Default is the manifest setting.")
+	root.PersistentFlags().StringVar((*string)(&commonOpts.RTEDNSPolicy), "rte-dns-policy", "", "DNS policy of the topology updater pods: \"ClusterFirst\", \"ClusterFirstWithHostNet\" or \"Default\". Default is the manifest setting.")
+	root.PersistentFlags().StringSliceVar(&commonOpts.RTEFinalizers, "rte-finalizers", nil, "comma-separated list of finalizers to add to the topology updater daemonset.")
+	root.PersistentFlags().StringToStringVar(&commonOpts.ExtraLabels, "extra-labels", nil, "comma-separated key=value labels to add to all the objects, overriding the existing ones on key collision.")
+	root.PersistentFlags().StringToStringVar(&commonOpts.ExtraAnnotations, "extra-annotations", nil, "comma-separated key=value annotations to add to all the objects, overriding the existing ones on key collision.")
+	root.PersistentFlags().StringVar(&commonOpts.kubeconfig, "kubeconfig", "", "path of the kubeconfig to use, overriding the KUBECONFIG environment variable and the in-cluster configuration.")
+	root.PersistentFlags().StringVar(&commonOpts.impersonateUser, "as", "", "username to impersonate, e.g. system:serviceaccount:<namespace>:<name>, to check its permissions.")
+	root.PersistentFlags().StringArrayVar(&commonOpts.impersonateGroups, "as-group", nil, "group to impersonate, can be repeated. Requires --as.")
+	root.PersistentFlags().StringVar(&commonOpts.MetricsAddr, "metrics-addr", "", "serve the metrics about the operations on this address, under /metrics. Empty disables the metrics.")
 	root.PersistentFlags().StringVar(&commonOpts.rteConfigFile, "rte-config-file", "", "inject rte configuration reading from this file.")
+	root.PersistentFlags().StringVar(&commonOpts.updaterConfigFile, "updater-config-file", "", "same as --rte-config-file.")
+	root.PersistentFlags().StringVar(&commonOpts.RTEConfigMapName, "rte-config-name", "", "name of the rte configuration configmap. Default is \"rte-config\".")
+	root.PersistentFlags().BoolVar(&commonOpts.RTEImmutableConfig, "rte-immutable-config", false, "make the rte configuration immutable, naming its configmap after the content hash.")
 
 	root.AddCommand(
 		NewRenderCommand(commonOpts),
 		NewValidateCommand(commonOpts),
+		NewCheckCommand(commonOpts),
 		NewDeployCommand(commonOpts),
 		NewRemoveCommand(commonOpts),
 		NewSetupCommand(commonOpts),
 		NewDetectCommand(commonOpts),
 		NewVersionCommand(commonOpts),
 		NewImagesCommand(commonOpts),
+		NewReloadConfigCommand(commonOpts),
+		NewCanaryCommand(commonOpts),
+		NewApplyCommand(commonOpts),
+		NewDiffCommand(commonOpts),
+		NewMissingRTECommand(commonOpts),
+		NewStatusCommand(commonOpts),
+		NewConfigCommand(commonOpts),
+		NewUpgradeCommand(commonOpts),
 	)
 	for _, extraCmd := range extraCmds {
 		root.AddCommand(extraCmd(commonOpts))
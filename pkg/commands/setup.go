@@ -30,7 +30,7 @@ func NewSetupCommand(commonOpts *CommonOptions) *cobra.Command {
 			if err := validateCluster(cmd, commonOpts, valOpts, args); err != nil {
 				return err
 			}
-			return deployOnCluster(commonOpts, depOpts)
+			return deployOnCluster(cmd.Context(), commonOpts, depOpts)
 		},
 		Args: cobra.NoArgs,
 	}
@@ -0,0 +1,128 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/api"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/rte"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/sched"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/tlog"
+)
+
+type statusOptions struct {
+	jsonOutput bool
+}
+
+type componentStatus struct {
+	Component string `json:"component"`
+	deployer.Status
+}
+
+func NewStatusCommand(commonOpts *CommonOptions) *cobra.Command {
+	opts := &statusOptions{}
+	status := &cobra.Command{
+		Use:   "status",
+		Short: "report if the topology-aware-scheduling components are deployed and healthy, failing if any is not",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			la := newLogAdapter(commonOpts, commonOpts.DebugLog, commonOpts.DebugLog)
+			platDetect := detectPlatform(commonOpts.DebugLog, commonOpts.UserPlatform, commonOpts.DetectTimeout)
+			if err := platDetect.Err(); err != nil {
+				return err
+			}
+			statuses, err := getStatuses(la, commonOpts, platDetect)
+			if err != nil {
+				return err
+			}
+			if opts.jsonOutput {
+				err = json.NewEncoder(os.Stdout).Encode(statuses)
+			} else {
+				err = writeStatuses(os.Stdout, statuses)
+			}
+			if err != nil {
+				return err
+			}
+			notReady := 0
+			for _, st := range statuses {
+				if !st.Ready {
+					notReady++
+				}
+			}
+			if notReady > 0 {
+				return fmt.Errorf("%d of %d components not ready", notReady, len(statuses))
+			}
+			return nil
+		},
+		Args: cobra.NoArgs,
+	}
+	status.Flags().BoolVarP(&opts.jsonOutput, "json", "J", false, "output JSON, not text.")
+	return status
+}
+
+func getStatuses(la tlog.Logger, commonOpts *CommonOptions, platDetect detectionOutput) ([]componentStatus, error) {
+	apiSt, err := api.Status(la, api.Options{
+		Platform:        platDetect.Discovered,
+		ManifestsSource: commonOpts.ManifestsSource,
+		APIGroup:        commonOpts.APIGroup,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the %s status: %w", componentAPI, err)
+	}
+	rteSt, err := rte.Status(la, rte.Options{
+		Platform:        platDetect.Discovered,
+		ManifestsSource: commonOpts.ManifestsSource,
+		Namespace:       commonOpts.UpdaterNamespace,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the %s status: %w", componentTopologyUpdater, err)
+	}
+	schedSt, err := sched.Status(la, sched.Options{
+		Platform:        platDetect.Discovered,
+		ManifestsSource: commonOpts.ManifestsSource,
+		Mode:            commonOpts.SchedulerMode,
+		SchedulerName:   commonOpts.SchedulerName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the %s status: %w", componentSchedulerPlugin, err)
+	}
+	return []componentStatus{
+		{Component: componentAPI, Status: apiSt},
+		{Component: componentTopologyUpdater, Status: rteSt},
+		{Component: componentSchedulerPlugin, Status: schedSt},
+	}, nil
+}
+
+func writeStatuses(w io.Writer, statuses []componentStatus) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "COMPONENT\tNAMESPACE\tREADY\tREASON")
+	for _, st := range statuses {
+		namespace := st.Namespace
+		if namespace == "" {
+			namespace = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%t\t%s\n", st.Component, namespace, st.Ready, st.Reason)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,190 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/objects"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/diff"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/upgrade"
+	deployerversion "github.com/k8stopologyawareschedwg/deployer/pkg/version"
+)
+
+type upgradeOptions struct {
+	allowDowngrade bool
+	waitCompletion bool
+	dryRun         bool
+}
+
+// componentUpgrade is the installed state of a component, and the objects to apply to bring it to the target version.
+type componentUpgrade struct {
+	name      string
+	installed string
+	direction upgrade.Direction
+	// deployed is false if none of the objects of the component is on the cluster
+	deployed bool
+	changed  []client.Object
+}
+
+func NewUpgradeCommand(commonOpts *CommonOptions) *cobra.Command {
+	opts := &upgradeOptions{}
+	upgradeCmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "upgrade in place the deployed components to the version of this deployer, applying only the changed objects",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			la := newLogAdapter(commonOpts, commonOpts.Log, commonOpts.DebugLog)
+			platDetect := detectPlatform(commonOpts.DebugLog, commonOpts.UserPlatform, commonOpts.DetectTimeout)
+			if err := platDetect.Err(); err != nil {
+				return err
+			}
+			clusterOpts := *commonOpts
+			clusterOpts.UserPlatform = platDetect.Discovered
+			comps, err := makeComponentObjects(&clusterOpts, nil)
+			if err != nil {
+				return err
+			}
+
+			hp, err := deployer.NewHelper("UPG", la)
+			if err != nil {
+				return err
+			}
+			hp.WithContext(cmd.Context())
+
+			target := deployerversion.GitVersion
+			var upgrades []componentUpgrade
+			for _, comp := range comps {
+				// same metadata as deploy, but the version: the objects changed only by the version are left alone
+				for _, obj := range comp.objs {
+					manifests.UpdateMetadata(obj, deployLabels(commonOpts), commonOpts.ExtraAnnotations)
+				}
+				cu, err := planComponentUpgrade(hp, comp, target)
+				if err != nil {
+					return err
+				}
+				if cu.direction == upgrade.DirectionDowngrade && !opts.allowDowngrade {
+					return fmt.Errorf("refusing to downgrade %s from %s to %s: use --allow-downgrade to proceed", cu.name, cu.installed, target)
+				}
+				upgrades = append(upgrades, cu)
+			}
+
+			for _, cu := range upgrades {
+				if !cu.deployed {
+					la.Printf("%s: not deployed, skipped", cu.name)
+					continue
+				}
+				if cu.direction == upgrade.DirectionUnknown {
+					la.Printf("WARNING: %s: the installed version is unknown, upgrading anyway", cu.name)
+				}
+				if len(cu.changed) == 0 {
+					la.Printf("%s: %s, up to date", cu.name, describeInstalled(cu.installed))
+					continue
+				}
+				la.Printf("%s: %s, %s to %s: %d objects changed", cu.name, describeInstalled(cu.installed), describeDirection(cu.direction), target, len(cu.changed))
+				if opts.dryRun {
+					for _, obj := range cu.changed {
+						la.Printf("%s: would apply %s", cu.name, manifests.ObjectName(obj))
+					}
+					continue
+				}
+				for _, obj := range cu.changed {
+					manifests.UpdateMetadata(obj, nil, map[string]string{manifests.AnnotationDeployerVersion: target})
+				}
+				_, err := objects.Deploy(cmd.Context(), la, cu.changed, objects.Options{
+					WaitCompletion: opts.waitCompletion,
+					WaitTimeout:    commonOpts.WaitTimeout,
+					PollInterval:   commonOpts.PollInterval,
+				})
+				if err != nil {
+					return fmt.Errorf("cannot upgrade %s: %w", cu.name, err)
+				}
+			}
+			return nil
+		},
+		Args: cobra.NoArgs,
+	}
+	upgradeCmd.Flags().BoolVar(&opts.allowDowngrade, "allow-downgrade", false, "proceed even if the components were deployed by a newer deployer.")
+	upgradeCmd.Flags().BoolVarP(&opts.waitCompletion, "wait", "W", false, "wait for the upgraded objects to be ready.")
+	upgradeCmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "report the objects which would be applied, without changing the cluster.")
+	return upgradeCmd
+}
+
+// planComponentUpgrade reads the cluster counterparts of the component objects, to find the installed version
+// and the objects which are missing or differ from the manifests.
+func planComponentUpgrade(hp *deployer.Helper, comp componentObjects, target string) (componentUpgrade, error) {
+	cu := componentUpgrade{
+		name: comp.name,
+	}
+	var lives []client.Object
+	for _, obj := range comp.objs {
+		live := &unstructured.Unstructured{}
+		live.SetGroupVersionKind(obj.GetObjectKind().GroupVersionKind())
+		err := hp.GetObject(client.ObjectKeyFromObject(obj), live)
+		if k8serrors.IsNotFound(err) {
+			cu.changed = append(cu.changed, obj)
+			continue
+		}
+		if err != nil {
+			return cu, err
+		}
+		lives = append(lives, live)
+		changes, err := diff.Compare(obj, live)
+		if err != nil {
+			return cu, fmt.Errorf("cannot compare %s: %w", manifests.ObjectName(obj), err)
+		}
+		if len(changes) > 0 {
+			cu.changed = append(cu.changed, obj)
+		}
+	}
+	cu.deployed = len(lives) > 0
+
+	var err error
+	cu.installed, err = upgrade.InstalledVersion(lives)
+	if err != nil {
+		return cu, err
+	}
+	cu.direction, err = upgrade.Compare(cu.installed, target)
+	if err != nil {
+		return cu, fmt.Errorf("%s: %w", cu.name, err)
+	}
+	return cu, nil
+}
+
+func describeInstalled(installed string) string {
+	if installed == "" {
+		return "installed version unknown"
+	}
+	return "installed " + installed
+}
+
+func describeDirection(direction upgrade.Direction) string {
+	switch direction {
+	case upgrade.DirectionDowngrade:
+		return "downgrading"
+	case upgrade.DirectionSame:
+		return "reconciling"
+	default:
+		return "upgrading"
+	}
+}
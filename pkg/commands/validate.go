@@ -20,15 +20,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/k8stopologyawareschedwg/deployer/pkg/clientutil/nodes"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
 	"github.com/k8stopologyawareschedwg/deployer/pkg/validator"
 )
 
 type validateOptions struct {
 	jsonOutput bool
+	nrtMaxAge  time.Duration
 }
 
 func NewValidateCommand(commonOpts *CommonOptions) *cobra.Command {
@@ -42,52 +45,72 @@ func NewValidateCommand(commonOpts *CommonOptions) *cobra.Command {
 		Args: cobra.NoArgs,
 	}
 	validate.Flags().BoolVarP(&opts.jsonOutput, "json", "J", false, "output JSON, not text.")
+	validate.Flags().DurationVar(&opts.nrtMaxAge, "nrt-max-age", 0, "also check all the NodeResourceTopology objects were updated within this time. 0 disables the check.")
 	return validate
 }
 
 type validationOutput struct {
 	Success bool                         `json:"success"`
 	Errors  []validator.ValidationResult `json:"errors,omitempty"`
+	// Warnings are the issues which may not be real, so they don't fail the validation.
+	Warnings []validator.ValidationResult `json:"warnings,omitempty"`
 }
 
 func validateCluster(cmd *cobra.Command, commonOpts *CommonOptions, opts *validateOptions, args []string) error {
-	nodeList, err := nodes.GetWorkers()
-	if err != nil {
-		return err
-	}
-
 	vd := validator.Validator{
 		Log: commonOpts.DebugLog,
 	}
-	items, err := vd.ValidateClusterConfig(nodeList)
+
+	platDetect := detectPlatform(commonOpts.DebugLog, commonOpts.UserPlatform, commonOpts.DetectTimeout)
+	items, err := validateKubeletConfig(vd, platDetect.Discovered)
 	if err != nil {
 		return err
 	}
 
-	printValidationResults(items, opts.jsonOutput)
+	// an idle topology updater looks stale too, so the freshness issues are only warnings
+	var warnings []validator.ValidationResult
+	if opts.nrtMaxAge > 0 {
+		warnings, err = vd.ValidateNRTFreshness(opts.nrtMaxAge)
+		if err != nil {
+			return err
+		}
+	}
+
+	printValidationResults(items, warnings, opts.jsonOutput)
 	return nil
 }
 
+// validateKubeletConfig checks the kubelet configuration of the worker nodes. On OpenShift the configuration
+// is read from the KubeletConfig objects, elsewhere from the nodes.
+func validateKubeletConfig(vd validator.Validator, plat platform.Platform) ([]validator.ValidationResult, error) {
+	if plat == platform.OpenShift {
+		return vd.ValidateMachineConfig()
+	}
+	nodeList, err := nodes.GetWorkers()
+	if err != nil {
+		return nil, err
+	}
+	return vd.ValidateClusterConfig(nodeList)
+}
+
 // we need undecorated output, so we need to use fmt.Printf here. log packages add no value.
-func printValidationResults(items []validator.ValidationResult, jsonOutput bool) {
+func printValidationResults(items, warnings []validator.ValidationResult, jsonOutput bool) {
+	if jsonOutput {
+		json.NewEncoder(os.Stdout).Encode(validationOutput{
+			Success:  len(items) == 0,
+			Errors:   items,
+			Warnings: warnings,
+		})
+		return
+	}
+	for idx, item := range warnings {
+		fmt.Printf("WARNING#%03d: %s\n", idx, item.String())
+	}
 	if len(items) == 0 {
-		if jsonOutput {
-			json.NewEncoder(os.Stdout).Encode(validationOutput{
-				Success: true,
-			})
-		} else {
-			fmt.Printf("PASSED>>: the cluster configuration looks ok!\n")
-		}
-	} else {
-		if jsonOutput {
-			json.NewEncoder(os.Stdout).Encode(validationOutput{
-				Success: false,
-				Errors:  items,
-			})
-		} else {
-			for idx, item := range items {
-				fmt.Printf("ERROR#%03d: %s\n", idx, item.String())
-			}
-		}
+		fmt.Printf("PASSED>>: the cluster configuration looks ok!\n")
+		return
+	}
+	for idx, item := range items {
+		fmt.Printf("ERROR#%03d: %s\n", idx, item.String())
 	}
 }
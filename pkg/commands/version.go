@@ -18,15 +18,21 @@ package commands
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/k8stopologyawareschedwg/deployer/pkg/updatecheck"
 	deployerversion "github.com/k8stopologyawareschedwg/deployer/pkg/version"
 )
 
+const updateCheckTimeout = 10 * time.Second
+
 type versionOptions struct {
-	fullOutput bool
-	hashOnly   bool
+	fullOutput     bool
+	hashOnly       bool
+	checkUpdate    bool
+	updateIndexURL string
 }
 
 func NewVersionCommand(commonOpts *CommonOptions) *cobra.Command {
@@ -42,11 +48,44 @@ func NewVersionCommand(commonOpts *CommonOptions) *cobra.Command {
 			} else {
 				fmt.Printf("%s\n", deployerversion.GitVersion)
 			}
+			if opts.checkUpdate {
+				return checkUpdate(opts.updateIndexURL)
+			}
 			return nil
 		},
 		Args: cobra.NoArgs,
 	}
 	version.PersistentFlags().BoolVar(&opts.fullOutput, "full", false, "emit version and git hash.")
 	version.PersistentFlags().BoolVar(&opts.hashOnly, "hash", false, "emit only the git hash.")
+	version.PersistentFlags().BoolVar(&opts.checkUpdate, "check-update", false, "check if newer deployer or components are available. Requires --update-index-url.")
+	version.PersistentFlags().StringVar(&opts.updateIndexURL, "update-index-url", "", "URL of the remote version index to check updates against.")
 	return version
 }
+
+func checkUpdate(indexURL string) error {
+	if indexURL == "" {
+		return fmt.Errorf("must provide the remote index using --update-index-url")
+	}
+	remote, err := updatecheck.Fetch(indexURL, updateCheckTimeout)
+	if err != nil {
+		return err
+	}
+
+	imo := newImageOutput()
+	current := updatecheck.Index{
+		Deployer: deployerversion.GitVersion,
+		Components: map[string]string{
+			"topology_updater":     updatecheck.VersionFromImage(imo.TopologyUpdater),
+			"scheduler_plugin":     updatecheck.VersionFromImage(imo.SchedulerPlugin),
+			"scheduler_controller": updatecheck.VersionFromImage(imo.SchedulerController),
+		},
+	}
+	results, err := updatecheck.Compare(current, remote)
+	if err != nil {
+		return err
+	}
+	for _, res := range results {
+		fmt.Println(res.String())
+	}
+	return nil
+}
@@ -17,66 +17,172 @@
 package api
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 
 	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer"
 	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/wait"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests"
 	apimanifests "github.com/k8stopologyawareschedwg/deployer/pkg/manifests/api"
 	"github.com/k8stopologyawareschedwg/deployer/pkg/tlog"
 )
 
 type Options struct {
-	Platform platform.Platform
+	Platform       platform.Platform
+	ServedVersions []string
+	StorageVersion string
+	Categories     []string
+	ShortNames     []string
+	APIGroup       string
+	DryRun         bool
+	// ExtraLabels and ExtraAnnotations are added to all the created objects.
+	ExtraLabels      map[string]string
+	ExtraAnnotations map[string]string
+	OnCreate         deployer.ObjectFunc
+	// WaitCompletion makes Deploy wait for the CRD to be established, for at most WaitTimeout.
+	// OnReady is called once it is.
+	WaitCompletion bool
+	WaitTimeout    time.Duration
+	// PollInterval, if not zero, overrides the interval of the wait.
+	PollInterval time.Duration
+	OnReady      deployer.ObjectFunc
+	// OnProgress, if set, receives the progress of each object while deploying.
+	OnProgress deployer.ProgressFunc
+	// IgnoreNotFound makes the removal skip quietly the objects already gone, e.g. never deployed.
+	IgnoreNotFound bool
+	// Force clears the finalizers of the removed objects created by the deployer which are still there after
+	// ForceGracePeriod, zero meaning deployer.DefaultForceGracePeriod.
+	Force            bool
+	ForceGracePeriod time.Duration
+	// ManifestsSource selects the embedded manifests to deploy. The zero value is the default ones.
+	ManifestsSource manifests.Source
 }
 
 func SetupNamespace(plat platform.Platform) (*corev1.Namespace, string, error) {
 	return nil, "", fmt.Errorf("the API is a cluster scoped resource")
 }
 
-func Deploy(log tlog.Logger, opts Options) error {
+func Deploy(ctx context.Context, log tlog.Logger, opts Options) (*deployer.Result, error) {
 	var err error
 	log.Printf("deploying topology-aware-scheduling API...")
 
-	mf, err := apimanifests.GetManifests(opts.Platform)
+	mf, err := apimanifests.GetManifestsFromSource(opts.ManifestsSource, opts.Platform)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	mf, err = mf.Update(apimanifests.UpdateOptions{
+		ServedVersions: opts.ServedVersions,
+		StorageVersion: opts.StorageVersion,
+		Categories:     opts.Categories,
+		ShortNames:     opts.ShortNames,
+		APIGroup:       opts.APIGroup,
+	})
+	if err != nil {
+		return nil, err
 	}
 	log.Debugf("API manifests loaded")
 
 	hp, err := deployer.NewHelper("API", log)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	hp.WithContext(ctx).WithOnCreate(opts.OnCreate).WithOnProgress(opts.OnProgress).WithDryRun(opts.DryRun).WithWaitTimeout(opts.WaitTimeout).WithPollInterval(opts.PollInterval).WithExtraMetadata(opts.ExtraLabels, opts.ExtraAnnotations)
 
-	if err = hp.CreateObject(mf.Crd); err != nil {
-		return err
+	if err = hp.ApplyObject(mf.Crd); err != nil {
+		return hp.Result(), err
+	}
+	if opts.DryRun {
+		return hp.Result(), hp.DryRunResult()
+	}
+	if opts.WaitCompletion {
+		// the components using the API would race with its establishment
+		err = hp.WaitObject(deployer.WaitableObject{
+			Obj: mf.Crd,
+			Wait: func() error {
+				return wait.CRDToBeEstablished(hp, log, mf.Crd.Name)
+			},
+		})
+		if err != nil {
+			return hp.Result(), err
+		}
+		if opts.OnReady != nil {
+			opts.OnReady(mf.Crd)
+		}
 	}
 
 	log.Printf("...deployed topology-aware-scheduling API!")
-	return nil
+	return hp.Result(), nil
 }
 
-func Remove(log tlog.Logger, opts Options) error {
+func Remove(ctx context.Context, log tlog.Logger, opts Options) (*deployer.Result, error) {
 	var err error
 	log.Printf("removing topology-aware-scheduling API...")
 
-	mf, err := apimanifests.GetManifests(opts.Platform)
+	mf, err := apimanifests.GetManifestsFromSource(opts.ManifestsSource, opts.Platform)
+	if err != nil {
+		return nil, err
+	}
+	mf, err = mf.Update(apimanifests.UpdateOptions{
+		APIGroup: opts.APIGroup,
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 	log.Debugf("API manifests loaded")
 
 	hp, err := deployer.NewHelper("API", log)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	hp.WithContext(ctx).WithIgnoreNotFound(opts.IgnoreNotFound)
 
 	if err = hp.DeleteObject(mf.Crd); err != nil {
-		return err
+		return hp.Result(), err
+	}
+	if opts.Force {
+		if err = hp.ForceRemoval(mf.Crd, opts.ForceGracePeriod); err != nil {
+			return hp.Result(), err
+		}
 	}
 
 	log.Printf("...removed topology-aware-scheduling API!")
-	return nil
+	return hp.Result(), nil
+}
+
+// Status reports if the API CRD is established. Does not change the cluster.
+func Status(log tlog.Logger, opts Options) (deployer.Status, error) {
+	st := deployer.Status{}
+	mf, err := apimanifests.GetManifestsFromSource(opts.ManifestsSource, opts.Platform)
+	if err != nil {
+		return st, err
+	}
+	mf, err = mf.Update(apimanifests.UpdateOptions{
+		APIGroup: opts.APIGroup,
+	})
+	if err != nil {
+		return st, err
+	}
+
+	hp, err := deployer.NewHelper("API", log)
+	if err != nil {
+		return st, err
+	}
+
+	st.Ready, err = hp.IsCRDEstablished(mf.Crd.Name)
+	if k8serrors.IsNotFound(err) {
+		st.Reason = fmt.Sprintf("crd %q not found", mf.Crd.Name)
+		return st, nil
+	}
+	if err != nil {
+		return st, err
+	}
+	if !st.Ready {
+		st.Reason = fmt.Sprintf("crd %q not established", mf.Crd.Name)
+	}
+	return st, nil
 }
@@ -18,15 +18,30 @@ package deployer
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"regexp"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/k8stopologyawareschedwg/deployer/pkg/clientutil"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/clientutil/nodes"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/diff"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/images"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/metrics"
 	"github.com/k8stopologyawareschedwg/deployer/pkg/tlog"
 )
 
@@ -35,10 +50,117 @@ type WaitableObject struct {
 	Wait func() error
 }
 
+// ObjectFunc is called on the objects successfully handled by a Helper.
+type ObjectFunc func(obj client.Object)
+
+// The stages of the objects reported by the progress events.
+const (
+	ProgressStarting = "starting"
+	ProgressCreated  = "created"
+	ProgressWaiting  = "waiting"
+	ProgressReady    = "ready"
+	ProgressFailed   = "failed"
+)
+
+// ProgressEvent is a status update about an object handled by a deployment.
+// Created means the object is in the cluster as desired, either created, updated or already up to date.
+type ProgressEvent struct {
+	Component string
+	Object    client.Object
+	Stage     string
+	// Err is set only on the failed stage.
+	Err error
+}
+
+// ProgressFunc receives the progress events of a deployment, in order, from the deploying goroutine.
+// It should not block: consumers like UIs can forward the events to a buffered channel.
+// The progress events are not reported in dry-run mode.
+type ProgressFunc func(ev ProgressEvent)
+
+// ErrDryRunChanges is returned by the dry-run deployments when some objects would be created or updated.
+var ErrDryRunChanges = errors.New("dry run: the deployment would change the cluster")
+
+// Status is the health of a deployed component, as observed without changing the cluster.
+type Status struct {
+	Namespace string `json:"namespace,omitempty"`
+	Ready     bool   `json:"ready"`
+	// Reason tells why the component is not ready.
+	Reason string `json:"reason,omitempty"`
+}
+
+// The actions on the objects, as recorded in the Result.
+const (
+	ActionCreated     = "created"
+	ActionUpdated     = "updated"
+	ActionUnchanged   = "unchanged"
+	ActionDeleted     = "deleted"
+	ActionWouldCreate = "would create"
+	ActionWouldUpdate = "would update"
+	// ActionFinalizersCleared is recorded by the forced removals.
+	ActionFinalizersCleared = "finalizers cleared"
+)
+
+// ObjectResult is an action successfully taken on an object.
+type ObjectResult struct {
+	Component string `json:"component"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Action    string `json:"action"`
+}
+
+// Result describes the objects acted on by a deployment or a removal, in order.
+// The Deploy and Remove flows return it also on failure, covering the objects acted on until then.
+type Result struct {
+	Objects []ObjectResult `json:"objects"`
+}
+
+// Merge appends the objects of the other result, which may be nil.
+func (res *Result) Merge(other *Result) {
+	if other == nil {
+		return
+	}
+	res.Objects = append(res.Objects, other.Objects...)
+}
+
+const (
+	// DefaultWaitTimeout bounds the waits on the objects, unless overridden using WithWaitTimeout.
+	DefaultWaitTimeout = 3 * time.Minute
+	// NoWaitTimeout makes the waits on the objects unbounded.
+	NoWaitTimeout time.Duration = -1
+
+	// retryInitialDelay is the delay before the first retry, doubled at each retry.
+	retryInitialDelay = 500 * time.Millisecond
+
+	// DefaultForceGracePeriod is how long ForceRemoval waits for the deleted objects to be gone, unless overridden.
+	DefaultForceGracePeriod = 30 * time.Second
+	// forcePollInterval is how often ForceRemoval checks if the deleted objects are gone.
+	forcePollInterval = 1 * time.Second
+)
+
+// Retries is how many times the helpers retry the requests failing with transient errors, like
+// conflicts or the API server being temporarily unavailable, unless overridden using WithRetries.
+var Retries = 2
+
 type Helper struct {
-	tag string
-	cli client.Client
-	log tlog.Logger
+	ctx         context.Context
+	tag         string
+	cli         client.Client
+	log         tlog.Logger
+	onCreate    ObjectFunc
+	onProgress  ProgressFunc
+	dryRun      bool
+	changed     bool
+	waitTimeout time.Duration
+	// pollInterval, if not zero, overrides the interval of all the waits
+	pollInterval time.Duration
+	retries      int
+	result       Result
+	// extraLabels and extraAnnotations are added to the objects before their creation
+	extraLabels      map[string]string
+	extraAnnotations map[string]string
+	// ignoreNotFound makes DeleteObject succeed quietly on the objects already gone
+	ignoreNotFound bool
 }
 
 func NewHelper(tag string, log tlog.Logger) (*Helper, error) {
@@ -51,39 +173,438 @@ func NewHelper(tag string, log tlog.Logger) (*Helper, error) {
 
 func NewHelperWithClient(cli client.Client, tag string, log tlog.Logger) *Helper {
 	return &Helper{
-		tag: tag,
-		cli: cli,
-		log: log,
+		ctx:         context.Background(),
+		tag:         tag,
+		cli:         cli,
+		log:         log,
+		waitTimeout: DefaultWaitTimeout,
+		retries:     Retries,
+	}
+}
+
+// WithOnCreate sets the function to be called after each successful object creation.
+func (hp *Helper) WithOnCreate(fn ObjectFunc) *Helper {
+	hp.onCreate = fn
+	return hp
+}
+
+// WithOnProgress sets the function to be called on each progress of the objects created and waited on.
+func (hp *Helper) WithOnProgress(fn ProgressFunc) *Helper {
+	hp.onProgress = fn
+	return hp
+}
+
+// WithIgnoreNotFound makes DeleteObject treat the objects already gone as deleted, logging them only in the debug log.
+func (hp *Helper) WithIgnoreNotFound(ignoreNotFound bool) *Helper {
+	hp.ignoreNotFound = ignoreNotFound
+	return hp
+}
+
+// WithWaitTimeout bounds the waits on the objects handled by the Helper.
+// Zero keeps DefaultWaitTimeout, a negative timeout like NoWaitTimeout waits indefinitely.
+func (hp *Helper) WithWaitTimeout(timeout time.Duration) *Helper {
+	if timeout != 0 {
+		hp.waitTimeout = timeout
+	}
+	return hp
+}
+
+// WithPollInterval overrides how often the waits on the objects poll the cluster.
+// Zero keeps the default interval of each wait.
+func (hp *Helper) WithPollInterval(interval time.Duration) *Helper {
+	hp.pollInterval = interval
+	return hp
+}
+
+// PollInterval returns the interval overriding the one of each wait, or zero if not overridden.
+func (hp *Helper) PollInterval() time.Duration {
+	return hp.pollInterval
+}
+
+// WithRetries sets how many times the requests failing with transient errors are retried. Zero disables the retries.
+func (hp *Helper) WithRetries(retries int) *Helper {
+	hp.retries = retries
+	return hp
+}
+
+// WaitTimeout returns how long to wait on the objects. Negative means indefinitely.
+func (hp *Helper) WaitTimeout() time.Duration {
+	return hp.waitTimeout
+}
+
+// WithContext makes the Helper use the context for all its requests and waits, so they can be cancelled.
+func (hp *Helper) WithContext(ctx context.Context) *Helper {
+	hp.ctx = ctx
+	return hp
+}
+
+// Context returns the context of the requests and the waits of the Helper.
+func (hp *Helper) Context() context.Context {
+	return hp.ctx
+}
+
+// WithDryRun makes the Helper report how the objects would change instead of creating them.
+func (hp *Helper) WithDryRun(dryRun bool) *Helper {
+	hp.dryRun = dryRun
+	return hp
+}
+
+// WithExtraMetadata makes the helper add the labels and the annotations to the objects it creates,
+// overriding the existing ones only on key collision.
+func (hp *Helper) WithExtraMetadata(labels, annotations map[string]string) *Helper {
+	hp.extraLabels = labels
+	hp.extraAnnotations = annotations
+	return hp
+}
+
+// DryRunResult returns ErrDryRunChanges if, in dry-run mode, any object would have been created or updated.
+func (hp *Helper) DryRunResult() error {
+	if hp.changed {
+		return ErrDryRunChanges
+	}
+	return nil
+}
+
+// Result returns the objects acted on so far by the Helper.
+func (hp *Helper) Result() *Result {
+	return &Result{
+		Objects: append([]ObjectResult{}, hp.result.Objects...),
 	}
 }
 
 func (hp *Helper) CreateObject(obj client.Object) error {
+	manifests.UpdateMetadata(obj, hp.extraLabels, hp.extraAnnotations)
+	if hp.dryRun {
+		return hp.reportObject(obj)
+	}
+	hp.progress(ProgressStarting, obj, nil)
+	return hp.progressDone(ProgressCreated, obj, hp.createObject(obj, isTransientError))
+}
+
+// ApplyObject creates the object, or updates it if its cluster counterpart differs, so repeated deployments
+// converge. Like in dry-run mode, only the fields set in the object are compared, so the fields set by the
+// server or by other controllers do not trigger updates.
+// The whole apply is retried from scratch on transient errors, and if it races with a concurrent change
+// of the object: a fresh read tells if the object must be created or updated.
+func (hp *Helper) ApplyObject(obj client.Object) error {
+	manifests.UpdateMetadata(obj, hp.extraLabels, hp.extraAnnotations)
+	if hp.dryRun {
+		return hp.reportObject(obj)
+	}
+	if err := manifests.EnsureTypeMeta(obj); err != nil {
+		return err
+	}
+	hp.progress(ProgressStarting, obj, nil)
+	err := hp.retry("apply", obj, isApplyRetriable, func() error {
+		// the resource version of the previous attempt is stale
+		obj.SetResourceVersion("")
+		return hp.applyObject(obj)
+	})
+	return hp.progressDone(ProgressCreated, obj, err)
+}
+
+// WaitObject waits on the object, if it has a wait, reporting the progress.
+func (hp *Helper) WaitObject(wo WaitableObject) error {
+	if wo.Wait == nil {
+		return nil
+	}
+	hp.progress(ProgressWaiting, wo.Obj, nil)
+	return hp.progressDone(ProgressReady, wo.Obj, wo.Wait())
+}
+
+func (hp *Helper) progress(stage string, obj client.Object, err error) {
+	if hp.onProgress == nil || hp.dryRun {
+		return
+	}
+	hp.onProgress(ProgressEvent{
+		Component: hp.tag,
+		Object:    obj,
+		Stage:     stage,
+		Err:       err,
+	})
+}
+
+// progressDone reports the stage reached by the object, or its failure if err is not nil. Returns err.
+func (hp *Helper) progressDone(stage string, obj client.Object, err error) error {
+	if err != nil {
+		hp.progress(ProgressFailed, obj, err)
+		return err
+	}
+	hp.progress(stage, obj, nil)
+	return nil
+}
+
+func (hp *Helper) applyObject(obj client.Object) error {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(gvk)
+	err := hp.cli.Get(hp.ctx, client.ObjectKeyFromObject(obj), live)
+	// ApplyObject retries: the create and update calls must not retry on their own
+	if k8serrors.IsNotFound(err) {
+		return hp.createObject(obj, noRetry)
+	}
+	if err != nil {
+		return err
+	}
+	changes, err := diff.Compare(obj, live)
+	if err != nil {
+		return fmt.Errorf("cannot compare %s: %w", manifests.ObjectName(obj), err)
+	}
+	if len(changes) == 0 {
+		tlog.PrintfFields(hp.log, hp.objectFields("unchanged", gvk.Kind, obj), "-%5s> unchanged %s %q", hp.tag, gvk.Kind, obj.GetName())
+		hp.record(ActionUnchanged, gvk.Kind, obj)
+		return nil
+	}
+	obj.SetResourceVersion(live.GetResourceVersion())
+	if err := hp.updateObject(obj, noRetry); err != nil {
+		if k8serrors.IsInvalid(err) {
+			return fmt.Errorf("cannot update %s, its changes may involve immutable fields: remove it and deploy again: %w", manifests.ObjectName(obj), err)
+		}
+		return err
+	}
+	return nil
+}
+
+// createObject creates the object, retrying the errors matching retriable. If a retried create finds
+// the object already there, the previous attempt went through despite its error: the create succeeded.
+func (hp *Helper) createObject(obj client.Object, retriable func(error) bool) error {
 	objKind := obj.GetObjectKind().GroupVersionKind().Kind // shortcut
-	if err := hp.cli.Create(context.TODO(), obj); err != nil {
-		hp.log.Printf("-%5s> error creating %s %q: %v", hp.tag, objKind, obj.GetName(), err)
+	attempts := 0
+	err := hp.retry("create", obj, retriable, func() error {
+		attempts++
+		err := hp.cli.Create(hp.ctx, obj)
+		if attempts > 1 && k8serrors.IsAlreadyExists(err) {
+			hp.log.Debugf("-%5s> %s %q already created by a previous attempt", hp.tag, objKind, obj.GetName())
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		tlog.PrintfFields(hp.log, hp.objectFields("error creating", objKind, obj), "-%5s> error creating %s %q: %v", hp.tag, objKind, obj.GetName(), err)
+		metrics.Default.OperationFailed(metrics.OperationCreate)
+		return err
+	}
+	tlog.PrintfFields(hp.log, hp.objectFields("created", objKind, obj), "-%5s> created %s %q", hp.tag, objKind, obj.GetName())
+	metrics.Default.ObjectCreated(objKind)
+	hp.record(ActionCreated, objKind, obj)
+	if hp.onCreate != nil {
+		hp.onCreate(obj)
+	}
+	return nil
+}
+
+// reportObject compares the object with its cluster counterpart, logging if it would be created,
+// updated or left unchanged.
+func (hp *Helper) reportObject(obj client.Object) error {
+	if err := manifests.EnsureTypeMeta(obj); err != nil {
 		return err
 	}
-	hp.log.Printf("-%5s> created %s %q", hp.tag, objKind, obj.GetName())
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(gvk)
+	err := hp.cli.Get(hp.ctx, client.ObjectKeyFromObject(obj), live)
+	if k8serrors.IsNotFound(err) {
+		tlog.PrintfFields(hp.log, hp.objectFields("would create", gvk.Kind, obj), "-%5s> would create %s %q", hp.tag, gvk.Kind, obj.GetName())
+		hp.record(ActionWouldCreate, gvk.Kind, obj)
+		hp.changed = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	changes, err := diff.Compare(obj, live)
+	if err != nil {
+		return fmt.Errorf("cannot compare %s: %w", manifests.ObjectName(obj), err)
+	}
+	if len(changes) > 0 {
+		tlog.PrintfFields(hp.log, hp.objectFields("would update", gvk.Kind, obj), "-%5s> would update %s %q (%d changes)", hp.tag, gvk.Kind, obj.GetName(), len(changes))
+		hp.record(ActionWouldUpdate, gvk.Kind, obj)
+		hp.changed = true
+		return nil
+	}
+	tlog.PrintfFields(hp.log, hp.objectFields("unchanged", gvk.Kind, obj), "-%5s> unchanged %s %q", hp.tag, gvk.Kind, obj.GetName())
+	hp.record(ActionUnchanged, gvk.Kind, obj)
+	return nil
+}
+
+func (hp *Helper) UpdateObject(obj client.Object) error {
+	return hp.updateObject(obj, isTransientError)
+}
+
+// updateObject updates the object, retrying the errors matching retriable.
+func (hp *Helper) updateObject(obj client.Object, retriable func(error) bool) error {
+	objKind := obj.GetObjectKind().GroupVersionKind().Kind // shortcut
+	err := hp.retry("update", obj, retriable, func() error {
+		return hp.cli.Update(hp.ctx, obj)
+	})
+	if err != nil {
+		tlog.PrintfFields(hp.log, hp.objectFields("error updating", objKind, obj), "-%5s> error updating %s %q: %v", hp.tag, objKind, obj.GetName(), err)
+		metrics.Default.OperationFailed(metrics.OperationUpdate)
+		return err
+	}
+	tlog.PrintfFields(hp.log, hp.objectFields("updated", objKind, obj), "-%5s> updated %s %q", hp.tag, objKind, obj.GetName())
+	hp.record(ActionUpdated, objKind, obj)
 	return nil
 }
 
 func (hp *Helper) DeleteObject(obj client.Object) error {
 	objKind := obj.GetObjectKind().GroupVersionKind().Kind // shortcut
-	if err := hp.cli.Delete(context.TODO(), obj); err != nil {
-		hp.log.Printf("-%5s> error deleting %s %q: %v", hp.tag, objKind, obj.GetName(), err)
+	if err := hp.cli.Delete(hp.ctx, obj); err != nil {
+		if hp.ignoreNotFound && k8serrors.IsNotFound(err) {
+			hp.log.Debugf("-%5s> %s %q not found, nothing to delete", hp.tag, objKind, obj.GetName())
+			return nil
+		}
+		tlog.PrintfFields(hp.log, hp.objectFields("error deleting", objKind, obj), "-%5s> error deleting %s %q: %v", hp.tag, objKind, obj.GetName(), err)
+		metrics.Default.OperationFailed(metrics.OperationDelete)
+		return err
+	}
+	tlog.PrintfFields(hp.log, hp.objectFields("deleted", objKind, obj), "-%5s> deleted %s %q", hp.tag, objKind, obj.GetName())
+	hp.record(ActionDeleted, objKind, obj)
+	return nil
+}
+
+// ForceRemoval clears the finalizers of the deleted object if it is still there, stuck terminating, after the grace
+// period, so its deletion completes. Zero grace means DefaultForceGracePeriod. Only the objects labeled as created by
+// the deployer are touched, the others are left to their owners. Only the metadata finalizers are cleared: the spec
+// finalizer of the namespaces is left to the namespace controller, which removes it once their content is gone.
+func (hp *Helper) ForceRemoval(obj client.Object, grace time.Duration) error {
+	if err := manifests.EnsureTypeMeta(obj); err != nil {
+		return err
+	}
+	if grace <= 0 {
+		grace = DefaultForceGracePeriod
+	}
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(gvk)
+	expired := time.Now().Add(grace)
+	for {
+		err := hp.cli.Get(hp.ctx, client.ObjectKeyFromObject(obj), live)
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !time.Now().Before(expired) {
+			break
+		}
+		timer := time.NewTimer(forcePollInterval)
+		select {
+		case <-hp.ctx.Done():
+			timer.Stop()
+			return hp.ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if live.GetLabels()[manifests.LabelManagedBy] != manifests.ManagedByDeployer {
+		hp.log.Printf("-%5s> %s %q still present after %v, not created by the deployer: leaving its finalizers", hp.tag, gvk.Kind, obj.GetName(), grace)
+		return nil
+	}
+	finalizers := live.GetFinalizers()
+	if len(finalizers) == 0 {
+		hp.log.Printf("-%5s> %s %q still present after %v, without finalizers to clear", hp.tag, gvk.Kind, obj.GetName(), grace)
+		return nil
+	}
+	patch := client.RawPatch(types.MergePatchType, []byte(`{"metadata":{"finalizers":null}}`))
+	if err := hp.cli.Patch(hp.ctx, live, patch); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		tlog.PrintfFields(hp.log, hp.objectFields("error clearing finalizers", gvk.Kind, obj), "-%5s> error clearing the finalizers of %s %q: %v", hp.tag, gvk.Kind, obj.GetName(), err)
 		return err
 	}
-	hp.log.Printf("-%5s> deleted %s %q", hp.tag, objKind, obj.GetName())
+	tlog.PrintfFields(hp.log, hp.objectFields("finalizers cleared", gvk.Kind, obj), "-%5s> cleared the finalizers %v of %s %q", hp.tag, finalizers, gvk.Kind, obj.GetName())
+	hp.record(ActionFinalizersCleared, gvk.Kind, obj)
 	return nil
 }
 
+// objectFields returns the structured context of the log messages about the action on the object.
+func (hp *Helper) objectFields(action, kind string, obj client.Object) tlog.Fields {
+	return tlog.Fields{
+		Component: hp.tag,
+		Action:    action,
+		Kind:      kind,
+		Name:      obj.GetName(),
+	}
+}
+
+// record adds the action taken on the object to the Result.
+func (hp *Helper) record(action, kind string, obj client.Object) {
+	hp.result.Objects = append(hp.result.Objects, ObjectResult{
+		Component: hp.tag,
+		Kind:      kind,
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		Action:    action,
+	})
+}
+
+// retry calls fn until it succeeds, it fails with an error not worth retrying, or the retries are exhausted,
+// waiting with exponential backoff between the attempts. Returns the last error.
+func (hp *Helper) retry(op string, obj client.Object, retriable func(error) bool, fn func() error) error {
+	delay := retryInitialDelay
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= hp.retries || !retriable(err) {
+			return err
+		}
+		hp.log.Printf("-%5s> %s %s %q failed (attempt %d/%d), retrying in %v: %v", hp.tag, op, obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), attempt+1, hp.retries+1, delay, err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-hp.ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// isTransientError tells if the request may succeed if sent again unchanged. Conflicts are not,
+// the object must be read again first.
+func isTransientError(err error) bool {
+	return k8serrors.IsInternalError(err) ||
+		k8serrors.IsServiceUnavailable(err) ||
+		k8serrors.IsServerTimeout(err) ||
+		k8serrors.IsTimeout(err) ||
+		k8serrors.IsTooManyRequests(err) ||
+		utilnet.IsConnectionReset(err) ||
+		utilnet.IsProbableEOF(err)
+}
+
+// isApplyRetriable tells if an apply may succeed if done again from scratch: besides the transient errors,
+// the conflicts and the creations racing with another creator, both solved reading the object again.
+func isApplyRetriable(err error) bool {
+	return isTransientError(err) || k8serrors.IsConflict(err) || k8serrors.IsAlreadyExists(err)
+}
+
+func noRetry(err error) bool {
+	return false
+}
+
 func (hp *Helper) GetObject(key client.ObjectKey, obj client.Object) error {
-	return hp.cli.Get(context.TODO(), key, obj)
+	return hp.cli.Get(hp.ctx, key, obj)
+}
+
+// ListObjects returns the objects of the given kind matching all the labels, in all the namespaces.
+func (hp *Helper) ListObjects(gvk schema.GroupVersionKind, matchLabels map[string]string) ([]client.Object, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := hp.cli.List(hp.ctx, list, client.MatchingLabels(matchLabels)); err != nil {
+		return nil, err
+	}
+	ret := make([]client.Object, 0, len(list.Items))
+	for idx := range list.Items {
+		ret = append(ret, &list.Items[idx])
+	}
+	return ret, nil
 }
 
 func (hp *Helper) GetPodsByPattern(namespace, pattern string) ([]*corev1.Pod, error) {
 	var podList corev1.PodList
-	err := hp.cli.List(context.TODO(), &podList)
+	err := hp.cli.List(hp.ctx, &podList)
 	if err != nil {
 		return nil, err
 	}
@@ -104,6 +625,49 @@ func (hp *Helper) GetPodsByPattern(namespace, pattern string) ([]*corev1.Pod, er
 	return ret, nil
 }
 
+func (hp *Helper) GetPodsBySelector(namespace string, selector labels.Selector) ([]corev1.Pod, error) {
+	var podList corev1.PodList
+	err := hp.cli.List(hp.ctx, &podList, &client.ListOptions{Namespace: namespace, LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	hp.log.Debugf("found %d pods in namespace %q matching selector %q", len(podList.Items), namespace, selector.String())
+	return podList.Items, nil
+}
+
+// ArePodsRunningByPattern tells if some pods match the pattern in the namespace, and all of them are running.
+func (hp *Helper) ArePodsRunningByPattern(namespace, pattern string) (bool, error) {
+	pods, err := hp.GetPodsByPattern(namespace, pattern)
+	if err != nil {
+		return false, err
+	}
+	if len(pods) == 0 {
+		hp.log.Printf("no pods found in namespace %q matching %q", namespace, pattern)
+		return false, nil
+	}
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodRunning {
+			hp.log.Printf("pod %s %s not ready yet (%s)", pod.Namespace, pod.Name, pod.Status.Phase)
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// IsCRDEstablished tells if the CRD is established, thus ready to serve its objects.
+func (hp *Helper) IsCRDEstablished(name string) (bool, error) {
+	var crd apiextensionsv1.CustomResourceDefinition
+	if err := hp.GetObject(client.ObjectKey{Name: name}, &crd); err != nil {
+		return false, err
+	}
+	for _, cond := range crd.Status.Conditions {
+		if cond.Type == apiextensionsv1.Established {
+			return cond.Status == apiextensionsv1.ConditionTrue, nil
+		}
+	}
+	return false, nil
+}
+
 func (hp *Helper) GetDaemonSetByName(namespace, name string) (*appsv1.DaemonSet, error) {
 	key := client.ObjectKey{
 		Namespace: namespace,
@@ -117,6 +681,19 @@ func (hp *Helper) GetDaemonSetByName(namespace, name string) (*appsv1.DaemonSet,
 	return &ds, nil
 }
 
+func (hp *Helper) GetDeploymentByName(namespace, name string) (*appsv1.Deployment, error) {
+	key := client.ObjectKey{
+		Namespace: namespace,
+		Name:      name,
+	}
+	var dp appsv1.Deployment
+	err := hp.GetObject(key, &dp)
+	if err != nil {
+		return nil, err
+	}
+	return &dp, nil
+}
+
 func (hp *Helper) IsDaemonSetRunning(namespace, name string) (bool, error) {
 	ds, err := hp.GetDaemonSetByName(namespace, name)
 	if err != nil {
@@ -130,6 +707,19 @@ func (hp *Helper) IsDaemonSetRunning(namespace, name string) (bool, error) {
 	return (ds.Status.DesiredNumberScheduled > 0 && ds.Status.DesiredNumberScheduled == ds.Status.NumberReady), nil
 }
 
+func (hp *Helper) IsDaemonSetRolledOut(namespace, name string) (bool, error) {
+	ds, err := hp.GetDaemonSetByName(namespace, name)
+	if err != nil {
+		return false, err
+	}
+	if ds.Status.ObservedGeneration < ds.Generation {
+		hp.log.Printf("daemonset %q %q generation %d not yet observed (%d)", namespace, name, ds.Generation, ds.Status.ObservedGeneration)
+		return false, nil
+	}
+	hp.log.Printf("daemonset %q %q desired %d updated %d available %d", namespace, name, ds.Status.DesiredNumberScheduled, ds.Status.UpdatedNumberScheduled, ds.Status.NumberAvailable)
+	return (ds.Status.UpdatedNumberScheduled == ds.Status.DesiredNumberScheduled && ds.Status.NumberAvailable == ds.Status.DesiredNumberScheduled), nil
+}
+
 func (hp *Helper) IsDaemonSetGone(namespace, name string) (bool, error) {
 	ds, err := hp.GetDaemonSetByName(namespace, name)
 	if err != nil {
@@ -142,3 +732,46 @@ func (hp *Helper) IsDaemonSetGone(namespace, name string) (bool, error) {
 	hp.log.Printf("daemonset %q %q running count %d", namespace, name, ds.Status.CurrentNumberScheduled)
 	return false, nil
 }
+
+// ValidateReplicasFit checks there are enough nodes to run all the replicas of the deployment,
+// if its pods are required to run on different nodes. Otherwise the exceeding replicas would
+// stay Pending forever.
+func (hp *Helper) ValidateReplicasFit(dp *appsv1.Deployment) error {
+	if dp.Spec.Replicas == nil || *dp.Spec.Replicas <= 1 || !manifests.HasHostnameAntiAffinity(&dp.Spec.Template) {
+		return nil
+	}
+	var nodeList corev1.NodeList
+	if err := hp.cli.List(hp.ctx, &nodeList); err != nil {
+		return err
+	}
+	schedNodes := nodes.GetSchedulable(nodeList.Items, &dp.Spec.Template.Spec)
+	replicas := int(*dp.Spec.Replicas)
+	if replicas > len(schedNodes) {
+		return fmt.Errorf("deployment %q requests %d replicas on different nodes, but only %d nodes can run them: %d replicas would stay Pending", dp.Name, replicas, len(schedNodes), replicas-len(schedNodes))
+	}
+	return nil
+}
+
+// WarnUnsupportedArchitectures logs a warning for each image which is known to lack
+// a variant for some of the architectures of the cluster nodes. The images unknown
+// to the deployer (e.g. user-provided) cannot be checked, and are skipped.
+func (hp *Helper) WarnUnsupportedArchitectures(imgs ...string) error {
+	var nodeList corev1.NodeList
+	if err := hp.cli.List(hp.ctx, &nodeList); err != nil {
+		return err
+	}
+	nodeArches := nodes.GetArchitectures(nodeList.Items)
+	hp.log.Debugf("cluster node architectures: %v", nodeArches)
+
+	for _, img := range imgs {
+		imgArches, ok := images.Architectures(img)
+		if !ok {
+			hp.log.Debugf("cannot verify the architectures of image %q", img)
+			continue
+		}
+		if missing := sets.NewString(nodeArches...).Difference(sets.NewString(imgArches...)); missing.Len() > 0 {
+			hp.log.Printf("WARNING: image %q is not available for architectures %v: pods on these nodes will fail to start", img, missing.List())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,346 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package deployer
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/tlog"
+)
+
+func TestRetry(t *testing.T) {
+	gr := schema.GroupResource{Resource: "configmaps"}
+	unavailable := k8serrors.NewServiceUnavailable("try later")
+	testCases := []struct {
+		name          string
+		retries       int
+		errs          []error
+		expectedCalls int
+		expectedErr   bool
+	}{
+		{name: "success", retries: 2, expectedCalls: 1},
+		{name: "transient then success", retries: 2, errs: []error{unavailable}, expectedCalls: 2},
+		{name: "retries exhausted", retries: 1, errs: []error{unavailable, unavailable, unavailable}, expectedCalls: 2, expectedErr: true},
+		{name: "retries disabled", retries: 0, errs: []error{unavailable}, expectedCalls: 1, expectedErr: true},
+		{name: "fail fast", retries: 2, errs: []error{k8serrors.NewForbidden(gr, "cm", errors.New("nope"))}, expectedCalls: 1, expectedErr: true},
+		{name: "conflicts need a new read", retries: 2, errs: []error{k8serrors.NewConflict(gr, "cm", errors.New("stale"))}, expectedCalls: 1, expectedErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hp := NewHelperWithClient(nil, "TEST", tlog.NewNullLogAdapter()).WithRetries(tc.retries)
+			calls := 0
+			err := hp.retry("create", &corev1.ConfigMap{}, isTransientError, func() error {
+				calls++
+				if calls <= len(tc.errs) {
+					return tc.errs[calls-1]
+				}
+				return nil
+			})
+			if calls != tc.expectedCalls {
+				t.Errorf("expected %d calls, got %d", tc.expectedCalls, calls)
+			}
+			if (err != nil) != tc.expectedErr {
+				t.Errorf("expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	hp := NewHelperWithClient(nil, "TEST", tlog.NewNullLogAdapter()).WithContext(ctx)
+	calls := 0
+	err := hp.retry("create", &corev1.ConfigMap{}, isTransientError, func() error {
+		calls++
+		return unavailable
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("retries did not stop on cancellation: calls=%d err=%v", calls, err)
+	}
+}
+
+func TestHelperResult(t *testing.T) {
+	hp := NewHelperWithClient(nil, "RTE", tlog.NewNullLogAdapter())
+	cm := &corev1.ConfigMap{}
+	cm.Namespace = "tas-topology-updater"
+	cm.Name = "rte-config"
+	hp.record(ActionCreated, "ConfigMap", cm)
+
+	res := hp.Result()
+	hp.record(ActionDeleted, "ConfigMap", cm)
+	if len(res.Objects) != 1 {
+		t.Fatalf("result changed after being returned: %+v", res.Objects)
+	}
+	expected := ObjectResult{Component: "RTE", Kind: "ConfigMap", Namespace: "tas-topology-updater", Name: "rte-config", Action: ActionCreated}
+	if res.Objects[0] != expected {
+		t.Errorf("unexpected object result: got %+v expected %+v", res.Objects[0], expected)
+	}
+
+	res.Merge(nil)
+	res.Merge(hp.Result())
+	if len(res.Objects) != 3 || res.Objects[2].Action != ActionDeleted {
+		t.Errorf("unexpected merged result: %+v", res.Objects)
+	}
+}
+
+func TestWaitObjectProgress(t *testing.T) {
+	failure := errors.New("timed out")
+	testCases := []struct {
+		name     string
+		wait     func() error
+		dryRun   bool
+		expected []string
+	}{
+		{name: "no wait"},
+		{name: "ready", wait: func() error { return nil }, expected: []string{ProgressWaiting, ProgressReady}},
+		{name: "failed", wait: func() error { return failure }, expected: []string{ProgressWaiting, ProgressFailed}},
+		{name: "dry run", wait: func() error { return nil }, dryRun: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			hp := NewHelperWithClient(nil, "SCD", tlog.NewNullLogAdapter()).WithDryRun(tc.dryRun).WithOnProgress(func(ev ProgressEvent) {
+				if ev.Component != "SCD" {
+					t.Errorf("unexpected component %q", ev.Component)
+				}
+				if (ev.Stage == ProgressFailed) != (ev.Err != nil) {
+					t.Errorf("unexpected error %v for stage %q", ev.Err, ev.Stage)
+				}
+				got = append(got, ev.Stage)
+			})
+			err := hp.WaitObject(WaitableObject{Obj: &corev1.ConfigMap{}, Wait: tc.wait})
+			if (err != nil) != (tc.name == "failed") {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("unexpected stages: got %v expected %v", got, tc.expected)
+			}
+		})
+	}
+}
+
+// deleteClient fails all the deletions with err. Calling its other methods panics.
+type deleteClient struct {
+	client.Client
+	err error
+}
+
+func (dc deleteClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	return dc.err
+}
+
+func TestDeleteObjectIgnoreNotFound(t *testing.T) {
+	gr := schema.GroupResource{Resource: "configmaps"}
+	testCases := []struct {
+		name           string
+		err            error
+		ignoreNotFound bool
+		expectedErr    bool
+	}{
+		{name: "deleted"},
+		{name: "not found", err: k8serrors.NewNotFound(gr, "rte-config"), expectedErr: true},
+		{name: "not found ignored", err: k8serrors.NewNotFound(gr, "rte-config"), ignoreNotFound: true},
+		{name: "failure not ignored", err: k8serrors.NewForbidden(gr, "rte-config", errors.New("nope")), ignoreNotFound: true, expectedErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hp := NewHelperWithClient(deleteClient{err: tc.err}, "RTE", tlog.NewNullLogAdapter()).WithIgnoreNotFound(tc.ignoreNotFound)
+			cm := &corev1.ConfigMap{}
+			cm.Name = "rte-config"
+			err := hp.DeleteObject(cm)
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			deleted := len(hp.Result().Objects) == 1
+			if deleted != (tc.err == nil) {
+				t.Errorf("unexpected result: %+v", hp.Result().Objects)
+			}
+		})
+	}
+}
+
+// createClient fails the creations with errs, in order, then succeeds. Calling its other methods panics.
+type createClient struct {
+	client.Client
+	errs  []error
+	calls *int
+}
+
+func (cc createClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	*cc.calls++
+	if *cc.calls <= len(cc.errs) {
+		return cc.errs[*cc.calls-1]
+	}
+	return nil
+}
+
+func TestCreateObjectRetried(t *testing.T) {
+	gr := schema.GroupResource{Resource: "configmaps"}
+	unavailable := k8serrors.NewServiceUnavailable("try later")
+	exists := k8serrors.NewAlreadyExists(gr, "rte-config")
+	testCases := []struct {
+		name          string
+		errs          []error
+		expectedCalls int
+		expectedErr   bool
+	}{
+		{name: "created", expectedCalls: 1},
+		{name: "already there", errs: []error{exists}, expectedCalls: 1, expectedErr: true},
+		{name: "created by the failed attempt", errs: []error{unavailable, exists}, expectedCalls: 2},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			hp := NewHelperWithClient(createClient{errs: tc.errs, calls: &calls}, "RTE", tlog.NewNullLogAdapter()).WithRetries(1)
+			cm := &corev1.ConfigMap{}
+			cm.Name = "rte-config"
+			err := hp.CreateObject(cm)
+			if (err != nil) != tc.expectedErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if calls != tc.expectedCalls {
+				t.Errorf("expected %d calls, got %d", tc.expectedCalls, calls)
+			}
+		})
+	}
+}
+
+func TestIsApplyRetriable(t *testing.T) {
+	gr := schema.GroupResource{Resource: "configmaps"}
+	if !isApplyRetriable(k8serrors.NewConflict(gr, "cm", errors.New("stale"))) {
+		t.Errorf("conflicts must be retried")
+	}
+	if !isApplyRetriable(k8serrors.NewAlreadyExists(gr, "cm")) {
+		t.Errorf("racing creations must be retried")
+	}
+	if !isApplyRetriable(k8serrors.NewServiceUnavailable("try later")) {
+		t.Errorf("transient errors must be retried")
+	}
+	if isApplyRetriable(k8serrors.NewForbidden(gr, "cm", errors.New("nope"))) {
+		t.Errorf("forbidden must not be retried")
+	}
+}
+
+// applyClient serves live as the cluster counterpart of every object, NotFound if nil,
+// and records the creations and the updates, failing the latter with updateErr.
+// Calling its other methods panics.
+type applyClient struct {
+	client.Client
+	live      *unstructured.Unstructured
+	updateErr error
+	created   *[]string
+	updated   *[]string
+}
+
+func (ac applyClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	if ac.live == nil {
+		return k8serrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, key.Name)
+	}
+	obj.(*unstructured.Unstructured).Object = ac.live.DeepCopy().Object
+	return nil
+}
+
+func (ac applyClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	*ac.created = append(*ac.created, obj.GetName())
+	return nil
+}
+
+func (ac applyClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	*ac.updated = append(*ac.updated, obj.GetName()+"@"+obj.GetResourceVersion())
+	return ac.updateErr
+}
+
+func newLiveConfigMap(data map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"namespace":       "tas",
+			"name":            "rte-config",
+			"resourceVersion": "42",
+		},
+		"data": data,
+	}}
+}
+
+func TestApplyObject(t *testing.T) {
+	invalid := k8serrors.NewInvalid(schema.GroupKind{Kind: "ConfigMap"}, "rte-config", nil)
+	testCases := []struct {
+		name            string
+		dryRun          bool
+		live            *unstructured.Unstructured
+		updateErr       error
+		expectedCreated []string
+		expectedUpdated []string
+		expectedAction  string
+		expectedErr     bool
+		expectedChanges bool
+	}{
+		{name: "missing", expectedCreated: []string{"rte-config"}, expectedAction: ActionCreated},
+		{name: "unchanged", live: newLiveConfigMap(map[string]interface{}{"config.yaml": "foo: bar"}), expectedAction: ActionUnchanged},
+		{name: "changed", live: newLiveConfigMap(map[string]interface{}{"config.yaml": "foo: baz"}), expectedUpdated: []string{"rte-config@42"}, expectedAction: ActionUpdated},
+		{name: "immutable changed", live: newLiveConfigMap(map[string]interface{}{"config.yaml": "foo: baz"}), updateErr: invalid, expectedUpdated: []string{"rte-config@42"}, expectedErr: true},
+		{name: "dry run missing", dryRun: true, expectedAction: ActionWouldCreate, expectedChanges: true},
+		{name: "dry run unchanged", dryRun: true, live: newLiveConfigMap(map[string]interface{}{"config.yaml": "foo: bar"}), expectedAction: ActionUnchanged},
+		{name: "dry run changed", dryRun: true, live: newLiveConfigMap(map[string]interface{}{"config.yaml": "foo: baz"}), expectedAction: ActionWouldUpdate, expectedChanges: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var created, updated []string
+			cli := applyClient{live: tc.live, updateErr: tc.updateErr, created: &created, updated: &updated}
+			hp := NewHelperWithClient(cli, "RTE", tlog.NewNullLogAdapter()).WithRetries(0).WithDryRun(tc.dryRun)
+			cm := &corev1.ConfigMap{}
+			cm.Namespace = "tas"
+			cm.Name = "rte-config"
+			cm.Data = map[string]string{"config.yaml": "foo: bar"}
+
+			err := hp.ApplyObject(cm)
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err != nil && !strings.Contains(err.Error(), "immutable fields") {
+				t.Errorf("the error does not tell about the immutable fields: %v", err)
+			}
+			if !reflect.DeepEqual(created, tc.expectedCreated) {
+				t.Errorf("unexpected creations: got %v expected %v", created, tc.expectedCreated)
+			}
+			if !reflect.DeepEqual(updated, tc.expectedUpdated) {
+				t.Errorf("unexpected updates: got %v expected %v", updated, tc.expectedUpdated)
+			}
+			res := hp.Result()
+			if tc.expectedAction == "" {
+				if len(res.Objects) != 0 {
+					t.Errorf("unexpected result: %+v", res.Objects)
+				}
+			} else if len(res.Objects) != 1 || res.Objects[0].Action != tc.expectedAction {
+				t.Errorf("unexpected result: %+v expected action %q", res.Objects, tc.expectedAction)
+			}
+			if changes := errors.Is(hp.DryRunResult(), ErrDryRunChanges); changes != tc.expectedChanges {
+				t.Errorf("unexpected dry run result: changes=%v", changes)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package objects
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/wait"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/tlog"
+)
+
+type Options struct {
+	WaitCompletion bool
+	WaitTimeout    time.Duration
+	// PollInterval, if not zero, overrides the interval of all the waits.
+	PollInterval time.Duration
+	OnCreate     deployer.ObjectFunc
+	OnReady      deployer.ObjectFunc
+	// OnProgress, if set, receives the progress of each object while deploying.
+	OnProgress deployer.ProgressFunc
+	// IgnoreNotFound makes the removal skip quietly the objects already gone, e.g. never deployed.
+	IgnoreNotFound bool
+	// Force clears the finalizers of the removed objects created by the deployer which are still there after
+	// ForceGracePeriod, zero meaning deployer.DefaultForceGracePeriod.
+	Force            bool
+	ForceGracePeriod time.Duration
+}
+
+// Deploy creates, or updates if they exist, an arbitrary set of objects, like the ones previously
+// rendered, honoring the same ordering and waiting rules of the component
+// deploy flows.
+func Deploy(ctx context.Context, log tlog.Logger, objs []client.Object, opts Options) (*deployer.Result, error) {
+	log.Printf("deploying %d objects...", len(objs))
+
+	hp, err := deployer.NewHelper("OBJ", log)
+	if err != nil {
+		return nil, err
+	}
+	hp.WithContext(ctx).WithOnCreate(opts.OnCreate).WithOnProgress(opts.OnProgress).WithWaitTimeout(opts.WaitTimeout).WithPollInterval(opts.PollInterval)
+
+	for _, wo := range ToCreatableObjects(hp, log, objs) {
+		if err := hp.ApplyObject(wo.Obj); err != nil {
+			return hp.Result(), err
+		}
+		if opts.WaitCompletion && wo.Wait != nil {
+			err = hp.WaitObject(wo)
+			if err != nil {
+				return hp.Result(), err
+			}
+			if opts.OnReady != nil {
+				opts.OnReady(wo.Obj)
+			}
+		}
+	}
+
+	log.Printf("...deployed %d objects!", len(objs))
+	return hp.Result(), nil
+}
+
+// Remove deletes exactly the given set of objects, like the ones produced by
+// a previous render, in reverse creation order. Errors are logged and the
+// removal keeps going to delete as much as possible, like the component flows.
+func Remove(ctx context.Context, log tlog.Logger, objs []client.Object, opts Options) (*deployer.Result, error) {
+	log.Printf("removing %d objects...", len(objs))
+
+	hp, err := deployer.NewHelper("OBJ", log)
+	if err != nil {
+		return nil, err
+	}
+	hp.WithContext(ctx).WithWaitTimeout(opts.WaitTimeout).WithPollInterval(opts.PollInterval).WithIgnoreNotFound(opts.IgnoreNotFound)
+
+	for _, wo := range ToDeletableObjects(hp, log, objs) {
+		err = hp.DeleteObject(wo.Obj)
+		if err != nil {
+			log.Printf("failed to remove: %v", err)
+			continue
+		}
+		if opts.Force {
+			if err := hp.ForceRemoval(wo.Obj, opts.ForceGracePeriod); err != nil {
+				log.Printf("failed to force the removal: %v", err)
+			}
+		}
+
+		if !opts.WaitCompletion || wo.Wait == nil {
+			continue
+		}
+
+		err = wo.Wait()
+		if err != nil {
+			log.Printf("failed to wait for removal: %v", err)
+		}
+	}
+
+	log.Printf("...removed %d objects!", len(objs))
+	return hp.Result(), nil
+}
+
+// Stale returns the objects labeled with all the given labels, like the ones created by the previous
+// deployments, which are not in the given set anymore. Only the kinds the deployer creates are looked up,
+// skipping the ones the cluster does not serve.
+func Stale(ctx context.Context, log tlog.Logger, objs []client.Object, matchLabels map[string]string) ([]client.Object, error) {
+	if len(matchLabels) == 0 {
+		return nil, fmt.Errorf("cannot find the stale objects without labels")
+	}
+
+	hp, err := deployer.NewHelper("OBJ", log)
+	if err != nil {
+		return nil, err
+	}
+	return staleObjects(hp.WithContext(ctx), log, objs, matchLabels)
+}
+
+func staleObjects(hp *deployer.Helper, log tlog.Logger, objs []client.Object, matchLabels map[string]string) ([]client.Object, error) {
+	current := sets.NewString()
+	for _, obj := range objs {
+		if err := manifests.EnsureTypeMeta(obj); err != nil {
+			return nil, err
+		}
+		current.Insert(objectKey(obj))
+	}
+
+	var stale []client.Object
+	for _, gvk := range prunableKinds {
+		live, err := hp.ListObjects(gvk, matchLabels)
+		if meta.IsNoMatchError(err) || k8serrors.IsNotFound(err) {
+			log.Debugf("cannot list %s: not served by the cluster", gvk.Kind)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range live {
+			if !current.Has(objectKey(obj)) {
+				stale = append(stale, obj)
+			}
+		}
+	}
+	return stale, nil
+}
+
+// Labeled returns all the objects labeled with all the given labels, like the ones created by the
+// deployments, regardless of the manifests they were created from.
+func Labeled(ctx context.Context, log tlog.Logger, matchLabels map[string]string) ([]client.Object, error) {
+	return Stale(ctx, log, nil, matchLabels)
+}
+
+// objectKey returns the identity of the object: group, kind, namespace and name.
+func objectKey(obj client.Object) string {
+	gk := obj.GetObjectKind().GroupVersionKind().GroupKind()
+	return gk.String() + "/" + obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// ToCreatableObjects sorts the objects in creation order and attaches the
+// readiness checks for the workloads.
+func ToCreatableObjects(hp *deployer.Helper, log tlog.Logger, objs []client.Object) []deployer.WaitableObject {
+	sorted := make([]client.Object, len(objs))
+	copy(sorted, objs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return kindOrder(sorted[i]) < kindOrder(sorted[j])
+	})
+
+	var ret []deployer.WaitableObject
+	for _, obj := range sorted {
+		ret = append(ret, deployer.WaitableObject{
+			Obj:  obj,
+			Wait: waitForCreation(hp, log, obj),
+		})
+	}
+	return ret
+}
+
+// ToDeletableObjects sorts the objects in reverse creation order and attaches
+// the checks for the objects whose deletion completes asynchronously.
+func ToDeletableObjects(hp *deployer.Helper, log tlog.Logger, objs []client.Object) []deployer.WaitableObject {
+	sorted := make([]client.Object, 0, len(objs))
+	for idx := len(objs) - 1; idx >= 0; idx-- {
+		sorted = append(sorted, objs[idx])
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return kindOrder(sorted[i]) > kindOrder(sorted[j])
+	})
+
+	var ret []deployer.WaitableObject
+	for _, obj := range sorted {
+		ret = append(ret, deployer.WaitableObject{
+			Obj:  obj,
+			Wait: waitForDeletion(hp, log, obj),
+		})
+	}
+	return ret
+}
+
+func waitForCreation(hp *deployer.Helper, log tlog.Logger, obj client.Object) func() error {
+	namespace, name := obj.GetNamespace(), obj.GetName()
+	switch obj.GetObjectKind().GroupVersionKind().Kind {
+	case "CustomResourceDefinition":
+		return func() error { return wait.CRDToBeEstablished(hp, log, name) }
+	case "DaemonSet":
+		return func() error { return wait.DaemonSetToBeRunning(hp, log, namespace, name) }
+	case "Deployment":
+		return func() error { return wait.PodsToBeRunningByRegex(hp, log, namespace, name) }
+	default:
+		return nil
+	}
+}
+
+func waitForDeletion(hp *deployer.Helper, log tlog.Logger, obj client.Object) func() error {
+	namespace, name := obj.GetNamespace(), obj.GetName()
+	switch obj.GetObjectKind().GroupVersionKind().Kind {
+	case "Namespace":
+		return func() error { return wait.NamespaceToBeGone(hp, log, name) }
+	case "DaemonSet":
+		return func() error { return wait.DaemonSetToBeGone(hp, log, namespace, name) }
+	case "Deployment":
+		return func() error { return wait.PodsToBeGoneByRegex(hp, log, namespace, name) }
+	default:
+		return nil
+	}
+}
+
+// prunableKinds are all the kinds of the objects the deployer creates.
+var prunableKinds = []schema.GroupVersionKind{
+	{Version: "v1", Kind: "Namespace"},
+	{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"},
+	{Version: "v1", Kind: "ServiceAccount"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRoleBinding"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "Role"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "RoleBinding"},
+	{Group: "security.openshift.io", Version: "v1", Kind: "SecurityContextConstraints"},
+	{Version: "v1", Kind: "ConfigMap"},
+	{Group: "networking.k8s.io", Version: "v1", Kind: "NetworkPolicy"},
+	{Version: "v1", Kind: "Service"},
+	{Group: "apps", Version: "v1", Kind: "DaemonSet"},
+	{Group: "apps", Version: "v1", Kind: "Deployment"},
+	manifests.ValidatingAdmissionPolicyGVK,
+	manifests.ValidatingAdmissionPolicyBindingGVK,
+	manifests.ServiceMonitorGVK,
+}
+
+// kinds not listed in manifests.KindOrder are created after the listed ones.
+func kindOrder(obj client.Object) int {
+	return manifests.KindRank(obj.GetObjectKind().GroupVersionKind().Kind)
+}
@@ -0,0 +1,239 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package objects
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests"
+	schedmanifests "github.com/k8stopologyawareschedwg/deployer/pkg/manifests/sched"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/tlog"
+)
+
+func TestCreationAndDeletionOrder(t *testing.T) {
+	objs := []client.Object{
+		newLabeledObject("monitoring.coreos.com/v1", "ServiceMonitor", "", "sm", nil),
+		newLabeledObject("admissionregistration.k8s.io/v1", "ValidatingAdmissionPolicyBinding", "", "vapb", nil),
+		newLabeledObject("admissionregistration.k8s.io/v1", "ValidatingAdmissionPolicy", "", "vap", nil),
+		&appsv1.DaemonSet{
+			TypeMeta:   metav1.TypeMeta{Kind: "DaemonSet", APIVersion: "apps/v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "ds"},
+		},
+		&corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "cm-a"},
+		},
+		&corev1.Namespace{
+			TypeMeta:   metav1.TypeMeta{Kind: "Namespace", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "ns"},
+		},
+		&corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "cm-b"},
+		},
+	}
+
+	created := names(ToCreatableObjects(nil, nil, objs))
+	expected := []string{"ns", "cm-a", "cm-b", "ds", "vap", "vapb", "sm"}
+	if !reflect.DeepEqual(created, expected) {
+		t.Errorf("unexpected creation order: %v expected %v", created, expected)
+	}
+
+	// render emits the objects in the same order they are created
+	rendered := make([]client.Object, len(objs))
+	copy(rendered, objs)
+	manifests.SortObjects(rendered)
+	var renderedNames []string
+	for _, obj := range rendered {
+		renderedNames = append(renderedNames, obj.GetName())
+	}
+	if !reflect.DeepEqual(renderedNames, expected) {
+		t.Errorf("unexpected render order: %v expected %v", renderedNames, expected)
+	}
+
+	deleted := names(ToDeletableObjects(nil, nil, objs))
+	expected = []string{"sm", "vapb", "vap", "ds", "cm-b", "cm-a", "ns"}
+	if !reflect.DeepEqual(deleted, expected) {
+		t.Errorf("unexpected deletion order: %v expected %v", deleted, expected)
+	}
+}
+
+func names(wos []deployer.WaitableObject) []string {
+	var ret []string
+	for _, wo := range wos {
+		ret = append(ret, wo.Obj.GetName())
+	}
+	return ret
+}
+
+// listClient lists the objects it holds matching the label selector. The kinds in unserved fail like
+// the ones the cluster does not serve. Calling its other methods panics.
+type listClient struct {
+	client.Client
+	objs     []*unstructured.Unstructured
+	unserved map[string]bool
+}
+
+func (lc listClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	ul := list.(*unstructured.UnstructuredList)
+	gvk := ul.GroupVersionKind()
+	kind := gvk.Kind[:len(gvk.Kind)-len("List")]
+	if lc.unserved[kind] {
+		return &meta.NoKindMatchError{GroupKind: schema.GroupKind{Group: gvk.Group, Kind: kind}}
+	}
+	lo := &client.ListOptions{}
+	lo.ApplyOptions(opts)
+	for _, obj := range lc.objs {
+		if obj.GetKind() != kind {
+			continue
+		}
+		if lo.LabelSelector != nil && !lo.LabelSelector.Matches(labels.Set(obj.GetLabels())) {
+			continue
+		}
+		ul.Items = append(ul.Items, *obj.DeepCopy())
+	}
+	return nil
+}
+
+func newLabeledObject(apiVersion, kind, namespace, name string, lbls map[string]string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetLabels(lbls)
+	return obj
+}
+
+func TestStaleObjects(t *testing.T) {
+	owned := map[string]string{"app.kubernetes.io/managed-by": "deployer"}
+	cli := listClient{
+		objs: []*unstructured.Unstructured{
+			newLabeledObject("v1", "ConfigMap", "tas", "rte-config", owned),
+			newLabeledObject("v1", "ConfigMap", "tas", "rte-config-old", owned),
+			newLabeledObject("v1", "ConfigMap", "tas", "someone-else", nil),
+			newLabeledObject("apps/v1", "DaemonSet", "tas", "rte", owned),
+			newLabeledObject("apps/v1", "DaemonSet", "tas", "rte-gpu", owned),
+			newLabeledObject("monitoring.coreos.com/v1", "ServiceMonitor", "tas", "rte", owned),
+		},
+		unserved: map[string]bool{"ServiceMonitor": true, "SecurityContextConstraints": true},
+	}
+	current := []client.Object{
+		&corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "tas", Name: "rte-config"},
+		},
+		&appsv1.DaemonSet{
+			TypeMeta:   metav1.TypeMeta{Kind: "DaemonSet", APIVersion: "apps/v1"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "tas", Name: "rte"},
+		},
+	}
+
+	hp := deployer.NewHelperWithClient(cli, "OBJ", tlog.NewNullLogAdapter())
+	stale, err := staleObjects(hp, tlog.NewNullLogAdapter(), current, owned)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, obj := range stale {
+		got = append(got, objectKey(obj))
+	}
+	sort.Strings(got)
+	expected := []string{"ConfigMap/tas/rte-config-old", "DaemonSet.apps/tas/rte-gpu"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected stale objects: got %v expected %v", got, expected)
+	}
+
+	labeled, err := staleObjects(hp, tlog.NewNullLogAdapter(), nil, owned)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(labeled) != 4 {
+		t.Errorf("expected all the 4 labeled objects of the served kinds, got %d", len(labeled))
+	}
+
+	if _, err := Stale(context.Background(), tlog.NewNullLogAdapter(), current, nil); err == nil {
+		t.Errorf("expected error without labels")
+	}
+}
+
+func TestStaleObjectsInstances(t *testing.T) {
+	mf, err := schedmanifests.GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	log := tlog.NewNullLogAdapter()
+	first := mf.Update(log, schedmanifests.UpdateOptions{SchedulerName: "tas-first"})
+	second := mf.Update(log, schedmanifests.UpdateOptions{SchedulerName: "tas-second"})
+	// the previous deployment of the second instance also enforced the scheduler name
+	secondPrev := mf.Update(log, schedmanifests.UpdateOptions{SchedulerName: "tas-second", EnforcedPodSelector: map[string]string{"numa": "true"}})
+
+	// the shared objects are labeled by the instance deployed last
+	live := make(map[string]*unstructured.Unstructured)
+	for _, dep := range []struct {
+		instance string
+		objs     []client.Object
+	}{
+		{instance: "tas-first", objs: first.ToObjects()},
+		{instance: "tas-second", objs: secondPrev.ToObjects()},
+	} {
+		for _, obj := range dep.objs {
+			if err := manifests.EnsureTypeMeta(obj); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			gvk := obj.GetObjectKind().GroupVersionKind()
+			live[objectKey(obj)] = newLabeledObject(gvk.GroupVersion().String(), gvk.Kind, obj.GetNamespace(), obj.GetName(), manifests.InstanceLabels(dep.instance))
+		}
+	}
+	cli := listClient{}
+	for _, obj := range live {
+		cli.objs = append(cli.objs, obj)
+	}
+
+	hp := deployer.NewHelperWithClient(cli, "OBJ", log)
+	stale, err := staleObjects(hp, log, second.ToObjects(), manifests.InstanceLabels("tas-second"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, obj := range stale {
+		got = append(got, objectKey(obj))
+	}
+	sort.Strings(got)
+	expected := []string{
+		"ValidatingAdmissionPolicy.admissionregistration.k8s.io//tas-second-name",
+		"ValidatingAdmissionPolicyBinding.admissionregistration.k8s.io//tas-second-name",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected stale objects: got %v expected %v", got, expected)
+	}
+}
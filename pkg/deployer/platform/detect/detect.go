@@ -18,28 +18,93 @@ package detect
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"time"
 
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/version"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/k8stopologyawareschedwg/deployer/pkg/clientutil"
 	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
 )
 
+const (
+	// DefaultTimeout is a reasonable bound for the detection requests, which are few and lightweight.
+	DefaultTimeout = 5 * time.Second
+	// NoTimeout makes the detection requests unbounded.
+	NoTimeout time.Duration = -1
+)
+
 func Detect() (platform.Platform, error) {
+	return DetectContext(context.Background())
+}
+
+// DetectContext is like Detect, but its requests stop once the context is done.
+func DetectContext(ctx context.Context) (platform.Platform, error) {
 	ocpCli, err := clientutil.NewOCPClientSet()
 	if err != nil {
-		return platform.Unknown, err
+		return platform.Unknown, fmt.Errorf("cannot create the client: %w", err)
 	}
-	sccs, err := ocpCli.SecurityV1.SecurityContextConstraints().List(context.TODO(), metav1.ListOptions{})
+	sccs, err := ocpCli.SecurityV1.SecurityContextConstraints().List(ctx, metav1.ListOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
 			return platform.Kubernetes, nil
 		}
-		return platform.Unknown, err
+		return platform.Unknown, fmt.Errorf("cannot list the security context constraints: %w", err)
 	}
 	if len(sccs.Items) > 0 {
 		return platform.OpenShift, nil
 	}
 	return platform.Kubernetes, nil
 }
+
+// Version detects the version of the platform: the OpenShift release, read from the ClusterVersion,
+// or the version of the Kubernetes API server.
+func Version(plat platform.Platform) (platform.Version, error) {
+	return VersionContext(context.Background(), plat)
+}
+
+// VersionContext is like Version, but its requests stop once the context is done.
+func VersionContext(ctx context.Context, plat platform.Platform) (platform.Version, error) {
+	if plat == platform.OpenShift {
+		return openShiftVersion(ctx)
+	}
+	k8sCli, err := clientutil.NewK8s()
+	if err != nil {
+		return "", fmt.Errorf("cannot create the client: %w", err)
+	}
+	// like Discovery().ServerVersion(), which does not take a context
+	data, err := k8sCli.Discovery().RESTClient().Get().AbsPath("/version").Do(ctx).Raw()
+	if err != nil {
+		return "", fmt.Errorf("cannot get the server version: %w", err)
+	}
+	var ver version.Info
+	if err := json.Unmarshal(data, &ver); err != nil {
+		return "", fmt.Errorf("cannot decode the server version: %w", err)
+	}
+	return platform.Version(ver.GitVersion), nil
+}
+
+func openShiftVersion(ctx context.Context) (platform.Version, error) {
+	cli, err := clientutil.New()
+	if err != nil {
+		return "", fmt.Errorf("cannot create the client: %w", err)
+	}
+	// use unstructured to avoid depending on the OpenShift config API only for this
+	cv := unstructured.Unstructured{}
+	cv.SetGroupVersionKind(schema.GroupVersionKind{Group: "config.openshift.io", Version: "v1", Kind: "ClusterVersion"})
+	if err := cli.Get(ctx, client.ObjectKey{Name: "version"}, &cv); err != nil {
+		return "", fmt.Errorf("cannot get the cluster version: %w", err)
+	}
+	ver, found, err := unstructured.NestedString(cv.Object, "status", "desired", "version")
+	if err != nil || !found {
+		return "", fmt.Errorf("cannot find the version in the cluster version status (err=%v)", err)
+	}
+	return platform.Version(ver), nil
+}
@@ -16,7 +16,10 @@
 
 package platform
 
-import "strings"
+import (
+	"strconv"
+	"strings"
+)
 
 type Platform string
 
@@ -41,3 +44,39 @@ func FromString(plat string) (Platform, bool) {
 		return Unknown, false
 	}
 }
+
+// Version is the version of the platform as reported by the cluster, e.g. "4.10.3" for OpenShift
+// or "v1.21.1" for Kubernetes. Empty means unknown.
+type Version string
+
+func (v Version) String() string {
+	return string(v)
+}
+
+// MajorMinor returns the major and minor components of the version.
+// Returns false if the version is unknown or malformed.
+func (v Version) MajorMinor() (int, int, bool) {
+	items := strings.SplitN(strings.TrimPrefix(string(v), "v"), ".", 3)
+	if len(items) < 2 {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(items[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	// tolerate suffixes like "21+" reported by some managed clusters
+	minor, err := strconv.Atoi(strings.TrimRight(items[1], "+"))
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
+
+// AtLeast tells if the version is equal or newer than major.minor. An unknown version is never.
+func (v Version) AtLeast(major, minor int) bool {
+	vMajor, vMinor, ok := v.MajorMinor()
+	if !ok {
+		return false
+	}
+	return vMajor > major || (vMajor == major && vMinor >= minor)
+}
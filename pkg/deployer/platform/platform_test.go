@@ -0,0 +1,43 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package platform
+
+import "testing"
+
+func TestVersionAtLeast(t *testing.T) {
+	testCases := []struct {
+		version  Version
+		major    int
+		minor    int
+		expected bool
+	}{
+		{version: "", major: 4, minor: 8},
+		{version: "garbage", major: 4, minor: 8},
+		{version: "4.7.21", major: 4, minor: 8},
+		{version: "4.8.0", major: 4, minor: 8, expected: true},
+		{version: "4.10.3", major: 4, minor: 8, expected: true},
+		{version: "5.0.0", major: 4, minor: 8, expected: true},
+		{version: "v1.21.1", major: 1, minor: 21, expected: true},
+		{version: "v1.20+", major: 1, minor: 21},
+		{version: "v1.22+", major: 1, minor: 21, expected: true},
+	}
+	for _, tc := range testCases {
+		if got := tc.version.AtLeast(tc.major, tc.minor); got != tc.expected {
+			t.Errorf("%q at least %d.%d: expected %v got %v", tc.version, tc.major, tc.minor, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package rte
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/wait"
+	rtemanifests "github.com/k8stopologyawareschedwg/deployer/pkg/manifests/rte"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/tlog"
+)
+
+// DeployCanary runs the new RTE configuration on the nodes matching opts.CanaryNodeSelector,
+// alongside the stable RTE, which is moved off these nodes first. If the canary cannot be deployed,
+// it is removed and the stable RTE gets back on these nodes. Deploying again the same canary is safe.
+// Use PromoteCanary to roll out the configuration on all the nodes, or RemoveCanary to discard it.
+func DeployCanary(log tlog.Logger, opts Options) error {
+	if opts.RTEConfigData == "" {
+		return fmt.Errorf("missing RTE configuration data")
+	}
+	if err := rtemanifests.ValidateConfigData(opts.RTEConfigData); err != nil {
+		return err
+	}
+	if err := rtemanifests.ValidateCanaryNodeSelector(opts.CanaryNodeSelector); err != nil {
+		return err
+	}
+	log.Printf("deploying topology-aware-scheduling topology updater canary...")
+
+	mf, canary, err := getCanaryManifests(opts)
+	if err != nil {
+		return err
+	}
+
+	hp, err := deployer.NewHelper("RTE", log)
+	if err != nil {
+		return err
+	}
+	hp.WithOnCreate(opts.OnCreate).WithOnProgress(opts.OnProgress).WithWaitTimeout(opts.WaitTimeout).WithPollInterval(opts.PollInterval).WithExtraMetadata(opts.ExtraLabels, opts.ExtraAnnotations)
+
+	// two RTEs on the same node would fight over its NodeResourceTopology object
+	if err := updateStableNodeAffinity(hp, log, mf.DaemonSet, opts.CanaryNodeSelector); err != nil {
+		return err
+	}
+
+	if err := applyCanaryObjects(hp, canary, log, opts); err != nil {
+		log.Printf("canary deployment failed: %v - rolling back", err)
+		for _, wo := range canary.ToDeletableObjects(hp, log) {
+			if delErr := hp.DeleteObject(wo.Obj); delErr != nil && !k8serrors.IsNotFound(delErr) {
+				log.Printf("failed to remove: %v", delErr)
+			}
+		}
+		if rbErr := updateStableNodeAffinity(hp, log, mf.DaemonSet, nil); rbErr != nil {
+			return fmt.Errorf("canary deployment failed: %v; restoring the stable RTE failed too: %w", err, rbErr)
+		}
+		return fmt.Errorf("canary deployment failed and was rolled back: %w", err)
+	}
+
+	log.Printf("...deployed topology-aware-scheduling topology updater canary!")
+	return nil
+}
+
+func applyCanaryObjects(hp *deployer.Helper, canary rtemanifests.CanaryManifests, log tlog.Logger, opts Options) error {
+	for _, wo := range canary.ToCreatableObjects(hp, log) {
+		if err := hp.ApplyObject(wo.Obj); err != nil {
+			return err
+		}
+		if opts.WaitCompletion && wo.Wait != nil {
+			if err := hp.WaitObject(wo); err != nil {
+				return err
+			}
+			if opts.OnReady != nil {
+				opts.OnReady(wo.Obj)
+			}
+		}
+	}
+	return nil
+}
+
+// PromoteCanary rolls out the canary configuration, which must be given again in opts, on the stable RTE,
+// then removes the canary and lets the stable RTE run again on the canary nodes.
+func PromoteCanary(log tlog.Logger, opts Options) error {
+	log.Printf("promoting topology-aware-scheduling topology updater canary...")
+	if err := ReloadConfig(log, opts); err != nil {
+		return err
+	}
+	if err := RemoveCanary(log, opts); err != nil {
+		return err
+	}
+	log.Printf("...promoted topology-aware-scheduling topology updater canary!")
+	return nil
+}
+
+// RemoveCanary removes the canary RTE and lets the stable RTE run again on the canary nodes.
+func RemoveCanary(log tlog.Logger, opts Options) error {
+	log.Printf("removing topology-aware-scheduling topology updater canary...")
+
+	mf, canary, err := getCanaryManifests(opts)
+	if err != nil {
+		return err
+	}
+
+	hp, err := deployer.NewHelper("RTE", log)
+	if err != nil {
+		return err
+	}
+	hp.WithWaitTimeout(opts.WaitTimeout).WithPollInterval(opts.PollInterval)
+
+	for _, wo := range canary.ToDeletableObjects(hp, log) {
+		if err := hp.DeleteObject(wo.Obj); err != nil {
+			log.Printf("failed to remove: %v", err)
+			continue
+		}
+		// the stable RTE must not get back on the canary nodes before the canary pods are gone
+		if wo.Wait != nil {
+			if err := wo.Wait(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := updateStableNodeAffinity(hp, log, mf.DaemonSet, nil); err != nil {
+		return err
+	}
+
+	log.Printf("...removed topology-aware-scheduling topology updater canary!")
+	return nil
+}
+
+func getCanaryManifests(opts Options) (rtemanifests.Manifests, rtemanifests.CanaryManifests, error) {
+	_, namespace, err := setupNamespace(opts)
+	if err != nil {
+		return rtemanifests.Manifests{}, rtemanifests.CanaryManifests{}, err
+	}
+	mf, err := rtemanifests.GetManifestsForNamespaceFromSource(opts.ManifestsSource, opts.Platform, namespace)
+	if err != nil {
+		return rtemanifests.Manifests{}, rtemanifests.CanaryManifests{}, err
+	}
+	mf = updateManifests(mf, namespace, opts)
+	canary, err := mf.Canary(opts.CanaryNodeSelector)
+	if err != nil {
+		return rtemanifests.Manifests{}, rtemanifests.CanaryManifests{}, err
+	}
+	return mf, canary, nil
+}
+
+// updateStableNodeAffinity restores the rendered node affinity on the running stable DaemonSet,
+// excluding the canary nodes if canaryNodeSelector is not empty, and waits for the rollout.
+func updateStableNodeAffinity(hp *deployer.Helper, log tlog.Logger, ref *appsv1.DaemonSet, canaryNodeSelector map[string]string) error {
+	ds, err := hp.GetDaemonSetByName(ref.Namespace, ref.Name)
+	if err != nil {
+		return fmt.Errorf("cannot get the running daemonset: %w", err)
+	}
+	ds.TypeMeta = ref.TypeMeta
+	ds.Spec.Template.Spec.Affinity = ref.Spec.Template.Spec.Affinity.DeepCopy()
+	if len(canaryNodeSelector) > 0 {
+		rtemanifests.ExcludeCanaryNodes(ds, canaryNodeSelector)
+	}
+	if err := hp.UpdateObject(ds); err != nil {
+		return err
+	}
+	return wait.DaemonSetRolloutToComplete(hp, log, ds.Namespace, ds.Name)
+}
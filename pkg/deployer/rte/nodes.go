@@ -0,0 +1,144 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package rte
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/clientutil/nodes"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer"
+	rtemanifests "github.com/k8stopologyawareschedwg/deployer/pkg/manifests/rte"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/tlog"
+)
+
+type MissingNode struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// MissingNodes returns the nodes which don't run a RTE pod, with the likely reason.
+func MissingNodes(log tlog.Logger, opts Options) ([]MissingNode, error) {
+	_, namespace, err := setupNamespace(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	mf, err := rtemanifests.GetManifestsForNamespaceFromSource(opts.ManifestsSource, opts.Platform, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	hp, err := deployer.NewHelper("RTE", log)
+	if err != nil {
+		return nil, err
+	}
+
+	ds, err := hp.GetDaemonSetByName(namespace, mf.DaemonSet.Name)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the running daemonset: %w", err)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(ds.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := hp.GetPodsBySelector(ds.Namespace, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeList, err := nodes.GetBySelector(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	return FindMissingNodes(ds, nodeList, pods), nil
+}
+
+// FindMissingNodes cross-references the nodes with the pods of the DaemonSet, returning
+// the nodes without a running pod, sorted by name.
+func FindMissingNodes(ds *appsv1.DaemonSet, nodeList []corev1.Node, pods []corev1.Pod) []MissingNode {
+	podsByNode := make(map[string]*corev1.Pod)
+	for idx := range pods {
+		podsByNode[pods[idx].Spec.NodeName] = &pods[idx]
+	}
+
+	var ret []MissingNode
+	for idx := range nodeList {
+		node := &nodeList[idx]
+		pod, ok := podsByNode[node.Name]
+		if ok && pod.Status.Phase == corev1.PodRunning {
+			continue
+		}
+		reason := "unknown"
+		if ok {
+			reason = fmt.Sprintf("pod %q is %s", pod.Name, pod.Status.Phase)
+		} else if why := whyNotScheduled(&ds.Spec.Template.Spec, node); why != "" {
+			reason = why
+		}
+		ret = append(ret, MissingNode{Name: node.Name, Reason: reason})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret
+}
+
+func whyNotScheduled(podSpec *corev1.PodSpec, node *corev1.Node) string {
+	for idx := range node.Spec.Taints {
+		taint := &node.Spec.Taints[idx]
+		if taint.Effect == corev1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		if !toleratesTaint(podSpec.Tolerations, taint) {
+			return fmt.Sprintf("taint %s not tolerated", taint.ToString())
+		}
+	}
+
+	for key, val := range podSpec.NodeSelector {
+		if nodeVal, ok := node.Labels[key]; !ok || nodeVal != val {
+			return fmt.Sprintf("nodeSelector %s=%s not matched", key, val)
+		}
+	}
+
+	var pressures []string
+	for _, cond := range node.Status.Conditions {
+		switch cond.Type {
+		case corev1.NodeMemoryPressure, corev1.NodeDiskPressure, corev1.NodePIDPressure:
+			if cond.Status == corev1.ConditionTrue {
+				pressures = append(pressures, string(cond.Type))
+			}
+		}
+	}
+	if len(pressures) > 0 {
+		return fmt.Sprintf("resource pressure: %s", strings.Join(pressures, ","))
+	}
+	return ""
+}
+
+func toleratesTaint(tolerations []corev1.Toleration, taint *corev1.Taint) bool {
+	for idx := range tolerations {
+		if tolerations[idx].ToleratesTaint(taint) {
+			return true
+		}
+	}
+	return false
+}
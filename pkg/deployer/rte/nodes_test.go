@@ -0,0 +1,107 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package rte
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFindMissingNodes(t *testing.T) {
+	ds := &appsv1.DaemonSet{
+		Spec: appsv1.DaemonSetSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					NodeSelector: map[string]string{"numa": "true"},
+				},
+			},
+		},
+	}
+
+	numaLabels := map[string]string{"numa": "true"}
+	nodeList := []corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "running", Labels: numaLabels}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pending", Labels: numaLabels}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "unlabeled"}},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "tainted", Labels: numaLabels},
+			Spec: corev1.NodeSpec{
+				Taints: []corev1.Taint{{Key: "dedicated", Value: "db", Effect: corev1.TaintEffectNoSchedule}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pressure", Labels: numaLabels},
+			Status: corev1.NodeStatus{
+				Conditions: []corev1.NodeCondition{{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionTrue}},
+			},
+		},
+	}
+	pods := []corev1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "rte-1"},
+			Spec:       corev1.PodSpec{NodeName: "running"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "rte-2"},
+			Spec:       corev1.PodSpec{NodeName: "pending"},
+			Status:     corev1.PodStatus{Phase: corev1.PodPending},
+		},
+	}
+
+	got := FindMissingNodes(ds, nodeList, pods)
+	expected := []MissingNode{
+		{Name: "pending", Reason: `pod "rte-2" is Pending`},
+		{Name: "pressure", Reason: "resource pressure: MemoryPressure"},
+		{Name: "tainted", Reason: "taint dedicated=db:NoSchedule not tolerated"},
+		{Name: "unlabeled", Reason: "nodeSelector numa=true not matched"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected missing nodes: %v expected %v", got, expected)
+	}
+}
+
+func TestConfigMapVolumeName(t *testing.T) {
+	tmpl := &corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{
+				{
+					Name: "other",
+					VolumeSource: corev1.VolumeSource{
+						ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "foo"}},
+					},
+				},
+				{
+					Name: "config",
+					VolumeSource: corev1.VolumeSource{
+						ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "rte-config-abcd"}},
+					},
+				},
+			},
+		},
+	}
+	if got := configMapVolumeName(tmpl, "config"); got != "rte-config-abcd" {
+		t.Errorf("unexpected configmap %q", got)
+	}
+	if got := configMapVolumeName(tmpl, "missing"); got != "" {
+		t.Errorf("unexpected configmap %q for a missing volume", got)
+	}
+}
@@ -17,9 +17,16 @@
 package rte
 
 import (
+	"context"
 	"fmt"
+	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer"
 	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
@@ -30,10 +37,70 @@ import (
 )
 
 type Options struct {
-	Platform         platform.Platform
-	WaitCompletion   bool
-	RTEConfigData    string
-	PullIfNotPresent bool
+	Platform       platform.Platform
+	WaitCompletion bool
+	WaitTimeout    time.Duration
+	// PollInterval, if not zero, overrides the interval of all the waits.
+	PollInterval                 time.Duration
+	RTEConfigData                string
+	ImmutableConfig              bool
+	PullIfNotPresent             bool
+	AllNodes                     bool
+	NodeSelector                 map[string]string
+	Tolerations                  []corev1.Toleration
+	StartupProbeFailureThreshold int32
+	StartupProbePeriodSeconds    int32
+	Finalizers                   []string
+	PodSchedulerName             string
+	ExtraInitContainers          []corev1.Container
+	ExtraContainers              []corev1.Container
+	ExtraVolumes                 []corev1.Volume
+	APIGroup                     string
+	// ImagePullSecrets are the secrets the pods use to pull the images from private registries.
+	ImagePullSecrets []string
+	// PriorityClassName is the priority class of the pods, which must exist. Empty means the cluster default.
+	PriorityClassName string
+	// WithNetworkPolicy adds a NetworkPolicy letting the RTE pods reach the API server. See rtemanifests.UpdateOptions.
+	WithNetworkPolicy bool
+	// MaxUnavailable, if set, makes the DaemonSets roll out gradually. See rtemanifests.UpdateOptions.
+	MaxUnavailable *intstr.IntOrString
+	// HostNetwork and DNSPolicy adapt the RTE pods to the cluster networking. See rtemanifests.UpdateOptions.
+	HostNetwork *bool
+	DNSPolicy   corev1.DNSPolicy
+	// WithServiceMonitor adds a ServiceMonitor scraping the RTE metrics. See rtemanifests.UpdateOptions.
+	// Deploy fails if the prometheus operator CRDs are not installed.
+	WithServiceMonitor bool
+	// Namespace, if not empty, is the namespace of the RTE objects, instead of the platform default.
+	// Supported only on kubernetes.
+	Namespace string
+	// SkipNamespace leaves the namespace to be managed by others: Deploy requires it to exist
+	// and does not create it, Remove does not delete it. Kubernetes only.
+	SkipNamespace bool
+	// ConfigMapName, if not empty, is the name of the RTE configuration ConfigMap.
+	ConfigMapName string
+	// ConfigProfiles are additional configurations, each served by its own DaemonSet on the nodes it selects.
+	// Must be given to Remove too, to remove their objects.
+	ConfigProfiles []rtemanifests.ConfigProfile
+	// ExtraLabels and ExtraAnnotations are added to all the created objects.
+	ExtraLabels      map[string]string
+	ExtraAnnotations map[string]string
+	// CanaryNodeSelector selects the nodes running the canary RTE, see DeployCanary.
+	CanaryNodeSelector map[string]string
+	// ForceRemoveFinalizers clears the DaemonSet finalizers on removal, without waiting for the external controllers.
+	ForceRemoveFinalizers bool
+	// IgnoreNotFound makes the removal skip quietly the objects already gone, e.g. never deployed.
+	IgnoreNotFound bool
+	// Force clears the finalizers of the removed objects created by the deployer which are still there after
+	// ForceGracePeriod, zero meaning deployer.DefaultForceGracePeriod.
+	Force            bool
+	ForceGracePeriod time.Duration
+	DryRun           bool
+	OnCreate         deployer.ObjectFunc
+	OnReady          deployer.ObjectFunc
+	// OnProgress, if set, receives the progress of each object while deploying.
+	OnProgress deployer.ProgressFunc
+	// ManifestsSource selects the embedded manifests to deploy. The zero value is the default ones.
+	ManifestsSource manifests.Source
 }
 
 func SetupNamespace(plat platform.Platform) (*corev1.Namespace, string, error) {
@@ -50,84 +117,150 @@ func SetupNamespace(plat platform.Platform) (*corev1.Namespace, string, error) {
 	return nil, "", fmt.Errorf("unsupported platform: %q", plat)
 }
 
-func Deploy(log tlog.Logger, opts Options) error {
+// setupNamespace is like SetupNamespace, honoring the namespace set in the options.
+func setupNamespace(opts Options) (*corev1.Namespace, string, error) {
+	if err := rtemanifests.ValidateNamespace(opts.Platform, opts.Namespace); err != nil {
+		return nil, "", err
+	}
+	ns, namespace, err := SetupNamespace(opts.Platform)
+	if err != nil || opts.Namespace == "" {
+		return ns, namespace, err
+	}
+	ns.Name = opts.Namespace
+	return ns, opts.Namespace, nil
+}
+
+func Deploy(ctx context.Context, log tlog.Logger, opts Options) (*deployer.Result, error) {
 	log.Printf("deploying topology-aware-scheduling topology updater...")
 
-	ns, namespace, err := SetupNamespace(opts.Platform)
+	if err := rtemanifests.ValidateConfigMapName(opts.ConfigMapName); err != nil {
+		return nil, err
+	}
+	if err := rtemanifests.ValidateNodeSelector(opts.NodeSelector); err != nil {
+		return nil, err
+	}
+	if opts.RTEConfigData != "" {
+		if err := rtemanifests.ValidateConfigData(opts.RTEConfigData); err != nil {
+			return nil, err
+		}
+	}
+	if err := rtemanifests.ValidateConfigProfiles(opts.ConfigProfiles); err != nil {
+		return nil, err
+	}
+
+	ns, namespace, err := setupNamespace(opts)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	mf, err := rtemanifests.GetManifests(opts.Platform)
+	mf, err := rtemanifests.GetManifestsForNamespaceFromSource(opts.ManifestsSource, opts.Platform, namespace)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	mf = updateManifests(mf, namespace, opts)
+	if opts.AllNodes {
+		if err := rtemanifests.ValidateAllNodes(mf.DaemonSet); err != nil {
+			return nil, err
+		}
+	}
+	if len(opts.ExtraContainers) > 0 || len(opts.ExtraVolumes) > 0 {
+		if err := rtemanifests.ValidateExtraContainers(mf.DaemonSet); err != nil {
+			return nil, err
+		}
 	}
-	mf = mf.Update(rtemanifests.UpdateOptions{
-		ConfigData:       opts.RTEConfigData,
-		PullIfNotPresent: opts.PullIfNotPresent,
-		Namespace:        namespace,
-	})
 	log.Debugf("RTE manifests loaded")
 
 	hp, err := deployer.NewHelper("RTE", log)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	hp.WithContext(ctx).WithOnCreate(opts.OnCreate).WithOnProgress(opts.OnProgress).WithDryRun(opts.DryRun).WithWaitTimeout(opts.WaitTimeout).WithPollInterval(opts.PollInterval).WithExtraMetadata(opts.ExtraLabels, opts.ExtraAnnotations)
+
+	if err := hp.WarnUnsupportedArchitectures(mf.DaemonSet.Spec.Template.Spec.Containers[0].Image); err != nil {
+		log.Printf("cannot check the node architectures: %v", err)
+	}
+
+	if opts.Platform == platform.Kubernetes && opts.SkipNamespace {
+		if err := hp.GetObject(client.ObjectKeyFromObject(ns), &corev1.Namespace{}); err != nil {
+			if k8serrors.IsNotFound(err) {
+				return hp.Result(), fmt.Errorf("namespace %q not found: it must exist when not managed by the deployer", ns.Name)
+			}
+			return hp.Result(), err
+		}
+	}
+
+	if opts.WithServiceMonitor {
+		if err := checkServiceMonitorCRD(hp); err != nil {
+			return hp.Result(), err
+		}
 	}
 
 	objs := mf.ToCreatableObjects(hp, log)
-	if opts.Platform == platform.Kubernetes {
+	if opts.Platform == platform.Kubernetes && !opts.SkipNamespace {
 		objs = append([]deployer.WaitableObject{{Obj: ns}}, objs...)
 	}
 	for _, wo := range objs {
-		if err := hp.CreateObject(wo.Obj); err != nil {
-			return err
+		if err := hp.ApplyObject(wo.Obj); err != nil {
+			return hp.Result(), err
 		}
-		if opts.WaitCompletion && wo.Wait != nil {
-			err = wo.Wait()
+		if opts.WaitCompletion && !opts.DryRun && wo.Wait != nil {
+			err = hp.WaitObject(wo)
 			if err != nil {
-				return err
+				return hp.Result(), err
+			}
+			if opts.OnReady != nil {
+				opts.OnReady(wo.Obj)
 			}
 		}
 	}
 
+	if opts.DryRun {
+		return hp.Result(), hp.DryRunResult()
+	}
 	log.Printf("...deployed topology-aware-scheduling topology updater!")
-	return nil
+	return hp.Result(), nil
 }
 
-func Remove(log tlog.Logger, opts Options) error {
+func Remove(ctx context.Context, log tlog.Logger, opts Options) (*deployer.Result, error) {
 	var err error
 	log.Printf("removing topology-aware-scheduling topology updater...")
 
 	hp, err := deployer.NewHelper("RTE", log)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	hp.WithContext(ctx).WithWaitTimeout(opts.WaitTimeout).WithPollInterval(opts.PollInterval).WithIgnoreNotFound(opts.IgnoreNotFound)
+	err = remove(hp, log, opts)
+	return hp.Result(), err
+}
 
-	ns, err := manifests.Namespace(manifests.ComponentResourceTopologyExporter)
+func remove(hp *deployer.Helper, log tlog.Logger, opts Options) error {
+	ns, namespace, err := setupNamespace(opts)
 	if err != nil {
 		return err
 	}
-	namespace := ""
-	if opts.Platform == platform.Kubernetes {
-		namespace = ns.Name
-	}
-	if opts.Platform == platform.OpenShift {
-		namespace = rtemanifests.NamespaceOpenShift
-	}
 
-	mf, err := rtemanifests.GetManifests(opts.Platform)
+	mf, err := rtemanifests.GetManifestsForNamespaceFromSource(opts.ManifestsSource, opts.Platform, namespace)
 	if err != nil {
 		return err
 	}
-	mf = mf.Update(rtemanifests.UpdateOptions{
-		ConfigData:       opts.RTEConfigData,
-		PullIfNotPresent: opts.PullIfNotPresent,
-		Namespace:        namespace,
-	})
+	mf = updateManifests(mf, namespace, opts)
 	log.Debugf("RTE manifests loaded")
 
+	if opts.ForceRemoveFinalizers {
+		if err := clearFinalizers(hp, mf.DaemonSet); err != nil {
+			log.Printf("failed to clear the finalizers: %v", err)
+		}
+		for _, prof := range mf.Profiles {
+			if err := clearFinalizers(hp, prof.DaemonSet); err != nil {
+				log.Printf("failed to clear the finalizers: %v", err)
+			}
+		}
+	}
+
 	objs := mf.ToDeletableObjects(hp, log)
-	if opts.Platform == platform.Kubernetes {
+	// a namespace managed by others may be shared, never delete it
+	if opts.Platform == platform.Kubernetes && !opts.SkipNamespace {
 		objs = append(objs, deployer.WaitableObject{
 			Obj:  ns,
 			Wait: func() error { return wait.NamespaceToBeGone(hp, log, ns.Name) },
@@ -139,6 +272,11 @@ func Remove(log tlog.Logger, opts Options) error {
 			log.Printf("failed to remove: %v", err)
 			continue
 		}
+		if opts.Force {
+			if err := hp.ForceRemoval(wo.Obj, opts.ForceGracePeriod); err != nil {
+				log.Printf("failed to force the removal: %v", err)
+			}
+		}
 
 		if !opts.WaitCompletion || wo.Wait == nil {
 			continue
@@ -150,6 +288,259 @@ func Remove(log tlog.Logger, opts Options) error {
 		}
 	}
 
+	// the config reloads leave content-addressed ConfigMaps the manifests don't know about
+	removeConfigMaps(hp, log, namespace)
+
 	log.Printf("...removed topology-aware-scheduling topology updater!")
 	return nil
 }
+
+// ReloadConfig rolls out a new RTE configuration on a running deployment.
+// The new configuration is stored in a new, content-addressed ConfigMap and the
+// DaemonSet is switched to it. If the rollout does not complete, the DaemonSet
+// is restored to its previous state and the new ConfigMap is removed. Once the rollout
+// completes, the ConfigMap created by the previous reload, if any, is removed.
+func ReloadConfig(log tlog.Logger, opts Options) error {
+	if opts.RTEConfigData == "" {
+		return fmt.Errorf("missing RTE configuration data")
+	}
+	if err := rtemanifests.ValidateConfigData(opts.RTEConfigData); err != nil {
+		return err
+	}
+	log.Printf("reloading topology-aware-scheduling topology updater configuration...")
+
+	_, namespace, err := setupNamespace(opts)
+	if err != nil {
+		return err
+	}
+
+	mf, err := rtemanifests.GetManifestsForNamespaceFromSource(opts.ManifestsSource, opts.Platform, namespace)
+	if err != nil {
+		return err
+	}
+	mf = updateManifests(mf, namespace, opts)
+	log.Debugf("RTE manifests loaded")
+
+	hp, err := deployer.NewHelper("RTE", log)
+	if err != nil {
+		return err
+	}
+	hp.WithWaitTimeout(opts.WaitTimeout).WithPollInterval(opts.PollInterval).WithExtraMetadata(opts.ExtraLabels, opts.ExtraAnnotations)
+
+	ds, err := hp.GetDaemonSetByName(mf.DaemonSet.Namespace, mf.DaemonSet.Name)
+	if err != nil {
+		return fmt.Errorf("cannot get the running daemonset: %w", err)
+	}
+	ds.TypeMeta = mf.DaemonSet.TypeMeta
+	prevTemplate := ds.Spec.Template.DeepCopy()
+
+	cfgHash := mf.DaemonSet.Spec.Template.Annotations[manifests.AnnotationConfigHash]
+	if ds.Spec.Template.Annotations[manifests.AnnotationConfigHash] == cfgHash {
+		log.Printf("...configuration unchanged (hash %s), nothing to do", cfgHash)
+		return nil
+	}
+
+	cm := mf.ConfigMap.DeepCopy()
+	if !opts.ImmutableConfig {
+		// immutable configmaps are already content-addressed
+		cm.Name = manifests.NameWithSuffix(mf.ConfigMap.Name, cfgHash)
+	}
+	cmCreated := true
+	if err := hp.CreateObject(cm); err != nil {
+		if !k8serrors.IsAlreadyExists(err) {
+			return err
+		}
+		// same content hash, same data: safe to reuse
+		cmCreated = false
+	}
+
+	if !setConfigMapVolume(ds, manifests.RTEConfigVolumeName, cm.Name) {
+		if cmCreated {
+			if delErr := hp.DeleteObject(cm); delErr != nil {
+				log.Printf("failed to remove: %v", delErr)
+			}
+		}
+		return fmt.Errorf("daemonset %q %q does not consume a configuration, redeploy it providing one", ds.Namespace, ds.Name)
+	}
+	if ds.Spec.Template.Annotations == nil {
+		ds.Spec.Template.Annotations = make(map[string]string)
+	}
+	ds.Spec.Template.Annotations[manifests.AnnotationConfigHash] = cfgHash
+
+	err = hp.UpdateObject(ds)
+	if err == nil {
+		err = wait.DaemonSetRolloutToComplete(hp, log, ds.Namespace, ds.Name)
+		if err == nil {
+			// the previous reload's ConfigMap is not consumed anymore. The one created by the deploy is kept.
+			prevName := configMapVolumeName(prevTemplate, manifests.RTEConfigVolumeName)
+			if prevName != "" && prevName != cm.Name && prevName != mf.ConfigMap.Name {
+				prevCM := mf.ConfigMap.DeepCopy()
+				prevCM.Name = prevName
+				if delErr := hp.DeleteObject(prevCM); delErr != nil && !k8serrors.IsNotFound(delErr) {
+					log.Printf("failed to remove the previous configuration: %v", delErr)
+				}
+			}
+			log.Printf("...reloaded topology-aware-scheduling topology updater configuration!")
+			return nil
+		}
+	}
+
+	log.Printf("configuration rollout failed: %v - rolling back", err)
+	if rbErr := rollbackDaemonSet(hp, ds.Namespace, ds.Name, prevTemplate); rbErr != nil {
+		return fmt.Errorf("rollout failed: %v; rollback failed: %w", err, rbErr)
+	}
+	if cmCreated {
+		if delErr := hp.DeleteObject(cm); delErr != nil {
+			log.Printf("failed to remove: %v", delErr)
+		}
+	}
+	return fmt.Errorf("rollout failed and was rolled back: %w", err)
+}
+
+// checkServiceMonitorCRD fails clearly if the ServiceMonitors are not served, instead of failing
+// after the other objects are created.
+func checkServiceMonitorCRD(hp *deployer.Helper) error {
+	established, err := hp.IsCRDEstablished(manifests.ServiceMonitorCRDName)
+	if k8serrors.IsNotFound(err) {
+		return fmt.Errorf("cannot add the ServiceMonitor: the CRD %q is missing, install the prometheus operator first", manifests.ServiceMonitorCRDName)
+	}
+	if err != nil {
+		return err
+	}
+	if !established {
+		return fmt.Errorf("cannot add the ServiceMonitor: the CRD %q is not established", manifests.ServiceMonitorCRDName)
+	}
+	return nil
+}
+
+func clearFinalizers(hp *deployer.Helper, ref *appsv1.DaemonSet) error {
+	ds, err := hp.GetDaemonSetByName(ref.Namespace, ref.Name)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if len(ds.Finalizers) == 0 {
+		return nil
+	}
+	ds.TypeMeta = ref.TypeMeta
+	ds.Finalizers = nil
+	return hp.UpdateObject(ds)
+}
+
+func rollbackDaemonSet(hp *deployer.Helper, namespace, name string, template *corev1.PodTemplateSpec) error {
+	ds, err := hp.GetDaemonSetByName(namespace, name)
+	if err != nil {
+		return err
+	}
+	ds.Spec.Template = *template
+	return hp.UpdateObject(ds)
+}
+
+// removeConfigMaps deletes the leftover RTE ConfigMaps in the given namespace. Errors are logged, not returned:
+// this is best-effort cleanup, like the rest of the removal.
+func removeConfigMaps(hp *deployer.Helper, log tlog.Logger, namespace string) {
+	objs, err := hp.ListObjects(corev1.SchemeGroupVersion.WithKind("ConfigMap"), map[string]string{
+		rtemanifests.LabelComponent: rtemanifests.ConfigComponentName,
+	})
+	if err != nil {
+		log.Printf("failed to list the configmaps: %v", err)
+		return
+	}
+	for _, obj := range objs {
+		if obj.GetNamespace() != namespace {
+			continue
+		}
+		if err := hp.DeleteObject(obj); err != nil && !k8serrors.IsNotFound(err) {
+			log.Printf("failed to remove: %v", err)
+		}
+	}
+}
+
+// configMapVolumeName returns the name of the ConfigMap backing the given volume, if any.
+func configMapVolumeName(tmpl *corev1.PodTemplateSpec, volumeName string) string {
+	for _, vol := range tmpl.Spec.Volumes {
+		if vol.Name == volumeName && vol.ConfigMap != nil {
+			return vol.ConfigMap.Name
+		}
+	}
+	return ""
+}
+
+func setConfigMapVolume(ds *appsv1.DaemonSet, volumeName, cmName string) bool {
+	for idx := range ds.Spec.Template.Spec.Volumes {
+		vol := &ds.Spec.Template.Spec.Volumes[idx]
+		if vol.Name != volumeName || vol.ConfigMap == nil {
+			continue
+		}
+		vol.ConfigMap.Name = cmName
+		return true
+	}
+	return false
+}
+
+func updateManifests(mf rtemanifests.Manifests, namespace string, opts Options) rtemanifests.Manifests {
+	return mf.Update(rtemanifests.UpdateOptions{
+		ConfigData:                   opts.RTEConfigData,
+		ImmutableConfig:              opts.ImmutableConfig,
+		PullIfNotPresent:             opts.PullIfNotPresent,
+		ImagePullSecrets:             opts.ImagePullSecrets,
+		PriorityClassName:            opts.PriorityClassName,
+		WithNetworkPolicy:            opts.WithNetworkPolicy,
+		MaxUnavailable:               opts.MaxUnavailable,
+		HostNetwork:                  opts.HostNetwork,
+		DNSPolicy:                    opts.DNSPolicy,
+		WithServiceMonitor:           opts.WithServiceMonitor,
+		Namespace:                    namespace,
+		ConfigMapName:                opts.ConfigMapName,
+		ConfigProfiles:               opts.ConfigProfiles,
+		AllNodes:                     opts.AllNodes,
+		StartupProbeFailureThreshold: opts.StartupProbeFailureThreshold,
+		StartupProbePeriodSeconds:    opts.StartupProbePeriodSeconds,
+		Finalizers:                   opts.Finalizers,
+		PodSchedulerName:             opts.PodSchedulerName,
+		NodeSelector:                 opts.NodeSelector,
+		Tolerations:                  opts.Tolerations,
+		ExtraInitContainers:          opts.ExtraInitContainers,
+		ExtraContainers:              opts.ExtraContainers,
+		ExtraVolumes:                 opts.ExtraVolumes,
+		APIGroup:                     opts.APIGroup,
+	})
+}
+
+// Status reports if the RTE daemonset runs and all its pods are ready. Does not change the cluster.
+func Status(log tlog.Logger, opts Options) (deployer.Status, error) {
+	st := deployer.Status{}
+	_, namespace, err := setupNamespace(opts)
+	if err != nil {
+		return st, err
+	}
+	st.Namespace = namespace
+
+	mf, err := rtemanifests.GetManifestsForNamespaceFromSource(opts.ManifestsSource, opts.Platform, namespace)
+	if err != nil {
+		return st, err
+	}
+
+	hp, err := deployer.NewHelper("RTE", log)
+	if err != nil {
+		return st, err
+	}
+
+	if _, err := hp.GetDaemonSetByName(mf.DaemonSet.Namespace, mf.DaemonSet.Name); err != nil {
+		if k8serrors.IsNotFound(err) {
+			st.Reason = fmt.Sprintf("daemonset %q not found", mf.DaemonSet.Name)
+			return st, nil
+		}
+		return st, err
+	}
+	st.Ready, err = hp.IsDaemonSetRunning(mf.DaemonSet.Namespace, mf.DaemonSet.Name)
+	if err != nil {
+		return st, err
+	}
+	if !st.Ready {
+		st.Reason = fmt.Sprintf("daemonset %q pods not all ready", mf.DaemonSet.Name)
+	}
+	return st, nil
+}
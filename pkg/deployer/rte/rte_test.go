@@ -0,0 +1,93 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package rte
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
+	rtemanifests "github.com/k8stopologyawareschedwg/deployer/pkg/manifests/rte"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/tlog"
+)
+
+// removeClient records the deleted objects and lists the given ConfigMaps.
+type removeClient struct {
+	client.Client
+	configMaps []unstructured.Unstructured
+	deleted    []string
+}
+
+func (rc *removeClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	rc.deleted = append(rc.deleted, obj.GetNamespace()+"/"+obj.GetName())
+	return nil
+}
+
+func (rc *removeClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	list.(*unstructured.UnstructuredList).Items = rc.configMaps
+	return nil
+}
+
+func configMap(namespace, name string) unstructured.Unstructured {
+	obj := unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+func TestRemove(t *testing.T) {
+	testCases := []struct {
+		name      string
+		plat      platform.Platform
+		namespace string
+	}{
+		{name: "kubernetes", plat: platform.Kubernetes, namespace: "tas-topology-updater"},
+		{name: "openshift", plat: platform.OpenShift, namespace: rtemanifests.NamespaceOpenShift},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cli := &removeClient{
+				configMaps: []unstructured.Unstructured{
+					configMap(tc.namespace, "rte-config-abcd"),
+					configMap("other", "rte-config-abcd"),
+				},
+			}
+			hp := deployer.NewHelperWithClient(cli, "RTE", tlog.NewNullLogAdapter())
+			if err := remove(hp, tlog.NewNullLogAdapter(), Options{Platform: tc.plat}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			found := false
+			for _, name := range cli.deleted {
+				if name == "other/rte-config-abcd" {
+					t.Errorf("configmap of another namespace removed")
+				}
+				if name == tc.namespace+"/rte-config-abcd" {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("reloaded configmap not removed: %v", cli.deleted)
+			}
+		})
+	}
+}
@@ -17,98 +17,244 @@
 package sched
 
 import (
+	"context"
 	"fmt"
+	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer"
 	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests"
 	rtemanifests "github.com/k8stopologyawareschedwg/deployer/pkg/manifests/rte"
 	schedmanifests "github.com/k8stopologyawareschedwg/deployer/pkg/manifests/sched"
 	"github.com/k8stopologyawareschedwg/deployer/pkg/tlog"
 )
 
 type Options struct {
-	Platform         platform.Platform
-	WaitCompletion   bool
+	Platform       platform.Platform
+	WaitCompletion bool
+	WaitTimeout    time.Duration
+	// PollInterval, if not zero, overrides the interval of all the waits.
+	PollInterval     time.Duration
 	Replicas         int32
 	RTEConfigData    string
 	PullIfNotPresent bool
+	Mode             string
+	NodeSelector     map[string]string
+	FeatureGates     map[string]bool
+	PodSchedulerName string
+	// SchedulerName, if not empty, names the scheduler plugin profile and deployment.
+	SchedulerName string
+	APIGroup      string
+	// ImagePullSecrets are the secrets the pods use to pull the images from private registries.
+	ImagePullSecrets []string
+	// PriorityClassName is the priority class of the pods, which must exist. Empty means the cluster default.
+	PriorityClassName string
+	// WithNetworkPolicy adds a NetworkPolicy letting the scheduler plugin pods reach the API server.
+	// See schedmanifests.UpdateOptions.
+	WithNetworkPolicy bool
+	// SpreadReplicas, if not empty, spreads the scheduler replicas across the nodes. See schedmanifests.UpdateOptions.
+	SpreadReplicas string
+	// Tolerations and PodNodeAffinity place the scheduler plugin pods. See schedmanifests.UpdateOptions.
+	Tolerations     []corev1.Toleration
+	PodNodeAffinity *corev1.NodeAffinity
+	// LeaderElect makes the scheduler replicas elect a leader. See schedmanifests.UpdateOptions.
+	LeaderElect bool
+	// ScoringStrategy makes the scheduler plugin score the nodes. See schedmanifests.UpdateOptions.
+	ScoringStrategy string
+	// TokenExpirationSeconds and TokenAudience configure the projected service account tokens. Zero expiration disables them.
+	TokenExpirationSeconds int64
+	TokenAudience          string
+	EnforcedPodSelector    map[string]string
+	// NodeResourcesNamespace is the namespace of the RTE objects, if not the platform default.
+	NodeResourcesNamespace string
+	// ExtraLabels and ExtraAnnotations are added to all the created objects.
+	ExtraLabels      map[string]string
+	ExtraAnnotations map[string]string
+	DryRun           bool
+	OnCreate         deployer.ObjectFunc
+	OnReady          deployer.ObjectFunc
+	// OnProgress, if set, receives the progress of each object while deploying.
+	OnProgress deployer.ProgressFunc
+	// IgnoreNotFound makes the removal skip quietly the objects already gone, e.g. never deployed.
+	IgnoreNotFound bool
+	// Force clears the finalizers of the removed objects created by the deployer which are still there after
+	// ForceGracePeriod, zero meaning deployer.DefaultForceGracePeriod.
+	Force            bool
+	ForceGracePeriod time.Duration
+	// ManifestsSource selects the embedded manifests to deploy. The zero value is the default ones.
+	ManifestsSource manifests.Source
 }
 
 func SetupNamespace(plat platform.Platform) (*corev1.Namespace, string, error) {
 	return nil, "", fmt.Errorf("not yet implemented")
 }
 
-func Deploy(log tlog.Logger, opts Options) error {
+func Deploy(ctx context.Context, log tlog.Logger, opts Options) (*deployer.Result, error) {
 	var err error
 	log.Printf("deploying topology-aware-scheduling scheduler plugin...")
 
-	mf, err := schedmanifests.GetManifests(opts.Platform)
+	if err := schedmanifests.ValidateMode(opts.Platform, opts.Mode); err != nil {
+		return nil, err
+	}
+	if err := schedmanifests.ValidateNodeSelector(opts.NodeSelector); err != nil {
+		return nil, err
+	}
+	if err := schedmanifests.ValidateFeatureGates(opts.FeatureGates); err != nil {
+		return nil, err
+	}
+	if err := schedmanifests.ValidatePodSchedulerName(opts.Mode, opts.PodSchedulerName); err != nil {
+		return nil, err
+	}
+	if err := schedmanifests.ValidateSchedulerName(opts.Mode, opts.SchedulerName, opts.PodSchedulerName); err != nil {
+		return nil, err
+	}
+	if err := schedmanifests.ValidateTokenExpiration(opts.TokenExpirationSeconds); err != nil {
+		return nil, err
+	}
+	if err := schedmanifests.ValidateEnforcedPodSelector(opts.EnforcedPodSelector); err != nil {
+		return nil, err
+	}
+	if err := schedmanifests.ValidateReplicas(opts.Replicas); err != nil {
+		return nil, err
+	}
+	if err := schedmanifests.ValidateSpreadReplicas(opts.SpreadReplicas); err != nil {
+		return nil, err
+	}
+
+	mf, err := schedmanifests.GetManifestsFromSource(opts.ManifestsSource, opts.Platform)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	rteMf, err := rtemanifests.GetManifests(opts.Platform)
+	rteMf, err := rtemanifests.GetManifestsFromSource(opts.ManifestsSource, opts.Platform)
 	if err != nil {
-		return fmt.Errorf("cannot get the rte manifests for sched: %w", err)
+		return nil, fmt.Errorf("cannot get the rte manifests for sched: %w", err)
 	}
 
-	rteMf = rteMf.Update(rtemanifests.UpdateOptions{ConfigData: opts.RTEConfigData})
+	rteMf = rteMf.Update(rtemanifests.UpdateOptions{
+		ConfigData: opts.RTEConfigData,
+		Namespace:  opts.NodeResourcesNamespace,
+	})
 	mf = mf.Update(log, schedmanifests.UpdateOptions{
 		Replicas:               opts.Replicas,
-		NodeResourcesNamespace: rteMf.DaemonSet.Name,
+		NodeResourcesNamespace: rteMf.DaemonSet.Namespace,
 		PullIfNotPresent:       opts.PullIfNotPresent,
+		ImagePullSecrets:       opts.ImagePullSecrets,
+		PriorityClassName:      opts.PriorityClassName,
+		WithNetworkPolicy:      opts.WithNetworkPolicy,
+		SpreadReplicas:         opts.SpreadReplicas,
+		Tolerations:            opts.Tolerations,
+		PodNodeAffinity:        opts.PodNodeAffinity,
+		LeaderElect:            opts.LeaderElect,
+		ScoringStrategy:        opts.ScoringStrategy,
+		Mode:                   opts.Mode,
+		NodeSelector:           opts.NodeSelector,
+		FeatureGates:           opts.FeatureGates,
+		PodSchedulerName:       opts.PodSchedulerName,
+		SchedulerName:          opts.SchedulerName,
+		APIGroup:               opts.APIGroup,
+		TokenExpirationSeconds: opts.TokenExpirationSeconds,
+		TokenAudience:          opts.TokenAudience,
+		EnforcedPodSelector:    opts.EnforcedPodSelector,
 	})
 	log.Debugf("SCD manifests loaded")
 
 	hp, err := deployer.NewHelper("SCD", log)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	hp.WithContext(ctx).WithOnCreate(opts.OnCreate).WithOnProgress(opts.OnProgress).WithDryRun(opts.DryRun).WithWaitTimeout(opts.WaitTimeout).WithPollInterval(opts.PollInterval).WithExtraMetadata(opts.ExtraLabels, opts.ExtraAnnotations)
+
+	if opts.WaitCompletion {
+		// waiting on replicas which can't be scheduled would just time out
+		for _, dp := range []*appsv1.Deployment{mf.DPScheduler, mf.DPController} {
+			if err := hp.ValidateReplicasFit(dp); err != nil {
+				return hp.Result(), err
+			}
+		}
+	}
+
+	if err := hp.WarnUnsupportedArchitectures(
+		mf.DPScheduler.Spec.Template.Spec.Containers[0].Image,
+		mf.DPController.Spec.Template.Spec.Containers[0].Image,
+	); err != nil {
+		log.Printf("cannot check the node architectures: %v", err)
 	}
 
 	for _, wo := range mf.ToCreatableObjects(hp, log) {
-		if err := hp.CreateObject(wo.Obj); err != nil {
-			return err
+		if err := hp.ApplyObject(wo.Obj); err != nil {
+			return hp.Result(), err
 		}
-		if opts.WaitCompletion && wo.Wait != nil {
-			err = wo.Wait()
+		if opts.WaitCompletion && !opts.DryRun && wo.Wait != nil {
+			err = hp.WaitObject(wo)
 			if err != nil {
-				return err
+				return hp.Result(), err
+			}
+			if opts.OnReady != nil {
+				opts.OnReady(wo.Obj)
 			}
 		}
 	}
 
+	if opts.DryRun {
+		return hp.Result(), hp.DryRunResult()
+	}
 	log.Printf("...deployed topology-aware-scheduling scheduler plugin!")
-	return nil
+	return hp.Result(), nil
 }
 
-func Remove(log tlog.Logger, opts Options) error {
+func Remove(ctx context.Context, log tlog.Logger, opts Options) (*deployer.Result, error) {
 	var err error
 	log.Printf("removing topology-aware-scheduling scheduler plugin...")
 
-	mf, err := schedmanifests.GetManifests(opts.Platform)
+	mf, err := schedmanifests.GetManifestsFromSource(opts.ManifestsSource, opts.Platform)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	rteMf, err := rtemanifests.GetManifests(opts.Platform)
+	rteMf, err := rtemanifests.GetManifestsFromSource(opts.ManifestsSource, opts.Platform)
 	if err != nil {
-		return fmt.Errorf("cannot get the rte manifests for sched: %w", err)
+		return nil, fmt.Errorf("cannot get the rte manifests for sched: %w", err)
 	}
 
-	rteMf = rteMf.Update(rtemanifests.UpdateOptions{ConfigData: opts.RTEConfigData})
+	rteMf = rteMf.Update(rtemanifests.UpdateOptions{
+		ConfigData: opts.RTEConfigData,
+		Namespace:  opts.NodeResourcesNamespace,
+	})
 	mf = mf.Update(log, schedmanifests.UpdateOptions{
 		Replicas:               opts.Replicas,
 		NodeResourcesNamespace: rteMf.DaemonSet.Namespace,
 		PullIfNotPresent:       opts.PullIfNotPresent,
+		ImagePullSecrets:       opts.ImagePullSecrets,
+		PriorityClassName:      opts.PriorityClassName,
+		WithNetworkPolicy:      opts.WithNetworkPolicy,
+		SpreadReplicas:         opts.SpreadReplicas,
+		Tolerations:            opts.Tolerations,
+		PodNodeAffinity:        opts.PodNodeAffinity,
+		LeaderElect:            opts.LeaderElect,
+		ScoringStrategy:        opts.ScoringStrategy,
+		Mode:                   opts.Mode,
+		NodeSelector:           opts.NodeSelector,
+		FeatureGates:           opts.FeatureGates,
+		PodSchedulerName:       opts.PodSchedulerName,
+		SchedulerName:          opts.SchedulerName,
+		APIGroup:               opts.APIGroup,
+		TokenExpirationSeconds: opts.TokenExpirationSeconds,
+		TokenAudience:          opts.TokenAudience,
+		EnforcedPodSelector:    opts.EnforcedPodSelector,
 	})
 	log.Debugf("SCD manifests loaded")
 
 	hp, err := deployer.NewHelper("SCD", log)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	hp.WithContext(ctx).WithWaitTimeout(opts.WaitTimeout).WithPollInterval(opts.PollInterval).WithIgnoreNotFound(opts.IgnoreNotFound)
 
 	for _, wo := range mf.ToDeletableObjects(hp, log) {
 		err = hp.DeleteObject(wo.Obj)
@@ -116,6 +262,11 @@ func Remove(log tlog.Logger, opts Options) error {
 			log.Printf("failed to remove: %v", err)
 			continue
 		}
+		if opts.Force {
+			if err := hp.ForceRemoval(wo.Obj, opts.ForceGracePeriod); err != nil {
+				log.Printf("failed to force the removal: %v", err)
+			}
+		}
 
 		if !opts.WaitCompletion || wo.Wait == nil {
 			continue
@@ -128,5 +279,45 @@ func Remove(log tlog.Logger, opts Options) error {
 	}
 
 	log.Printf("...removed topology-aware-scheduling scheduler plugin!")
-	return nil
+	return hp.Result(), nil
+}
+
+// Status reports if the scheduler plugin and its controller deployments run, with all their pods running.
+// Does not change the cluster.
+func Status(log tlog.Logger, opts Options) (deployer.Status, error) {
+	st := deployer.Status{}
+	mf, err := schedmanifests.GetManifestsFromSource(opts.ManifestsSource, opts.Platform)
+	if err != nil {
+		return st, err
+	}
+	mf = mf.Update(log, schedmanifests.UpdateOptions{
+		Mode:          opts.Mode,
+		SchedulerName: opts.SchedulerName,
+	})
+	st.Namespace = mf.DPScheduler.Namespace
+
+	hp, err := deployer.NewHelper("SCD", log)
+	if err != nil {
+		return st, err
+	}
+
+	for _, dp := range []*appsv1.Deployment{mf.DPScheduler, mf.DPController} {
+		if err := hp.GetObject(client.ObjectKeyFromObject(dp), &appsv1.Deployment{}); err != nil {
+			if k8serrors.IsNotFound(err) {
+				st.Reason = fmt.Sprintf("deployment %q not found", dp.Name)
+				return st, nil
+			}
+			return st, err
+		}
+		running, err := hp.ArePodsRunningByPattern(dp.Namespace, fmt.Sprintf("%s-*", dp.Name))
+		if err != nil {
+			return st, err
+		}
+		if !running {
+			st.Reason = fmt.Sprintf("deployment %q pods not all running", dp.Name)
+			return st, nil
+		}
+	}
+	st.Ready = true
+	return st, nil
 }
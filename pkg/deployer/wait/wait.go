@@ -17,35 +17,103 @@
 package wait
 
 import (
+	"context"
 	"fmt"
 	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 
 	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/metrics"
 	"github.com/k8stopologyawareschedwg/deployer/pkg/tlog"
 )
 
-func PodsToBeRunningByRegex(hp *deployer.Helper, log tlog.Logger, namespace, name string) error {
-	log.Printf("wait for all the pods in group %s %s to be running and ready", namespace, name)
-	return wait.PollImmediate(1*time.Second, 3*time.Minute, func() (bool, error) {
-		pods, err := hp.GetPodsByPattern(namespace, fmt.Sprintf("%s-*", name))
+// PollJitter is the maximum factor by which each poll interval of the helpers
+// in this package is randomly extended, to avoid concurrent callers polling the
+// API server in lockstep. Zero, the default, disables the jitter.
+var PollJitter float64
+
+// PollBackoff is the factor by which the poll interval of the helpers in this package
+// grows after each unsatisfied poll, up to MaxPollInterval. Values not above 1, like
+// the default zero, keep the interval constant.
+var PollBackoff float64
+
+// MaxPollInterval caps the poll interval grown by PollBackoff. Intervals explicitly
+// longer than the cap are never grown.
+const MaxPollInterval = 30 * time.Second
+
+// pollImmediate polls the condition, recording how long it took to be satisfied under the given name.
+// The wait stops early if the context of the helper is done.
+func pollImmediate(hp *deployer.Helper, name string, interval time.Duration, condition wait.ConditionFunc) error {
+	if hp.PollInterval() > 0 {
+		interval = hp.PollInterval()
+	}
+	start := time.Now()
+	err := poll(hp.Context(), interval, hp.WaitTimeout(), condition)
+	metrics.Default.ObserveWait(name, time.Since(start))
+	if err != nil {
+		metrics.Default.OperationFailed(metrics.OperationWait)
+	}
+	return err
+}
+
+// poll polls the condition until satisfied, until the timeout expires or until the context is done.
+// A negative timeout never expires. Returns wait.ErrWaitTimeout on timeout, the context error if done.
+func poll(ctx context.Context, interval, timeout time.Duration, condition wait.ConditionFunc) error {
+	var expired <-chan time.Time
+	if timeout >= 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	for {
+		done, err := condition()
 		if err != nil {
-			return false, err
+			return err
 		}
-		if len(pods) == 0 {
-			log.Printf("no pods found for %s %s", namespace, name)
-			return false, nil
+		if done {
+			return nil
+		}
+		delay := interval
+		if PollJitter > 0 {
+			delay = wait.Jitter(interval, PollJitter)
+		}
+		interval = nextInterval(interval)
+		tick := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			tick.Stop()
+			return ctx.Err()
+		case <-expired:
+			tick.Stop()
+			return wait.ErrWaitTimeout
+		case <-tick.C:
 		}
+	}
+}
 
-		for _, pod := range pods {
-			if pod.Status.Phase != corev1.PodRunning {
-				log.Printf("pod %s %s not ready yet (%s)", pod.Namespace, pod.Name, pod.Status.Phase)
-				return false, nil
-			}
+// nextInterval returns the interval to use after an unsatisfied poll, as grown by PollBackoff.
+func nextInterval(interval time.Duration) time.Duration {
+	if PollBackoff <= 1 || interval >= MaxPollInterval {
+		return interval
+	}
+	next := time.Duration(float64(interval) * PollBackoff)
+	if next > MaxPollInterval {
+		return MaxPollInterval
+	}
+	return next
+}
+
+func PodsToBeRunningByRegex(hp *deployer.Helper, log tlog.Logger, namespace, name string) error {
+	log.Printf("wait for all the pods in group %s %s to be running and ready", namespace, name)
+	return pollImmediate(hp, "pods_running", 1*time.Second, func() (bool, error) {
+		running, err := hp.ArePodsRunningByPattern(namespace, fmt.Sprintf("%s-*", name))
+		if err != nil || !running {
+			return false, err
 		}
 		log.Printf("all the pods in daemonset %s %s are running and ready!", namespace, name)
 		return true, nil
@@ -54,7 +122,7 @@ func PodsToBeRunningByRegex(hp *deployer.Helper, log tlog.Logger, namespace, nam
 
 func PodsToBeGoneByRegex(hp *deployer.Helper, log tlog.Logger, namespace, name string) error {
 	log.Printf("wait for all the pods in deployment %s %s to be gone", namespace, name)
-	return wait.PollImmediate(10*time.Second, 3*time.Minute, func() (bool, error) {
+	return pollImmediate(hp, "pods_gone", 10*time.Second, func() (bool, error) {
 		pods, err := hp.GetPodsByPattern(namespace, fmt.Sprintf("%s-*", name))
 		if err != nil {
 			return false, err
@@ -69,7 +137,7 @@ func PodsToBeGoneByRegex(hp *deployer.Helper, log tlog.Logger, namespace, name s
 
 func NamespaceToBeGone(hp *deployer.Helper, log tlog.Logger, namespace string) error {
 	log.Printf("wait for the namespace %q to be gone", namespace)
-	return wait.PollImmediate(1*time.Second, 3*time.Minute, func() (bool, error) {
+	return pollImmediate(hp, "namespace_gone", 1*time.Second, func() (bool, error) {
 		nsKey := types.NamespacedName{
 			Name: namespace,
 		}
@@ -87,16 +155,93 @@ func NamespaceToBeGone(hp *deployer.Helper, log tlog.Logger, namespace string) e
 	})
 }
 
+// CRDToBeEstablished waits for the CRD to be established, thus ready to serve its objects.
+func CRDToBeEstablished(hp *deployer.Helper, log tlog.Logger, name string) error {
+	log.Printf("wait for the crd %q to be established", name)
+	return pollImmediate(hp, "crd_established", 1*time.Second, func() (bool, error) {
+		established, err := hp.IsCRDEstablished(name)
+		if k8serrors.IsNotFound(err) {
+			log.Printf("crd %q not found - retrying", name)
+			return false, nil
+		}
+		if err != nil || !established {
+			return false, err
+		}
+		log.Printf("crd %q established!", name)
+		return true, nil
+	})
+}
+
 func DaemonSetToBeRunning(hp *deployer.Helper, log tlog.Logger, namespace, name string) error {
 	log.Printf("wait for the daemonset %q %q to be running", namespace, name)
-	return wait.PollImmediate(3*time.Second, 3*time.Minute, func() (bool, error) {
+	return pollImmediate(hp, "daemonset_running", 3*time.Second, func() (bool, error) {
 		return hp.IsDaemonSetRunning(namespace, name)
 	})
 }
 
+func DaemonSetRolloutToComplete(hp *deployer.Helper, log tlog.Logger, namespace, name string) error {
+	log.Printf("wait for the daemonset %q %q rollout to complete", namespace, name)
+	return pollImmediate(hp, "daemonset_rollout", 3*time.Second, func() (bool, error) {
+		return hp.IsDaemonSetRolledOut(namespace, name)
+	})
+}
+
 func DaemonSetToBeGone(hp *deployer.Helper, log tlog.Logger, namespace, name string) error {
 	log.Printf("wait for the daemonset %q %q to be gone", namespace, name)
-	return wait.PollImmediate(3*time.Second, 3*time.Minute, func() (bool, error) {
+	return pollImmediate(hp, "daemonset_gone", 3*time.Second, func() (bool, error) {
 		return hp.IsDaemonSetGone(namespace, name)
 	})
 }
+
+// DeploymentToBeAvailable waits for the rollout of the deployment to complete, with all
+// the desired replicas updated and available. Fails early if the rollout exceeds the
+// progress deadline of the deployment, reporting why it stalled.
+func DeploymentToBeAvailable(hp *deployer.Helper, log tlog.Logger, namespace, name string) error {
+	log.Printf("wait for the deployment %q %q to be available", namespace, name)
+	return pollImmediate(hp, "deployment_available", 3*time.Second, func() (bool, error) {
+		dp, err := hp.GetDeploymentByName(namespace, name)
+		if err != nil {
+			if k8serrors.IsNotFound(err) {
+				log.Printf("deployment %q %q not found - retrying", namespace, name)
+				return false, nil
+			}
+			return false, err
+		}
+		available, err := isDeploymentAvailable(log, dp)
+		if err != nil || !available {
+			return false, err
+		}
+		log.Printf("deployment %q %q is available!", namespace, name)
+		return true, nil
+	})
+}
+
+// isDeploymentAvailable tells if the rollout of the deployment completed, like `kubectl rollout status` does.
+// Returns error if the rollout stalled past the progress deadline.
+func isDeploymentAvailable(log tlog.Logger, dp *appsv1.Deployment) (bool, error) {
+	if dp.Status.ObservedGeneration < dp.Generation {
+		log.Printf("deployment %q %q generation %d not yet observed (%d)", dp.Namespace, dp.Name, dp.Generation, dp.Status.ObservedGeneration)
+		return false, nil
+	}
+	for _, cond := range dp.Status.Conditions {
+		if cond.Type == appsv1.DeploymentProgressing && cond.Reason == "ProgressDeadlineExceeded" {
+			return false, fmt.Errorf("deployment %q %q exceeded its progress deadline: %s", dp.Namespace, dp.Name, cond.Message)
+		}
+		if cond.Type == appsv1.DeploymentReplicaFailure && cond.Status == corev1.ConditionTrue {
+			log.Printf("deployment %q %q failing to create replicas: %s: %s", dp.Namespace, dp.Name, cond.Reason, cond.Message)
+		}
+	}
+	desired := int32(1)
+	if dp.Spec.Replicas != nil {
+		desired = *dp.Spec.Replicas
+	}
+	log.Printf("deployment %q %q desired %d updated %d available %d", dp.Namespace, dp.Name, desired, dp.Status.UpdatedReplicas, dp.Status.AvailableReplicas)
+	if dp.Status.UpdatedReplicas < desired {
+		return false, nil
+	}
+	if dp.Status.Replicas > dp.Status.UpdatedReplicas {
+		// old replicas pending termination
+		return false, nil
+	}
+	return dp.Status.AvailableReplicas >= desired, nil
+}
@@ -0,0 +1,175 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package wait
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/tlog"
+)
+
+func TestPoll(t *testing.T) {
+	never := func() (bool, error) { return false, nil }
+
+	calls := 0
+	err := poll(context.Background(), time.Millisecond, time.Second, func() (bool, error) {
+		calls++
+		return calls == 3, nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("unexpected result: calls=%d err=%v", calls, err)
+	}
+
+	err = poll(context.Background(), time.Millisecond, 10*time.Millisecond, never)
+	if !errors.Is(err, wait.ErrWaitTimeout) {
+		t.Errorf("expected timeout, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = poll(ctx, time.Hour, -1, never)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected cancellation, got %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = poll(ctx, time.Millisecond, time.Hour, never)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the context deadline to be honored, got %v", err)
+	}
+}
+
+func TestNextInterval(t *testing.T) {
+	defer func(backoff float64) { PollBackoff = backoff }(PollBackoff)
+
+	testCases := []struct {
+		name     string
+		backoff  float64
+		interval time.Duration
+		expected time.Duration
+	}{
+		{
+			name:     "no backoff",
+			interval: time.Second,
+			expected: time.Second,
+		},
+		{
+			name:     "unit backoff",
+			backoff:  1,
+			interval: time.Second,
+			expected: time.Second,
+		},
+		{
+			name:     "grown",
+			backoff:  2,
+			interval: time.Second,
+			expected: 2 * time.Second,
+		},
+		{
+			name:     "capped",
+			backoff:  2,
+			interval: 20 * time.Second,
+			expected: MaxPollInterval,
+		},
+		{
+			name:     "above the cap",
+			backoff:  2,
+			interval: time.Minute,
+			expected: time.Minute,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			PollBackoff = tc.backoff
+			if got := nextInterval(tc.interval); got != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestIsDeploymentAvailable(t *testing.T) {
+	two := int32(2)
+	testCases := []struct {
+		name          string
+		generation    int64
+		status        appsv1.DeploymentStatus
+		expected      bool
+		expectedError bool
+	}{
+		{
+			name:       "generation not observed",
+			generation: 2,
+			status:     appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2},
+		},
+		{
+			name:   "rolling out",
+			status: appsv1.DeploymentStatus{Replicas: 3, UpdatedReplicas: 2, AvailableReplicas: 2},
+		},
+		{
+			name:   "not available",
+			status: appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 1},
+		},
+		{
+			name:     "available",
+			status:   appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2},
+			expected: true,
+		},
+		{
+			name: "stalled",
+			status: appsv1.DeploymentStatus{
+				Replicas:        2,
+				UpdatedReplicas: 2,
+				Conditions: []appsv1.DeploymentCondition{
+					{
+						Type:    appsv1.DeploymentProgressing,
+						Status:  corev1.ConditionFalse,
+						Reason:  "ProgressDeadlineExceeded",
+						Message: "ReplicaSet has timed out progressing.",
+					},
+				},
+			},
+			expectedError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dp := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "sched", Namespace: "ns", Generation: tc.generation},
+				Spec:       appsv1.DeploymentSpec{Replicas: &two},
+				Status:     tc.status,
+			}
+			got, err := isDeploymentAvailable(tlog.NewNullLogAdapter(), dp)
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error %v, got %v", tc.expectedError, err)
+			}
+			if got != tc.expected {
+				t.Errorf("expected available %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
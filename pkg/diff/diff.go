@@ -0,0 +1,136 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+// Package diff computes the drift between the desired objects and the ones
+// found on the cluster. Only the fields set in the desired objects are
+// compared, so the fields managed by the server or by other actors
+// (status, defaults, resourceVersion...) never show up as changes.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+type Change struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+type ObjectDiff struct {
+	Object  string   `json:"object"`
+	Missing bool     `json:"missing,omitempty"`
+	Changes []Change `json:"changes,omitempty"`
+}
+
+func (od ObjectDiff) IsEmpty() bool {
+	return !od.Missing && len(od.Changes) == 0
+}
+
+// ignoredFields are top-level fields owned by the server.
+var ignoredFields = map[string]bool{
+	"status": true,
+}
+
+// Compare returns the changes needed to make live match desired. Both
+// objects can be anything which serializes to a JSON object, like typed or
+// unstructured kubernetes objects.
+func Compare(desired, live interface{}) ([]Change, error) {
+	desiredData, err := normalize(desired)
+	if err != nil {
+		return nil, err
+	}
+	liveData, err := normalize(live)
+	if err != nil {
+		return nil, err
+	}
+	desiredMap, ok := desiredData.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("desired object is not a JSON object: %T", desiredData)
+	}
+	liveMap, _ := liveData.(map[string]interface{})
+
+	var changes []Change
+	for _, key := range sortedKeys(desiredMap) {
+		if ignoredFields[key] {
+			continue
+		}
+		changes = compareValues(changes, key, desiredMap[key], lookup(liveMap, key))
+	}
+	return changes, nil
+}
+
+func compareValues(changes []Change, path string, desired, live interface{}) []Change {
+	if desired == nil {
+		// unset in the desired object, not managed by us
+		return changes
+	}
+	switch desiredVal := desired.(type) {
+	case map[string]interface{}:
+		liveVal, ok := live.(map[string]interface{})
+		if !ok && live != nil {
+			return append(changes, Change{Path: path, Old: live, New: desired})
+		}
+		for _, key := range sortedKeys(desiredVal) {
+			changes = compareValues(changes, path+"."+key, desiredVal[key], lookup(liveVal, key))
+		}
+		return changes
+	case []interface{}:
+		liveVal, ok := live.([]interface{})
+		if !ok || len(liveVal) != len(desiredVal) {
+			return append(changes, Change{Path: path, Old: live, New: desired})
+		}
+		for idx := range desiredVal {
+			changes = compareValues(changes, fmt.Sprintf("%s[%d]", path, idx), desiredVal[idx], liveVal[idx])
+		}
+		return changes
+	default:
+		if !reflect.DeepEqual(desired, live) {
+			return append(changes, Change{Path: path, Old: live, New: desired})
+		}
+		return changes
+	}
+}
+
+// normalize makes the values comparable, regardless of their original types
+func normalize(obj interface{}) (interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var ret interface{}
+	err = json.Unmarshal(data, &ret)
+	return ret, err
+}
+
+func lookup(obj map[string]interface{}, key string) interface{} {
+	if obj == nil {
+		return nil
+	}
+	return obj[key]
+}
+
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
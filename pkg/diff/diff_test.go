@@ -0,0 +1,73 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package diff
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCompare(t *testing.T) {
+	desired := &appsv1.DaemonSet{
+		TypeMeta: metav1.TypeMeta{Kind: "DaemonSet", APIVersion: "apps/v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "rte",
+			Labels: map[string]string{"app": "rte"},
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "rte", Image: "quay.io/rte:v2"},
+					},
+				},
+			},
+		},
+	}
+
+	live := desired.DeepCopy()
+	live.ResourceVersion = "42"
+	live.Labels["extra"] = "by-someone-else"
+	live.Spec.Template.Spec.Containers[0].TerminationMessagePath = "/dev/termination-log"
+	live.Status.NumberReady = 3
+
+	changes, err := Compare(desired, live)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("unexpected changes: %v", changes)
+	}
+
+	live.Spec.Template.Spec.Containers[0].Image = "quay.io/rte:v1"
+	delete(live.Labels, "app")
+	changes, err = Compare(desired, live)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []Change{
+		{Path: "metadata.labels.app", Old: nil, New: "rte"},
+		{Path: "spec.template.spec.containers[0].image", Old: "quay.io/rte:v1", New: "quay.io/rte:v2"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("unexpected changes: %v expected %v", changes, expected)
+	}
+}
@@ -0,0 +1,228 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Relevant returns the fields set in the desired object, and the same fields of the live object, both normalized
+// like Compare does, so they can be shown side by side. The live object may be nil, if missing.
+func Relevant(desired, live interface{}) (map[string]interface{}, map[string]interface{}, error) {
+	desiredData, err := normalize(desired)
+	if err != nil {
+		return nil, nil, err
+	}
+	desiredMap, ok := prune(desiredData).(map[string]interface{})
+	if !ok {
+		return nil, nil, fmt.Errorf("desired object is not a JSON object: %T", desiredData)
+	}
+	for key := range ignoredFields {
+		delete(desiredMap, key)
+	}
+	if live == nil {
+		return desiredMap, nil, nil
+	}
+	liveData, err := normalize(live)
+	if err != nil {
+		return nil, nil, err
+	}
+	liveMap, _ := project(desiredMap, liveData).(map[string]interface{})
+	return desiredMap, liveMap, nil
+}
+
+// prune drops the unset fields, which Compare ignores.
+func prune(obj interface{}) interface{} {
+	switch val := obj.(type) {
+	case map[string]interface{}:
+		ret := make(map[string]interface{}, len(val))
+		for key, item := range val {
+			if item == nil {
+				continue
+			}
+			ret[key] = prune(item)
+		}
+		return ret
+	case []interface{}:
+		ret := make([]interface{}, len(val))
+		for idx, item := range val {
+			ret[idx] = prune(item)
+		}
+		return ret
+	default:
+		return obj
+	}
+}
+
+// project returns the parts of live which desired sets. Like in Compare, the lists whose length
+// changed and the values whose type changed are returned whole.
+func project(desired, live interface{}) interface{} {
+	if live == nil {
+		return nil
+	}
+	switch desiredVal := desired.(type) {
+	case map[string]interface{}:
+		liveVal, ok := live.(map[string]interface{})
+		if !ok {
+			return live
+		}
+		ret := make(map[string]interface{}, len(desiredVal))
+		for key, item := range desiredVal {
+			if proj := project(item, liveVal[key]); proj != nil {
+				ret[key] = proj
+			}
+		}
+		return ret
+	case []interface{}:
+		liveVal, ok := live.([]interface{})
+		if !ok || len(liveVal) != len(desiredVal) {
+			return live
+		}
+		ret := make([]interface{}, len(liveVal))
+		for idx := range desiredVal {
+			ret[idx] = project(desiredVal[idx], liveVal[idx])
+		}
+		return ret
+	default:
+		return live
+	}
+}
+
+type lineOp struct {
+	kind byte // ' ', '-' or '+'
+	text string
+}
+
+// Unified returns the unified diff turning oldText into newText, with up to context unchanged lines
+// around the changes. Returns an empty string if the texts are equal.
+func Unified(oldName, newName, oldText, newText string, context int) string {
+	ops := diffLines(splitLines(oldText), splitLines(newText))
+
+	var changed []int
+	for idx, op := range ops {
+		if op.kind != ' ' {
+			changed = append(changed, idx)
+		}
+	}
+	if len(changed) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+	for first := 0; first < len(changed); {
+		// merge the changes whose contexts touch in the same hunk
+		last := first
+		for last+1 < len(changed) && changed[last+1]-changed[last] <= 2*context+1 {
+			last++
+		}
+		start := changed[first] - context
+		if start < 0 {
+			start = 0
+		}
+		end := changed[last] + context + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+		writeHunk(&sb, ops, start, end)
+		first = last + 1
+	}
+	return sb.String()
+}
+
+func writeHunk(sb *strings.Builder, ops []lineOp, start, end int) {
+	oldBefore, newBefore := countLines(ops[:start])
+	oldCount, newCount := countLines(ops[start:end])
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(oldBefore, oldCount), hunkRange(newBefore, newCount))
+	for _, op := range ops[start:end] {
+		fmt.Fprintf(sb, "%c%s\n", op.kind, op.text)
+	}
+}
+
+// hunkRange formats the range of a hunk side, which refers to the line before the hunk if empty.
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
+
+func countLines(ops []lineOp) (int, int) {
+	oldCount, newCount := 0, 0
+	for _, op := range ops {
+		if op.kind != '+' {
+			oldCount++
+		}
+		if op.kind != '-' {
+			newCount++
+		}
+	}
+	return oldCount, newCount
+}
+
+// diffLines computes the shortest edit script between the lines using their longest common subsequence.
+// The manifests are small enough for the quadratic cost.
+func diffLines(oldLines, newLines []string) []lineOp {
+	n, m := len(oldLines), len(newLines)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []lineOp
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case oldLines[i] == newLines[j]:
+			ops = append(ops, lineOp{kind: ' ', text: oldLines[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, lineOp{kind: '-', text: oldLines[i]})
+			i++
+		default:
+			ops = append(ops, lineOp{kind: '+', text: newLines[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, lineOp{kind: '-', text: oldLines[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, lineOp{kind: '+', text: newLines[j]})
+	}
+	return ops
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,125 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	testCases := []struct {
+		name     string
+		oldText  string
+		newText  string
+		context  int
+		expected string
+	}{
+		{
+			name:     "equal",
+			oldText:  "a\nb\n",
+			newText:  "a\nb\n",
+			context:  3,
+			expected: "",
+		},
+		{
+			name:     "added",
+			oldText:  "",
+			newText:  "a\nb\n",
+			context:  3,
+			expected: "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name:     "changed line with context",
+			oldText:  "a\nb\nc\nd\ne\n",
+			newText:  "a\nb\nC\nd\ne\n",
+			context:  1,
+			expected: "--- old\n+++ new\n@@ -2,3 +2,3 @@\n b\n-c\n+C\n d\n",
+		},
+		{
+			name:     "distant changes in separate hunks",
+			oldText:  "a\nb\nc\nd\ne\nf\ng\n",
+			newText:  "A\nb\nc\nd\ne\nf\nG\n",
+			context:  1,
+			expected: "--- old\n+++ new\n@@ -1,2 +1,2 @@\n-a\n+A\n b\n@@ -6,2 +6,2 @@\n f\n-g\n+G\n",
+		},
+		{
+			name:     "close changes in the same hunk",
+			oldText:  "a\nb\nc\nd\n",
+			newText:  "A\nb\nc\nD\n",
+			context:  1,
+			expected: "--- old\n+++ new\n@@ -1,4 +1,4 @@\n-a\n+A\n b\n c\n-d\n+D\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Unified("old", "new", tc.oldText, tc.newText, tc.context)
+			if got != tc.expected {
+				t.Errorf("unexpected diff:\ngot:\n%s\nexpected:\n%s", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestRelevant(t *testing.T) {
+	desired := map[string]interface{}{
+		"kind":   "ConfigMap",
+		"status": map[string]interface{}{"ready": true},
+		"metadata": map[string]interface{}{
+			"name":              "rte-config",
+			"creationTimestamp": nil,
+		},
+		"data": map[string]interface{}{"config.yaml": "new"},
+	}
+	live := map[string]interface{}{
+		"kind": "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":            "rte-config",
+			"resourceVersion": "42",
+		},
+		"data": map[string]interface{}{"config.yaml": "old", "other": "x"},
+	}
+
+	desiredFields, liveFields, err := Relevant(desired, live)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedDesired := map[string]interface{}{
+		"kind":     "ConfigMap",
+		"metadata": map[string]interface{}{"name": "rte-config"},
+		"data":     map[string]interface{}{"config.yaml": "new"},
+	}
+	if !reflect.DeepEqual(desiredFields, expectedDesired) {
+		t.Errorf("unexpected desired fields: %v", desiredFields)
+	}
+	expectedLive := map[string]interface{}{
+		"kind":     "ConfigMap",
+		"metadata": map[string]interface{}{"name": "rte-config"},
+		"data":     map[string]interface{}{"config.yaml": "old"},
+	}
+	if !reflect.DeepEqual(liveFields, expectedLive) {
+		t.Errorf("unexpected live fields: %v", liveFields)
+	}
+
+	_, liveFields, err = Relevant(desired, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if liveFields != nil {
+		t.Errorf("unexpected live fields for a missing object: %v", liveFields)
+	}
+}
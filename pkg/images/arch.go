@@ -0,0 +1,34 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package images
+
+// defaultImageArchitectures are the architectures the default images are published for.
+var defaultImageArchitectures = map[string][]string{
+	SchedulerPluginSchedulerDefaultImageTag:  {"amd64"},
+	SchedulerPluginControllerDefaultImageTag: {"amd64"},
+	ResourceTopologyExporterDefaultImageTag:  {"amd64"},
+	SchedulerPluginSchedulerDefaultImageSHA:  {"amd64"},
+	SchedulerPluginControllerDefaultImageSHA: {"amd64"},
+	ResourceTopologyExporterDefaultImageSHA:  {"amd64"},
+}
+
+// Architectures returns the architectures the given image is known to be published for.
+// Returns false if the image is unknown, e.g. it was overridden by the user.
+func Architectures(image string) ([]string, bool) {
+	arches, ok := defaultImageArchitectures[image]
+	return arches, ok
+}
@@ -30,3 +30,56 @@ func TestImageURISanity(t *testing.T) {
 		t.Fatalf("invalid Resource Topology Exporter Image pull URL")
 	}
 }
+
+func TestOverride(t *testing.T) {
+	saved := ResourceTopologyExporterImage
+	defer func() { ResourceTopologyExporterImage = saved }()
+
+	testCases := []struct {
+		name          string
+		refs          map[string]string
+		expectedImage string
+		expectedError bool
+	}{
+		{
+			name:          "no overrides",
+			expectedImage: saved,
+		},
+		{
+			name:          "known component",
+			refs:          map[string]string{ComponentTopologyUpdater: "registry.internal/rte:v0.2.3"},
+			expectedImage: "registry.internal/rte:v0.2.3",
+		},
+		{
+			name: "unknown component",
+			refs: map[string]string{
+				ComponentTopologyUpdater: "registry.internal/rte:v0.2.3",
+				"topology-exporter":      "registry.internal/rte:v0.2.3",
+			},
+			expectedImage: saved,
+			expectedError: true,
+		},
+		{
+			name:          "malformed image",
+			refs:          map[string]string{ComponentTopologyUpdater: "registry.internal/rte v0.2.3"},
+			expectedImage: saved,
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ResourceTopologyExporterImage = saved
+			err := Override(tc.refs)
+			if tc.expectedError && err == nil {
+				t.Errorf("expected error, got none")
+			}
+			if !tc.expectedError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if ResourceTopologyExporterImage != tc.expectedImage {
+				t.Errorf("image %q expected %q", ResourceTopologyExporterImage, tc.expectedImage)
+			}
+		})
+	}
+}
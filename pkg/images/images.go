@@ -16,16 +16,28 @@
 
 package images
 
-import "os"
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// the environment variables replacing the default images
+const (
+	EnvSchedulerPluginImage           = "TAS_SCHEDULER_PLUGIN_IMAGE"
+	EnvSchedulerPluginControllerImage = "TAS_SCHEDULER_PLUGIN_CONTROLLER_IMAGE"
+	EnvResourceTopologyExporterImage  = "TAS_RESOURCE_EXPORTER_IMAGE"
+)
 
 func init() {
-	if schedImage, ok := os.LookupEnv("TAS_SCHEDULER_PLUGIN_IMAGE"); ok {
+	if schedImage, ok := os.LookupEnv(EnvSchedulerPluginImage); ok {
 		SchedulerPluginSchedulerImage = schedImage
 	}
-	if schedCtrlImage, ok := os.LookupEnv("TAS_SCHEDULER_PLUGIN_CONTROLLER_IMAGE"); ok {
+	if schedCtrlImage, ok := os.LookupEnv(EnvSchedulerPluginControllerImage); ok {
 		SchedulerPluginControllerImage = schedCtrlImage
 	}
-	if rteImage, ok := os.LookupEnv("TAS_RESOURCE_EXPORTER_IMAGE"); ok {
+	if rteImage, ok := os.LookupEnv(EnvResourceTopologyExporterImage); ok {
 		ResourceTopologyExporterImage = rteImage
 	}
 }
@@ -35,3 +47,44 @@ var (
 	SchedulerPluginControllerImage = SchedulerPluginSchedulerDefaultImageTag
 	ResourceTopologyExporterImage  = ResourceTopologyExporterDefaultImageTag
 )
+
+// the components whose image can be overridden
+const (
+	ComponentTopologyUpdater     = "topology-updater"
+	ComponentSchedulerPlugin     = "scheduler-plugin"
+	ComponentSchedulerController = "scheduler-controller"
+)
+
+func componentImages() map[string]*string {
+	return map[string]*string{
+		ComponentTopologyUpdater:     &ResourceTopologyExporterImage,
+		ComponentSchedulerPlugin:     &SchedulerPluginSchedulerImage,
+		ComponentSchedulerController: &SchedulerPluginControllerImage,
+	}
+}
+
+// Override replaces the images of the given components, e.g. to pull them from a mirror registry.
+// The overrides take precedence over the environment variables. Nothing is replaced if any
+// component is unknown or any image reference is malformed.
+func Override(refs map[string]string) error {
+	imgs := componentImages()
+	components := make([]string, 0, len(refs))
+	for component := range refs {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+
+	for _, component := range components {
+		if _, ok := imgs[component]; !ok {
+			return fmt.Errorf("unknown component %q: must be one of %s, %s, %s", component, ComponentTopologyUpdater, ComponentSchedulerPlugin, ComponentSchedulerController)
+		}
+		ref := refs[component]
+		if ref == "" || strings.ContainsAny(ref, " \t\n") {
+			return fmt.Errorf("invalid image %q for component %q", ref, component)
+		}
+	}
+	for _, component := range components {
+		*imgs[component] = refs[component]
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package kubeletconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubeletconfigv1beta1 "k8s.io/kubelet/config/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	// on OpenShift the kubelet configuration is managed by the machine config operator
+	KubeletConfigListGVK = schema.GroupVersionKind{
+		Group:   "machineconfiguration.openshift.io",
+		Version: "v1",
+		Kind:    "KubeletConfigList",
+	}
+)
+
+// GetKubeletConfigFromMachineConfig returns the kubelet configuration fragments
+// carried by the OpenShift KubeletConfig objects, keyed by object name.
+func GetKubeletConfigFromMachineConfig(cli client.Client, logger *log.Logger) (map[string]*kubeletconfigv1beta1.KubeletConfiguration, error) {
+	kcList := &unstructured.UnstructuredList{}
+	kcList.SetGroupVersionKind(KubeletConfigListGVK)
+	if err := cli.List(context.TODO(), kcList); err != nil {
+		return nil, fmt.Errorf("cannot list the KubeletConfig objects: %w", err)
+	}
+
+	kubeletConfs := make(map[string]*kubeletconfigv1beta1.KubeletConfiguration)
+	for _, item := range kcList.Items {
+		data, found, err := unstructured.NestedMap(item.Object, "spec", "kubeletConfig")
+		if err != nil {
+			logger.Printf("malformed KubeletConfig %q: %v - skipped", item.GetName(), err)
+			continue
+		}
+		if !found {
+			logger.Printf("KubeletConfig %q carries no kubelet configuration - skipped", item.GetName())
+			continue
+		}
+
+		blob, err := json.Marshal(data)
+		if err != nil {
+			logger.Printf("cannot encode KubeletConfig %q: %v - skipped", item.GetName(), err)
+			continue
+		}
+		conf := kubeletconfigv1beta1.KubeletConfiguration{}
+		if err := json.Unmarshal(blob, &conf); err != nil {
+			logger.Printf("cannot decode KubeletConfig %q: %v - skipped", item.GetName(), err)
+			continue
+		}
+		kubeletConfs[item.GetName()] = &conf
+	}
+	return kubeletConfs, nil
+}
@@ -0,0 +1,98 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package manifests
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// the vendored API predates the ValidatingAdmissionPolicy types (kubernetes 1.30), so we use unstructured objects.
+var (
+	ValidatingAdmissionPolicyGVK = schema.GroupVersionKind{
+		Group:   "admissionregistration.k8s.io",
+		Version: "v1",
+		Kind:    "ValidatingAdmissionPolicy",
+	}
+	ValidatingAdmissionPolicyBindingGVK = schema.GroupVersionKind{
+		Group:   "admissionregistration.k8s.io",
+		Version: "v1",
+		Kind:    "ValidatingAdmissionPolicyBinding",
+	}
+)
+
+// SchedulerNameAdmissionPolicy returns a ValidatingAdmissionPolicy rejecting the creation
+// of the pods which don't request the given scheduler.
+func SchedulerNameAdmissionPolicy(name, schedulerName string) *unstructured.Unstructured {
+	vap := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"name": name,
+			},
+			"spec": map[string]interface{}{
+				"failurePolicy": "Fail",
+				"matchConstraints": map[string]interface{}{
+					"resourceRules": []interface{}{
+						map[string]interface{}{
+							"apiGroups":   []interface{}{""},
+							"apiVersions": []interface{}{"v1"},
+							"operations":  []interface{}{"CREATE"},
+							"resources":   []interface{}{"pods"},
+						},
+					},
+				},
+				"validations": []interface{}{
+					map[string]interface{}{
+						"expression": fmt.Sprintf("object.spec.schedulerName == '%s'", schedulerName),
+						"message":    fmt.Sprintf("the pod must set spec.schedulerName to %q", schedulerName),
+					},
+				},
+			},
+		},
+	}
+	vap.SetGroupVersionKind(ValidatingAdmissionPolicyGVK)
+	return vap
+}
+
+// SchedulerNameAdmissionPolicyBinding returns a ValidatingAdmissionPolicyBinding enforcing
+// the given policy on the pods matching all the given labels.
+func SchedulerNameAdmissionPolicyBinding(name, policyName string, podSelector map[string]string) *unstructured.Unstructured {
+	matchLabels := make(map[string]interface{}, len(podSelector))
+	for key, val := range podSelector {
+		matchLabels[key] = val
+	}
+	vapb := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"name": name,
+			},
+			"spec": map[string]interface{}{
+				"policyName":        policyName,
+				"validationActions": []interface{}{"Deny"},
+				"matchResources": map[string]interface{}{
+					"objectSelector": map[string]interface{}{
+						"matchLabels": matchLabels,
+					},
+				},
+			},
+		},
+	}
+	vapb.SetGroupVersionKind(ValidatingAdmissionPolicyBindingGVK)
+	return vapb
+}
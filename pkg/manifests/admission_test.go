@@ -0,0 +1,48 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package manifests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSchedulerNameAdmissionPolicy(t *testing.T) {
+	vap := SchedulerNameAdmissionPolicy("enforce", "my-scheduler")
+	vapb := SchedulerNameAdmissionPolicyBinding("enforce", "enforce", map[string]string{"numa": "required"})
+
+	var buf bytes.Buffer
+	if err := SerializeObject(vap, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := SerializeObject(vapb, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, exp := range []string{
+		"kind: ValidatingAdmissionPolicy\n",
+		"kind: ValidatingAdmissionPolicyBinding\n",
+		"object.spec.schedulerName == 'my-scheduler'",
+		"policyName: enforce",
+		"numa: required",
+	} {
+		if !strings.Contains(out, exp) {
+			t.Errorf("missing %q in:\n%s", exp, out)
+		}
+	}
+}
@@ -17,7 +17,12 @@
 package api
 
 import (
+	"fmt"
+	"strings"
+
 	apiextensionv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -59,10 +64,84 @@ func (mf Manifests) Clone() Manifests {
 	}
 }
 
-func (mf Manifests) Update() Manifests {
+type UpdateOptions struct {
+	// ServedVersions, if not empty, is the exhaustive list of the CRD versions to serve.
+	ServedVersions []string
+	// StorageVersion, if not empty, is the CRD version to be persisted. Must be served.
+	StorageVersion string
+	// Categories, if not empty, replace the CRD categories (e.g. "all" for `kubectl get all`).
+	Categories []string
+	// ShortNames, if not empty, replace the CRD short names.
+	ShortNames []string
+	// APIGroup, if not empty, replaces the upstream API group of the CRD.
+	APIGroup string
+}
+
+// Update applies the options to a copy of the manifests. Unlike the other
+// components, the options can only be checked against the loaded CRD, so
+// Update fails if the requested version policy is inconsistent.
+func (mf Manifests) Update(options UpdateOptions) (Manifests, error) {
 	ret := mf.Clone()
-	// nothing to do atm
-	return ret
+
+	known := sets.NewString()
+	for _, ver := range ret.Crd.Spec.Versions {
+		known.Insert(ver.Name)
+	}
+
+	if len(options.ServedVersions) > 0 {
+		served := sets.NewString(options.ServedVersions...)
+		if unknown := served.Difference(known); unknown.Len() > 0 {
+			return ret, fmt.Errorf("unknown served versions: %v (available: %v)", unknown.List(), known.List())
+		}
+		for idx := range ret.Crd.Spec.Versions {
+			ret.Crd.Spec.Versions[idx].Served = served.Has(ret.Crd.Spec.Versions[idx].Name)
+		}
+	}
+
+	if options.StorageVersion != "" {
+		if !known.Has(options.StorageVersion) {
+			return ret, fmt.Errorf("unknown storage version: %q (available: %v)", options.StorageVersion, known.List())
+		}
+		for idx := range ret.Crd.Spec.Versions {
+			ret.Crd.Spec.Versions[idx].Storage = (ret.Crd.Spec.Versions[idx].Name == options.StorageVersion)
+		}
+	}
+
+	for _, ver := range ret.Crd.Spec.Versions {
+		if ver.Storage && !ver.Served {
+			return ret, fmt.Errorf("storage version %q is not served", ver.Name)
+		}
+	}
+
+	if len(options.Categories) > 0 {
+		if err := validateNames("category", options.Categories); err != nil {
+			return ret, err
+		}
+		ret.Crd.Spec.Names.Categories = options.Categories
+	}
+	if len(options.ShortNames) > 0 {
+		if err := validateNames("short name", options.ShortNames); err != nil {
+			return ret, err
+		}
+		ret.Crd.Spec.Names.ShortNames = options.ShortNames
+	}
+	if options.APIGroup != "" {
+		if err := manifests.ValidateAPIGroup(options.APIGroup); err != nil {
+			return ret, err
+		}
+		manifests.UpdateCRDAPIGroup(ret.Crd, options.APIGroup)
+	}
+	return ret, nil
+}
+
+// validateNames applies the same rules of the apiserver to the CRD categories and short names.
+func validateNames(kind string, names []string) error {
+	for _, name := range names {
+		if errs := validation.IsDNS1035Label(name); len(errs) > 0 {
+			return fmt.Errorf("invalid %s %q: %s", kind, name, strings.Join(errs, "; "))
+		}
+	}
+	return nil
 }
 
 func New(plat platform.Platform) Manifests {
@@ -72,10 +151,15 @@ func New(plat platform.Platform) Manifests {
 }
 
 func GetManifests(plat platform.Platform) (Manifests, error) {
+	return GetManifestsFromSource(manifests.Source{}, plat)
+}
+
+// GetManifestsFromSource returns the manifests loaded from the given embedded manifests version.
+func GetManifestsFromSource(src manifests.Source, plat platform.Platform) (Manifests, error) {
 	var err error
 	mf := New(plat)
 
-	mf.Crd, err = manifests.APICRD()
+	mf.Crd, err = src.APICRD()
 	if err != nil {
 		return mf, err
 	}
@@ -0,0 +1,123 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
+)
+
+func TestUpdateVersionPolicy(t *testing.T) {
+	testCases := []struct {
+		name        string
+		options     UpdateOptions
+		expectError bool
+	}{
+		{name: "defaults", options: UpdateOptions{}},
+		{name: "explicit", options: UpdateOptions{ServedVersions: []string{"v1alpha1"}, StorageVersion: "v1alpha1"}},
+		{name: "unknown served", options: UpdateOptions{ServedVersions: []string{"v1foo"}}, expectError: true},
+		{name: "unknown storage", options: UpdateOptions{StorageVersion: "v1foo"}, expectError: true},
+	}
+
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ret, err := mf.Update(tc.options)
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			storage := 0
+			for _, ver := range ret.Crd.Spec.Versions {
+				if ver.Storage {
+					storage++
+				}
+			}
+			if storage != 1 {
+				t.Errorf("expected exactly one storage version, got %d", storage)
+			}
+		})
+	}
+}
+
+func TestUpdateNames(t *testing.T) {
+	testCases := []struct {
+		name        string
+		options     UpdateOptions
+		expectError bool
+	}{
+		{name: "names", options: UpdateOptions{Categories: []string{"all", "topology"}, ShortNames: []string{"nrt"}}},
+		{name: "invalid category", options: UpdateOptions{Categories: []string{"Not Valid"}}, expectError: true},
+		{name: "invalid short name", options: UpdateOptions{ShortNames: []string{"nrt!"}}, expectError: true},
+	}
+
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ret, err := mf.Update(tc.options)
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(ret.Crd.Spec.Names.Categories, tc.options.Categories) {
+				t.Errorf("unexpected categories: %v", ret.Crd.Spec.Names.Categories)
+			}
+			if !reflect.DeepEqual(ret.Crd.Spec.Names.ShortNames, tc.options.ShortNames) {
+				t.Errorf("unexpected short names: %v", ret.Crd.Spec.Names.ShortNames)
+			}
+		})
+	}
+}
+
+func TestUpdateAPIGroup(t *testing.T) {
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ret, err := mf.Update(UpdateOptions{APIGroup: "topology.example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ret.Crd.Spec.Group != "topology.example.com" || ret.Crd.Name != "noderesourcetopologies.topology.example.com" {
+		t.Errorf("unexpected group %q for CRD %q", ret.Crd.Spec.Group, ret.Crd.Name)
+	}
+
+	if _, err := mf.Update(UpdateOptions{APIGroup: "topology"}); err == nil {
+		t.Errorf("expected error for an invalid group")
+	}
+}
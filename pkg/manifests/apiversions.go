@@ -0,0 +1,149 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package manifests
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/go-version"
+	rbacv1 "k8s.io/api/rbac/v1"
+	rbacv1beta1 "k8s.io/api/rbac/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type groupVersionLifecycle struct {
+	groupVersion string
+	deprecatedIn string
+	removedIn    string
+}
+
+// deprecatedGroupVersions lists the group versions which the manifests may plausibly use and
+// which are deprecated on recent kubernetes versions.
+var deprecatedGroupVersions = []groupVersionLifecycle{
+	{groupVersion: "extensions/v1beta1", deprecatedIn: "1.8", removedIn: "1.22"},
+	{groupVersion: "apps/v1beta1", deprecatedIn: "1.9", removedIn: "1.16"},
+	{groupVersion: "apps/v1beta2", deprecatedIn: "1.9", removedIn: "1.16"},
+	{groupVersion: "apiextensions.k8s.io/v1beta1", deprecatedIn: "1.16", removedIn: "1.22"},
+	{groupVersion: "rbac.authorization.k8s.io/v1beta1", deprecatedIn: "1.17", removedIn: "1.22"},
+	{groupVersion: "scheduling.k8s.io/v1beta1", deprecatedIn: "1.14", removedIn: "1.22"},
+	{groupVersion: "policy/v1beta1", deprecatedIn: "1.21", removedIn: "1.25"},
+	{groupVersion: "batch/v1beta1", deprecatedIn: "1.21", removedIn: "1.25"},
+}
+
+const (
+	// RBACAPIVersionV1 is the version of the rbac.authorization.k8s.io group the manifests use.
+	RBACAPIVersionV1 = "v1"
+	// RBACAPIVersionV1Beta1 is the version of the rbac.authorization.k8s.io group served by the clusters older than 1.8.
+	RBACAPIVersionV1Beta1 = "v1beta1"
+)
+
+// ValidateRBACAPIVersion checks the RBAC objects can be converted to the given version. Empty means RBACAPIVersionV1.
+func ValidateRBACAPIVersion(ver string) error {
+	if ver != "" && ver != RBACAPIVersionV1 && ver != RBACAPIVersionV1Beta1 {
+		return fmt.Errorf("unsupported RBAC API version %q: must be %q or %q", ver, RBACAPIVersionV1, RBACAPIVersionV1Beta1)
+	}
+	return nil
+}
+
+// ConvertRBACObjects replaces in place the RBAC objects with their equivalent in the given version
+// of the rbac.authorization.k8s.io group. Empty means RBACAPIVersionV1, which leaves the objects unchanged.
+func ConvertRBACObjects(objs []client.Object, ver string) error {
+	if err := ValidateRBACAPIVersion(ver); err != nil {
+		return err
+	}
+	if ver != RBACAPIVersionV1Beta1 {
+		return nil
+	}
+	for idx, obj := range objs {
+		var converted client.Object
+		switch obj.(type) {
+		case *rbacv1.Role:
+			converted = &rbacv1beta1.Role{}
+		case *rbacv1.RoleBinding:
+			converted = &rbacv1beta1.RoleBinding{}
+		case *rbacv1.ClusterRole:
+			converted = &rbacv1beta1.ClusterRole{}
+		case *rbacv1.ClusterRoleBinding:
+			converted = &rbacv1beta1.ClusterRoleBinding{}
+		default:
+			continue
+		}
+		// the v1beta1 types have the same fields as the v1 ones
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, converted); err != nil {
+			return err
+		}
+		kind := converted.GetObjectKind().GroupVersionKind().Kind
+		if kind == "" {
+			gvks, _, err := scheme.Scheme.ObjectKinds(obj)
+			if err != nil {
+				return err
+			}
+			kind = gvks[0].Kind
+		}
+		converted.GetObjectKind().SetGroupVersionKind(rbacv1beta1.SchemeGroupVersion.WithKind(kind))
+		objs[idx] = converted
+	}
+	return nil
+}
+
+// EnsureTypeMeta sets the apiVersion and kind of the object from the scheme if they are missing,
+// so the serialized object never relies on defaulting.
+func EnsureTypeMeta(obj runtime.Object) error {
+	if !obj.GetObjectKind().GroupVersionKind().Empty() {
+		return nil
+	}
+	gvks, _, err := scheme.Scheme.ObjectKinds(obj)
+	if err != nil {
+		return err
+	}
+	if len(gvks) == 0 {
+		return fmt.Errorf("cannot find the kind of %T", obj)
+	}
+	obj.GetObjectKind().SetGroupVersionKind(gvks[0])
+	return nil
+}
+
+// ValidateAPIVersions checks none of the objects uses a group version deprecated
+// or removed on the given kubernetes version.
+func ValidateAPIVersions(objs []client.Object, kubeVersion string) error {
+	target, err := version.NewVersion(kubeVersion)
+	if err != nil {
+		return fmt.Errorf("invalid kubernetes version %q: %w", kubeVersion, err)
+	}
+	for _, obj := range objs {
+		gv := obj.GetObjectKind().GroupVersionKind().GroupVersion().String()
+		for _, lc := range deprecatedGroupVersions {
+			if lc.groupVersion != gv {
+				continue
+			}
+			if target.GreaterThanOrEqual(version.Must(version.NewVersion(lc.removedIn))) {
+				return fmt.Errorf("%s uses %s, removed in kubernetes %s", ObjectName(obj), gv, lc.removedIn)
+			}
+			if target.GreaterThanOrEqual(version.Must(version.NewVersion(lc.deprecatedIn))) {
+				return fmt.Errorf("%s uses %s, deprecated since kubernetes %s", ObjectName(obj), gv, lc.deprecatedIn)
+			}
+		}
+	}
+	return nil
+}
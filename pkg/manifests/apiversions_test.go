@@ -0,0 +1,115 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package manifests
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	rbacv1beta1 "k8s.io/api/rbac/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestEnsureTypeMeta(t *testing.T) {
+	cm := &corev1.ConfigMap{}
+	if err := EnsureTypeMeta(cm); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cm.Kind != "ConfigMap" || cm.APIVersion != "v1" {
+		t.Errorf("unexpected TypeMeta: %+v", cm.TypeMeta)
+	}
+}
+
+func TestValidateAPIVersions(t *testing.T) {
+	rb := &rbacv1beta1.RoleBinding{
+		TypeMeta:   metav1.TypeMeta{Kind: "RoleBinding", APIVersion: "rbac.authorization.k8s.io/v1beta1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "legacy"},
+	}
+	objs := []client.Object{rb}
+
+	if err := ValidateAPIVersions(objs, "1.16.0"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateAPIVersions(objs, "1.21.2"); err == nil {
+		t.Errorf("expected deprecation error, got none")
+	}
+	if err := ValidateAPIVersions(objs, "v1.22"); err == nil {
+		t.Errorf("expected removal error, got none")
+	}
+	if err := ValidateAPIVersions(objs, "not-a-version"); err == nil {
+		t.Errorf("expected error for invalid version, got none")
+	}
+
+	ds, err := DaemonSet(ComponentResourceTopologyExporter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ValidateAPIVersions([]client.Object{ds}, "1.22"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestConvertRBACObjects(t *testing.T) {
+	role, err := Role(ComponentResourceTopologyExporter, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	crb := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "binding"},
+		RoleRef:    rbacv1.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "ClusterRole", Name: "role"},
+		Subjects:   []rbacv1.Subject{{Kind: "ServiceAccount", Name: "sa", Namespace: "ns"}},
+	}
+	cm := &corev1.ConfigMap{}
+
+	objs := []client.Object{role, crb, cm}
+	if err := ConvertRBACObjects(objs, RBACAPIVersionV1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if objs[0] != role || objs[1] != crb {
+		t.Errorf("expected the objects to be unchanged")
+	}
+
+	if err := ConvertRBACObjects(objs, RBACAPIVersionV1Beta1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	convRole, ok := objs[0].(*rbacv1beta1.Role)
+	if !ok {
+		t.Fatalf("expected a v1beta1 role, got %T", objs[0])
+	}
+	if convRole.APIVersion != "rbac.authorization.k8s.io/v1beta1" || convRole.Kind != "Role" {
+		t.Errorf("unexpected TypeMeta: %+v", convRole.TypeMeta)
+	}
+	if convRole.Name != role.Name || len(convRole.Rules) != len(role.Rules) {
+		t.Errorf("unexpected converted role: %+v", convRole)
+	}
+	convCRB, ok := objs[1].(*rbacv1beta1.ClusterRoleBinding)
+	if !ok {
+		t.Fatalf("expected a v1beta1 cluster role binding, got %T", objs[1])
+	}
+	if convCRB.Kind != "ClusterRoleBinding" || convCRB.RoleRef.Name != "role" || len(convCRB.Subjects) != 1 || convCRB.Subjects[0].Namespace != "ns" {
+		t.Errorf("unexpected converted cluster role binding: %+v", convCRB)
+	}
+	if objs[2] != cm {
+		t.Errorf("expected the non-RBAC objects to be unchanged")
+	}
+
+	if err := ConvertRBACObjects(objs, "v2"); err == nil {
+		t.Errorf("expected error for an unsupported version")
+	}
+}
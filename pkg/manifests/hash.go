@@ -0,0 +1,101 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package manifests
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// AnnotationConfigHash is set on pod templates consuming a ConfigMap,
+	// so any change in the configuration content triggers a rollout.
+	AnnotationConfigHash = "configmap.hash"
+)
+
+const (
+	configHashLen = 10
+	nameHashLen   = 8
+)
+
+const (
+	// MaxNameLength is the longest name usable everywhere: as DNS label, and as label value.
+	MaxNameLength = 63
+	// MaxSuffixLength is the longest suffix NameWithSuffix keeps whole, leaving room for the digest of the name.
+	MaxSuffixLength = MaxNameLength - nameHashLen - 1
+)
+
+// ConfigMapDataHash returns a short, stable digest of the ConfigMap data.
+func ConfigMapDataHash(cm *corev1.ConfigMap) string {
+	keys := make([]string, 0, len(cm.Data))
+	for key := range cm.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, key := range keys {
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+		h.Write([]byte(cm.Data[key]))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:configHashLen]
+}
+
+// UpdateConfigMapImmutable makes the ConfigMap immutable, suffixing its name with the content hash,
+// so any change in the configuration content requires a new ConfigMap and its consumers roll out.
+func UpdateConfigMapImmutable(cm *corev1.ConfigMap) *corev1.ConfigMap {
+	cm.Name = NameWithSuffix(cm.Name, ConfigMapDataHash(cm))
+	cm.Immutable = newBool(true)
+	return cm
+}
+
+// NameWithSuffix returns "<name>-<suffix>", shortening name if needed to fit MaxNameLength.
+// The suffix is kept whole if not longer than MaxSuffixLength, otherwise the whole result is shortened.
+func NameWithSuffix(name, suffix string) string {
+	if len(suffix) > MaxSuffixLength {
+		return TruncateName(name+"-"+suffix, MaxNameLength)
+	}
+	return TruncateName(name, MaxNameLength-len(suffix)-1) + "-" + suffix
+}
+
+// TruncateName deterministically shortens the name to maxLen characters, if longer.
+// The truncated names end with a digest of the whole name, so different long names
+// sharing the same prefix are still different once truncated.
+func TruncateName(name string, maxLen int) string {
+	if maxLen <= 0 {
+		return ""
+	}
+	if len(name) <= maxLen {
+		return name
+	}
+	sum := sha256.Sum256([]byte(name))
+	digest := hex.EncodeToString(sum[:])[:nameHashLen]
+	if maxLen <= nameHashLen {
+		return digest[:maxLen]
+	}
+	prefix := strings.TrimRight(name[:maxLen-nameHashLen-1], "-.")
+	if prefix == "" {
+		return digest
+	}
+	return prefix + "-" + digest
+}
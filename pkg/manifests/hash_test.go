@@ -0,0 +1,130 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package manifests
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestConfigMapDataHash(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		Data: map[string]string{
+			"config.yaml": "foo: bar",
+			"extra.yaml":  "baz: 42",
+		},
+	}
+	h1 := ConfigMapDataHash(cm)
+	if len(h1) != configHashLen {
+		t.Fatalf("unexpected hash length: %q", h1)
+	}
+	h2 := ConfigMapDataHash(cm.DeepCopy())
+	if h1 != h2 {
+		t.Fatalf("hash not stable: %q vs %q", h1, h2)
+	}
+	cm.Data["config.yaml"] = "foo: baz"
+	if h3 := ConfigMapDataHash(cm); h3 == h1 {
+		t.Fatalf("hash did not change on data change: %q", h3)
+	}
+}
+
+func TestTruncateName(t *testing.T) {
+	long := strings.Repeat("a", 300)
+	longToo := strings.Repeat("a", 299) + "b"
+
+	type testCase struct {
+		name   string
+		maxLen int
+	}
+	testCases := []testCase{
+		{name: "short", maxLen: MaxNameLength},
+		{name: strings.Repeat("x", MaxNameLength), maxLen: MaxNameLength},
+		{name: long, maxLen: MaxNameLength},
+		{name: long, maxLen: 20},
+		{name: long, maxLen: 4},
+		{name: long, maxLen: nameHashLen + 1},
+		{name: long, maxLen: 0},
+		{name: long, maxLen: -5},
+		{name: strings.Repeat("a-", 100), maxLen: MaxNameLength},
+		{name: strings.Repeat("-", 100), maxLen: 20},
+	}
+	for _, tc := range testCases {
+		got := TruncateName(tc.name, tc.maxLen)
+		if len(got) > tc.maxLen && got != "" {
+			t.Errorf("name %q longer than %d", got, tc.maxLen)
+		}
+		if len(tc.name) <= tc.maxLen && got != tc.name {
+			t.Errorf("short name changed: %q -> %q", tc.name, got)
+		}
+		if got != TruncateName(tc.name, tc.maxLen) {
+			t.Errorf("truncation not deterministic for %q", tc.name)
+		}
+		if len(tc.name) > tc.maxLen && tc.maxLen > 0 && got == "" {
+			t.Errorf("name %q truncated to nothing", tc.name)
+		}
+		if strings.HasPrefix(got, "-") || strings.HasSuffix(got, "-") || strings.Contains(got, "--") {
+			t.Errorf("malformed truncated name %q", got)
+		}
+	}
+
+	if TruncateName(long, MaxNameLength) == TruncateName(longToo, MaxNameLength) {
+		t.Errorf("different long names truncated to the same name")
+	}
+}
+
+func TestNameWithSuffix(t *testing.T) {
+	if got := NameWithSuffix("rte-config", "0123456789"); got != "rte-config-0123456789" {
+		t.Errorf("unexpected name: %q", got)
+	}
+
+	got := NameWithSuffix(strings.Repeat("rte-config-", 20), "0123456789")
+	if len(got) > MaxNameLength {
+		t.Errorf("name %q longer than %d", got, MaxNameLength)
+	}
+	if !strings.HasSuffix(got, "-0123456789") {
+		t.Errorf("suffix lost: %q", got)
+	}
+
+	for _, suffix := range []string{strings.Repeat("s", MaxSuffixLength), strings.Repeat("s", MaxSuffixLength+1), strings.Repeat("s", 100)} {
+		got := NameWithSuffix("rte-config", suffix)
+		if len(got) > MaxNameLength {
+			t.Errorf("name %q longer than %d", got, MaxNameLength)
+		}
+		if strings.HasPrefix(got, "-") || strings.HasSuffix(got, "-") {
+			t.Errorf("malformed name %q", got)
+		}
+	}
+	if got := NameWithSuffix("rte-config", strings.Repeat("s", MaxSuffixLength)); !strings.HasSuffix(got, "-"+strings.Repeat("s", MaxSuffixLength)) {
+		t.Errorf("suffix lost: %q", got)
+	}
+}
+
+func TestUpdateConfigMapImmutableLongName(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		Data: map[string]string{"config.yaml": "foo: bar"},
+	}
+	cm.Name = strings.Repeat("very-long-instance-name-", 10)
+	UpdateConfigMapImmutable(cm)
+	if len(cm.Name) > MaxNameLength {
+		t.Errorf("name %q longer than %d", cm.Name, MaxNameLength)
+	}
+	if !strings.HasSuffix(cm.Name, ConfigMapDataHash(cm)) {
+		t.Errorf("hash suffix lost: %q", cm.Name)
+	}
+}
@@ -17,12 +17,16 @@
 package manifests
 
 import (
+	"bufio"
 	"bytes"
 	"embed"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/fs"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -30,8 +34,11 @@ import (
 	apiextensionv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	k8sjson "k8s.io/apimachinery/pkg/runtime/serializer/json"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
 
+	securityv1 "github.com/openshift/api/security/v1"
 	kubeschedulerconfigv1beta1 "k8s.io/kube-scheduler/config/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	apiconfig "sigs.k8s.io/scheduler-plugins/pkg/apis/config"
 
 	"k8s.io/client-go/kubernetes/scheme"
@@ -55,14 +62,19 @@ func init() {
 	apiextensionv1.AddToScheme(scheme.Scheme)
 	apiconfig.AddToScheme(scheme.Scheme)
 	kubeschedulerconfigv1beta1.AddToScheme(scheme.Scheme)
+	securityv1.AddToScheme(scheme.Scheme)
 }
 
 func Namespace(component string) (*corev1.Namespace, error) {
+	return Source{}.Namespace(component)
+}
+
+func (s Source) Namespace(component string) (*corev1.Namespace, error) {
 	if err := validateComponent(component); err != nil {
 		return nil, err
 	}
 
-	obj, err := loadObject(filepath.Join("yaml", component, "namespace.yaml"))
+	obj, err := s.loadObject(filepath.Join(s.dir(), component, "namespace.yaml"))
 	if err != nil {
 		return nil, err
 	}
@@ -75,6 +87,10 @@ func Namespace(component string) (*corev1.Namespace, error) {
 }
 
 func ServiceAccount(component, subComponent string) (*corev1.ServiceAccount, error) {
+	return Source{}.ServiceAccount(component, subComponent)
+}
+
+func (s Source) ServiceAccount(component, subComponent string) (*corev1.ServiceAccount, error) {
 	if err := validateComponent(component); err != nil {
 		return nil, err
 	}
@@ -82,7 +98,7 @@ func ServiceAccount(component, subComponent string) (*corev1.ServiceAccount, err
 		return nil, err
 	}
 
-	obj, err := loadObject(filepath.Join("yaml", component, subComponent, "serviceaccount.yaml"))
+	obj, err := s.loadObject(filepath.Join(s.dir(), component, subComponent, "serviceaccount.yaml"))
 	if err != nil {
 		return nil, err
 	}
@@ -95,6 +111,10 @@ func ServiceAccount(component, subComponent string) (*corev1.ServiceAccount, err
 }
 
 func Role(component, subComponent string) (*rbacv1.Role, error) {
+	return Source{}.Role(component, subComponent)
+}
+
+func (s Source) Role(component, subComponent string) (*rbacv1.Role, error) {
 	if err := validateComponent(component); err != nil {
 		return nil, err
 	}
@@ -102,7 +122,7 @@ func Role(component, subComponent string) (*rbacv1.Role, error) {
 		return nil, err
 	}
 
-	obj, err := loadObject(filepath.Join("yaml", component, subComponent, "role.yaml"))
+	obj, err := s.loadObject(filepath.Join(s.dir(), component, subComponent, "role.yaml"))
 	if err != nil {
 		return nil, err
 	}
@@ -115,6 +135,10 @@ func Role(component, subComponent string) (*rbacv1.Role, error) {
 }
 
 func RoleBinding(component, subComponent string) (*rbacv1.RoleBinding, error) {
+	return Source{}.RoleBinding(component, subComponent)
+}
+
+func (s Source) RoleBinding(component, subComponent string) (*rbacv1.RoleBinding, error) {
 	if err := validateComponent(component); err != nil {
 		return nil, err
 	}
@@ -122,7 +146,7 @@ func RoleBinding(component, subComponent string) (*rbacv1.RoleBinding, error) {
 		return nil, err
 	}
 
-	obj, err := loadObject(filepath.Join("yaml", component, subComponent, "rolebinding.yaml"))
+	obj, err := s.loadObject(filepath.Join(s.dir(), component, subComponent, "rolebinding.yaml"))
 	if err != nil {
 		return nil, err
 	}
@@ -135,6 +159,10 @@ func RoleBinding(component, subComponent string) (*rbacv1.RoleBinding, error) {
 }
 
 func ClusterRole(component, subComponent string) (*rbacv1.ClusterRole, error) {
+	return Source{}.ClusterRole(component, subComponent)
+}
+
+func (s Source) ClusterRole(component, subComponent string) (*rbacv1.ClusterRole, error) {
 	if err := validateComponent(component); err != nil {
 		return nil, err
 	}
@@ -142,7 +170,7 @@ func ClusterRole(component, subComponent string) (*rbacv1.ClusterRole, error) {
 		return nil, err
 	}
 
-	obj, err := loadObject(filepath.Join("yaml", component, subComponent, "clusterrole.yaml"))
+	obj, err := s.loadObject(filepath.Join(s.dir(), component, subComponent, "clusterrole.yaml"))
 	if err != nil {
 		return nil, err
 	}
@@ -155,6 +183,10 @@ func ClusterRole(component, subComponent string) (*rbacv1.ClusterRole, error) {
 }
 
 func ClusterRoleBinding(component, subComponent string) (*rbacv1.ClusterRoleBinding, error) {
+	return Source{}.ClusterRoleBinding(component, subComponent)
+}
+
+func (s Source) ClusterRoleBinding(component, subComponent string) (*rbacv1.ClusterRoleBinding, error) {
 	if err := validateComponent(component); err != nil {
 		return nil, err
 	}
@@ -162,7 +194,7 @@ func ClusterRoleBinding(component, subComponent string) (*rbacv1.ClusterRoleBind
 		return nil, err
 	}
 
-	obj, err := loadObject(filepath.Join("yaml", component, subComponent, "clusterrolebinding.yaml"))
+	obj, err := s.loadObject(filepath.Join(s.dir(), component, subComponent, "clusterrolebinding.yaml"))
 	if err != nil {
 		return nil, err
 	}
@@ -175,7 +207,11 @@ func ClusterRoleBinding(component, subComponent string) (*rbacv1.ClusterRoleBind
 }
 
 func APICRD() (*apiextensionv1.CustomResourceDefinition, error) {
-	obj, err := loadObject("yaml/api/crd.yaml")
+	return Source{}.APICRD()
+}
+
+func (s Source) APICRD() (*apiextensionv1.CustomResourceDefinition, error) {
+	obj, err := s.loadObject(filepath.Join(s.dir(), "api", "crd.yaml"))
 	if err != nil {
 		return nil, err
 	}
@@ -188,7 +224,11 @@ func APICRD() (*apiextensionv1.CustomResourceDefinition, error) {
 }
 
 func SchedulerCRD() (*apiextensionv1.CustomResourceDefinition, error) {
-	obj, err := loadObject("yaml/sched/podgroup.crd.yaml")
+	return Source{}.SchedulerCRD()
+}
+
+func (s Source) SchedulerCRD() (*apiextensionv1.CustomResourceDefinition, error) {
+	obj, err := s.loadObject(filepath.Join(s.dir(), "sched", "podgroup.crd.yaml"))
 	if err != nil {
 		return nil, err
 	}
@@ -201,13 +241,17 @@ func SchedulerCRD() (*apiextensionv1.CustomResourceDefinition, error) {
 }
 
 func ConfigMap(component, subComponent string) (*corev1.ConfigMap, error) {
+	return Source{}.ConfigMap(component, subComponent)
+}
+
+func (s Source) ConfigMap(component, subComponent string) (*corev1.ConfigMap, error) {
 	if err := validateComponent(component); err != nil {
 		return nil, err
 	}
 	if err := validateSubComponent(component, subComponent); err != nil {
 		return nil, err
 	}
-	obj, err := loadObject(filepath.Join("yaml", component, subComponent, "configmap.yaml"))
+	obj, err := s.loadObject(filepath.Join(s.dir(), component, subComponent, "configmap.yaml"))
 	if err != nil {
 		return nil, err
 	}
@@ -220,13 +264,17 @@ func ConfigMap(component, subComponent string) (*corev1.ConfigMap, error) {
 }
 
 func Deployment(component, subComponent string) (*appsv1.Deployment, error) {
+	return Source{}.Deployment(component, subComponent)
+}
+
+func (s Source) Deployment(component, subComponent string) (*appsv1.Deployment, error) {
 	if err := validateComponent(component); err != nil {
 		return nil, err
 	}
 	if err := validateSubComponent(component, subComponent); err != nil {
 		return nil, err
 	}
-	obj, err := loadObject(filepath.Join("yaml", "sched", subComponent, "deployment.yaml"))
+	obj, err := s.loadObject(filepath.Join(s.dir(), "sched", subComponent, "deployment.yaml"))
 	if err != nil {
 		return nil, err
 	}
@@ -239,10 +287,14 @@ func Deployment(component, subComponent string) (*appsv1.Deployment, error) {
 }
 
 func DaemonSet(component string) (*appsv1.DaemonSet, error) {
+	return Source{}.DaemonSet(component)
+}
+
+func (s Source) DaemonSet(component string) (*appsv1.DaemonSet, error) {
 	if err := validateComponent(component); err != nil {
 		return nil, err
 	}
-	obj, err := loadObject(filepath.Join("yaml", component, "daemonset.yaml"))
+	obj, err := s.loadObject(filepath.Join(s.dir(), component, "daemonset.yaml"))
 	if err != nil {
 		return nil, err
 	}
@@ -254,6 +306,28 @@ func DaemonSet(component string) (*appsv1.DaemonSet, error) {
 	return ds, nil
 }
 
+// SecurityContextConstraints returns the OpenShift SCC granting the component pods the host access they need.
+func SecurityContextConstraints(component string) (*securityv1.SecurityContextConstraints, error) {
+	return Source{}.SecurityContextConstraints(component)
+}
+
+// SecurityContextConstraints returns the OpenShift SCC granting the component pods the host access they need.
+func (s Source) SecurityContextConstraints(component string) (*securityv1.SecurityContextConstraints, error) {
+	if err := validateComponent(component); err != nil {
+		return nil, err
+	}
+	obj, err := s.loadObject(filepath.Join(s.dir(), component, "securitycontextconstraints.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	scc, ok := obj.(*securityv1.SecurityContextConstraints)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type, got %t", obj)
+	}
+	return scc, nil
+}
+
 func KubeSchedulerConfigurationFromData(data []byte) (*kubeschedulerconfigv1beta1.KubeSchedulerConfiguration, error) {
 	obj, err := deserializeObjectFromData(data)
 	if err != nil {
@@ -295,11 +369,172 @@ func NodeResourceTopologyMatchArgsToData(ma *apiconfig.NodeResourceTopologyMatch
 	return json.Marshal(cfg)
 }
 
+// DecodeObjects decodes all the objects found in the given stream,
+// which may contain multiple YAML documents.
+func DecodeObjects(r io.Reader) ([]client.Object, error) {
+	var objs []client.Object
+	rd := k8syaml.NewYAMLReader(bufio.NewReader(r))
+	for {
+		data, err := rd.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+		obj, err := deserializeObjectFromData(data)
+		if err != nil {
+			return nil, err
+		}
+		cObj, ok := obj.(client.Object)
+		if !ok {
+			return nil, fmt.Errorf("unexpected type, got %T", obj)
+		}
+		objs = append(objs, cObj)
+	}
+	return objs, nil
+}
+
+// ObjectName returns the object identity in the same "kind.group/name"
+// format used by `kubectl -o name`.
+func ObjectName(obj client.Object) string {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	kind := strings.ToLower(gvk.Kind)
+	if gvk.Group != "" {
+		kind += "." + gvk.Group
+	}
+	return kind + "/" + obj.GetName()
+}
+
+// ValidateUniqueObjects checks no two objects share the same identity (group, kind, namespace and name),
+// because creating or applying them in sequence would silently overwrite the earlier ones.
+// The objects must have their TypeMeta set, see EnsureTypeMeta.
+func ValidateUniqueObjects(objs []client.Object) error {
+	type identity struct {
+		groupKind string
+		namespace string
+		name      string
+	}
+	seen := make(map[identity]int)
+	var dups []string
+	for idx, obj := range objs {
+		id := identity{
+			groupKind: obj.GetObjectKind().GroupVersionKind().GroupKind().String(),
+			namespace: obj.GetNamespace(),
+			name:      obj.GetName(),
+		}
+		first, ok := seen[id]
+		if !ok {
+			seen[id] = idx
+			continue
+		}
+		desc := ObjectName(obj)
+		if id.namespace != "" {
+			desc += " in namespace " + id.namespace
+		}
+		dups = append(dups, fmt.Sprintf("%s (objects #%d and #%d)", desc, first, idx))
+	}
+	if len(dups) > 0 {
+		return fmt.Errorf("duplicate objects: %s", strings.Join(dups, ", "))
+	}
+	return nil
+}
+
+// KindOrder is the canonical order of the kinds, used to emit and to create the objects:
+// each kind only depends on the ones preceding it.
+var KindOrder = []string{
+	"Namespace",
+	"CustomResourceDefinition",
+	"ServiceAccount",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"Role",
+	"RoleBinding",
+	"SecurityContextConstraints",
+	"ConfigMap",
+	"NetworkPolicy",
+	"Service",
+	"DaemonSet",
+	"Deployment",
+	"ValidatingAdmissionPolicy",
+	"ValidatingAdmissionPolicyBinding",
+	"ServiceMonitor",
+}
+
+// KindRank returns the position of the kind in KindOrder. The unknown kinds rank after all the known ones.
+func KindRank(kind string) int {
+	for idx, cur := range KindOrder {
+		if cur == kind {
+			return idx
+		}
+	}
+	return len(KindOrder)
+}
+
+// SortObjects sorts the objects by kind, in the canonical kind order, then by namespace and name,
+// so the same objects are always emitted in the same order. The unknown kinds go last, sorted by kind.
+// The objects must have their TypeMeta set, see EnsureTypeMeta.
+func SortObjects(objs []client.Object) {
+	sort.SliceStable(objs, func(i, j int) bool {
+		ki, kj := objs[i].GetObjectKind().GroupVersionKind().Kind, objs[j].GetObjectKind().GroupVersionKind().Kind
+		if ri, rj := KindRank(ki), KindRank(kj); ri != rj {
+			return ri < rj
+		}
+		if ki != kj {
+			return ki < kj
+		}
+		if objs[i].GetNamespace() != objs[j].GetNamespace() {
+			return objs[i].GetNamespace() < objs[j].GetNamespace()
+		}
+		return objs[i].GetName() < objs[j].GetName()
+	})
+}
+
 func SerializeObject(obj runtime.Object, out io.Writer) error {
+	if err := EnsureTypeMeta(obj); err != nil {
+		return err
+	}
 	srz := k8sjson.NewYAMLSerializer(k8sjson.DefaultMetaFactory, scheme.Scheme, scheme.Scheme)
 	return srz.Encode(obj, out)
 }
 
+// SerializeObjectJSON writes the object as indented JSON.
+func SerializeObjectJSON(obj runtime.Object, out io.Writer) error {
+	if err := EnsureTypeMeta(obj); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%s\n", data)
+	return err
+}
+
+// SerializeObjectsJSON writes the objects as an indented JSON array.
+func SerializeObjectsJSON(objs []client.Object, out io.Writer) error {
+	items := make([]json.RawMessage, 0, len(objs))
+	for _, obj := range objs {
+		if err := EnsureTypeMeta(obj); err != nil {
+			return err
+		}
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		items = append(items, data)
+	}
+	data, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%s\n", data)
+	return err
+}
+
 func deserializeObjectFromData(data []byte) (runtime.Object, error) {
 	decode := scheme.Codecs.UniversalDeserializer().Decode
 	obj, _, err := decode(data, nil, nil)
@@ -309,8 +544,8 @@ func deserializeObjectFromData(data []byte) (runtime.Object, error) {
 	return obj, nil
 }
 
-func loadObject(path string) (runtime.Object, error) {
-	data, err := src.ReadFile(path)
+func (s Source) loadObject(path string) (runtime.Object, error) {
+	data, err := fs.ReadFile(s.fs(), path)
 	if err != nil {
 		return nil, err
 	}
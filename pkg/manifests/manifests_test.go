@@ -17,7 +17,16 @@
 package manifests
 
 import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
 	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func TestGetNamespace(t *testing.T) {
@@ -437,3 +446,336 @@ func TestGetDaemonSet(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeObjects(t *testing.T) {
+	data := `---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: foo
+---
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: bar
+  namespace: foo
+`
+	objs, err := DecodeObjects(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(objs) != 2 {
+		t.Fatalf("expected 2 objects, got %d", len(objs))
+	}
+	if objs[0].GetName() != "foo" || objs[1].GetName() != "bar" || objs[1].GetNamespace() != "foo" {
+		t.Fatalf("unexpected objects: %v", objs)
+	}
+	if kind := objs[1].GetObjectKind().GroupVersionKind().Kind; kind != "ServiceAccount" {
+		t.Fatalf("unexpected kind: %q", kind)
+	}
+}
+
+func TestObjectName(t *testing.T) {
+	ns, err := Namespace(ComponentResourceTopologyExporter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := ObjectName(ns); got != "namespace/"+ns.Name {
+		t.Errorf("unexpected name for namespace: %q", got)
+	}
+
+	ds, err := DaemonSet(ComponentResourceTopologyExporter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := ObjectName(ds); got != "daemonset.apps/"+ds.Name {
+		t.Errorf("unexpected name for daemonset: %q", got)
+	}
+}
+
+func TestValidateUniqueObjects(t *testing.T) {
+	ns, err := Namespace(ComponentResourceTopologyExporter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ds, err := DaemonSet(ComponentResourceTopologyExporter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := ValidateUniqueObjects([]client.Object{ns, ds}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	otherDs := ds.DeepCopy()
+	otherDs.Namespace = "other"
+	if err := ValidateUniqueObjects([]client.Object{ns, ds, otherDs}); err != nil {
+		t.Errorf("unexpected error for objects in different namespaces: %v", err)
+	}
+
+	err = ValidateUniqueObjects([]client.Object{ns, ds, ns.DeepCopy(), ds.DeepCopy()})
+	if err == nil {
+		t.Fatalf("expected error, got none")
+	}
+	for _, name := range []string{ObjectName(ns), ObjectName(ds)} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("conflicting object %q not reported: %v", name, err)
+		}
+	}
+}
+
+func TestHasHostnameAntiAffinity(t *testing.T) {
+	ownLabels := map[string]string{"app": "scheduler"}
+	antiAffinity := func(topologyKey string, matchLabels map[string]string) *corev1.Affinity {
+		return &corev1.Affinity{
+			PodAntiAffinity: &corev1.PodAntiAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+					{
+						TopologyKey:   topologyKey,
+						LabelSelector: &metav1.LabelSelector{MatchLabels: matchLabels},
+					},
+				},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		affinity *corev1.Affinity
+		expected bool
+	}{
+		{
+			name: "no affinity",
+		},
+		{
+			name:     "hostname anti-affinity against itself",
+			affinity: antiAffinity(corev1.LabelHostname, ownLabels),
+			expected: true,
+		},
+		{
+			name:     "zone anti-affinity",
+			affinity: antiAffinity(corev1.LabelTopologyZone, ownLabels),
+		},
+		{
+			name:     "hostname anti-affinity against other pods",
+			affinity: antiAffinity(corev1.LabelHostname, map[string]string{"app": "other"}),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpl := corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: ownLabels},
+				Spec:       corev1.PodSpec{Affinity: tc.affinity},
+			}
+			if got := HasHostnameAntiAffinity(&tmpl); got != tc.expected {
+				t.Errorf("got %v expected %v", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestSerializeObjectsJSON(t *testing.T) {
+	ns, err := Namespace(ComponentResourceTopologyExporter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sa, err := ServiceAccount(ComponentResourceTopologyExporter, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := SerializeObjectsJSON([]client.Object{ns, sa}, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var items []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &items); err != nil {
+		t.Fatalf("output is not a JSON array: %v\n%s", err, buf.String())
+	}
+	if len(items) != 2 || items[0]["kind"] != "Namespace" || items[1]["kind"] != "ServiceAccount" {
+		t.Errorf("unexpected objects: %v", items)
+	}
+}
+
+func TestUpdateMetadata(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "test",
+			Labels: map[string]string{"app": "rte", "team": "old"},
+		},
+	}
+	UpdateMetadata(cm, map[string]string{"team": "tas", "cost-center": "42"}, map[string]string{"owner": "tas@example.com"})
+
+	expectedLabels := map[string]string{"app": "rte", "team": "tas", "cost-center": "42"}
+	if !reflect.DeepEqual(cm.Labels, expectedLabels) {
+		t.Errorf("unexpected labels: %v", cm.Labels)
+	}
+	if cm.Annotations["owner"] != "tas@example.com" {
+		t.Errorf("unexpected annotations: %v", cm.Annotations)
+	}
+}
+
+func TestValidateMetadata(t *testing.T) {
+	testCases := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		expectedErr bool
+	}{
+		{name: "empty"},
+		{name: "valid", labels: map[string]string{"example.com/team": "tas"}, annotations: map[string]string{"owner": "any value: ok"}},
+		{name: "bad label key", labels: map[string]string{"bad key": "tas"}, expectedErr: true},
+		{name: "bad label value", labels: map[string]string{"team": "not a label value"}, expectedErr: true},
+		{name: "bad annotation key", annotations: map[string]string{"-owner": "x"}, expectedErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateMetadata(tc.labels, tc.annotations)
+			if (err != nil) != tc.expectedErr {
+				t.Errorf("expected error %v got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestValidatePriorityClassName(t *testing.T) {
+	testCases := []struct {
+		name        string
+		expectedErr bool
+	}{
+		{name: ""},
+		{name: "system-node-critical"},
+		{name: "tas.high-priority"},
+		{name: "High_Priority", expectedErr: true},
+	}
+	for _, tc := range testCases {
+		err := ValidatePriorityClassName(tc.name)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("%q: expected error %v got %v", tc.name, tc.expectedErr, err)
+		}
+	}
+}
+
+func TestValidateMaxUnavailable(t *testing.T) {
+	testCases := []struct {
+		value       string
+		expectedErr bool
+	}{
+		{value: "1"},
+		{value: "10%"},
+		{value: "100%"},
+		{value: "0", expectedErr: true},
+		{value: "0%", expectedErr: true},
+		{value: "101%", expectedErr: true},
+		{value: "ten%", expectedErr: true},
+		{value: "10m", expectedErr: true},
+	}
+	for _, tc := range testCases {
+		err := ValidateMaxUnavailable(intstr.Parse(tc.value))
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("%q: expected error %v got %v", tc.value, tc.expectedErr, err)
+		}
+	}
+}
+
+func TestValidateDNSPolicy(t *testing.T) {
+	testCases := []struct {
+		policy      corev1.DNSPolicy
+		expectedErr bool
+	}{
+		{policy: ""},
+		{policy: corev1.DNSClusterFirst},
+		{policy: corev1.DNSClusterFirstWithHostNet},
+		{policy: corev1.DNSDefault},
+		{policy: corev1.DNSNone, expectedErr: true},
+		{policy: "clusterfirst", expectedErr: true},
+	}
+	for _, tc := range testCases {
+		err := ValidateDNSPolicy(tc.policy)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("%q: expected error %v got %v", tc.policy, tc.expectedErr, err)
+		}
+	}
+}
+
+func TestParseToleration(t *testing.T) {
+	testCases := []struct {
+		spec        string
+		expected    corev1.Toleration
+		expectedErr bool
+	}{
+		{
+			spec:     "example.com/numa",
+			expected: corev1.Toleration{Key: "example.com/numa", Operator: corev1.TolerationOpExists},
+		},
+		{
+			spec:     "example.com/numa:NoSchedule",
+			expected: corev1.Toleration{Key: "example.com/numa", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+		},
+		{
+			spec:     "example.com/numa=true",
+			expected: corev1.Toleration{Key: "example.com/numa", Operator: corev1.TolerationOpEqual, Value: "true"},
+		},
+		{
+			spec:     "example.com/numa=true:NoExecute",
+			expected: corev1.Toleration{Key: "example.com/numa", Operator: corev1.TolerationOpEqual, Value: "true", Effect: corev1.TaintEffectNoExecute},
+		},
+		{spec: "example.com/numa:Never", expectedErr: true},
+		{spec: "bad key=true", expectedErr: true},
+		{spec: "example.com/numa=bad value", expectedErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			got, err := ParseToleration(tc.spec)
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("expected error %v got %v", tc.expectedErr, err)
+			}
+			if !tc.expectedErr && !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %+v got %+v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSortObjects(t *testing.T) {
+	newObj := func(kind, namespace, name string) client.Object {
+		obj := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+		obj.Kind = kind
+		return obj
+	}
+	objs := []client.Object{
+		newObj("Deployment", "tas", "scheduler"),
+		newObj("Widget", "", "b"),
+		newObj("ConfigMap", "tas", "b"),
+		newObj("Gadget", "", "a"),
+		newObj("ConfigMap", "rte", "c"),
+		newObj("ServiceAccount", "tas", "a"),
+		newObj("ConfigMap", "tas", "a"),
+		newObj("Namespace", "", "tas"),
+		newObj("CustomResourceDefinition", "", "crd"),
+		newObj("Namespace", "", "rte"),
+	}
+	SortObjects(objs)
+
+	var got []string
+	for _, obj := range objs {
+		got = append(got, obj.GetObjectKind().GroupVersionKind().Kind+"/"+obj.GetNamespace()+"/"+obj.GetName())
+	}
+	expected := []string{
+		"Namespace//rte",
+		"Namespace//tas",
+		"CustomResourceDefinition//crd",
+		"ServiceAccount/tas/a",
+		"ConfigMap/rte/c",
+		"ConfigMap/tas/a",
+		"ConfigMap/tas/b",
+		"Deployment/tas/scheduler",
+		"Gadget//a",
+		"Widget//b",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected order:\ngot      %v\nexpected %v", got, expected)
+	}
+}
@@ -0,0 +1,75 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package manifests
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// APIServerPorts are the ports the pods reach the API server on: the port of the kubernetes service, and the
+// usual port of the API server endpoints, which some network plugins match after the service translation.
+var APIServerPorts = []int32{443, 6443}
+
+// NetworkPolicy returns a NetworkPolicy letting the selected pods of the namespace reach the API server, denying
+// them any other outgoing traffic. If ingressPorts are given, the pods are reachable on them, and only on them.
+// The API server addresses are not known beforehand, so the egress is allowed towards any address on APIServerPorts.
+func NetworkPolicy(name, namespace string, podSelector metav1.LabelSelector, ingressPorts []int32) *networkingv1.NetworkPolicy {
+	np := &networkingv1.NetworkPolicy{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: networkingv1.SchemeGroupVersion.String(),
+			Kind:       "NetworkPolicy",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: podSelector,
+			Egress: []networkingv1.NetworkPolicyEgressRule{
+				{
+					Ports: networkPolicyPorts(APIServerPorts),
+				},
+			},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+		},
+	}
+	if len(ingressPorts) > 0 {
+		np.Spec.Ingress = []networkingv1.NetworkPolicyIngressRule{
+			{
+				Ports: networkPolicyPorts(ingressPorts),
+			},
+		}
+		np.Spec.PolicyTypes = append(np.Spec.PolicyTypes, networkingv1.PolicyTypeIngress)
+	}
+	return np
+}
+
+func networkPolicyPorts(ports []int32) []networkingv1.NetworkPolicyPort {
+	ret := make([]networkingv1.NetworkPolicyPort, 0, len(ports))
+	for _, port := range ports {
+		protocol := corev1.ProtocolTCP
+		portVal := intstr.FromInt(int(port))
+		ret = append(ret, networkingv1.NetworkPolicyPort{
+			Protocol: &protocol,
+			Port:     &portVal,
+		})
+	}
+	return ret
+}
@@ -0,0 +1,162 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package rte
+
+import (
+	"fmt"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/wait"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/tlog"
+)
+
+const (
+	// CanarySuffix is appended to the names of the canary objects, and to the values of their pod labels.
+	CanarySuffix = "canary"
+)
+
+// CanaryManifests are the objects of a canary RTE, running a new configuration on a subset of the nodes
+// alongside the stable RTE. The canary reuses the stable RBAC objects.
+type CanaryManifests struct {
+	ConfigMap *corev1.ConfigMap
+	DaemonSet *appsv1.DaemonSet
+}
+
+// Canary returns the canary objects for the updated manifests, which should carry the configuration
+// to try out. The canary runs only on the nodes matching nodeSelector, which must be validated using
+// ValidateCanaryNodeSelector. Its pod labels never match the stable DaemonSet selector, so the two
+// DaemonSets never compete for the same pods. The stable DaemonSet must be kept off the canary nodes,
+// see ExcludeCanaryNodes.
+func (mf Manifests) Canary(nodeSelector map[string]string) (CanaryManifests, error) {
+	ds := mf.DaemonSet.DeepCopy()
+	if ds.Spec.Selector == nil || len(ds.Spec.Selector.MatchLabels) == 0 || len(ds.Spec.Selector.MatchExpressions) > 0 {
+		return CanaryManifests{}, fmt.Errorf("daemonset %q selector cannot be isolated from the canary", mf.DaemonSet.Name)
+	}
+	isolateDaemonSet(ds, CanarySuffix)
+
+	podSpec := &ds.Spec.Template.Spec
+	if podSpec.NodeSelector == nil {
+		podSpec.NodeSelector = make(map[string]string)
+	}
+	for key, val := range nodeSelector {
+		podSpec.NodeSelector[key] = val
+	}
+
+	// the canary configmap name depends only on the stable one, so the canary can be removed without knowing its configuration
+	cm := createConfigMap(ds.Namespace, manifests.NameWithSuffix(mf.ConfigMapName(), CanarySuffix), "")
+	if mf.ConfigMap != nil {
+		cm.Data = mf.ConfigMap.Data
+	}
+	for idx := range podSpec.Volumes {
+		vol := &podSpec.Volumes[idx]
+		if vol.Name == manifests.RTEConfigVolumeName && vol.ConfigMap != nil {
+			vol.ConfigMap.Name = cm.Name
+		}
+	}
+	return CanaryManifests{
+		ConfigMap: cm,
+		DaemonSet: ds,
+	}, nil
+}
+
+// ValidateCanaryNodeSelector checks the node selector is suitable to select the canary nodes.
+func ValidateCanaryNodeSelector(nodeSelector map[string]string) error {
+	if len(nodeSelector) == 0 {
+		return fmt.Errorf("missing canary node selector")
+	}
+	return validateSelector("canary node selector", nodeSelector)
+}
+
+// ExcludeCanaryNodes keeps the DaemonSet off the canary nodes, see ExcludeNodes.
+func ExcludeCanaryNodes(ds *appsv1.DaemonSet, nodeSelector map[string]string) {
+	ExcludeNodes(ds, nodeSelector)
+}
+
+// ExcludeNodes keeps the DaemonSet off the nodes matching all the labels in nodeSelector,
+// preserving its existing node affinity.
+func ExcludeNodes(ds *appsv1.DaemonSet, nodeSelector map[string]string) {
+	keys := make([]string, 0, len(nodeSelector))
+	for key := range nodeSelector {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	podSpec := &ds.Spec.Template.Spec
+	if podSpec.Affinity == nil {
+		podSpec.Affinity = &corev1.Affinity{}
+	}
+	if podSpec.Affinity.NodeAffinity == nil {
+		podSpec.Affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	nodeAff := podSpec.Affinity.NodeAffinity
+	if nodeAff.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		nodeAff.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{}
+	}
+	terms := nodeAff.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(terms) == 0 {
+		terms = []corev1.NodeSelectorTerm{{}}
+	}
+
+	// the terms are ORed, the expressions within a term are ANDed: a node is excluded only if
+	// it fails "key NotIn value" for every key, in every original term.
+	var excludingTerms []corev1.NodeSelectorTerm
+	for _, term := range terms {
+		for _, key := range keys {
+			excl := *term.DeepCopy()
+			excl.MatchExpressions = append(excl.MatchExpressions, corev1.NodeSelectorRequirement{
+				Key:      key,
+				Operator: corev1.NodeSelectorOpNotIn,
+				Values:   []string{nodeSelector[key]},
+			})
+			excludingTerms = append(excludingTerms, excl)
+		}
+	}
+	nodeAff.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms = excludingTerms
+}
+
+func (cm CanaryManifests) ToObjects() []client.Object {
+	return []client.Object{
+		cm.ConfigMap,
+		cm.DaemonSet,
+	}
+}
+
+func (cm CanaryManifests) ToCreatableObjects(hp *deployer.Helper, log tlog.Logger) []deployer.WaitableObject {
+	return []deployer.WaitableObject{
+		{Obj: cm.ConfigMap},
+		{
+			Obj:  cm.DaemonSet,
+			Wait: func() error { return wait.DaemonSetToBeRunning(hp, log, cm.DaemonSet.Namespace, cm.DaemonSet.Name) },
+		},
+	}
+}
+
+func (cm CanaryManifests) ToDeletableObjects(hp *deployer.Helper, log tlog.Logger) []deployer.WaitableObject {
+	return []deployer.WaitableObject{
+		{
+			Obj:  cm.DaemonSet,
+			Wait: func() error { return wait.DaemonSetToBeGone(hp, log, cm.DaemonSet.Namespace, cm.DaemonSet.Name) },
+		},
+		{Obj: cm.ConfigMap},
+	}
+}
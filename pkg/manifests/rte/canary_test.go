@@ -0,0 +1,183 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package rte
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests"
+)
+
+func TestCanary(t *testing.T) {
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mf = mf.Update(UpdateOptions{
+		ConfigData: "resources:\n  reservedcpus: \"0\"\n",
+	})
+
+	nodeSelector := map[string]string{"rte-canary": "true"}
+	canary, err := mf.Canary(nodeSelector)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if canary.DaemonSet.Name == mf.DaemonSet.Name || canary.ConfigMap.Name == mf.ConfigMap.Name {
+		t.Errorf("canary objects share the stable names: %q %q", canary.DaemonSet.Name, canary.ConfigMap.Name)
+	}
+	if canary.ConfigMap.Data["config.yaml"] != mf.ConfigMap.Data["config.yaml"] {
+		t.Errorf("unexpected canary configuration: %v", canary.ConfigMap.Data)
+	}
+
+	stableSel := labels.SelectorFromSet(mf.DaemonSet.Spec.Selector.MatchLabels)
+	if stableSel.Matches(labels.Set(canary.DaemonSet.Spec.Template.Labels)) {
+		t.Errorf("stable selector %v matches the canary pods %v", stableSel, canary.DaemonSet.Spec.Template.Labels)
+	}
+	canarySel := labels.SelectorFromSet(canary.DaemonSet.Spec.Selector.MatchLabels)
+	if !canarySel.Matches(labels.Set(canary.DaemonSet.Spec.Template.Labels)) {
+		t.Errorf("canary selector %v does not match the canary pods %v", canarySel, canary.DaemonSet.Spec.Template.Labels)
+	}
+	if canary.DaemonSet.Spec.Template.Spec.NodeSelector["rte-canary"] != "true" {
+		t.Errorf("canary not restricted to the canary nodes: %v", canary.DaemonSet.Spec.Template.Spec.NodeSelector)
+	}
+
+	for _, vol := range canary.DaemonSet.Spec.Template.Spec.Volumes {
+		if vol.Name == manifests.RTEConfigVolumeName && vol.ConfigMap.Name != canary.ConfigMap.Name {
+			t.Errorf("canary consumes the configmap %q expected %q", vol.ConfigMap.Name, canary.ConfigMap.Name)
+		}
+	}
+	if mf.DaemonSet.Spec.Template.Labels["name"] == canary.DaemonSet.Spec.Template.Labels["name"] {
+		t.Errorf("stable manifests modified: %v", mf.DaemonSet.Spec.Template.Labels)
+	}
+}
+
+func TestValidateCanaryNodeSelector(t *testing.T) {
+	testCases := []struct {
+		name          string
+		nodeSelector  map[string]string
+		expectedError bool
+	}{
+		{
+			name:          "empty",
+			expectedError: true,
+		},
+		{
+			name:         "valid",
+			nodeSelector: map[string]string{"example.com/rte-canary": "true"},
+		},
+		{
+			name:          "invalid key",
+			nodeSelector:  map[string]string{"-canary": "true"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid value",
+			nodeSelector:  map[string]string{"rte-canary": "not valid"},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateCanaryNodeSelector(tc.nodeSelector)
+			if tc.expectedError && err == nil {
+				t.Errorf("expected error, got none")
+			}
+			if !tc.expectedError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestExcludeCanaryNodes(t *testing.T) {
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ds := mf.DaemonSet.DeepCopy()
+	ds.Spec.Template.Spec.Affinity = &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{
+						MatchExpressions: []corev1.NodeSelectorRequirement{
+							{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a"}},
+						},
+					},
+				},
+			},
+		},
+	}
+	ExcludeCanaryNodes(ds, map[string]string{"rte-canary": "true", "pool": "blue"})
+
+	terms := ds.Spec.Template.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	testCases := []struct {
+		name      string
+		labels    map[string]string
+		scheduled bool
+	}{
+		{
+			name:      "stable node",
+			labels:    map[string]string{"zone": "a"},
+			scheduled: true,
+		},
+		{
+			name:      "partially matching node",
+			labels:    map[string]string{"zone": "a", "rte-canary": "true"},
+			scheduled: true,
+		},
+		{
+			name:   "canary node",
+			labels: map[string]string{"zone": "a", "rte-canary": "true", "pool": "blue"},
+		},
+		{
+			name:   "node excluded by the original affinity",
+			labels: map[string]string{"zone": "b"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := matchesTerms(terms, tc.labels); got != tc.scheduled {
+				t.Errorf("node %v scheduled=%v expected %v", tc.labels, got, tc.scheduled)
+			}
+		})
+	}
+}
+
+func matchesTerms(terms []corev1.NodeSelectorTerm, nodeLabels map[string]string) bool {
+	for _, term := range terms {
+		matched := true
+		for _, expr := range term.MatchExpressions {
+			val, ok := nodeLabels[expr.Key]
+			in := ok && val == expr.Values[0]
+			if (expr.Operator == corev1.NodeSelectorOpIn && !in) || (expr.Operator == corev1.NodeSelectorOpNotIn && in) {
+				matched = false
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package rte
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// config mirrors the configuration file consumed by the RTE. Keys are matched case-insensitively,
+// like the RTE does.
+type config struct {
+	Resources             resourcesConfig     `json:"resources,omitempty"`
+	ExcludeList           map[string][]string `json:"excludelist,omitempty"`
+	PodExcludes           map[string]string   `json:"podexcludes,omitempty"`
+	TopologyManagerPolicy string              `json:"topologymanagerpolicy,omitempty"`
+	TopologyManagerScope  string              `json:"topologymanagerscope,omitempty"`
+}
+
+type resourcesConfig struct {
+	ReservedCPUs    string            `json:"reservedcpus,omitempty"`
+	ResourceMapping map[string]string `json:"resourcemapping,omitempty"`
+}
+
+// ValidateConfigData checks the configuration data can be parsed as the RTE configuration,
+// rejecting the unknown keys, which the RTE would otherwise silently ignore.
+func ValidateConfigData(configData string) error {
+	if strings.TrimSpace(configData) == "" {
+		return fmt.Errorf("empty configuration")
+	}
+	data, err := k8syaml.ToJSON([]byte(configData))
+	if err != nil {
+		return fmt.Errorf("malformed configuration: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var cfg config
+	if err := dec.Decode(&cfg); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package rte
+
+import (
+	"testing"
+)
+
+func TestValidateConfigData(t *testing.T) {
+	testCases := []struct {
+		name        string
+		data        string
+		expectedErr bool
+	}{
+		{name: "valid", data: "resources:\n  reservedcpus: \"0\"\nexcludelist:\n  \"*\": [sample.com/exampledevice]\n"},
+		{name: "valid camel case", data: "resources:\n  reservedCpus: \"0\"\ntopologyManagerPolicy: single-numa-node\n"},
+		{name: "unknown key", data: "exludelist:\n  \"*\": [sample.com/exampledevice]\n", expectedErr: true},
+		{name: "unknown nested key", data: "resources:\n  reservedcpu: \"0\"\n", expectedErr: true},
+		{name: "wrong type", data: "excludelist: foo\n", expectedErr: true},
+		{name: "empty", data: "  \n", expectedErr: true},
+		{name: "malformed", data: "foo: [bar\n", expectedErr: true},
+		{name: "not a mapping", data: "- foo\n- bar\n", expectedErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateConfigData(tc.data)
+			if (err != nil) != tc.expectedErr {
+				t.Errorf("expected error %v got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
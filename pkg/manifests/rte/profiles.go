@@ -0,0 +1,119 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package rte
+
+import (
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests"
+)
+
+// ConfigProfile is a RTE configuration for the nodes matching NodeSelector, e.g. a node pool.
+// Each profile is served by its own ConfigMap and DaemonSet, named after the profile.
+type ConfigProfile struct {
+	Name         string
+	NodeSelector map[string]string
+	ConfigData   string
+}
+
+// ProfileManifests are the objects serving a ConfigProfile. The profiles reuse the RBAC objects of the main RTE.
+type ProfileManifests struct {
+	Name      string
+	ConfigMap *corev1.ConfigMap
+	DaemonSet *appsv1.DaemonSet
+}
+
+func (pm ProfileManifests) Clone() ProfileManifests {
+	return ProfileManifests{
+		Name:      pm.Name,
+		ConfigMap: pm.ConfigMap.DeepCopy(),
+		DaemonSet: pm.DaemonSet.DeepCopy(),
+	}
+}
+
+// ValidateConfigProfiles checks the profiles can be rendered: the names must be unique, short DNS labels,
+// and each profile must select its nodes and carry a valid configuration. The profiles should select
+// disjoint sets of nodes, which cannot be verified without the nodes.
+func ValidateConfigProfiles(profiles []ConfigProfile) error {
+	names := make(map[string]bool)
+	for _, prof := range profiles {
+		if errs := validation.IsDNS1123Label(prof.Name); len(errs) > 0 {
+			return fmt.Errorf("invalid config profile name %q: %s", prof.Name, strings.Join(errs, "; "))
+		}
+		// the name suffixes the names of the profile objects
+		if len(prof.Name) > manifests.MaxSuffixLength {
+			return fmt.Errorf("invalid config profile name %q: must be no more than %d characters", prof.Name, manifests.MaxSuffixLength)
+		}
+		if names[prof.Name] {
+			return fmt.Errorf("duplicate config profile %q", prof.Name)
+		}
+		names[prof.Name] = true
+		if len(prof.NodeSelector) == 0 {
+			return fmt.Errorf("config profile %q: missing node selector", prof.Name)
+		}
+		if err := validateSelector("config profile node selector", prof.NodeSelector); err != nil {
+			return fmt.Errorf("config profile %q: %w", prof.Name, err)
+		}
+		if err := ValidateConfigData(prof.ConfigData); err != nil {
+			return fmt.Errorf("config profile %q: %w", prof.Name, err)
+		}
+	}
+	return nil
+}
+
+// updateProfile renders the objects of the profile from the manifests before the update,
+// applying the same options of the main RTE.
+func (mf Manifests) updateProfile(options UpdateOptions, prof ConfigProfile) ProfileManifests {
+	profOpts := options
+	profOpts.ConfigProfiles = nil
+	profOpts.ConfigData = prof.ConfigData
+	baseName := options.ConfigMapName
+	if baseName == "" {
+		baseName = mf.ConfigMapName()
+	}
+	profOpts.ConfigMapName = manifests.NameWithSuffix(baseName, prof.Name)
+	profOpts.NodeSelector = make(map[string]string, len(options.NodeSelector)+len(prof.NodeSelector))
+	for key, val := range options.NodeSelector {
+		profOpts.NodeSelector[key] = val
+	}
+	for key, val := range prof.NodeSelector {
+		profOpts.NodeSelector[key] = val
+	}
+	profMf := mf.Update(profOpts)
+	isolateDaemonSet(profMf.DaemonSet, prof.Name)
+	return ProfileManifests{
+		Name:      prof.Name,
+		ConfigMap: profMf.ConfigMap,
+		DaemonSet: profMf.DaemonSet,
+	}
+}
+
+// isolateDaemonSet renames the DaemonSet adding the suffix, and makes its pod labels never match
+// the ones of the original DaemonSet, so the two DaemonSets never compete for the same pods.
+func isolateDaemonSet(ds *appsv1.DaemonSet, suffix string) {
+	ds.Name = manifests.NameWithSuffix(ds.Name, suffix)
+	for key, val := range ds.Spec.Selector.MatchLabels {
+		newVal := manifests.TruncateName(val+"-"+suffix, validation.LabelValueMaxLength)
+		ds.Spec.Selector.MatchLabels[key] = newVal
+		ds.Spec.Template.Labels[key] = newVal
+	}
+}
@@ -0,0 +1,107 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package rte
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests"
+)
+
+func TestUpdateConfigProfiles(t *testing.T) {
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	profiles := []ConfigProfile{
+		{Name: "gpu", NodeSelector: map[string]string{"pool": "gpu"}, ConfigData: "resources:\n  reservedcpus: \"0-1\"\n"},
+		{Name: "cpu", NodeSelector: map[string]string{"pool": "cpu"}, ConfigData: "resources:\n  reservedcpus: \"0\"\n"},
+	}
+	ret := mf.Update(UpdateOptions{
+		ConfigData:     "resources:\n  reservedcpus: \"0\"\n",
+		ConfigProfiles: profiles,
+	})
+
+	if len(ret.Profiles) != len(profiles) {
+		t.Fatalf("expected %d profiles, got %d", len(profiles), len(ret.Profiles))
+	}
+	base := mf.Update(UpdateOptions{ConfigData: "resources:\n  reservedcpus: \"0\"\n"})
+	if got, expected := len(ret.ToObjects()), len(base.ToObjects())+2*len(profiles); got != expected {
+		t.Errorf("expected %d objects, got %d", expected, got)
+	}
+	mainSel := labels.SelectorFromSet(ret.DaemonSet.Spec.Selector.MatchLabels)
+	names := map[string]bool{ret.DaemonSet.Name: true, ret.ConfigMap.Name: true}
+	for idx, prof := range ret.Profiles {
+		ds := prof.DaemonSet
+		if names[ds.Name] || names[prof.ConfigMap.Name] {
+			t.Errorf("profile %q objects reuse the names %q %q", prof.Name, ds.Name, prof.ConfigMap.Name)
+		}
+		names[ds.Name], names[prof.ConfigMap.Name] = true, true
+
+		if prof.ConfigMap.Data["config.yaml"] != profiles[idx].ConfigData {
+			t.Errorf("profile %q unexpected configuration: %v", prof.Name, prof.ConfigMap.Data)
+		}
+		if mainSel.Matches(labels.Set(ds.Spec.Template.Labels)) {
+			t.Errorf("main selector %v matches the profile %q pods", mainSel, prof.Name)
+		}
+		if ds.Spec.Template.Spec.NodeSelector["pool"] != profiles[idx].NodeSelector["pool"] {
+			t.Errorf("profile %q not restricted to its nodes: %v", prof.Name, ds.Spec.Template.Spec.NodeSelector)
+		}
+		for _, vol := range ds.Spec.Template.Spec.Volumes {
+			if vol.Name == manifests.RTEConfigVolumeName && vol.ConfigMap.Name != prof.ConfigMap.Name {
+				t.Errorf("profile %q consumes the configmap %q", prof.Name, vol.ConfigMap.Name)
+			}
+		}
+	}
+	terms := ret.DaemonSet.Spec.Template.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(terms) != 1 || len(terms[0].MatchExpressions) != 2 {
+		t.Errorf("main daemonset not kept off the profile nodes: %v", terms)
+	}
+	if len(mf.Profiles) > 0 || mf.DaemonSet.Spec.Template.Spec.Affinity != nil {
+		t.Errorf("update modified the original manifests")
+	}
+}
+
+func TestValidateConfigProfiles(t *testing.T) {
+	cfg := "resources:\n  reservedcpus: \"0\"\n"
+	sel := map[string]string{"pool": "gpu"}
+	testCases := []struct {
+		name        string
+		profiles    []ConfigProfile
+		expectedErr bool
+	}{
+		{name: "none"},
+		{name: "valid", profiles: []ConfigProfile{{Name: "gpu", NodeSelector: sel, ConfigData: cfg}}},
+		{name: "long name", profiles: []ConfigProfile{{Name: strings.Repeat("g", manifests.MaxSuffixLength+1), NodeSelector: sel, ConfigData: cfg}}, expectedErr: true},
+		{name: "bad name", profiles: []ConfigProfile{{Name: "GPU", NodeSelector: sel, ConfigData: cfg}}, expectedErr: true},
+		{name: "duplicate", profiles: []ConfigProfile{{Name: "gpu", NodeSelector: sel, ConfigData: cfg}, {Name: "gpu", NodeSelector: sel, ConfigData: cfg}}, expectedErr: true},
+		{name: "no selector", profiles: []ConfigProfile{{Name: "gpu", ConfigData: cfg}}, expectedErr: true},
+		{name: "no config", profiles: []ConfigProfile{{Name: "gpu", NodeSelector: sel}}, expectedErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateConfigProfiles(tc.profiles)
+			if (err != nil) != tc.expectedErr {
+				t.Errorf("expected error %v got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
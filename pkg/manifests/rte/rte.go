@@ -17,10 +17,20 @@
 package rte
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
+	securityv1 "github.com/openshift/api/security/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -34,6 +44,19 @@ import (
 const (
 	NamespaceOpenShift      = "openshift-monitoring"
 	ServiceAccountOpenShift = "node-exporter"
+	DefaultConfigMapName    = "rte-config"
+	// NetworkPolicyName is the name of the network policy of the RTE pods.
+	NetworkPolicyName = "resource-topology-exporter"
+	// MetricsPort is the default port the RTE serves its prometheus metrics on.
+	MetricsPort int32 = 2112
+	// ServiceMonitorName is the name of the ServiceMonitor scraping the RTE metrics, and of its Service.
+	ServiceMonitorName = "resource-topology-exporter"
+	// LabelComponent marks the RTE pods of all the DaemonSets, to be selected by the metrics Service.
+	LabelComponent = "app.kubernetes.io/component"
+	ComponentName  = "resource-topology-exporter"
+	// ConfigComponentName marks all the RTE ConfigMaps, including the content-addressed ones
+	// created by the config reloads, so they can be found and removed.
+	ConfigComponentName = "resource-topology-exporter-config"
 )
 
 type Manifests struct {
@@ -42,30 +65,116 @@ type Manifests struct {
 	RoleBinding    *rbacv1.RoleBinding
 	ConfigMap      *corev1.ConfigMap
 	DaemonSet      *appsv1.DaemonSet
+	// SecurityContextConstraints lets the RTE pods access the host. OpenShift only.
+	SecurityContextConstraints *securityv1.SecurityContextConstraints
+	// NetworkPolicy, optional, lets the RTE pods reach the API server and be scraped for metrics in locked-down clusters.
+	NetworkPolicy *networkingv1.NetworkPolicy
+	// MetricsService and ServiceMonitor, optional, make the prometheus operator scrape the metrics of all the RTE pods.
+	// The ServiceMonitor requires the prometheus operator CRDs.
+	MetricsService *corev1.Service
+	ServiceMonitor *unstructured.Unstructured
+	// Profiles serve the ConfigProfiles, on the nodes the main DaemonSet is kept off.
+	Profiles []ProfileManifests
 	// internal fields
 	plat           platform.Platform
 	serviceAccount string
+	configMapName  string
 }
 
+// Clone returns a deep copy of the manifests, which can be changed without affecting the original ones.
 func (mf Manifests) Clone() Manifests {
 	ret := Manifests{
 		plat:           mf.plat,
 		serviceAccount: mf.serviceAccount,
+		configMapName:  mf.configMapName,
 		// objects
 		Role:        mf.Role.DeepCopy(),
 		RoleBinding: mf.RoleBinding.DeepCopy(),
 		DaemonSet:   mf.DaemonSet.DeepCopy(),
+		// optional objects
+		ConfigMap:      mf.ConfigMap.DeepCopy(),
+		NetworkPolicy:  mf.NetworkPolicy.DeepCopy(),
+		MetricsService: mf.MetricsService.DeepCopy(),
+		ServiceMonitor: mf.ServiceMonitor.DeepCopy(),
 	}
 	if mf.plat == platform.Kubernetes {
 		ret.ServiceAccount = mf.ServiceAccount.DeepCopy()
 	}
+	if mf.plat == platform.OpenShift {
+		ret.SecurityContextConstraints = mf.SecurityContextConstraints.DeepCopy()
+	}
+	for _, prof := range mf.Profiles {
+		ret.Profiles = append(ret.Profiles, prof.Clone())
+	}
 	return ret
 }
 
 type UpdateOptions struct {
-	ConfigData       string
+	ConfigData string
+	// ImmutableConfig makes the ConfigMap immutable, naming it after its content hash.
+	ImmutableConfig  bool
 	PullIfNotPresent bool
 	Namespace        string
+	// ConfigMapName is the name of the ConfigMap carrying ConfigData, DefaultConfigMapName if empty.
+	// Must be validated using ValidateConfigMapName.
+	ConfigMapName string
+	// AllNodes makes the DaemonSet tolerate the control-plane taints
+	AllNodes bool
+	// NodeSelector restricts the DaemonSet to the nodes matching all the labels, and Tolerations
+	// let it run on tainted nodes. Both are merged with the existing ones.
+	// NodeSelector must be validated using ValidateNodeSelector.
+	NodeSelector map[string]string
+	Tolerations  []corev1.Toleration
+	// StartupProbeFailureThreshold and StartupProbePeriodSeconds, if any is set, make the RTE
+	// container wait for its startup to complete before its liveness is checked.
+	StartupProbeFailureThreshold int32
+	StartupProbePeriodSeconds    int32
+	// Finalizers are added to the DaemonSet, to let external controllers cleanup before its deletion.
+	// Once set, the DaemonSet removal completes only after the external controllers clear them.
+	Finalizers []string
+	// PodSchedulerName is the scheduler of the RTE pods. Empty means the cluster default.
+	PodSchedulerName string
+	// ExtraInitContainers run before the init containers already in the DaemonSet, e.g. to prepare the host.
+	ExtraInitContainers []corev1.Container
+	// ExtraContainers run alongside the RTE containers, e.g. log shippers or exporters.
+	// ExtraVolumes are added to the pod, to be shared with the extra containers.
+	// The result must be validated using ValidateExtraContainers.
+	ExtraContainers []corev1.Container
+	ExtraVolumes    []corev1.Volume
+	// APIGroup, if not empty, is the API group of the NodeResourceTopology objects the RTE is allowed to manage.
+	// Must match the API CRD group. Must be validated using manifests.ValidateAPIGroup.
+	APIGroup string
+	// RTEResources are the requests and limits of the RTE container. Unset resources keep the manifest values.
+	RTEResources corev1.ResourceRequirements
+	// ImagePullSecrets are the secrets the pods use to pull the images from private registries.
+	// Must be validated using manifests.ValidateImagePullSecrets.
+	ImagePullSecrets []string
+	// PriorityClassName is the priority class of the pods, which must exist. Empty means the cluster default.
+	// Must be validated using manifests.ValidatePriorityClassName.
+	PriorityClassName string
+	// ConfigProfiles are served by their own ConfigMap and DaemonSet, on the nodes they select, replacing
+	// the previous ones. The other options apply to them too. Must be validated using ValidateConfigProfiles.
+	ConfigProfiles []ConfigProfile
+	// RTEVerbosity is the log level of the RTE container. Zero keeps the manifest default.
+	RTEVerbosity int
+	// RTEEnv are merged into the environment variables of the RTE container, overriding the existing ones
+	// only on name collision, so the manifest ones like NODE_NAME are kept unless explicitly replaced.
+	RTEEnv []corev1.EnvVar
+	// WithNetworkPolicy adds a NetworkPolicy letting the RTE pods, the ConfigProfiles ones included, reach the API server
+	// and be reached on MetricsPort, denying any other traffic.
+	WithNetworkPolicy bool
+	// MaxUnavailable, if set, makes the DaemonSets roll their pods out gradually, replacing at most this number
+	// or percentage of them at a time. Nil keeps the manifest update strategy.
+	// Must be validated using manifests.ValidateMaxUnavailable.
+	MaxUnavailable *intstr.IntOrString
+	// WithServiceMonitor adds a ServiceMonitor, and the Service backing it, making the prometheus operator scrape
+	// the RTE pods, the ConfigProfiles ones included, on MetricsPort. Requires the prometheus operator CRDs.
+	WithServiceMonitor bool
+	// HostNetwork, if set, makes the RTE pods use the host network or not, e.g. when the CNI prevents them to reach
+	// the kubelet. On OpenShift the SecurityContextConstraints allow it. DNSPolicy, if not empty, replaces the manifest
+	// one, usually ClusterFirstWithHostNet along with the host network. Must be validated using manifests.ValidateDNSPolicy.
+	HostNetwork *bool
+	DNSPolicy   corev1.DNSPolicy
 }
 
 func (mf Manifests) Update(options UpdateOptions) Manifests {
@@ -74,6 +183,7 @@ func (mf Manifests) Update(options UpdateOptions) Manifests {
 		if options.Namespace != "" {
 			ret.ServiceAccount.Namespace = options.Namespace
 		}
+		manifests.UpdateServiceAccountImagePullSecrets(ret.ServiceAccount, options.ImagePullSecrets)
 	}
 
 	ret.DaemonSet.Spec.Template.Spec.ServiceAccountName = mf.serviceAccount
@@ -82,24 +192,217 @@ func (mf Manifests) Update(options UpdateOptions) Manifests {
 		ret.DaemonSet.Namespace = options.Namespace
 	}
 	manifests.UpdateRoleBinding(ret.RoleBinding, mf.serviceAccount, ret.Role.Namespace)
+	if ret.SecurityContextConstraints != nil {
+		manifests.UpdateSecurityContextConstraintsUsers(ret.SecurityContextConstraints, mf.serviceAccount, ret.Role.Namespace)
+	}
+	if options.APIGroup != "" {
+		manifests.UpdatePolicyRulesAPIGroup(ret.Role.Rules, options.APIGroup)
+	}
 
+	if options.ConfigMapName != "" {
+		ret.configMapName = options.ConfigMapName
+	}
 	if len(options.ConfigData) > 0 {
-		ret.ConfigMap = createConfigMap(ret.DaemonSet.Namespace, options.ConfigData)
+		ret.ConfigMap = createConfigMap(ret.DaemonSet.Namespace, ret.ConfigMapName(), options.ConfigData)
+		if options.ImmutableConfig {
+			manifests.UpdateConfigMapImmutable(ret.ConfigMap)
+		}
 	}
 	manifests.UpdateResourceTopologyExporterDaemonSet(ret.plat, ret.DaemonSet, ret.ConfigMap, options.PullIfNotPresent)
+	manifests.UpdateDaemonSetMaxUnavailable(ret.DaemonSet, options.MaxUnavailable)
+	manifests.UpdatePodSpecNetworking(&ret.DaemonSet.Spec.Template.Spec, options.HostNetwork, options.DNSPolicy)
+	if ret.SecurityContextConstraints != nil && options.HostNetwork != nil && *options.HostNetwork {
+		// the container ports become host ports too
+		ret.SecurityContextConstraints.AllowHostNetwork = true
+		ret.SecurityContextConstraints.AllowHostPorts = true
+	}
+	if options.RTEVerbosity > 0 {
+		manifests.UpdateResourceTopologyExporterVerbosity(ret.DaemonSet, options.RTEVerbosity)
+	}
+	// TODO: better match by name than assume container#0 is RTE proper (not minion)
+	manifests.UpdateContainerEnv(&ret.DaemonSet.Spec.Template.Spec.Containers[0], options.RTEEnv)
+	if options.AllNodes {
+		manifests.UpdateDaemonSetTolerations(ret.DaemonSet, manifests.ControlPlaneTolerations())
+	}
+	manifests.UpdateDaemonSetTolerations(ret.DaemonSet, options.Tolerations)
+	manifests.UpdateDaemonSetNodeSelector(ret.DaemonSet, options.NodeSelector)
+	manifests.UpdateFinalizers(ret.DaemonSet, options.Finalizers)
+	if options.PodSchedulerName != "" {
+		ret.DaemonSet.Spec.Template.Spec.SchedulerName = options.PodSchedulerName
+	}
+	if len(options.ExtraInitContainers) > 0 {
+		podSpec := &ret.DaemonSet.Spec.Template.Spec
+		initContainers := make([]corev1.Container, 0, len(options.ExtraInitContainers)+len(podSpec.InitContainers))
+		for idx := range options.ExtraInitContainers {
+			initContainers = append(initContainers, *options.ExtraInitContainers[idx].DeepCopy())
+		}
+		podSpec.InitContainers = append(initContainers, podSpec.InitContainers...)
+	}
+	// the RTE containers must stay first, the startup probe update below depends on it
+	for idx := range options.ExtraContainers {
+		podSpec := &ret.DaemonSet.Spec.Template.Spec
+		podSpec.Containers = append(podSpec.Containers, *options.ExtraContainers[idx].DeepCopy())
+	}
+	for idx := range options.ExtraVolumes {
+		podSpec := &ret.DaemonSet.Spec.Template.Spec
+		podSpec.Volumes = append(podSpec.Volumes, *options.ExtraVolumes[idx].DeepCopy())
+	}
+	if options.StartupProbeFailureThreshold > 0 || options.StartupProbePeriodSeconds > 0 {
+		// TODO: better match by name than assume container#0 is RTE proper (not minion)
+		// without liveness probe, the RTE is started once it serves its metrics
+		manifests.UpdateContainerStartupProbe(&ret.DaemonSet.Spec.Template.Spec.Containers[0], MetricsPort, options.StartupProbeFailureThreshold, options.StartupProbePeriodSeconds)
+	}
+	manifests.UpdateContainerResources(&ret.DaemonSet.Spec.Template.Spec.Containers[0], options.RTEResources)
+	manifests.UpdatePodSpecImagePullSecrets(&ret.DaemonSet.Spec.Template.Spec, options.ImagePullSecrets)
+	if options.PriorityClassName != "" {
+		ret.DaemonSet.Spec.Template.Spec.PriorityClassName = options.PriorityClassName
+	}
+	if options.WithServiceMonitor {
+		// the profiles are updated with the same options, so their pods get the label too
+		manifests.UpdateMetadata(&ret.DaemonSet.Spec.Template, map[string]string{LabelComponent: ComponentName}, nil)
+		ret.MetricsService = manifests.MetricsService(ServiceMonitorName, ret.Role.Namespace, map[string]string{LabelComponent: ComponentName}, MetricsPort)
+		ret.ServiceMonitor = manifests.ServiceMonitor(ServiceMonitorName, ret.Role.Namespace, ret.MetricsService.Labels)
+	}
+	if len(options.ConfigProfiles) > 0 {
+		ret.Profiles = nil
+		for _, prof := range options.ConfigProfiles {
+			ret.Profiles = append(ret.Profiles, mf.updateProfile(options, prof))
+			ExcludeNodes(ret.DaemonSet, prof.NodeSelector)
+		}
+	}
+	if options.WithNetworkPolicy {
+		ret.NetworkPolicy = manifests.NetworkPolicy(NetworkPolicyName, ret.Role.Namespace, ret.podSelector(), []int32{MetricsPort})
+	}
 	return ret
 }
 
-func createConfigMap(namespace string, configData string) *corev1.ConfigMap {
+// podSelector matches the pods of all the DaemonSets, but not the other pods of the namespace, which may be shared.
+func (mf Manifests) podSelector() metav1.LabelSelector {
+	dss := []*appsv1.DaemonSet{mf.DaemonSet}
+	for _, prof := range mf.Profiles {
+		dss = append(dss, prof.DaemonSet)
+	}
+	var sel metav1.LabelSelector
+	for key := range mf.DaemonSet.Spec.Selector.MatchLabels {
+		values := sets.NewString()
+		for _, ds := range dss {
+			values.Insert(ds.Spec.Selector.MatchLabels[key])
+		}
+		sel.MatchExpressions = append(sel.MatchExpressions, metav1.LabelSelectorRequirement{
+			Key:      key,
+			Operator: metav1.LabelSelectorOpIn,
+			Values:   values.List(),
+		})
+	}
+	sort.Slice(sel.MatchExpressions, func(i, j int) bool {
+		return sel.MatchExpressions[i].Key < sel.MatchExpressions[j].Key
+	})
+	return sel
+}
+
+// ValidateAllNodes checks the DaemonSet can run on all the nodes, control-plane included:
+// the control-plane taints must be tolerated, and the node selection must not exclude them.
+func ValidateAllNodes(ds *appsv1.DaemonSet) error {
+	podSpec := &ds.Spec.Template.Spec
+	for _, tol := range manifests.ControlPlaneTolerations() {
+		tolerated := false
+		for _, cur := range podSpec.Tolerations {
+			if cur.ToleratesTaint(&corev1.Taint{Key: tol.Key, Effect: tol.Effect}) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return fmt.Errorf("daemonset %q does not tolerate the taint %q", ds.Name, tol.Key)
+		}
+	}
+
+	for key, val := range podSpec.NodeSelector {
+		if strings.HasPrefix(key, manifests.LabelNodeRolePrefix) && !isControlPlaneLabel(key) {
+			return fmt.Errorf("daemonset %q nodeSelector %s=%s excludes the control-plane nodes", ds.Name, key, val)
+		}
+	}
+
+	if podSpec.Affinity == nil || podSpec.Affinity.NodeAffinity == nil || podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return nil
+	}
+	for _, term := range podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		for _, expr := range term.MatchExpressions {
+			if !isControlPlaneLabel(expr.Key) {
+				continue
+			}
+			if expr.Operator == corev1.NodeSelectorOpDoesNotExist || expr.Operator == corev1.NodeSelectorOpNotIn {
+				return fmt.Errorf("daemonset %q node affinity on %q excludes the control-plane nodes", ds.Name, expr.Key)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateExtraContainers checks the DaemonSet pod is consistent once the extra containers and volumes are added:
+// the container and volume names must be unique, and all the volume mounts must refer to a volume of the pod.
+func ValidateExtraContainers(ds *appsv1.DaemonSet) error {
+	podSpec := &ds.Spec.Template.Spec
+	volumes := make(map[string]bool)
+	for _, vol := range podSpec.Volumes {
+		if volumes[vol.Name] {
+			return fmt.Errorf("daemonset %q has duplicate volume %q", ds.Name, vol.Name)
+		}
+		volumes[vol.Name] = true
+	}
+
+	containers := make(map[string]bool)
+	allContainers := append(append([]corev1.Container{}, podSpec.InitContainers...), podSpec.Containers...)
+	for _, cnt := range allContainers {
+		if containers[cnt.Name] {
+			return fmt.Errorf("daemonset %q has duplicate container %q", ds.Name, cnt.Name)
+		}
+		containers[cnt.Name] = true
+		for _, vm := range cnt.VolumeMounts {
+			if !volumes[vm.Name] {
+				return fmt.Errorf("daemonset %q container %q mounts the missing volume %q", ds.Name, cnt.Name, vm.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func isControlPlaneLabel(key string) bool {
+	return key == manifests.LabelNodeRoleMaster || key == manifests.LabelNodeRoleControlPlane
+}
+
+// ConfigMapName returns the name of the ConfigMap carrying the configuration, before any content-addressing.
+func (mf Manifests) ConfigMapName() string {
+	if mf.configMapName == "" {
+		return DefaultConfigMapName
+	}
+	return mf.configMapName
+}
+
+// ValidateConfigMapName checks the name is suitable for the RTE ConfigMap. Empty means the default.
+func ValidateConfigMapName(name string) error {
+	if name == "" {
+		return nil
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return fmt.Errorf("invalid configmap name %q: %s", name, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func createConfigMap(namespace, name, configData string) *corev1.ConfigMap {
 	cm := &corev1.ConfigMap{
-		// TODO: why is this needed?
+		// objects built in code don't get their TypeMeta set by the decoder
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "ConfigMap",
 			APIVersion: "v1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "rte-config",
+			Name:      name,
 			Namespace: namespace,
+			Labels: map[string]string{
+				LabelComponent: ConfigComponentName,
+			},
 		},
 		Data: map[string]string{
 			"config.yaml": configData,
@@ -116,11 +419,27 @@ func (mf Manifests) ToObjects() []client.Object {
 	if mf.ConfigMap != nil {
 		objs = append(objs, mf.ConfigMap)
 	}
-	return append(objs,
+	objs = append(objs,
 		mf.Role,
 		mf.RoleBinding,
-		mf.DaemonSet,
 	)
+	if mf.SecurityContextConstraints != nil {
+		objs = append(objs, mf.SecurityContextConstraints)
+	}
+	if mf.NetworkPolicy != nil {
+		objs = append(objs, mf.NetworkPolicy)
+	}
+	if mf.MetricsService != nil {
+		objs = append(objs, mf.MetricsService)
+	}
+	objs = append(objs, mf.DaemonSet)
+	for _, prof := range mf.Profiles {
+		objs = append(objs, prof.ConfigMap, prof.DaemonSet)
+	}
+	if mf.ServiceMonitor != nil {
+		objs = append(objs, mf.ServiceMonitor)
+	}
+	return objs
 }
 
 func (mf Manifests) ToCreatableObjects(hp *deployer.Helper, log tlog.Logger) []deployer.WaitableObject {
@@ -135,24 +454,74 @@ func (mf Manifests) ToCreatableObjects(hp *deployer.Helper, log tlog.Logger) []d
 			Obj: mf.ConfigMap,
 		})
 	}
-	return append(objs,
+	objs = append(objs,
 		deployer.WaitableObject{Obj: mf.Role},
 		deployer.WaitableObject{Obj: mf.RoleBinding},
+	)
+	if mf.SecurityContextConstraints != nil {
+		// the pods can't be admitted without it
+		objs = append(objs, deployer.WaitableObject{Obj: mf.SecurityContextConstraints})
+	}
+	if mf.NetworkPolicy != nil {
+		// the pods can't reach the API server without it in locked-down clusters
+		objs = append(objs, deployer.WaitableObject{Obj: mf.NetworkPolicy})
+	}
+	if mf.MetricsService != nil {
+		objs = append(objs, deployer.WaitableObject{Obj: mf.MetricsService})
+	}
+	objs = append(objs,
 		deployer.WaitableObject{
 			Obj:  mf.DaemonSet,
 			Wait: func() error { return wait.DaemonSetToBeRunning(hp, log, mf.DaemonSet.Namespace, mf.DaemonSet.Name) },
 		},
 	)
+	for _, prof := range mf.Profiles {
+		ds := prof.DaemonSet
+		objs = append(objs,
+			deployer.WaitableObject{Obj: prof.ConfigMap},
+			deployer.WaitableObject{
+				Obj:  ds,
+				Wait: func() error { return wait.DaemonSetToBeRunning(hp, log, ds.Namespace, ds.Name) },
+			},
+		)
+	}
+	if mf.ServiceMonitor != nil {
+		objs = append(objs, deployer.WaitableObject{Obj: mf.ServiceMonitor})
+	}
+	return objs
 }
 
 func (mf Manifests) ToDeletableObjects(hp *deployer.Helper, log tlog.Logger) []deployer.WaitableObject {
-	objs := []deployer.WaitableObject{
+	var objs []deployer.WaitableObject
+	if mf.ServiceMonitor != nil {
+		objs = append(objs, deployer.WaitableObject{Obj: mf.ServiceMonitor})
+	}
+	for _, prof := range mf.Profiles {
+		ds := prof.DaemonSet
+		objs = append(objs,
+			deployer.WaitableObject{
+				Obj:  ds,
+				Wait: func() error { return wait.DaemonSetToBeGone(hp, log, ds.Namespace, ds.Name) },
+			},
+			deployer.WaitableObject{Obj: prof.ConfigMap},
+		)
+	}
+	objs = append(objs, []deployer.WaitableObject{
 		{
 			Obj:  mf.DaemonSet,
 			Wait: func() error { return wait.DaemonSetToBeGone(hp, log, mf.DaemonSet.Namespace, mf.DaemonSet.Name) },
 		},
 		{Obj: mf.RoleBinding},
 		{Obj: mf.Role},
+	}...)
+	if mf.MetricsService != nil {
+		objs = append(objs, deployer.WaitableObject{Obj: mf.MetricsService})
+	}
+	if mf.NetworkPolicy != nil {
+		objs = append(objs, deployer.WaitableObject{Obj: mf.NetworkPolicy})
+	}
+	if mf.SecurityContextConstraints != nil {
+		objs = append(objs, deployer.WaitableObject{Obj: mf.SecurityContextConstraints})
 	}
 	if mf.ConfigMap != nil {
 		objs = append(objs, deployer.WaitableObject{Obj: mf.ConfigMap})
@@ -176,26 +545,89 @@ func New(plat platform.Platform) Manifests {
 }
 
 func GetManifests(plat platform.Platform) (Manifests, error) {
+	return GetManifestsFromSource(manifests.Source{}, plat)
+}
+
+// GetManifestsFromSource returns the manifests loaded from the given embedded manifests version.
+func GetManifestsFromSource(src manifests.Source, plat platform.Platform) (Manifests, error) {
 	var err error
 	mf := New(plat)
 	if plat == platform.Kubernetes {
-		mf.ServiceAccount, err = manifests.ServiceAccount(manifests.ComponentResourceTopologyExporter, "")
+		mf.ServiceAccount, err = src.ServiceAccount(manifests.ComponentResourceTopologyExporter, "")
 		if err != nil {
 			return mf, err
 		}
 		mf.serviceAccount = mf.ServiceAccount.Name
 	}
-	mf.Role, err = manifests.Role(manifests.ComponentResourceTopologyExporter, "")
+	if plat == platform.OpenShift {
+		mf.SecurityContextConstraints, err = src.SecurityContextConstraints(manifests.ComponentResourceTopologyExporter)
+		if err != nil {
+			return mf, err
+		}
+	}
+	mf.Role, err = src.Role(manifests.ComponentResourceTopologyExporter, "")
 	if err != nil {
 		return mf, err
 	}
-	mf.RoleBinding, err = manifests.RoleBinding(manifests.ComponentResourceTopologyExporter, "")
+	mf.RoleBinding, err = src.RoleBinding(manifests.ComponentResourceTopologyExporter, "")
 	if err != nil {
 		return mf, err
 	}
-	mf.DaemonSet, err = manifests.DaemonSet(manifests.ComponentResourceTopologyExporter)
+	mf.DaemonSet, err = src.DaemonSet(manifests.ComponentResourceTopologyExporter)
 	if err != nil {
 		return mf, err
 	}
 	return mf, nil
 }
+
+// GetManifestsForNamespace returns the manifests with all the namespaced objects in the given namespace,
+// which must be validated using ValidateNamespace. An empty namespace keeps the manifests default.
+func GetManifestsForNamespace(plat platform.Platform, namespace string) (Manifests, error) {
+	return GetManifestsForNamespaceFromSource(manifests.Source{}, plat, namespace)
+}
+
+// GetManifestsForNamespaceFromSource is GetManifestsForNamespace loading the given embedded manifests version.
+func GetManifestsForNamespaceFromSource(src manifests.Source, plat platform.Platform, namespace string) (Manifests, error) {
+	mf, err := GetManifestsFromSource(src, plat)
+	if err != nil || namespace == "" {
+		return mf, err
+	}
+	if mf.ServiceAccount != nil {
+		mf.ServiceAccount.Namespace = namespace
+	}
+	mf.Role.Namespace = namespace
+	mf.DaemonSet.Namespace = namespace
+	manifests.UpdateRoleBinding(mf.RoleBinding, mf.serviceAccount, namespace)
+	return mf, nil
+}
+
+// ValidateNamespace checks the topology updater can be deployed in the given namespace. Empty means the default.
+func ValidateNamespace(plat platform.Platform, namespace string) error {
+	if namespace == "" {
+		return nil
+	}
+	if plat != platform.Kubernetes {
+		return fmt.Errorf("a custom topology updater namespace is supported only on %s", platform.Kubernetes)
+	}
+	if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+		return fmt.Errorf("invalid namespace %q: %s", namespace, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// ValidateNodeSelector checks the node selector keys and values are valid label keys and values.
+func ValidateNodeSelector(nodeSelector map[string]string) error {
+	return validateSelector("node selector", nodeSelector)
+}
+
+func validateSelector(kind string, selector map[string]string) error {
+	for key, val := range selector {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid %s key %q: %s", kind, key, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(val); len(errs) > 0 {
+			return fmt.Errorf("invalid %s value %q for key %q: %s", kind, val, key, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
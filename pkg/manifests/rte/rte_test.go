@@ -0,0 +1,788 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package rte
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests"
+)
+
+func TestClone(t *testing.T) {
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mf = mf.Update(UpdateOptions{
+		ConfigData:        "foo: bar",
+		WithNetworkPolicy: true,
+		ConfigProfiles:    gpuProfiles(),
+	})
+
+	ret := mf.Clone()
+	if !reflect.DeepEqual(ret, mf) {
+		t.Fatalf("clone differs from the original")
+	}
+	ret.ConfigMap.Data["foo"] = "baz"
+	ret.DaemonSet.Name = "rte-variant"
+	ret.NetworkPolicy.Namespace = "variant"
+	ret.Profiles[0].DaemonSet.Name = "rte-gpu-variant"
+	if reflect.DeepEqual(ret, mf) {
+		t.Errorf("changing the clone changed the original")
+	}
+}
+
+// gpuProfiles returns a config profile for the nodes labeled as having a gpu.
+func gpuProfiles() []ConfigProfile {
+	return []ConfigProfile{
+		{Name: "gpu", NodeSelector: map[string]string{"gpu": "true"}, ConfigData: "a: b"},
+	}
+}
+
+// getManifests returns the manifests for plat, failing the test on error.
+func getManifests(t *testing.T, plat platform.Platform) Manifests {
+	t.Helper()
+	mf, err := GetManifests(plat)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return mf
+}
+
+// updateManifests updates mf with options, failing the test if the update modified mf itself.
+func updateManifests(t *testing.T, mf Manifests, options UpdateOptions) Manifests {
+	t.Helper()
+	orig := mf.Clone()
+	ret := mf.Update(options)
+	if !reflect.DeepEqual(mf, orig) {
+		t.Errorf("original manifests modified")
+	}
+	return ret
+}
+
+// daemonSets returns the default daemonset followed by the ones of the config profiles.
+func daemonSets(mf Manifests) []*appsv1.DaemonSet {
+	dss := []*appsv1.DaemonSet{mf.DaemonSet}
+	for _, profile := range mf.Profiles {
+		dss = append(dss, profile.DaemonSet)
+	}
+	return dss
+}
+
+func TestUpdateAllNodes(t *testing.T) {
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ret := mf.Update(UpdateOptions{})
+	if err := ValidateAllNodes(ret.DaemonSet); err == nil {
+		t.Errorf("expected error without the control-plane tolerations")
+	}
+
+	ret = mf.Update(UpdateOptions{AllNodes: true})
+	if err := ValidateAllNodes(ret.DaemonSet); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// updating twice must not duplicate the tolerations
+	manifests.UpdateDaemonSetTolerations(ret.DaemonSet, manifests.ControlPlaneTolerations())
+	if got := len(ret.DaemonSet.Spec.Template.Spec.Tolerations); got != len(manifests.ControlPlaneTolerations()) {
+		t.Errorf("unexpected tolerations count: %d", got)
+	}
+
+	ret.DaemonSet.Spec.Template.Spec.NodeSelector = map[string]string{
+		manifests.LabelNodeRolePrefix + "worker": "",
+	}
+	if err := ValidateAllNodes(ret.DaemonSet); err == nil {
+		t.Errorf("expected error with a worker-only nodeSelector")
+	}
+
+	ret.DaemonSet.Spec.Template.Spec.NodeSelector = nil
+	ret.DaemonSet.Spec.Template.Spec.Affinity = &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{
+						MatchExpressions: []corev1.NodeSelectorRequirement{
+							{
+								Key:      manifests.LabelNodeRoleControlPlane,
+								Operator: corev1.NodeSelectorOpDoesNotExist,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if err := ValidateAllNodes(ret.DaemonSet); err == nil {
+		t.Errorf("expected error with a node affinity excluding the control-plane")
+	}
+}
+
+func TestUpdateStartupProbe(t *testing.T) {
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	opts := UpdateOptions{
+		StartupProbeFailureThreshold: 30,
+		StartupProbePeriodSeconds:    10,
+	}
+
+	ret := mf.Update(opts)
+	probe := ret.DaemonSet.Spec.Template.Spec.Containers[0].StartupProbe
+	if probe == nil {
+		t.Fatalf("missing startup probe without liveness probe")
+	}
+	if probe.TCPSocket == nil || probe.TCPSocket.Port.IntValue() != int(MetricsPort) || probe.FailureThreshold != 30 || probe.PeriodSeconds != 10 {
+		t.Errorf("unexpected startup probe: %+v", probe)
+	}
+	if mf.Update(UpdateOptions{}).DaemonSet.Spec.Template.Spec.Containers[0].StartupProbe != nil {
+		t.Errorf("unexpected startup probe by default")
+	}
+
+	mf.DaemonSet.Spec.Template.Spec.Containers[0].LivenessProbe = &corev1.Probe{
+		Handler: corev1.Handler{
+			Exec: &corev1.ExecAction{Command: []string{"/bin/true"}},
+		},
+	}
+	ret = mf.Update(opts)
+	probe = ret.DaemonSet.Spec.Template.Spec.Containers[0].StartupProbe
+	if probe == nil {
+		t.Fatalf("missing startup probe")
+	}
+	if probe.Exec == nil || probe.FailureThreshold != 30 || probe.PeriodSeconds != 10 {
+		t.Errorf("unexpected startup probe: %+v", probe)
+	}
+}
+
+func TestUpdateFinalizers(t *testing.T) {
+	ret := updateManifests(t, getManifests(t, platform.Kubernetes), UpdateOptions{
+		Finalizers: []string{"example.com/cleanup", "example.com/cleanup"},
+	})
+	finalizers := ret.DaemonSet.Finalizers
+	if len(finalizers) != 1 || finalizers[0] != "example.com/cleanup" {
+		t.Errorf("unexpected finalizers: %v", finalizers)
+	}
+}
+
+func TestUpdateMaxUnavailable(t *testing.T) {
+	mf := getManifests(t, platform.Kubernetes)
+
+	ret := mf.Update(UpdateOptions{})
+	if !reflect.DeepEqual(ret.DaemonSet.Spec.UpdateStrategy, mf.DaemonSet.Spec.UpdateStrategy) {
+		t.Errorf("update strategy changed by default: %+v", ret.DaemonSet.Spec.UpdateStrategy)
+	}
+
+	maxUnavailable := intstr.FromString("10%")
+	ret = updateManifests(t, mf, UpdateOptions{
+		MaxUnavailable: &maxUnavailable,
+		ConfigProfiles: gpuProfiles(),
+	})
+	for _, ds := range daemonSets(ret) {
+		strategy := ds.Spec.UpdateStrategy
+		if strategy.Type != appsv1.RollingUpdateDaemonSetStrategyType || strategy.RollingUpdate == nil ||
+			strategy.RollingUpdate.MaxUnavailable.String() != "10%" {
+			t.Errorf("%s: unexpected update strategy: %+v", ds.Name, strategy)
+		}
+	}
+}
+
+func TestUpdateNetworking(t *testing.T) {
+	hostNetwork := true
+	testCases := []struct {
+		name        string
+		plat        platform.Platform
+		hostNetwork *bool
+		dnsPolicy   corev1.DNSPolicy
+	}{
+		{name: "manifest defaults", plat: platform.Kubernetes},
+		{name: "host network", plat: platform.Kubernetes, hostNetwork: &hostNetwork, dnsPolicy: corev1.DNSClusterFirstWithHostNet},
+		{name: "host network on openshift", plat: platform.OpenShift, hostNetwork: &hostNetwork},
+		{name: "dns policy only", plat: platform.OpenShift, dnsPolicy: corev1.DNSDefault},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mf := getManifests(t, tc.plat)
+			ret := updateManifests(t, mf, UpdateOptions{
+				HostNetwork:    tc.hostNetwork,
+				DNSPolicy:      tc.dnsPolicy,
+				ConfigProfiles: gpuProfiles(),
+			})
+			expectedHostNetwork := tc.hostNetwork != nil && *tc.hostNetwork
+			expectedDNSPolicy := tc.dnsPolicy
+			if expectedDNSPolicy == "" {
+				expectedDNSPolicy = mf.DaemonSet.Spec.Template.Spec.DNSPolicy
+			}
+			for _, ds := range daemonSets(ret) {
+				podSpec := ds.Spec.Template.Spec
+				if podSpec.HostNetwork != expectedHostNetwork || podSpec.DNSPolicy != expectedDNSPolicy {
+					t.Errorf("%s: unexpected networking: hostNetwork=%v dnsPolicy=%q", ds.Name, podSpec.HostNetwork, podSpec.DNSPolicy)
+				}
+			}
+			if ret.SecurityContextConstraints != nil {
+				scc := ret.SecurityContextConstraints
+				if scc.AllowHostNetwork != expectedHostNetwork || scc.AllowHostPorts != expectedHostNetwork {
+					t.Errorf("unexpected SCC: allowHostNetwork=%v allowHostPorts=%v", scc.AllowHostNetwork, scc.AllowHostPorts)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateAPIGroup(t *testing.T) {
+	ret := updateManifests(t, getManifests(t, platform.Kubernetes), UpdateOptions{
+		APIGroup: "topology.example.com",
+	})
+	for _, rule := range ret.Role.Rules {
+		for _, group := range rule.APIGroups {
+			if group == manifests.NRTAPIGroup {
+				t.Errorf("rule still references the upstream API group: %v", rule)
+			}
+		}
+	}
+	if ret.Role.Rules[0].APIGroups[0] != "topology.example.com" {
+		t.Errorf("unexpected API groups: %v", ret.Role.Rules[0].APIGroups)
+	}
+}
+
+func TestUpdateExtraInitContainers(t *testing.T) {
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mf.DaemonSet.Spec.Template.Spec.InitContainers = []corev1.Container{
+		{Name: "existing"},
+	}
+
+	ret := mf.Update(UpdateOptions{
+		ExtraInitContainers: []corev1.Container{
+			{Name: "prep-host", Image: "quay.io/example/prep:latest"},
+		},
+	})
+	initContainers := ret.DaemonSet.Spec.Template.Spec.InitContainers
+	if len(initContainers) != 2 || initContainers[0].Name != "prep-host" || initContainers[1].Name != "existing" {
+		t.Errorf("unexpected init containers: %v", initContainers)
+	}
+}
+
+func TestUpdateExtraContainers(t *testing.T) {
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rteName := mf.DaemonSet.Spec.Template.Spec.Containers[0].Name
+	logsVolume := corev1.Volume{
+		Name: "logs",
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		},
+	}
+	shipper := corev1.Container{
+		Name:  "log-shipper",
+		Image: "quay.io/example/shipper:latest",
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: "logs", MountPath: "/var/log/rte"},
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		containers    []corev1.Container
+		volumes       []corev1.Volume
+		expectedError bool
+	}{
+		{
+			name:       "sidecar with shared volume",
+			containers: []corev1.Container{shipper},
+			volumes:    []corev1.Volume{logsVolume},
+		},
+		{
+			name:          "sidecar with missing volume",
+			containers:    []corev1.Container{shipper},
+			expectedError: true,
+		},
+		{
+			name:          "sidecar named like rte",
+			containers:    []corev1.Container{{Name: rteName, Image: "quay.io/example/shipper:latest"}},
+			expectedError: true,
+		},
+		{
+			name:          "duplicate sidecars",
+			containers:    []corev1.Container{shipper, shipper},
+			volumes:       []corev1.Volume{logsVolume},
+			expectedError: true,
+		},
+		{
+			name:          "duplicate volume",
+			volumes:       []corev1.Volume{logsVolume, logsVolume},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ret := mf.Update(UpdateOptions{
+				ExtraContainers: tc.containers,
+				ExtraVolumes:    tc.volumes,
+			})
+			containers := ret.DaemonSet.Spec.Template.Spec.Containers
+			if containers[0].Name != rteName {
+				t.Errorf("rte container not first: %v", containers[0].Name)
+			}
+			if len(containers) != len(mf.DaemonSet.Spec.Template.Spec.Containers)+len(tc.containers) {
+				t.Errorf("unexpected containers: %v", containers)
+			}
+			err := ValidateExtraContainers(ret.DaemonSet)
+			if tc.expectedError && err == nil {
+				t.Errorf("expected error, got none")
+			}
+			if !tc.expectedError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestUpdateImmutableConfig(t *testing.T) {
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ret := mf.Update(UpdateOptions{
+		ConfigData:      "resources:\n  reservedcpus: \"0\"\n",
+		ImmutableConfig: true,
+	})
+	cm := ret.ConfigMap
+	if cm.Immutable == nil || !*cm.Immutable {
+		t.Errorf("configmap not immutable")
+	}
+	expectedName := "rte-config-" + manifests.ConfigMapDataHash(cm)
+	if cm.Name != expectedName {
+		t.Errorf("unexpected configmap name %q expected %q", cm.Name, expectedName)
+	}
+
+	found := false
+	for _, vol := range ret.DaemonSet.Spec.Template.Spec.Volumes {
+		if vol.Name != manifests.RTEConfigVolumeName || vol.ConfigMap == nil {
+			continue
+		}
+		found = true
+		if vol.ConfigMap.Name != cm.Name {
+			t.Errorf("daemonset volume references %q expected %q", vol.ConfigMap.Name, cm.Name)
+		}
+	}
+	if !found {
+		t.Errorf("daemonset does not consume the configuration")
+	}
+}
+
+func TestGetManifestsForNamespace(t *testing.T) {
+	mf, err := GetManifestsForNamespace(platform.Kubernetes, "tas-updater")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mf.ServiceAccount.Namespace != "tas-updater" || mf.Role.Namespace != "tas-updater" ||
+		mf.RoleBinding.Namespace != "tas-updater" || mf.DaemonSet.Namespace != "tas-updater" {
+		t.Errorf("objects not in the expected namespace: sa=%q role=%q rb=%q ds=%q",
+			mf.ServiceAccount.Namespace, mf.Role.Namespace, mf.RoleBinding.Namespace, mf.DaemonSet.Namespace)
+	}
+	for _, subj := range mf.RoleBinding.Subjects {
+		if subj.Namespace != "tas-updater" {
+			t.Errorf("rolebinding subject %q in unexpected namespace %q", subj.Name, subj.Namespace)
+		}
+	}
+
+	// the namespace must survive a later Update without the namespace
+	ret := mf.Update(UpdateOptions{ConfigData: "foo: bar"})
+	if ret.DaemonSet.Namespace != "tas-updater" || ret.ConfigMap.Namespace != "tas-updater" {
+		t.Errorf("objects moved out of the namespace: ds=%q cm=%q", ret.DaemonSet.Namespace, ret.ConfigMap.Namespace)
+	}
+}
+
+func TestValidateNamespace(t *testing.T) {
+	testCases := []struct {
+		name        string
+		plat        platform.Platform
+		namespace   string
+		expectedErr bool
+	}{
+		{name: "default", plat: platform.OpenShift, namespace: ""},
+		{name: "kubernetes", plat: platform.Kubernetes, namespace: "tas-updater"},
+		{name: "openshift", plat: platform.OpenShift, namespace: "tas-updater", expectedErr: true},
+		{name: "invalid name", plat: platform.Kubernetes, namespace: "Tas_Updater", expectedErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateNamespace(tc.plat, tc.namespace)
+			if (err != nil) != tc.expectedErr {
+				t.Errorf("expected error %v got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestUpdateConfigMapName(t *testing.T) {
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ret := mf.Update(UpdateOptions{ConfigData: "foo: bar"})
+	if ret.ConfigMap.Name != DefaultConfigMapName {
+		t.Errorf("unexpected default configmap name %q", ret.ConfigMap.Name)
+	}
+
+	ret = mf.Update(UpdateOptions{ConfigData: "foo: bar", ConfigMapName: "rte-config-gitops"})
+	if ret.ConfigMap.Name != "rte-config-gitops" {
+		t.Errorf("unexpected configmap name %q", ret.ConfigMap.Name)
+	}
+	if got := configMapVolumeName(ret.DaemonSet.Spec.Template.Spec.Volumes); got != "rte-config-gitops" {
+		t.Errorf("daemonset mounts the configmap %q", got)
+	}
+
+	canary, err := ret.Canary(map[string]string{"canary": "true"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if canary.ConfigMap.Name != "rte-config-gitops-"+CanarySuffix {
+		t.Errorf("unexpected canary configmap name %q", canary.ConfigMap.Name)
+	}
+	for _, cm := range []*corev1.ConfigMap{ret.ConfigMap, canary.ConfigMap} {
+		if cm.Labels[LabelComponent] != ConfigComponentName {
+			t.Errorf("configmap %q is not labeled: %v", cm.Name, cm.Labels)
+		}
+	}
+
+	if err := ValidateConfigMapName("RTE_config"); err == nil {
+		t.Errorf("expected error for an invalid name")
+	}
+}
+
+func configMapVolumeName(vols []corev1.Volume) string {
+	for _, vol := range vols {
+		if vol.Name == manifests.RTEConfigVolumeName && vol.ConfigMap != nil {
+			return vol.ConfigMap.Name
+		}
+	}
+	return ""
+}
+
+func TestUpdateNodeSelectorAndTolerations(t *testing.T) {
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	numaTol := corev1.Toleration{Key: "example.com/numa", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}
+	ret := mf.Update(UpdateOptions{
+		AllNodes:     true,
+		NodeSelector: map[string]string{"example.com/numa": "true"},
+		Tolerations:  []corev1.Toleration{numaTol},
+	})
+	podSpec := ret.DaemonSet.Spec.Template.Spec
+	if podSpec.NodeSelector["example.com/numa"] != "true" {
+		t.Errorf("missing node selector: %v", podSpec.NodeSelector)
+	}
+	expectedTols := append(manifests.ControlPlaneTolerations(), numaTol)
+	if len(podSpec.Tolerations) != len(expectedTols) {
+		t.Fatalf("unexpected tolerations: %v", podSpec.Tolerations)
+	}
+	for _, tol := range expectedTols {
+		found := false
+		for _, cur := range podSpec.Tolerations {
+			if cur.MatchToleration(&tol) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("missing toleration %v", tol)
+		}
+	}
+	if err := ValidateAllNodes(ret.DaemonSet); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := ValidateNodeSelector(map[string]string{"bad key": "true"}); err == nil {
+		t.Errorf("expected error for an invalid node selector")
+	}
+}
+
+func TestUpdateResources(t *testing.T) {
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ret := mf.Update(UpdateOptions{})
+	if res := ret.DaemonSet.Spec.Template.Spec.Containers[0].Resources; len(res.Requests) > 0 || len(res.Limits) > 0 {
+		t.Errorf("unset resources changed the manifest values: %v", res)
+	}
+
+	ret = mf.Update(UpdateOptions{
+		RTEResources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+		},
+	})
+	res := ret.DaemonSet.Spec.Template.Spec.Containers[0].Resources
+	if qty := res.Requests[corev1.ResourceCPU]; qty.Cmp(resource.MustParse("100m")) != 0 {
+		t.Errorf("unexpected cpu request: %v", qty.String())
+	}
+	if len(res.Limits) > 0 {
+		t.Errorf("unexpected limits: %v", res.Limits)
+	}
+}
+
+func TestUpdateRTEEnv(t *testing.T) {
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	orig := mf.DaemonSet.Spec.Template.Spec.Containers[0].Env
+
+	ret := mf.Update(UpdateOptions{
+		RTEEnv: []corev1.EnvVar{
+			{Name: "REFERENCE_NAMESPACE", Value: "custom"},
+			{Name: "RTE_EXTRA", Value: "1"},
+		},
+	})
+	env := ret.DaemonSet.Spec.Template.Spec.Containers[0].Env
+	if len(env) != len(orig)+1 {
+		t.Fatalf("unexpected env: %v", env)
+	}
+	for idx, ev := range env {
+		switch ev.Name {
+		case "NODE_NAME":
+			if ev.ValueFrom == nil || ev.ValueFrom.FieldRef == nil || ev.ValueFrom.FieldRef.FieldPath != "spec.nodeName" {
+				t.Errorf("manifest env var not preserved: %v", ev)
+			}
+		case "REFERENCE_NAMESPACE":
+			if ev.Value != "custom" || ev.ValueFrom != nil {
+				t.Errorf("colliding env var not overridden: %v", ev)
+			}
+		case "RTE_EXTRA":
+			if ev.Value != "1" || idx != len(env)-1 {
+				t.Errorf("new env var not appended: %v", ev)
+			}
+		}
+	}
+	if !reflect.DeepEqual(mf.DaemonSet.Spec.Template.Spec.Containers[0].Env, orig) {
+		t.Errorf("update modified the original manifests")
+	}
+}
+
+func TestUpdateImagePullSecrets(t *testing.T) {
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ret := mf.Update(UpdateOptions{ImagePullSecrets: []string{"regcred", "regcred"}})
+	expected := []corev1.LocalObjectReference{{Name: "regcred"}}
+	if got := ret.DaemonSet.Spec.Template.Spec.ImagePullSecrets; !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected daemonset image pull secrets: %v", got)
+	}
+	if got := ret.ServiceAccount.ImagePullSecrets; !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected serviceaccount image pull secrets: %v", got)
+	}
+	if len(mf.DaemonSet.Spec.Template.Spec.ImagePullSecrets) > 0 {
+		t.Errorf("update modified the original manifests")
+	}
+}
+
+func TestUpdateRTEVerbosity(t *testing.T) {
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	countVerbosity := func(args []string) (int, string) {
+		cnt, last := 0, ""
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "--v=") {
+				cnt++
+				last = arg
+			}
+		}
+		return cnt, last
+	}
+
+	ret := mf.Update(UpdateOptions{})
+	if cnt, _ := countVerbosity(ret.DaemonSet.Spec.Template.Spec.Containers[0].Command); cnt != 0 {
+		t.Errorf("unset verbosity changed the manifest args: %v", ret.DaemonSet.Spec.Template.Spec.Containers[0].Command)
+	}
+
+	ret = mf.Update(UpdateOptions{RTEVerbosity: 4})
+	ret = ret.Update(UpdateOptions{RTEVerbosity: 5})
+	if cnt, arg := countVerbosity(ret.DaemonSet.Spec.Template.Spec.Containers[0].Command); cnt != 1 || arg != "--v=5" {
+		t.Errorf("unexpected verbosity args: %v", ret.DaemonSet.Spec.Template.Spec.Containers[0].Command)
+	}
+}
+
+func TestUpdatePriorityClassName(t *testing.T) {
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ret := mf.Update(UpdateOptions{})
+	if got := ret.DaemonSet.Spec.Template.Spec.PriorityClassName; got != "" {
+		t.Errorf("unset priority class name changed the manifest: %q", got)
+	}
+	ret = mf.Update(UpdateOptions{PriorityClassName: "system-node-critical"})
+	if got := ret.DaemonSet.Spec.Template.Spec.PriorityClassName; got != "system-node-critical" {
+		t.Errorf("unexpected daemonset priority class name: %q", got)
+	}
+}
+
+func TestSecurityContextConstraints(t *testing.T) {
+	ret := getManifests(t, platform.Kubernetes).Update(UpdateOptions{})
+	if ret.SecurityContextConstraints != nil {
+		t.Errorf("unexpected SCC on %s", platform.Kubernetes)
+	}
+	for _, obj := range ret.ToObjects() {
+		if obj.GetObjectKind().GroupVersionKind().Kind == "SecurityContextConstraints" {
+			t.Errorf("unexpected SCC object on %s", platform.Kubernetes)
+		}
+	}
+
+	mf, err := GetManifestsForNamespace(platform.OpenShift, NamespaceOpenShift)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ret = updateManifests(t, mf, UpdateOptions{})
+	scc := ret.SecurityContextConstraints
+	if scc == nil {
+		t.Fatalf("missing SCC on %s", platform.OpenShift)
+	}
+	if !scc.AllowHostDirVolumePlugin || !scc.AllowPrivilegedContainer {
+		t.Errorf("SCC does not allow the host access: %+v", scc)
+	}
+	expectedUsers := []string{"system:serviceaccount:" + NamespaceOpenShift + ":" + ServiceAccountOpenShift}
+	if !reflect.DeepEqual(scc.Users, expectedUsers) {
+		t.Errorf("unexpected SCC users: got %v expected %v", scc.Users, expectedUsers)
+	}
+
+	found := false
+	for _, obj := range ret.ToObjects() {
+		if obj == scc {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("SCC missing from the objects")
+	}
+}
+
+func TestUpdateNetworkPolicy(t *testing.T) {
+	mf := getManifests(t, platform.Kubernetes)
+
+	ret := mf.Update(UpdateOptions{})
+	if ret.NetworkPolicy != nil {
+		t.Errorf("network policy added by default")
+	}
+
+	ret = updateManifests(t, mf, UpdateOptions{
+		Namespace:         "rte-ns",
+		WithNetworkPolicy: true,
+		ConfigProfiles:    gpuProfiles(),
+	})
+	np := ret.NetworkPolicy
+	if np == nil {
+		t.Fatalf("network policy missing")
+	}
+	if np.Namespace != "rte-ns" {
+		t.Errorf("unexpected network policy namespace %q", np.Namespace)
+	}
+	if len(np.Spec.Ingress) != 1 || len(np.Spec.Ingress[0].Ports) != 1 || np.Spec.Ingress[0].Ports[0].Port.IntVal != MetricsPort {
+		t.Errorf("metrics port not allowed: %+v", np.Spec.Ingress)
+	}
+	sel, err := metav1.LabelSelectorAsSelector(&np.Spec.PodSelector)
+	if err != nil {
+		t.Fatalf("invalid pod selector: %v", err)
+	}
+	for _, ds := range daemonSets(ret) {
+		if !sel.Matches(labels.Set(ds.Spec.Template.Labels)) {
+			t.Errorf("pod selector %v does not match the pods of %q", sel, ds.Name)
+		}
+	}
+	if sel.Matches(labels.Set{}) {
+		t.Errorf("pod selector %v matches all the pods", sel)
+	}
+	found := false
+	for _, obj := range ret.ToObjects() {
+		if obj == np {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("network policy not in the objects")
+	}
+}
+
+func TestUpdateServiceMonitor(t *testing.T) {
+	mf := getManifests(t, platform.Kubernetes)
+
+	ret := mf.Update(UpdateOptions{})
+	if ret.MetricsService != nil || ret.ServiceMonitor != nil {
+		t.Errorf("service monitor added by default")
+	}
+
+	ret = updateManifests(t, mf, UpdateOptions{
+		Namespace:          "rte-ns",
+		WithServiceMonitor: true,
+		ConfigProfiles:     gpuProfiles(),
+	})
+	svc, sm := ret.MetricsService, ret.ServiceMonitor
+	if svc == nil || sm == nil {
+		t.Fatalf("service monitor or service missing")
+	}
+	if svc.Namespace != "rte-ns" || sm.GetNamespace() != "rte-ns" {
+		t.Errorf("unexpected namespaces: service %q service monitor %q", svc.Namespace, sm.GetNamespace())
+	}
+	if len(svc.Spec.Ports) != 1 || svc.Spec.Ports[0].Port != MetricsPort || svc.Spec.Ports[0].Name != manifests.MetricsPortName {
+		t.Errorf("unexpected service ports: %+v", svc.Spec.Ports)
+	}
+	for _, ds := range daemonSets(ret) {
+		if !labels.SelectorFromSet(svc.Spec.Selector).Matches(labels.Set(ds.Spec.Template.Labels)) {
+			t.Errorf("service selector %v does not match the pods of %q", svc.Spec.Selector, ds.Name)
+		}
+	}
+	matchLabels, _, _ := unstructured.NestedStringMap(sm.Object, "spec", "selector", "matchLabels")
+	if !labels.SelectorFromSet(matchLabels).Matches(labels.Set(svc.Labels)) {
+		t.Errorf("service monitor selector %v does not match the service labels %v", matchLabels, svc.Labels)
+	}
+	objs := ret.ToObjects()
+	if objs[len(objs)-1] != sm {
+		t.Errorf("service monitor not the last object")
+	}
+}
@@ -17,16 +17,27 @@
 package sched
 
 import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	apiextensionv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer"
 	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
 	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/wait"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/images"
 	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests"
 	"github.com/k8stopologyawareschedwg/deployer/pkg/tlog"
 )
@@ -35,6 +46,292 @@ const (
 	NamespaceOpenShift = "openshift-topology-aware-scheduler"
 )
 
+const (
+	// ModeSecondary runs the plugin alongside the default scheduler, with its own
+	// scheduler name. Only the pods whose spec.schedulerName is explicitly set to
+	// that name are handled by the plugin; everything else keeps using the default.
+	ModeSecondary = "secondary"
+	// ModeReplaceDefault runs the plugin as "default-scheduler", so every pod not
+	// requesting a specific scheduler is handled by it. The stock kube-scheduler
+	// must be disabled by the cluster admin, otherwise the two will race.
+	// This is not possible on platforms with managed control planes.
+	ModeReplaceDefault = "replace-default"
+)
+
+const (
+	// AdmissionPolicyName is the name of the admission policy, and of its binding, enforcing the scheduler name.
+	// A named scheduler plugin instance uses its name with the AdmissionPolicySuffix instead.
+	AdmissionPolicyName   = "topology-aware-scheduler-name"
+	AdmissionPolicySuffix = "-name"
+	// The suffixes of the objects of a named scheduler plugin instance, which are named after it.
+	RoleBindingSuffix = "-as-kube-scheduler"
+	ConfigMapSuffix   = "-config"
+	// NetworkPolicyName is the name of the network policy of the scheduler plugin pods.
+	NetworkPolicyName = "topology-aware-scheduler"
+)
+
+const (
+	DefaultSchedulerName = "default-scheduler"
+	// SchedulerName is the name of the scheduler plugin profile in ModeSecondary
+	SchedulerName = "topology-aware-scheduler"
+)
+
+// ValidateMode checks the scheduler mode is known and usable on the given platform.
+func ValidateMode(plat platform.Platform, mode string) error {
+	switch mode {
+	case "", ModeSecondary:
+		return nil
+	case ModeReplaceDefault:
+		if plat == platform.OpenShift {
+			return fmt.Errorf("scheduler mode %q not supported on %s: the control plane is managed", mode, plat)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown scheduler mode: %q", mode)
+	}
+}
+
+// ValidatePodSchedulerName checks the scheduler plugin pods are not going to be scheduled by the scheduler
+// plugin itself, which would leave them pending forever. Empty means the cluster default scheduler, which
+// in ModeReplaceDefault is the scheduler plugin: another scheduler must be given.
+func ValidatePodSchedulerName(mode, podSchedulerName string) error {
+	if podSchedulerName == "" && mode == ModeReplaceDefault {
+		return fmt.Errorf("the scheduler plugin pods need another scheduler in mode %q: the default one is the scheduler plugin itself", mode)
+	}
+	if podSchedulerName == pluginSchedulerName(mode, "") {
+		return fmt.Errorf("the scheduler plugin pods cannot be scheduled by the scheduler plugin itself (%q)", podSchedulerName)
+	}
+	return nil
+}
+
+// ValidateSchedulerName checks the scheduler plugin can be named after schedulerName, which also names
+// its deployment. Empty means SchedulerName. Custom names are supported only in ModeSecondary, and
+// the scheduler plugin pods cannot be scheduled by the scheduler plugin itself.
+func ValidateSchedulerName(mode, schedulerName, podSchedulerName string) error {
+	if schedulerName == "" {
+		return nil
+	}
+	if mode == ModeReplaceDefault {
+		return fmt.Errorf("cannot name the scheduler plugin %q in mode %q", schedulerName, mode)
+	}
+	// also a label value, see manifests.LabelInstance
+	if errs := validation.IsDNS1123Label(schedulerName); len(errs) > 0 {
+		return fmt.Errorf("invalid scheduler name %q: %s", schedulerName, strings.Join(errs, "; "))
+	}
+	if podSchedulerName == schedulerName {
+		return fmt.Errorf("the scheduler plugin pods cannot be scheduled by the scheduler plugin itself (%q)", podSchedulerName)
+	}
+	return nil
+}
+
+// pluginSchedulerName returns the name of the scheduler plugin profile.
+func pluginSchedulerName(mode, schedulerName string) string {
+	if mode == ModeReplaceDefault {
+		return DefaultSchedulerName
+	}
+	if schedulerName != "" {
+		return schedulerName
+	}
+	return SchedulerName
+}
+
+// ValidateNodeSelector checks the node selector keys and values are valid label keys and values.
+func ValidateNodeSelector(nodeSelector map[string]string) error {
+	return validateSelector("node selector", nodeSelector)
+}
+
+// ValidateEnforcedPodSelector checks the selector of the pods forced to use the scheduler plugin
+// has valid label keys and values.
+func ValidateEnforcedPodSelector(podSelector map[string]string) error {
+	return validateSelector("enforced pod selector", podSelector)
+}
+
+func validateSelector(kind string, selector map[string]string) error {
+	for key, val := range selector {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid %s key %q: %s", kind, key, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(val); len(errs) > 0 {
+			return fmt.Errorf("invalid %s value %q for key %q: %s", kind, val, key, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+const (
+	// MinTokenExpirationSeconds is the shortest expiration the apiserver allows for the projected tokens.
+	MinTokenExpirationSeconds = 600
+)
+
+// ValidateTokenExpiration checks the projected token expiration is accepted by the apiserver. Zero disables the projection.
+func ValidateTokenExpiration(expirationSeconds int64) error {
+	if expirationSeconds != 0 && expirationSeconds < MinTokenExpirationSeconds {
+		return fmt.Errorf("invalid token expiration %ds: must be at least %ds", expirationSeconds, MinTokenExpirationSeconds)
+	}
+	return nil
+}
+
+// DefaultReplicas is the replica count of the scheduler plugin deployments, unless set.
+const DefaultReplicas int32 = 1
+
+// ValidateReplicas checks the replica count of the scheduler plugin deployments. Zero means DefaultReplicas.
+func ValidateReplicas(replicas int32) error {
+	if replicas < 0 {
+		return fmt.Errorf("invalid replicas %d: must be at least 1, or 0 for the default (%d)", replicas, DefaultReplicas)
+	}
+	return nil
+}
+
+const (
+	// SpreadReplicasPreferred makes the scheduler replicas prefer running on different nodes.
+	SpreadReplicasPreferred = "preferred"
+	// SpreadReplicasRequired makes the scheduler replicas run only on different nodes.
+	// The replicas exceeding the schedulable nodes stay Pending.
+	SpreadReplicasRequired = "required"
+)
+
+// The scoring strategies of the NodeResourceTopologyMatch plugin.
+const (
+	ScoringStrategyMostAllocated      = "MostAllocated"
+	ScoringStrategyBalancedAllocation = "BalancedAllocation"
+	ScoringStrategyLeastAllocated     = "LeastAllocated"
+)
+
+// ScoringKubeVersion is the oldest kubernetes minor version whose scheduler-plugins release lets the
+// NodeResourceTopologyMatch plugin score the nodes. The bundled kube-scheduler image only filters them.
+const ScoringKubeVersion = "1.21"
+
+// ValidateScoringStrategy checks the scoring strategy is known to the NodeResourceTopologyMatch plugin, and
+// the given kube-scheduler image is recognized as new enough to support it, see KubeVersionFromImage.
+// Empty is allowed, meaning the plugin does not score the nodes.
+func ValidateScoringStrategy(strategy, image string) error {
+	switch strategy {
+	case "":
+		return nil
+	case ScoringStrategyMostAllocated, ScoringStrategyBalancedAllocation, ScoringStrategyLeastAllocated:
+	default:
+		return fmt.Errorf("invalid scoring strategy %q: must be one of %q, %q or %q", strategy,
+			ScoringStrategyMostAllocated, ScoringStrategyBalancedAllocation, ScoringStrategyLeastAllocated)
+	}
+	if kubeMinor(KubeVersionFromImage(image)) < kubeMinor(ScoringKubeVersion) {
+		return fmt.Errorf("scoring strategy %q requires a scheduler image built from kubernetes %s or newer, %q is not known to be",
+			strategy, ScoringKubeVersion, image)
+	}
+	return nil
+}
+
+// ValidateSpreadReplicas checks how the scheduler replicas are spread across the nodes.
+// Empty means no spreading.
+func ValidateSpreadReplicas(spread string) error {
+	switch spread {
+	case "", SpreadReplicasPreferred, SpreadReplicasRequired:
+		return nil
+	default:
+		return fmt.Errorf("invalid replicas spreading %q: must be %q or %q", spread, SpreadReplicasPreferred, SpreadReplicasRequired)
+	}
+}
+
+// SchedulerKubeVersion is the kubernetes minor version the bundled kube-scheduler image is built from.
+// The scheduler-plugins releases follow the kubernetes ones: v0.19.x is built against kubernetes 1.19.
+const SchedulerKubeVersion = "1.19"
+
+// KubeVersionFromImage returns the kubernetes minor version the given kube-scheduler image is built from,
+// or empty if unknown. The default image is built from SchedulerKubeVersion, the others are recognized by
+// their scheduler-plugins release tag, like v0.21.6.
+func KubeVersionFromImage(image string) string {
+	if image == images.SchedulerPluginSchedulerDefaultImageTag || image == images.SchedulerPluginSchedulerDefaultImageSHA {
+		return SchedulerKubeVersion
+	}
+	if strings.Contains(image, "@") {
+		return ""
+	}
+	idx := strings.LastIndex(image, ":")
+	if idx == -1 || strings.Contains(image[idx:], "/") {
+		return ""
+	}
+	parts := strings.Split(image[idx+1:], ".")
+	if len(parts) < 3 || parts[0] != "v0" {
+		return ""
+	}
+	// the test builds, like v0.0.2021101805, are not releases
+	if minor, err := strconv.Atoi(parts[1]); err != nil || minor == 0 {
+		return ""
+	}
+	return "1." + parts[1]
+}
+
+// kubeMinor returns the minor number of the given kubernetes version, or -1 if malformed.
+func kubeMinor(kubeVersion string) int {
+	parts := strings.Split(kubeVersion, ".")
+	if len(parts) != 2 || parts[0] != "1" {
+		return -1
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return -1
+	}
+	return minor
+}
+
+// featureGatesByKubeVersion are the feature gates which affect the scheduling, per kubernetes minor version.
+// The gates of the versions not listed here are not checked.
+var featureGatesByKubeVersion = map[string]sets.String{
+	"1.19": sets.NewString(
+		"AllAlpha",
+		"AllBeta",
+		"BalanceAttachedNodeVolumes",
+		"CSIStorageCapacity",
+		"DefaultPodTopologySpread",
+		"EvenPodsSpread",
+		"GenericEphemeralVolume",
+		"LocalStorageCapacityIsolation",
+		"NonPreemptingPriority",
+		"PodOverhead",
+	),
+	"1.21": sets.NewString(
+		"AllAlpha",
+		"AllBeta",
+		"BalanceAttachedNodeVolumes",
+		"CSIStorageCapacity",
+		"DefaultPodTopologySpread",
+		"GenericEphemeralVolume",
+		"LocalStorageCapacityIsolation",
+		"NonPreemptingPriority",
+		"PodAffinityNamespaceSelector",
+		"PodOverhead",
+		"PreferNominatedNode",
+		"VolumeCapacityPriority",
+	),
+}
+
+// ValidateFeatureGates checks the feature gate names are well formed.
+// Gates unknown to the kube-scheduler are not errors, see UnknownFeatureGates.
+func ValidateFeatureGates(featureGates map[string]bool) error {
+	for name := range featureGates {
+		if name == "" || strings.ContainsAny(name, "=, ") {
+			return fmt.Errorf("invalid feature gate name: %q", name)
+		}
+	}
+	return nil
+}
+
+// UnknownFeatureGates returns the sorted names of the feature gates unknown to the kube-scheduler of the given
+// kubernetes minor version, see KubeVersionFromImage. Fails if the feature gates of that version are not known.
+func UnknownFeatureGates(kubeVersion string, featureGates map[string]bool) ([]string, error) {
+	known, ok := featureGatesByKubeVersion[kubeVersion]
+	if !ok {
+		return nil, fmt.Errorf("feature gates of kubernetes %q not known", kubeVersion)
+	}
+	var unknown []string
+	for name := range featureGates {
+		if !known.Has(name) {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown, nil
+}
+
 type Manifests struct {
 	// common
 	Crd       *apiextensionv1.CustomResourceDefinition
@@ -52,13 +349,22 @@ type Manifests struct {
 	RBScheduler  *rbacv1.RoleBinding
 	DPScheduler  *appsv1.Deployment
 	ConfigMap    *corev1.ConfigMap
+	// optional, enforcing the scheduler name on selected pods
+	AdmissionPolicy        *unstructured.Unstructured
+	AdmissionPolicyBinding *unstructured.Unstructured
+	// optional, letting the pods reach the API server in locked-down clusters
+	NetworkPolicy *networkingv1.NetworkPolicy
 	// internal fields
 	plat platform.Platform
+	// schedulerName is the name of the instance, if named, see updateInstanceNames
+	schedulerName string
 }
 
+// Clone returns a deep copy of the manifests, which can be changed without affecting the original ones.
 func (mf Manifests) Clone() Manifests {
 	return Manifests{
-		plat: mf.plat,
+		plat:          mf.plat,
+		schedulerName: mf.schedulerName,
 		// objects
 		Crd:           mf.Crd.DeepCopy(),
 		Namespace:     mf.Namespace.DeepCopy(),
@@ -73,6 +379,10 @@ func (mf Manifests) Clone() Manifests {
 		DPScheduler:   mf.DPScheduler.DeepCopy(),
 		ConfigMap:     mf.ConfigMap.DeepCopy(),
 		RBScheduler:   mf.RBScheduler.DeepCopy(),
+		// optional objects
+		AdmissionPolicy:        mf.AdmissionPolicy.DeepCopy(),
+		AdmissionPolicyBinding: mf.AdmissionPolicyBinding.DeepCopy(),
+		NetworkPolicy:          mf.NetworkPolicy.DeepCopy(),
 	}
 }
 
@@ -80,13 +390,67 @@ type UpdateOptions struct {
 	Replicas               int32
 	NodeResourcesNamespace string
 	PullIfNotPresent       bool
+	// Mode is one of ModeSecondary (default if empty) or ModeReplaceDefault.
+	// Must be validated using ValidateMode.
+	Mode string
+	// NodeSelector restricts the nodes the scheduler profile considers. Must be validated using ValidateNodeSelector.
+	NodeSelector map[string]string
+	// FeatureGates are passed to the scheduler. Must be validated using ValidateFeatureGates.
+	FeatureGates map[string]bool
+	// PodSchedulerName is the scheduler of the scheduler plugin pods. Must be validated using ValidatePodSchedulerName.
+	PodSchedulerName string
+	// SchedulerName, if not empty, names the scheduler plugin profile instead of SchedulerName, and names the objects
+	// of the scheduler proper after it, to run alongside other scheduler plugin instances. The instances share
+	// the namespace, the CRD and the controller. Must be validated using ValidateSchedulerName.
+	SchedulerName string
+	// TokenExpirationSeconds, if not zero, makes the scheduler use a projected service account token with this
+	// expiration instead of the auto-mounted one. Must be validated using ValidateTokenExpiration.
+	TokenExpirationSeconds int64
+	// TokenAudience, if not empty, adds a projected token with this audience, for consumers other than the apiserver.
+	// The token of the scheduler keeps the apiserver audience, which the apiserver requires.
+	TokenAudience string
+	// EnforcedPodSelector, if not empty, adds a ValidatingAdmissionPolicy rejecting the pods matching all these labels
+	// and not requesting the scheduler plugin. Requires kubernetes 1.30 or newer.
+	// Must be validated using ValidateEnforcedPodSelector.
+	EnforcedPodSelector map[string]string
+	// APIGroup, if not empty, is the API group of the NodeResourceTopology objects the scheduler is allowed to read.
+	// Must match the API CRD group. Must be validated using manifests.ValidateAPIGroup.
+	APIGroup string
+	// SchedulerResources and ControllerResources are the requests and limits of the scheduler and the controller
+	// containers. Unset resources keep the manifest values.
+	SchedulerResources  corev1.ResourceRequirements
+	ControllerResources corev1.ResourceRequirements
+	// ImagePullSecrets are the secrets the pods use to pull the images from private registries.
+	// Must be validated using manifests.ValidateImagePullSecrets.
+	ImagePullSecrets []string
+	// PriorityClassName is the priority class of the pods, which must exist. Empty means the cluster default.
+	// Must be validated using manifests.ValidatePriorityClassName.
+	PriorityClassName string
+	// SpreadReplicas, if not empty, is SpreadReplicasPreferred or SpreadReplicasRequired: the scheduler replicas
+	// get an anti-affinity on the node hostname. No-op with one replica.
+	// Must be validated using ValidateSpreadReplicas.
+	SpreadReplicas string
+	// WithNetworkPolicy adds a NetworkPolicy letting the scheduler and controller pods reach the API server,
+	// and denying them any other outgoing traffic.
+	WithNetworkPolicy bool
+	// Tolerations are added to the ones of the scheduler and controller pods, and PodNodeAffinity replaces their node
+	// affinity, e.g. to run them on the control-plane nodes. Unlike NodeSelector, PodNodeAffinity selects the nodes
+	// the pods run on.
+	Tolerations     []corev1.Toleration
+	PodNodeAffinity *corev1.NodeAffinity
+	// LeaderElect makes the scheduler replicas elect a leader, using a lease named after the scheduler deployment
+	// in its namespace. No-op with one replica. More replicas without leader election would schedule the same pods.
+	LeaderElect bool
+	// ScoringStrategy, if not empty, makes the NodeResourceTopologyMatch plugin score the nodes using this strategy,
+	// instead of only filtering them. Must be validated using ValidateScoringStrategy.
+	ScoringStrategy string
 }
 
 func (mf Manifests) Update(logger tlog.Logger, options UpdateOptions) Manifests {
 	ret := mf.Clone()
 	replicas := options.Replicas
 	if replicas <= 0 {
-		replicas = int32(1)
+		replicas = DefaultReplicas
 	}
 	ret.DPScheduler.Spec.Replicas = newInt32(replicas)
 	ret.DPController.Spec.Replicas = newInt32(replicas)
@@ -97,6 +461,10 @@ func (mf Manifests) Update(logger tlog.Logger, options UpdateOptions) Manifests
 		ret.Namespace.Name = NamespaceOpenShift
 	}
 
+	if options.Mode != ModeReplaceDefault && options.SchedulerName != "" {
+		updateInstanceNames(&ret, options.SchedulerName)
+	}
+
 	ret.SAController.Namespace = ret.Namespace.Name
 	manifests.UpdateClusterRoleBinding(ret.CRBController, ret.SAController.Name, ret.Namespace.Name)
 	manifests.UpdateRoleBinding(ret.RBController, ret.SAController.Name, ret.Namespace.Name)
@@ -111,11 +479,115 @@ func (mf Manifests) Update(logger tlog.Logger, options UpdateOptions) Manifests
 	if options.NodeResourcesNamespace != "" {
 		ret.ConfigMap = manifests.UpdateSchedulerConfigNamespaces(logger, ret.ConfigMap, options.NodeResourcesNamespace)
 	}
+	if options.ScoringStrategy != "" {
+		// must follow the namespaces update, which re-encodes the plugin args dropping the strategy
+		ret.ConfigMap = manifests.UpdateSchedulerConfigScoringStrategy(logger, ret.ConfigMap, options.ScoringStrategy)
+	}
+	if options.Mode == ModeReplaceDefault {
+		ret.ConfigMap = manifests.UpdateSchedulerConfigSchedulerName(logger, ret.ConfigMap, DefaultSchedulerName)
+		manifests.UpdateSchedulerPluginSchedulerDeploymentName(ret.DPScheduler, DefaultSchedulerName)
+	} else if options.SchedulerName != "" {
+		ret.ConfigMap = manifests.UpdateSchedulerConfigSchedulerName(logger, ret.ConfigMap, options.SchedulerName)
+		manifests.UpdateSchedulerPluginSchedulerDeploymentName(ret.DPScheduler, options.SchedulerName)
+	}
+	if len(options.NodeSelector) > 0 {
+		ret.ConfigMap = manifests.UpdateSchedulerConfigNodeSelector(logger, ret.ConfigMap, options.NodeSelector)
+	}
+	if len(options.FeatureGates) > 0 {
+		image := ret.DPScheduler.Spec.Template.Spec.Containers[0].Image
+		unknown, err := UnknownFeatureGates(KubeVersionFromImage(image), options.FeatureGates)
+		if err != nil {
+			logger.Printf("WARNING: cannot check the feature gates against the scheduler image %q: %v", image, err)
+		}
+		for _, name := range unknown {
+			logger.Printf("WARNING: feature gate %q unknown to the scheduler, passing it anyway", name)
+		}
+		manifests.UpdateSchedulerPluginSchedulerDeploymentFeatureGates(ret.DPScheduler, options.FeatureGates)
+	}
+	if options.PodSchedulerName != "" {
+		ret.DPScheduler.Spec.Template.Spec.SchedulerName = options.PodSchedulerName
+		ret.DPController.Spec.Template.Spec.SchedulerName = options.PodSchedulerName
+	}
+	if options.APIGroup != "" {
+		manifests.UpdatePolicyRulesAPIGroup(ret.CRScheduler.Rules, options.APIGroup)
+	}
+	if len(options.EnforcedPodSelector) > 0 {
+		schedulerName := pluginSchedulerName(options.Mode, options.SchedulerName)
+		policyName := AdmissionPolicyName
+		if ret.schedulerName != "" {
+			policyName = ret.schedulerName + AdmissionPolicySuffix
+		}
+		ret.AdmissionPolicy = manifests.SchedulerNameAdmissionPolicy(policyName, schedulerName)
+		ret.AdmissionPolicyBinding = manifests.SchedulerNameAdmissionPolicyBinding(policyName, policyName, options.EnforcedPodSelector)
+	}
+	if options.TokenExpirationSeconds > 0 {
+		manifests.UpdateDeploymentProjectedServiceAccountToken(ret.DPScheduler, options.TokenExpirationSeconds, options.TokenAudience)
+	}
+	manifests.UpdateContainerResources(&ret.DPScheduler.Spec.Template.Spec.Containers[0], options.SchedulerResources)
+	manifests.UpdateContainerResources(&ret.DPController.Spec.Template.Spec.Containers[0], options.ControllerResources)
+	manifests.UpdatePodSpecImagePullSecrets(&ret.DPScheduler.Spec.Template.Spec, options.ImagePullSecrets)
+	manifests.UpdatePodSpecImagePullSecrets(&ret.DPController.Spec.Template.Spec, options.ImagePullSecrets)
+	manifests.UpdateServiceAccountImagePullSecrets(ret.SAScheduler, options.ImagePullSecrets)
+	manifests.UpdateServiceAccountImagePullSecrets(ret.SAController, options.ImagePullSecrets)
+	if options.PriorityClassName != "" {
+		ret.DPScheduler.Spec.Template.Spec.PriorityClassName = options.PriorityClassName
+		ret.DPController.Spec.Template.Spec.PriorityClassName = options.PriorityClassName
+	}
+	if replicas > 1 {
+		if options.LeaderElect {
+			ret.ConfigMap = manifests.UpdateSchedulerConfigLeaderElection(logger, ret.ConfigMap, ret.DPScheduler.Name, ret.Namespace.Name)
+			ret.CRScheduler.Rules = append(ret.CRScheduler.Rules, manifests.LeasePolicyRule(ret.DPScheduler.Name))
+		} else {
+			logger.Printf("WARNING: %d scheduler replicas without leader election would compete for the same pods", replicas)
+		}
+	}
+	if options.SpreadReplicas != "" && replicas > 1 {
+		manifests.UpdatePodTemplateHostnameAntiAffinity(&ret.DPScheduler.Spec.Template, options.SpreadReplicas == SpreadReplicasRequired)
+	}
+	for _, podSpec := range []*corev1.PodSpec{&ret.DPScheduler.Spec.Template.Spec, &ret.DPController.Spec.Template.Spec} {
+		manifests.UpdatePodSpecTolerations(podSpec, options.Tolerations)
+		manifests.UpdatePodSpecNodeAffinity(podSpec, options.PodNodeAffinity)
+	}
+	if options.WithNetworkPolicy {
+		// the namespace holds only the scheduler plugin pods
+		ret.NetworkPolicy = manifests.NetworkPolicy(NetworkPolicyName, ret.Namespace.Name, metav1.LabelSelector{}, nil)
+	}
 	return ret
 }
 
+// updateInstanceNames names the objects of the scheduler proper after the scheduler name, and tells its pods
+// apart from the ones of the other instances, which share the namespace.
+func updateInstanceNames(mf *Manifests, schedulerName string) {
+	mf.schedulerName = schedulerName
+	mf.SAScheduler.Name = schedulerName
+	mf.CRScheduler.Name = schedulerName
+	mf.CRBScheduler.Name = schedulerName
+	mf.CRBScheduler.RoleRef.Name = schedulerName
+	mf.RBScheduler.Name = schedulerName + RoleBindingSuffix
+
+	prevConfigMapName := mf.ConfigMap.Name
+	mf.ConfigMap.Name = schedulerName + ConfigMapSuffix
+	podSpec := &mf.DPScheduler.Spec.Template.Spec
+	for idx := range podSpec.Volumes {
+		if cm := podSpec.Volumes[idx].ConfigMap; cm != nil && cm.Name == prevConfigMapName {
+			cm.Name = mf.ConfigMap.Name
+		}
+	}
+	podSpec.ServiceAccountName = schedulerName
+
+	mf.DPScheduler.Name = schedulerName
+	if mf.DPScheduler.Spec.Selector.MatchLabels == nil {
+		mf.DPScheduler.Spec.Selector.MatchLabels = make(map[string]string)
+	}
+	mf.DPScheduler.Spec.Selector.MatchLabels[manifests.LabelInstance] = schedulerName
+	if mf.DPScheduler.Spec.Template.Labels == nil {
+		mf.DPScheduler.Spec.Template.Labels = make(map[string]string)
+	}
+	mf.DPScheduler.Spec.Template.Labels[manifests.LabelInstance] = schedulerName
+}
+
 func (mf Manifests) ToObjects() []client.Object {
-	return []client.Object{
+	objs := []client.Object{
 		mf.Crd,
 		mf.Namespace,
 		mf.SAScheduler,
@@ -130,10 +602,17 @@ func (mf Manifests) ToObjects() []client.Object {
 		mf.DPController,
 		mf.RBController,
 	}
+	if mf.NetworkPolicy != nil {
+		objs = append(objs, mf.NetworkPolicy)
+	}
+	if mf.AdmissionPolicy != nil {
+		objs = append(objs, mf.AdmissionPolicy, mf.AdmissionPolicyBinding)
+	}
+	return objs
 }
 
 func (mf Manifests) ToCreatableObjects(hp *deployer.Helper, log tlog.Logger) []deployer.WaitableObject {
-	return []deployer.WaitableObject{
+	objs := []deployer.WaitableObject{
 		{Obj: mf.Crd},
 		{Obj: mf.Namespace},
 		{Obj: mf.SAScheduler},
@@ -141,10 +620,16 @@ func (mf Manifests) ToCreatableObjects(hp *deployer.Helper, log tlog.Logger) []d
 		{Obj: mf.CRBScheduler},
 		{Obj: mf.RBScheduler},
 		{Obj: mf.ConfigMap},
+	}
+	// the pods can't reach the API server without it in locked-down clusters
+	if mf.NetworkPolicy != nil {
+		objs = append(objs, deployer.WaitableObject{Obj: mf.NetworkPolicy})
+	}
+	objs = append(objs, []deployer.WaitableObject{
 		{
 			Obj: mf.DPScheduler,
 			Wait: func() error {
-				return wait.PodsToBeRunningByRegex(hp, log, mf.DPScheduler.Namespace, mf.DPScheduler.Name)
+				return wait.DeploymentToBeAvailable(hp, log, mf.DPScheduler.Namespace, mf.DPScheduler.Name)
 			},
 		},
 		{Obj: mf.SAController},
@@ -157,11 +642,43 @@ func (mf Manifests) ToCreatableObjects(hp *deployer.Helper, log tlog.Logger) []d
 				return wait.PodsToBeRunningByRegex(hp, log, mf.DPController.Namespace, mf.DPController.Name)
 			},
 		},
+	}...)
+	// enforce the scheduler name only once the scheduler is up
+	if mf.AdmissionPolicy != nil {
+		objs = append(objs,
+			deployer.WaitableObject{Obj: mf.AdmissionPolicy},
+			deployer.WaitableObject{Obj: mf.AdmissionPolicyBinding},
+		)
 	}
+	return objs
 }
 
 func (mf Manifests) ToDeletableObjects(hp *deployer.Helper, log tlog.Logger) []deployer.WaitableObject {
-	return []deployer.WaitableObject{
+	var objs []deployer.WaitableObject
+	// stop the enforcement before the scheduler goes away
+	if mf.AdmissionPolicy != nil {
+		objs = append(objs,
+			deployer.WaitableObject{Obj: mf.AdmissionPolicyBinding},
+			deployer.WaitableObject{Obj: mf.AdmissionPolicy},
+		)
+	}
+	// the other instances still need the shared objects
+	if mf.schedulerName != "" {
+		return append(objs, []deployer.WaitableObject{
+			{
+				Obj: mf.DPScheduler,
+				Wait: func() error {
+					return wait.PodsToBeGoneByRegex(hp, log, mf.DPScheduler.Namespace, mf.DPScheduler.Name)
+				},
+			},
+			{Obj: mf.ConfigMap},
+			{Obj: mf.RBScheduler},
+			{Obj: mf.CRBScheduler},
+			{Obj: mf.CRScheduler},
+			{Obj: mf.SAScheduler},
+		}...)
+	}
+	return append(objs, []deployer.WaitableObject{
 		{
 			Obj:  mf.Namespace,
 			Wait: func() error { return wait.NamespaceToBeGone(hp, log, mf.Namespace.Name) },
@@ -173,7 +690,7 @@ func (mf Manifests) ToDeletableObjects(hp *deployer.Helper, log tlog.Logger) []d
 		{Obj: mf.CRController},
 		{Obj: mf.RBController},
 		{Obj: mf.Crd},
-	}
+	}...)
 }
 
 func New(plat platform.Platform) Manifests {
@@ -183,59 +700,64 @@ func New(plat platform.Platform) Manifests {
 }
 
 func GetManifests(plat platform.Platform) (Manifests, error) {
+	return GetManifestsFromSource(manifests.Source{}, plat)
+}
+
+// GetManifestsFromSource returns the manifests loaded from the given embedded manifests version.
+func GetManifestsFromSource(src manifests.Source, plat platform.Platform) (Manifests, error) {
 	var err error
 	mf := New(plat)
-	mf.Crd, err = manifests.SchedulerCRD()
+	mf.Crd, err = src.SchedulerCRD()
 	if err != nil {
 		return mf, err
 	}
-	mf.Namespace, err = manifests.Namespace(manifests.ComponentSchedulerPlugin)
+	mf.Namespace, err = src.Namespace(manifests.ComponentSchedulerPlugin)
 	if err != nil {
 		return mf, err
 	}
 
-	mf.ConfigMap, err = manifests.ConfigMap(manifests.ComponentSchedulerPlugin, "")
+	mf.ConfigMap, err = src.ConfigMap(manifests.ComponentSchedulerPlugin, "")
 	if err != nil {
 		return mf, err
 	}
-	mf.SAScheduler, err = manifests.ServiceAccount(manifests.ComponentSchedulerPlugin, manifests.SubComponentSchedulerPluginScheduler)
+	mf.SAScheduler, err = src.ServiceAccount(manifests.ComponentSchedulerPlugin, manifests.SubComponentSchedulerPluginScheduler)
 	if err != nil {
 		return mf, err
 	}
-	mf.CRScheduler, err = manifests.ClusterRole(manifests.ComponentSchedulerPlugin, manifests.SubComponentSchedulerPluginScheduler)
+	mf.CRScheduler, err = src.ClusterRole(manifests.ComponentSchedulerPlugin, manifests.SubComponentSchedulerPluginScheduler)
 	if err != nil {
 		return mf, err
 	}
-	mf.CRBScheduler, err = manifests.ClusterRoleBinding(manifests.ComponentSchedulerPlugin, manifests.SubComponentSchedulerPluginScheduler)
+	mf.CRBScheduler, err = src.ClusterRoleBinding(manifests.ComponentSchedulerPlugin, manifests.SubComponentSchedulerPluginScheduler)
 	if err != nil {
 		return mf, err
 	}
-	mf.RBScheduler, err = manifests.RoleBinding(manifests.ComponentSchedulerPlugin, manifests.SubComponentSchedulerPluginScheduler)
+	mf.RBScheduler, err = src.RoleBinding(manifests.ComponentSchedulerPlugin, manifests.SubComponentSchedulerPluginScheduler)
 	if err != nil {
 		return mf, err
 	}
-	mf.DPScheduler, err = manifests.Deployment(manifests.ComponentSchedulerPlugin, manifests.SubComponentSchedulerPluginScheduler)
+	mf.DPScheduler, err = src.Deployment(manifests.ComponentSchedulerPlugin, manifests.SubComponentSchedulerPluginScheduler)
 	if err != nil {
 		return mf, err
 	}
 
-	mf.SAController, err = manifests.ServiceAccount(manifests.ComponentSchedulerPlugin, manifests.SubComponentSchedulerPluginController)
+	mf.SAController, err = src.ServiceAccount(manifests.ComponentSchedulerPlugin, manifests.SubComponentSchedulerPluginController)
 	if err != nil {
 		return mf, err
 	}
-	mf.CRController, err = manifests.ClusterRole(manifests.ComponentSchedulerPlugin, manifests.SubComponentSchedulerPluginController)
+	mf.CRController, err = src.ClusterRole(manifests.ComponentSchedulerPlugin, manifests.SubComponentSchedulerPluginController)
 	if err != nil {
 		return mf, err
 	}
-	mf.CRBController, err = manifests.ClusterRoleBinding(manifests.ComponentSchedulerPlugin, manifests.SubComponentSchedulerPluginController)
+	mf.CRBController, err = src.ClusterRoleBinding(manifests.ComponentSchedulerPlugin, manifests.SubComponentSchedulerPluginController)
 	if err != nil {
 		return mf, err
 	}
-	mf.RBController, err = manifests.RoleBinding(manifests.ComponentSchedulerPlugin, manifests.SubComponentSchedulerPluginController)
+	mf.RBController, err = src.RoleBinding(manifests.ComponentSchedulerPlugin, manifests.SubComponentSchedulerPluginController)
 	if err != nil {
 		return mf, err
 	}
-	mf.DPController, err = manifests.Deployment(manifests.ComponentSchedulerPlugin, manifests.SubComponentSchedulerPluginController)
+	mf.DPController, err = src.Deployment(manifests.ComponentSchedulerPlugin, manifests.SubComponentSchedulerPluginController)
 	if err != nil {
 		return mf, err
 	}
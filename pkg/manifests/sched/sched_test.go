@@ -0,0 +1,859 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package sched
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/images"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/tlog"
+)
+
+func TestValidateMode(t *testing.T) {
+	type testCase struct {
+		plat        platform.Platform
+		mode        string
+		expectError bool
+	}
+
+	testCases := []testCase{
+		{plat: platform.Kubernetes, mode: "", expectError: false},
+		{plat: platform.Kubernetes, mode: ModeSecondary, expectError: false},
+		{plat: platform.Kubernetes, mode: ModeReplaceDefault, expectError: false},
+		{plat: platform.OpenShift, mode: ModeSecondary, expectError: false},
+		{plat: platform.OpenShift, mode: ModeReplaceDefault, expectError: true},
+		{plat: platform.Kubernetes, mode: "unknown-wrong", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.plat)+"/"+tc.mode, func(t *testing.T) {
+			err := ValidateMode(tc.plat, tc.mode)
+			if tc.expectError && err == nil {
+				t.Fatalf("expected error, got none")
+			}
+			if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestUpdateModeReplaceDefault(t *testing.T) {
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mf = mf.Update(tlog.NewNullLogAdapter(), UpdateOptions{
+		Mode: ModeReplaceDefault,
+	})
+
+	if !strings.Contains(mf.ConfigMap.Data[manifests.SchedulerConfigFileName], "schedulerName: "+DefaultSchedulerName) {
+		t.Errorf("scheduler config not updated:\n%s", mf.ConfigMap.Data[manifests.SchedulerConfigFileName])
+	}
+	found := false
+	for _, arg := range mf.DPScheduler.Spec.Template.Spec.Containers[0].Command {
+		if arg == "--scheduler-name="+DefaultSchedulerName {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("scheduler deployment args not updated: %v", mf.DPScheduler.Spec.Template.Spec.Containers[0].Command)
+	}
+}
+
+func TestValidateNodeSelector(t *testing.T) {
+	type testCase struct {
+		nodeSelector map[string]string
+		expectError  bool
+	}
+
+	testCases := []testCase{
+		{nodeSelector: nil, expectError: false},
+		{nodeSelector: map[string]string{"node-role.kubernetes.io/worker": ""}, expectError: false},
+		{nodeSelector: map[string]string{"example.com/numa": "true"}, expectError: false},
+		{nodeSelector: map[string]string{"bad key": "true"}, expectError: true},
+		{nodeSelector: map[string]string{"example.com/numa": "bad value"}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		err := ValidateNodeSelector(tc.nodeSelector)
+		if tc.expectError && err == nil {
+			t.Errorf("%v: expected error, got none", tc.nodeSelector)
+		}
+		if !tc.expectError && err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.nodeSelector, err)
+		}
+	}
+}
+
+func TestUpdateNodeSelector(t *testing.T) {
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mf = mf.Update(tlog.NewNullLogAdapter(), UpdateOptions{
+		NodeSelector: map[string]string{"example.com/numa": "true"},
+	})
+
+	kc, err := manifests.KubeSchedulerConfigurationFromData([]byte(mf.ConfigMap.Data[manifests.SchedulerConfigFileName]))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	found := false
+	for _, pc := range kc.Profiles[0].PluginConfig {
+		if pc.Name != manifests.PluginNodeAffinity {
+			continue
+		}
+		found = true
+		if !strings.Contains(string(pc.Args.Raw), `"key":"example.com/numa","operator":"In","values":["true"]`) {
+			t.Errorf("unexpected NodeAffinity args: %s", string(pc.Args.Raw))
+		}
+	}
+	if !found {
+		t.Errorf("missing NodeAffinity plugin config")
+	}
+}
+
+func TestUpdateFeatureGates(t *testing.T) {
+	gates := map[string]bool{
+		"PodOverhead":    false,
+		"UnknownFeature": true,
+	}
+	if err := ValidateFeatureGates(gates); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ValidateFeatureGates(map[string]bool{"Bad=Gate": true}); err == nil {
+		t.Errorf("expected error, got none")
+	}
+	if unknown, err := UnknownFeatureGates(SchedulerKubeVersion, gates); err != nil || len(unknown) != 1 || unknown[0] != "UnknownFeature" {
+		t.Errorf("unexpected unknown gates: %v (%v)", unknown, err)
+	}
+	// introduced after the kubernetes version the bundled kube-scheduler is built from
+	preferNominated := map[string]bool{"PreferNominatedNode": true}
+	if unknown, err := UnknownFeatureGates(SchedulerKubeVersion, preferNominated); err != nil || len(unknown) != 1 {
+		t.Errorf("unexpected unknown gates: %v (%v)", unknown, err)
+	}
+	if unknown, err := UnknownFeatureGates(KubeVersionFromImage("example.com/kube-scheduler:v0.21.6"), preferNominated); err != nil || len(unknown) != 0 {
+		t.Errorf("unexpected unknown gates: %v (%v)", unknown, err)
+	}
+	if _, err := UnknownFeatureGates("", gates); err == nil {
+		t.Errorf("expected error for an unknown kubernetes version, got none")
+	}
+
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mf = mf.Update(tlog.NewNullLogAdapter(), UpdateOptions{
+		FeatureGates: gates,
+	})
+	found := false
+	for _, arg := range mf.DPScheduler.Spec.Template.Spec.Containers[0].Command {
+		if arg == "--feature-gates=PodOverhead=false,UnknownFeature=true" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("scheduler deployment args not updated: %v", mf.DPScheduler.Spec.Template.Spec.Containers[0].Command)
+	}
+}
+
+func TestSchedulerKubeVersion(t *testing.T) {
+	if _, ok := featureGatesByKubeVersion[SchedulerKubeVersion]; !ok {
+		t.Fatalf("no feature gates known for kubernetes %s", SchedulerKubeVersion)
+	}
+
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	image := mf.DPScheduler.Spec.Template.Spec.Containers[0].Image
+	minor := strings.TrimPrefix(SchedulerKubeVersion, "1.")
+	if !strings.Contains(image, ":v0."+minor+".") {
+		t.Errorf("scheduler image %q not built from kubernetes %s", image, SchedulerKubeVersion)
+	}
+}
+
+func TestKubeVersionFromImage(t *testing.T) {
+	testCases := []struct {
+		image    string
+		expected string
+	}{
+		{image: images.SchedulerPluginSchedulerDefaultImageTag, expected: SchedulerKubeVersion},
+		{image: images.SchedulerPluginSchedulerDefaultImageSHA, expected: SchedulerKubeVersion},
+		{image: "k8s.gcr.io/scheduler-plugins/kube-scheduler:v0.21.6", expected: "1.21"},
+		{image: "localhost:5000/kube-scheduler:v0.22.6-rc.1", expected: "1.22"},
+		{image: "quay.io/example/kube-scheduler:v0.0.2021101805"},
+		{image: "quay.io/example/kube-scheduler:latest"},
+		{image: "localhost:5000/kube-scheduler"},
+		{image: "quay.io/example/kube-scheduler@sha256:91fd822a2455edce8224171a9a5216ac7ed6fed967893c19f31ba25fd25ebe98"},
+	}
+	for _, tc := range testCases {
+		if got := KubeVersionFromImage(tc.image); got != tc.expected {
+			t.Errorf("%q: got %q expected %q", tc.image, got, tc.expected)
+		}
+	}
+}
+
+func TestValidatePodSchedulerName(t *testing.T) {
+	type testCase struct {
+		mode             string
+		podSchedulerName string
+		expectError      bool
+	}
+
+	testCases := []testCase{
+		{mode: ModeSecondary, podSchedulerName: "", expectError: false},
+		{mode: ModeSecondary, podSchedulerName: DefaultSchedulerName, expectError: false},
+		{mode: ModeSecondary, podSchedulerName: SchedulerName, expectError: true},
+		{mode: ModeReplaceDefault, podSchedulerName: "other-scheduler", expectError: false},
+		{mode: ModeReplaceDefault, podSchedulerName: DefaultSchedulerName, expectError: true},
+		{mode: ModeReplaceDefault, podSchedulerName: "", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		err := ValidatePodSchedulerName(tc.mode, tc.podSchedulerName)
+		if tc.expectError && err == nil {
+			t.Errorf("%s/%s: expected error, got none", tc.mode, tc.podSchedulerName)
+		}
+		if !tc.expectError && err != nil {
+			t.Errorf("%s/%s: unexpected error: %v", tc.mode, tc.podSchedulerName, err)
+		}
+	}
+}
+
+func TestUpdateProjectedToken(t *testing.T) {
+	if err := ValidateTokenExpiration(60); err == nil {
+		t.Errorf("expected error for a too short expiration, got none")
+	}
+
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ret := mf.Update(tlog.NewNullLogAdapter(), UpdateOptions{})
+	if ret.DPScheduler.Spec.Template.Spec.AutomountServiceAccountToken != nil {
+		t.Errorf("token automount changed by default")
+	}
+
+	ret = mf.Update(tlog.NewNullLogAdapter(), UpdateOptions{
+		TokenExpirationSeconds: 3600,
+		TokenAudience:          "example",
+	})
+	podSpec := ret.DPScheduler.Spec.Template.Spec
+	if podSpec.AutomountServiceAccountToken == nil || *podSpec.AutomountServiceAccountToken {
+		t.Errorf("token still auto-mounted")
+	}
+	found := false
+	for _, vol := range podSpec.Volumes {
+		if vol.Name != manifests.ServiceAccountTokenVolumeName || vol.Projected == nil {
+			continue
+		}
+		tok := vol.Projected.Sources[0].ServiceAccountToken
+		if tok == nil || *tok.ExpirationSeconds != 3600 || tok.Audience != "" {
+			t.Errorf("unexpected apiserver token projection: %+v", tok)
+		}
+		tok = vol.Projected.Sources[1].ServiceAccountToken
+		if tok == nil || *tok.ExpirationSeconds != 3600 || tok.Audience != "example" || tok.Path != manifests.ServiceAccountAudienceTokenPath {
+			t.Errorf("unexpected audience token projection: %+v", tok)
+		}
+		found = true
+	}
+	if !found {
+		t.Fatalf("projected token volume not found")
+	}
+	mounted := false
+	for _, vm := range podSpec.Containers[0].VolumeMounts {
+		if vm.Name == manifests.ServiceAccountTokenVolumeName && vm.MountPath == manifests.ServiceAccountTokenMountPath {
+			mounted = true
+		}
+	}
+	if !mounted {
+		t.Errorf("projected token volume not mounted: %v", podSpec.Containers[0].VolumeMounts)
+	}
+}
+
+func TestUpdateEnforcedPodSelector(t *testing.T) {
+	if err := ValidateEnforcedPodSelector(map[string]string{"numa": "required"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateEnforcedPodSelector(map[string]string{"not valid": "required"}); err == nil {
+		t.Errorf("expected error, got none")
+	}
+
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ret := mf.Update(tlog.NewNullLogAdapter(), UpdateOptions{})
+	if ret.AdmissionPolicy != nil || ret.AdmissionPolicyBinding != nil {
+		t.Errorf("admission policy added by default")
+	}
+
+	ret = mf.Update(tlog.NewNullLogAdapter(), UpdateOptions{
+		EnforcedPodSelector: map[string]string{"numa": "required"},
+	})
+	if ret.AdmissionPolicy == nil || ret.AdmissionPolicyBinding == nil {
+		t.Fatalf("admission policy missing")
+	}
+	objs := ret.ToObjects()
+	if objs[len(objs)-1] != ret.AdmissionPolicyBinding {
+		t.Errorf("admission policy binding not in the objects")
+	}
+	if ret.Clone().AdmissionPolicy == nil {
+		t.Errorf("admission policy lost in clone")
+	}
+}
+
+func TestUpdateNetworkPolicy(t *testing.T) {
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ret := mf.Update(tlog.NewNullLogAdapter(), UpdateOptions{})
+	if ret.NetworkPolicy != nil {
+		t.Errorf("network policy added by default")
+	}
+
+	ret = mf.Update(tlog.NewNullLogAdapter(), UpdateOptions{WithNetworkPolicy: true})
+	np := ret.NetworkPolicy
+	if np == nil {
+		t.Fatalf("network policy missing")
+	}
+	if np.Namespace != ret.Namespace.Name {
+		t.Errorf("network policy in namespace %q, expected %q", np.Namespace, ret.Namespace.Name)
+	}
+	if len(np.Spec.Ingress) > 0 || len(np.Spec.Egress) != 1 {
+		t.Errorf("unexpected network policy rules: %+v", np.Spec)
+	}
+	found := false
+	for _, obj := range ret.ToObjects() {
+		if obj == np {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("network policy not in the objects")
+	}
+	if ret.Clone().NetworkPolicy == nil {
+		t.Errorf("network policy lost in clone")
+	}
+}
+
+func TestUpdateReplicas(t *testing.T) {
+	type testCase struct {
+		replicas         int32
+		expectError      bool
+		expectedReplicas int32
+	}
+
+	testCases := []testCase{
+		{replicas: 0, expectedReplicas: DefaultReplicas},
+		{replicas: 1, expectedReplicas: 1},
+		{replicas: 3, expectedReplicas: 3},
+		{replicas: -1, expectError: true},
+	}
+
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, tc := range testCases {
+		err := ValidateReplicas(tc.replicas)
+		if tc.expectError {
+			if err == nil {
+				t.Errorf("%d: expected error, got none", tc.replicas)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: unexpected error: %v", tc.replicas, err)
+		}
+		ret := mf.Update(tlog.NewNullLogAdapter(), UpdateOptions{Replicas: tc.replicas})
+		if got := *ret.DPScheduler.Spec.Replicas; got != tc.expectedReplicas {
+			t.Errorf("%d: unexpected scheduler replicas %d", tc.replicas, got)
+		}
+		if got := *ret.DPController.Spec.Replicas; got != tc.expectedReplicas {
+			t.Errorf("%d: unexpected controller replicas %d", tc.replicas, got)
+		}
+	}
+}
+
+func TestUpdateResources(t *testing.T) {
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ret := mf.Update(tlog.NewNullLogAdapter(), UpdateOptions{})
+	expected := mf.DPScheduler.Spec.Template.Spec.Containers[0].Resources
+	if got := ret.DPScheduler.Spec.Template.Spec.Containers[0].Resources; !reflect.DeepEqual(got, expected) {
+		t.Errorf("unset resources changed the manifest values: %v", got)
+	}
+
+	ret = mf.Update(tlog.NewNullLogAdapter(), UpdateOptions{
+		SchedulerResources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+			Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+		},
+	})
+	res := ret.DPScheduler.Spec.Template.Spec.Containers[0].Resources
+	if qty := res.Requests[corev1.ResourceCPU]; qty.Cmp(resource.MustParse("500m")) != 0 {
+		t.Errorf("unexpected cpu request: %v", qty.String())
+	}
+	if qty := res.Requests[corev1.ResourceMemory]; qty.Cmp(resource.MustParse("256Mi")) != 0 {
+		t.Errorf("unexpected memory request: %v", qty.String())
+	}
+	if qty := res.Limits[corev1.ResourceCPU]; qty.Cmp(resource.MustParse("1")) != 0 {
+		t.Errorf("unexpected cpu limit: %v", qty.String())
+	}
+	if _, ok := mf.DPScheduler.Spec.Template.Spec.Containers[0].Resources.Requests[corev1.ResourceMemory]; ok {
+		t.Errorf("update modified the original manifests")
+	}
+}
+
+func TestUpdateImagePullSecrets(t *testing.T) {
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ret := mf.Update(tlog.NewNullLogAdapter(), UpdateOptions{ImagePullSecrets: []string{"regcred"}})
+	expected := []corev1.LocalObjectReference{{Name: "regcred"}}
+	for _, podSpec := range []*corev1.PodSpec{&ret.DPScheduler.Spec.Template.Spec, &ret.DPController.Spec.Template.Spec} {
+		if !reflect.DeepEqual(podSpec.ImagePullSecrets, expected) {
+			t.Errorf("unexpected deployment image pull secrets: %v", podSpec.ImagePullSecrets)
+		}
+	}
+	for _, sa := range []*corev1.ServiceAccount{ret.SAScheduler, ret.SAController} {
+		if !reflect.DeepEqual(sa.ImagePullSecrets, expected) {
+			t.Errorf("unexpected serviceaccount %q image pull secrets: %v", sa.Name, sa.ImagePullSecrets)
+		}
+	}
+}
+
+func TestUpdatePriorityClassName(t *testing.T) {
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ret := mf.Update(tlog.NewNullLogAdapter(), UpdateOptions{PriorityClassName: "system-cluster-critical"})
+	for _, podSpec := range []*corev1.PodSpec{&ret.DPScheduler.Spec.Template.Spec, &ret.DPController.Spec.Template.Spec} {
+		if podSpec.PriorityClassName != "system-cluster-critical" {
+			t.Errorf("unexpected deployment priority class name: %q", podSpec.PriorityClassName)
+		}
+	}
+}
+
+func TestUpdateSchedulerName(t *testing.T) {
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mf = mf.Update(tlog.NewNullLogAdapter(), UpdateOptions{
+		SchedulerName:       "tas-second",
+		EnforcedPodSelector: map[string]string{"numa": "true"},
+	})
+
+	if !strings.Contains(mf.ConfigMap.Data[manifests.SchedulerConfigFileName], "schedulerName: tas-second") {
+		t.Errorf("scheduler config not updated:\n%s", mf.ConfigMap.Data[manifests.SchedulerConfigFileName])
+	}
+	if mf.DPScheduler.Name != "tas-second" {
+		t.Errorf("scheduler deployment not renamed: %q", mf.DPScheduler.Name)
+	}
+	found := false
+	for _, arg := range mf.DPScheduler.Spec.Template.Spec.Containers[0].Command {
+		if arg == "--scheduler-name=tas-second" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("scheduler deployment args not updated: %v", mf.DPScheduler.Spec.Template.Spec.Containers[0].Command)
+	}
+	data, err := json.Marshal(mf.AdmissionPolicy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(data), "tas-second") {
+		t.Errorf("admission policy does not enforce the scheduler name: %s", data)
+	}
+	if name := mf.AdmissionPolicy.GetName(); name != "tas-second"+AdmissionPolicySuffix {
+		t.Errorf("admission policy not renamed: %q", name)
+	}
+}
+
+func TestUpdateSchedulerNameInstances(t *testing.T) {
+	mf := getManifests(t, platform.Kubernetes)
+	first := updateManifests(t, mf, UpdateOptions{})
+	second := updateManifests(t, mf, UpdateOptions{SchedulerName: "tas-second"})
+
+	sa, cr, crb, rb, cm := second.SAScheduler, second.CRScheduler, second.CRBScheduler, second.RBScheduler, second.ConfigMap
+	if sa.Name != "tas-second" || cr.Name != "tas-second" || crb.Name != "tas-second" || rb.Name != "tas-second"+RoleBindingSuffix {
+		t.Errorf("RBAC not renamed: %q %q %q %q", sa.Name, cr.Name, crb.Name, rb.Name)
+	}
+	if crb.RoleRef.Name != cr.Name || crb.Subjects[0].Name != sa.Name || rb.Subjects[0].Name != sa.Name {
+		t.Errorf("bindings not updated: %+v %+v %+v", crb.RoleRef, crb.Subjects, rb.Subjects)
+	}
+	if cm.Name != "tas-second"+ConfigMapSuffix {
+		t.Errorf("configmap not renamed: %q", cm.Name)
+	}
+	podSpec := second.DPScheduler.Spec.Template.Spec
+	if podSpec.ServiceAccountName != sa.Name {
+		t.Errorf("unexpected service account %q", podSpec.ServiceAccountName)
+	}
+	found := false
+	for _, vol := range podSpec.Volumes {
+		if vol.ConfigMap != nil && vol.ConfigMap.Name == cm.Name {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("configmap volume not updated: %+v", podSpec.Volumes)
+	}
+	sel := second.DPScheduler.Spec.Selector.MatchLabels
+	if sel[manifests.LabelInstance] != "tas-second" || !labels.SelectorFromSet(sel).Matches(labels.Set(second.DPScheduler.Spec.Template.Labels)) {
+		t.Errorf("unexpected selector %v for the pod labels %v", sel, second.DPScheduler.Spec.Template.Labels)
+	}
+	if labels.SelectorFromSet(first.DPScheduler.Spec.Selector.MatchLabels).Matches(labels.Set(second.DPScheduler.Spec.Template.Labels)) &&
+		labels.SelectorFromSet(sel).Matches(labels.Set(first.DPScheduler.Spec.Template.Labels)) {
+		t.Errorf("the selectors of the instances overlap")
+	}
+
+	// the instances share only the namespace, the CRD and the controller
+	shared := sets.NewString()
+	for _, obj := range []client.Object{first.Namespace, first.Crd, first.SAController, first.CRController, first.CRBController, first.RBController, first.DPController} {
+		shared.Insert(objectKey(obj))
+	}
+	firstKeys := sets.NewString()
+	for _, obj := range first.ToObjects() {
+		firstKeys.Insert(objectKey(obj))
+	}
+	for _, obj := range second.ToObjects() {
+		if key := objectKey(obj); firstKeys.Has(key) && !shared.Has(key) {
+			t.Errorf("object %s shared by the instances", key)
+		}
+	}
+	for _, wo := range second.ToDeletableObjects(nil, nil) {
+		if key := objectKey(wo.Obj); shared.Has(key) {
+			t.Errorf("removing the instance removes the shared object %s", key)
+		}
+	}
+}
+
+func objectKey(obj client.Object) string {
+	return fmt.Sprintf("%T/%s/%s", obj, obj.GetNamespace(), obj.GetName())
+}
+
+func TestValidateSchedulerName(t *testing.T) {
+	testCases := []struct {
+		mode             string
+		schedulerName    string
+		podSchedulerName string
+		expectError      bool
+	}{
+		{mode: ModeSecondary},
+		{mode: ModeReplaceDefault},
+		{mode: ModeSecondary, schedulerName: "tas-second"},
+		{mode: ModeSecondary, schedulerName: "tas-second", podSchedulerName: "tas-second", expectError: true},
+		{mode: ModeSecondary, schedulerName: "Not_Valid", expectError: true},
+		{mode: ModeSecondary, schedulerName: "tas.second", expectError: true},
+		{mode: ModeSecondary, schedulerName: strings.Repeat("a", 64), expectError: true},
+		{mode: ModeReplaceDefault, schedulerName: "tas-second", expectError: true},
+	}
+	for _, tc := range testCases {
+		err := ValidateSchedulerName(tc.mode, tc.schedulerName, tc.podSchedulerName)
+		if (err != nil) != tc.expectError {
+			t.Errorf("%s/%q/%q: expected error %v, got %v", tc.mode, tc.schedulerName, tc.podSchedulerName, tc.expectError, err)
+		}
+	}
+}
+
+// getManifests returns the manifests for plat, failing the test on error.
+func getManifests(t *testing.T, plat platform.Platform) Manifests {
+	t.Helper()
+	mf, err := GetManifests(plat)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return mf
+}
+
+// updateManifests updates mf with options, failing the test if the update modified mf itself.
+func updateManifests(t *testing.T, mf Manifests, options UpdateOptions) Manifests {
+	t.Helper()
+	orig := mf.Clone()
+	ret := mf.Update(tlog.NewNullLogAdapter(), options)
+	if !reflect.DeepEqual(mf, orig) {
+		t.Errorf("original manifests modified")
+	}
+	return ret
+}
+
+func TestUpdateSpreadReplicas(t *testing.T) {
+	mf := getManifests(t, platform.Kubernetes)
+
+	testCases := []struct {
+		name          string
+		replicas      int32
+		spread        string
+		expectedReq   bool
+		expectedPref  bool
+		expectedNoAff bool
+	}{
+		{name: "no spreading", replicas: 3, expectedNoAff: true},
+		{name: "single replica", replicas: 1, spread: SpreadReplicasRequired, expectedNoAff: true},
+		{name: "preferred", replicas: 3, spread: SpreadReplicasPreferred, expectedPref: true},
+		{name: "required", replicas: 3, spread: SpreadReplicasRequired, expectedReq: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ret := updateManifests(t, mf, UpdateOptions{Replicas: tc.replicas, SpreadReplicas: tc.spread})
+			tmpl := &ret.DPScheduler.Spec.Template
+			if got := manifests.HasHostnameAntiAffinity(tmpl); got != tc.expectedReq {
+				t.Errorf("required anti-affinity: got %v expected %v", got, tc.expectedReq)
+			}
+			aff := tmpl.Spec.Affinity
+			if tc.expectedNoAff {
+				if aff != nil && aff.PodAntiAffinity != nil {
+					t.Errorf("unexpected anti-affinity: %+v", aff.PodAntiAffinity)
+				}
+				return
+			}
+			prefs := aff.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+			if got := len(prefs) == 1 && prefs[0].PodAffinityTerm.TopologyKey == corev1.LabelHostname; got != tc.expectedPref {
+				t.Errorf("preferred anti-affinity: got %v expected %v (%+v)", got, tc.expectedPref, prefs)
+			}
+			if ctrlAff := ret.DPController.Spec.Template.Spec.Affinity; ctrlAff != nil && ctrlAff.PodAntiAffinity != nil {
+				t.Errorf("unexpected controller anti-affinity: %+v", ctrlAff.PodAntiAffinity)
+			}
+		})
+	}
+}
+
+func TestUpdateTolerations(t *testing.T) {
+	tols := manifests.ControlPlaneTolerations()
+	ret := updateManifests(t, getManifests(t, platform.Kubernetes), UpdateOptions{
+		Tolerations:     append(tols, tols...),
+		PodNodeAffinity: manifests.ControlPlaneNodeAffinity(),
+	})
+	for _, dp := range []struct {
+		name    string
+		podSpec corev1.PodSpec
+	}{
+		{name: "scheduler", podSpec: ret.DPScheduler.Spec.Template.Spec},
+		{name: "controller", podSpec: ret.DPController.Spec.Template.Spec},
+	} {
+		for _, tol := range tols {
+			count := 0
+			for _, got := range dp.podSpec.Tolerations {
+				if reflect.DeepEqual(got, tol) {
+					count++
+				}
+			}
+			if count != 1 {
+				t.Errorf("%s: toleration %+v found %d times", dp.name, tol, count)
+			}
+		}
+		if dp.podSpec.Affinity == nil || !reflect.DeepEqual(dp.podSpec.Affinity.NodeAffinity, manifests.ControlPlaneNodeAffinity()) {
+			t.Errorf("%s: missing control-plane node affinity: %+v", dp.name, dp.podSpec.Affinity)
+		}
+	}
+}
+
+func TestUpdateLeaderElection(t *testing.T) {
+	mf := getManifests(t, platform.Kubernetes)
+
+	testCases := []struct {
+		name          string
+		replicas      int32
+		leaderElect   bool
+		schedulerName string
+		expectedLease string
+	}{
+		{name: "single replica", replicas: 1, leaderElect: true},
+		{name: "no leader election", replicas: 3},
+		{name: "leader election", replicas: 3, leaderElect: true, expectedLease: "topology-aware-scheduler"},
+		{name: "named scheduler", replicas: 2, leaderElect: true, schedulerName: "tas-numa", expectedLease: "tas-numa"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ret := updateManifests(t, mf, UpdateOptions{Replicas: tc.replicas, LeaderElect: tc.leaderElect, SchedulerName: tc.schedulerName})
+			kc, err := manifests.KubeSchedulerConfigurationFromData([]byte(ret.ConfigMap.Data[manifests.SchedulerConfigFileName]))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			le := kc.LeaderElection
+			elected := le.LeaderElect != nil && *le.LeaderElect
+			if elected != (tc.expectedLease != "") {
+				t.Fatalf("leader election: got %v expected lease %q", elected, tc.expectedLease)
+			}
+			if !elected {
+				return
+			}
+			if le.ResourceName != tc.expectedLease || le.ResourceNamespace != ret.Namespace.Name {
+				t.Errorf("unexpected lease %s/%s", le.ResourceNamespace, le.ResourceName)
+			}
+			rule := ret.CRScheduler.Rules[len(ret.CRScheduler.Rules)-1]
+			if !reflect.DeepEqual(rule, manifests.LeasePolicyRule(tc.expectedLease)) {
+				t.Errorf("missing lease access rule, last rule is %+v", rule)
+			}
+		})
+	}
+}
+
+func TestClone(t *testing.T) {
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mf = mf.Update(tlog.NewNullLogAdapter(), UpdateOptions{
+		EnforcedPodSelector: map[string]string{"numa": "true"},
+		WithNetworkPolicy:   true,
+	})
+
+	ret := mf.Clone()
+	if !reflect.DeepEqual(ret, mf) {
+		t.Fatalf("clone differs from the original")
+	}
+	ret.Namespace.Name = "variant"
+	ret.ConfigMap.Data[manifests.SchedulerConfigFileName] = ""
+	ret.DPScheduler.Spec.Template.Spec.Containers[0].Image = "example.com/scheduler:variant"
+	ret.AdmissionPolicy.SetName("variant")
+	ret.NetworkPolicy.Namespace = "variant"
+	if mf.Namespace.Name == "variant" || mf.ConfigMap.Data[manifests.SchedulerConfigFileName] == "" ||
+		mf.DPScheduler.Spec.Template.Spec.Containers[0].Image == "example.com/scheduler:variant" ||
+		mf.AdmissionPolicy.GetName() == "variant" || mf.NetworkPolicy.Namespace == "variant" {
+		t.Errorf("changing the clone changed the original")
+	}
+}
+
+func TestValidateSpreadReplicas(t *testing.T) {
+	for _, spread := range []string{"", SpreadReplicasPreferred, SpreadReplicasRequired} {
+		if err := ValidateSpreadReplicas(spread); err != nil {
+			t.Errorf("unexpected error for %q: %v", spread, err)
+		}
+	}
+	if err := ValidateSpreadReplicas("always"); err == nil {
+		t.Errorf("expected error for invalid spreading")
+	}
+}
+
+func TestValidateScoringStrategy(t *testing.T) {
+	image := "k8s.gcr.io/scheduler-plugins/kube-scheduler:v0.21.6"
+	for _, strategy := range []string{"", ScoringStrategyMostAllocated, ScoringStrategyBalancedAllocation, ScoringStrategyLeastAllocated} {
+		if err := ValidateScoringStrategy(strategy, image); err != nil {
+			t.Errorf("unexpected error for %q: %v", strategy, err)
+		}
+	}
+	for _, strategy := range []string{"mostallocated", "LeastNUMANodes"} {
+		if err := ValidateScoringStrategy(strategy, image); err == nil {
+			t.Errorf("expected error for invalid scoring strategy %q", strategy)
+		}
+	}
+	for _, image := range []string{images.SchedulerPluginSchedulerDefaultImageTag, "k8s.gcr.io/scheduler-plugins/kube-scheduler:v0.20.10", "example.com/kube-scheduler:latest"} {
+		if err := ValidateScoringStrategy(ScoringStrategyMostAllocated, image); err == nil {
+			t.Errorf("expected error for scheduler image %q", image)
+		}
+		if err := ValidateScoringStrategy("", image); err != nil {
+			t.Errorf("unexpected error for filtering only on %q: %v", image, err)
+		}
+	}
+}
+
+func TestUpdateScoringStrategy(t *testing.T) {
+	mf, err := GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		strategy string
+	}{
+		{name: "filter only"},
+		{name: "most allocated", strategy: ScoringStrategyMostAllocated},
+		{name: "balanced allocation", strategy: ScoringStrategyBalancedAllocation},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ret := mf.Update(tlog.NewNullLogAdapter(), UpdateOptions{
+				NodeResourcesNamespace: "tas-nrt",
+				SchedulerName:          "tas-numa",
+				ScoringStrategy:        tc.strategy,
+			})
+			kc, err := manifests.KubeSchedulerConfigurationFromData([]byte(ret.ConfigMap.Data[manifests.SchedulerConfigFileName]))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			profile := kc.Profiles[0]
+
+			var args map[string]interface{}
+			for _, pc := range profile.PluginConfig {
+				if pc.Name != manifests.PluginNodeResourceTopologyMatch {
+					continue
+				}
+				if err := json.Unmarshal(pc.Args.Raw, &args); err != nil {
+					t.Fatalf("unexpected error decoding the plugin args: %v", err)
+				}
+			}
+			namespaces, _ := args["namespaces"].([]interface{})
+			if len(namespaces) == 0 || namespaces[len(namespaces)-1] != "tas-nrt" {
+				t.Errorf("lost namespaces: %v", args["namespaces"])
+			}
+
+			scoring := false
+			if profile.Plugins != nil && profile.Plugins.Score != nil {
+				for _, plugin := range profile.Plugins.Score.Enabled {
+					if plugin.Name == manifests.PluginNodeResourceTopologyMatch {
+						scoring = true
+					}
+				}
+			}
+			if scoring != (tc.strategy != "") {
+				t.Errorf("scoring enabled %v for strategy %q", scoring, tc.strategy)
+			}
+
+			if tc.strategy == "" {
+				if _, ok := args["scoringStrategy"]; ok {
+					t.Errorf("unexpected scoring strategy: %v", args["scoringStrategy"])
+				}
+				return
+			}
+			expected := map[string]interface{}{"type": tc.strategy}
+			if !reflect.DeepEqual(args["scoringStrategy"], expected) {
+				t.Errorf("unexpected scoring strategy: %v", args["scoringStrategy"])
+			}
+		})
+	}
+}
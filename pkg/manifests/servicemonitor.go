@@ -0,0 +1,105 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package manifests
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// the prometheus operator API is not vendored, so we use unstructured objects.
+var ServiceMonitorGVK = schema.GroupVersionKind{
+	Group:   "monitoring.coreos.com",
+	Version: "v1",
+	Kind:    "ServiceMonitor",
+}
+
+const (
+	// ServiceMonitorCRDName is the CRD the prometheus operator installs to serve the ServiceMonitors.
+	ServiceMonitorCRDName = "servicemonitors.monitoring.coreos.com"
+	// MetricsPortName names the port the metrics are scraped from, in the Service and in the ServiceMonitor.
+	MetricsPortName = "metrics"
+)
+
+// MetricsService returns a headless Service exposing the metrics port of the selected pods, so each of them
+// is scraped on its own. The Service is labeled like the pods it selects, to be selected by the ServiceMonitor.
+func MetricsService(name, namespace string, podSelector map[string]string, port int32) *corev1.Service {
+	labels := make(map[string]string, len(podSelector))
+	selector := make(map[string]string, len(podSelector))
+	for key, val := range podSelector {
+		labels[key] = val
+		selector[key] = val
+	}
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Service",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: corev1.ClusterIPNone,
+			Selector:  selector,
+			Ports: []corev1.ServicePort{
+				{
+					Name:       MetricsPortName,
+					Protocol:   corev1.ProtocolTCP,
+					Port:       port,
+					TargetPort: intstr.FromInt(int(port)),
+				},
+			},
+		},
+	}
+}
+
+// ServiceMonitor returns a ServiceMonitor making the prometheus operator scrape the metrics port of the
+// Services of the namespace matching all the given labels.
+func ServiceMonitor(name, namespace string, serviceLabels map[string]string) *unstructured.Unstructured {
+	matchLabels := make(map[string]interface{}, len(serviceLabels))
+	for key, val := range serviceLabels {
+		matchLabels[key] = val
+	}
+	sm := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": namespace,
+			},
+			"spec": map[string]interface{}{
+				"selector": map[string]interface{}{
+					"matchLabels": matchLabels,
+				},
+				"namespaceSelector": map[string]interface{}{
+					"matchNames": []interface{}{namespace},
+				},
+				"endpoints": []interface{}{
+					map[string]interface{}{
+						"port": MetricsPortName,
+					},
+				},
+			},
+		},
+	}
+	sm.SetGroupVersionKind(ServiceMonitorGVK)
+	return sm
+}
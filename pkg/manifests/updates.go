@@ -1,18 +1,197 @@
 package manifests
 
 import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	kubeschedulerconfigv1beta1 "k8s.io/kube-scheduler/config/v1beta1"
 
 	"github.com/drone/envsubst"
+	securityv1 "github.com/openshift/api/security/v1"
 
 	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
 	"github.com/k8stopologyawareschedwg/deployer/pkg/images"
 	"github.com/k8stopologyawareschedwg/deployer/pkg/tlog"
 )
 
+const (
+	RTEConfigVolumeName     = "rte-config"
+	SchedulerConfigFileName = "scheduler-config.yaml"
+)
+
+const (
+	PluginNodeAffinity              = "NodeAffinity"
+	PluginNodeResourceTopologyMatch = "NodeResourceTopologyMatch"
+)
+
+const (
+	ServiceAccountTokenVolumeName = "sa-token"
+	ServiceAccountTokenMountPath  = "/var/run/secrets/kubernetes.io/serviceaccount"
+	// ServiceAccountAudienceTokenPath is the file, in ServiceAccountTokenMountPath, of the token with a custom audience.
+	ServiceAccountAudienceTokenPath = "audience-token"
+)
+
+const (
+	// NRTAPIGroup is the upstream API group of the NodeResourceTopology objects
+	NRTAPIGroup = "topology.node.k8s.io"
+)
+
+const (
+	// LabelManagedBy marks the objects the deployer created, with the ManagedByDeployer value,
+	// so the ones no longer deployed can be found and pruned.
+	LabelManagedBy    = "app.kubernetes.io/managed-by"
+	ManagedByDeployer = "topology-aware-scheduling-deployer"
+	// LabelInstance tells apart the objects, and the pods, of the scheduler plugin instances running
+	// alongside each other, so each deployment prunes only its own objects.
+	LabelInstance = "app.kubernetes.io/instance"
+)
+
+// InstanceLabels returns the labels of the objects the deployer created for the given scheduler plugin instance.
+func InstanceLabels(instance string) map[string]string {
+	return map[string]string{
+		LabelManagedBy: ManagedByDeployer,
+		LabelInstance:  instance,
+	}
+}
+
+// AnnotationDeployerVersion is stamped by deploy on the objects it creates or updates,
+// with the version of the deployer, so upgrade can tell which version is installed.
+const AnnotationDeployerVersion = "topology-aware-scheduling-deployer/version"
+
+const (
+	LabelNodeRolePrefix       = "node-role.kubernetes.io/"
+	LabelNodeRoleMaster       = LabelNodeRolePrefix + "master"
+	LabelNodeRoleControlPlane = LabelNodeRolePrefix + "control-plane"
+)
+
+// ControlPlaneTolerations returns the tolerations needed to run on the
+// control-plane nodes, covering both the legacy and the current taint.
+func ControlPlaneTolerations() []corev1.Toleration {
+	return []corev1.Toleration{
+		{
+			Key:      LabelNodeRoleMaster,
+			Operator: corev1.TolerationOpExists,
+			Effect:   corev1.TaintEffectNoSchedule,
+		},
+		{
+			Key:      LabelNodeRoleControlPlane,
+			Operator: corev1.TolerationOpExists,
+			Effect:   corev1.TaintEffectNoSchedule,
+		},
+	}
+}
+
+// ControlPlaneNodeAffinity returns the node affinity requiring the control-plane nodes. The nodes can be labeled
+// with either role, depending on the kubernetes version which set them up.
+func ControlPlaneNodeAffinity() *corev1.NodeAffinity {
+	var terms []corev1.NodeSelectorTerm
+	for _, key := range []string{LabelNodeRoleControlPlane, LabelNodeRoleMaster} {
+		terms = append(terms, corev1.NodeSelectorTerm{
+			MatchExpressions: []corev1.NodeSelectorRequirement{
+				{
+					Key:      key,
+					Operator: corev1.NodeSelectorOpExists,
+				},
+			},
+		})
+	}
+	return &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+			NodeSelectorTerms: terms,
+		},
+	}
+}
+
+// UpdatePodSpecNodeAffinity replaces the node affinity of the pod spec, if nodeAffinity is not nil.
+func UpdatePodSpecNodeAffinity(podSpec *corev1.PodSpec, nodeAffinity *corev1.NodeAffinity) *corev1.PodSpec {
+	if nodeAffinity == nil {
+		return podSpec
+	}
+	if podSpec.Affinity == nil {
+		podSpec.Affinity = &corev1.Affinity{}
+	}
+	podSpec.Affinity.NodeAffinity = nodeAffinity.DeepCopy()
+	return podSpec
+}
+
+// HasHostnameAntiAffinity tells if the pods of the template require to run on different nodes,
+// because of a required anti-affinity against their own labels on the node hostname.
+func HasHostnameAntiAffinity(tmpl *corev1.PodTemplateSpec) bool {
+	aff := tmpl.Spec.Affinity
+	if aff == nil || aff.PodAntiAffinity == nil {
+		return false
+	}
+	for _, term := range aff.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+		if term.TopologyKey != corev1.LabelHostname || term.LabelSelector == nil {
+			continue
+		}
+		sel, err := metav1.LabelSelectorAsSelector(term.LabelSelector)
+		if err != nil {
+			continue
+		}
+		if sel.Matches(labels.Set(tmpl.Labels)) {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdatePodTemplateHostnameAntiAffinity makes the pods of the template avoid the nodes already running one of them,
+// using an anti-affinity against their own labels on the node hostname. Required anti-affinity leaves the pods
+// which can't run on a node of their own Pending, the preferred one lets them share nodes as last resort.
+// Any previous anti-affinity on the hostname is replaced.
+func UpdatePodTemplateHostnameAntiAffinity(tmpl *corev1.PodTemplateSpec, required bool) {
+	if tmpl.Spec.Affinity == nil {
+		tmpl.Spec.Affinity = &corev1.Affinity{}
+	}
+	if tmpl.Spec.Affinity.PodAntiAffinity == nil {
+		tmpl.Spec.Affinity.PodAntiAffinity = &corev1.PodAntiAffinity{}
+	}
+	antiAff := tmpl.Spec.Affinity.PodAntiAffinity
+
+	var reqTerms []corev1.PodAffinityTerm
+	for _, term := range antiAff.RequiredDuringSchedulingIgnoredDuringExecution {
+		if term.TopologyKey != corev1.LabelHostname {
+			reqTerms = append(reqTerms, term)
+		}
+	}
+	var prefTerms []corev1.WeightedPodAffinityTerm
+	for _, term := range antiAff.PreferredDuringSchedulingIgnoredDuringExecution {
+		if term.PodAffinityTerm.TopologyKey != corev1.LabelHostname {
+			prefTerms = append(prefTerms, term)
+		}
+	}
+
+	matchLabels := make(map[string]string, len(tmpl.Labels))
+	for key, val := range tmpl.Labels {
+		matchLabels[key] = val
+	}
+	term := corev1.PodAffinityTerm{
+		LabelSelector: &metav1.LabelSelector{MatchLabels: matchLabels},
+		TopologyKey:   corev1.LabelHostname,
+	}
+	if required {
+		reqTerms = append(reqTerms, term)
+	} else {
+		prefTerms = append(prefTerms, corev1.WeightedPodAffinityTerm{Weight: 100, PodAffinityTerm: term})
+	}
+	antiAff.RequiredDuringSchedulingIgnoredDuringExecution = reqTerms
+	antiAff.PreferredDuringSchedulingIgnoredDuringExecution = prefTerms
+}
+
 func UpdateRoleBinding(rb *rbacv1.RoleBinding, serviceAccount, namespace string) *rbacv1.RoleBinding {
 	rb.Namespace = namespace // TODO
 	for idx := 0; idx < len(rb.Subjects); idx++ {
@@ -34,6 +213,12 @@ func UpdateClusterRoleBinding(crb *rbacv1.ClusterRoleBinding, serviceAccount, na
 	return crb
 }
 
+// UpdateSecurityContextConstraintsUsers makes the SCC usable only by the service account.
+func UpdateSecurityContextConstraintsUsers(scc *securityv1.SecurityContextConstraints, serviceAccount, namespace string) *securityv1.SecurityContextConstraints {
+	scc.Users = []string{fmt.Sprintf("system:serviceaccount:%s:%s", namespace, serviceAccount)}
+	return scc
+}
+
 func UpdateSchedulerPluginSchedulerDeployment(dp *appsv1.Deployment, pullIfNotPresent bool) *appsv1.Deployment {
 	dp.Spec.Template.Spec.Containers[0].Image = images.SchedulerPluginSchedulerImage
 	dp.Spec.Template.Spec.Containers[0].ImagePullPolicy = pullPolicy(pullIfNotPresent)
@@ -47,48 +232,289 @@ func UpdateSchedulerPluginControllerDeployment(dp *appsv1.Deployment, pullIfNotP
 }
 
 func UpdateSchedulerConfigNamespaces(logger tlog.Logger, cm *corev1.ConfigMap, NodeResourcesNamespace string) *corev1.ConfigMap {
-	confData, ok := cm.Data["scheduler-config.yaml"]
-	if !ok {
-		logger.Debugf("missing data for scheduler-config.yaml")
-		return cm
-	}
-	kc, err := KubeSchedulerConfigurationFromData([]byte(confData))
-	if err != nil {
-		logger.Debugf("cannot decode the KubeSchedulerConfiguration: %v", err)
-		return cm
-	}
+	return updateSchedulerConfig(logger, cm, func(kc *kubeschedulerconfigv1beta1.KubeSchedulerConfiguration) {
+		for idx := 0; idx < len(kc.Profiles[0].PluginConfig); idx++ {
+			if kc.Profiles[0].PluginConfig[idx].Name == PluginNodeResourceTopologyMatch {
+				tcfg, err := NodeResourceTopologyMatchArgsFromData(kc.Profiles[0].PluginConfig[idx].Args.Raw)
+				if err != nil {
+					logger.Debugf("failed to decode NodeResourceTopologyMatchArgs: %v", err)
+					continue
+				}
 
-	for idx := 0; idx < len(kc.Profiles[0].PluginConfig); idx++ {
-		if kc.Profiles[0].PluginConfig[idx].Name == "NodeResourceTopologyMatch" {
-			tcfg, err := NodeResourceTopologyMatchArgsFromData(kc.Profiles[0].PluginConfig[idx].Args.Raw)
-			if err != nil {
-				logger.Debugf("failed to decode NodeResourceTopologyMatchArgs: %v", err)
-				continue
+				namespaces := sets.NewString(tcfg.Namespaces...)
+				namespaces.Insert(NodeResourcesNamespace)
+				tcfg.Namespaces = namespaces.List()
+				logger.Debugf("new namespace list: %v", tcfg.Namespaces)
+
+				blob, err := NodeResourceTopologyMatchArgsToData(tcfg)
+				if err != nil {
+					logger.Debugf("failed to re-encode NodeResourceTopologyMatchArgs: %v", err)
+					continue
+				}
+				kc.Profiles[0].PluginConfig[idx].Args.Raw = blob
 			}
+		}
+	})
+}
 
-			namespaces := sets.NewString(tcfg.Namespaces...)
-			namespaces.Insert(NodeResourcesNamespace)
-			tcfg.Namespaces = namespaces.List()
-			logger.Debugf("new namespace list: %v", tcfg.Namespaces)
+func UpdateSchedulerConfigSchedulerName(logger tlog.Logger, cm *corev1.ConfigMap, schedulerName string) *corev1.ConfigMap {
+	return updateSchedulerConfig(logger, cm, func(kc *kubeschedulerconfigv1beta1.KubeSchedulerConfiguration) {
+		kc.Profiles[0].SchedulerName = &schedulerName
+		logger.Debugf("new scheduler name: %q", schedulerName)
+	})
+}
 
-			blob, err := NodeResourceTopologyMatchArgsToData(tcfg)
+// UpdateSchedulerConfigScoringStrategy sets the scoring strategy of the NodeResourceTopologyMatch plugin, enabling
+// it at the score extension point. The plugin args are changed as raw JSON, because the vendored args type
+// predates the scoring strategy: updating the args through that type afterwards would drop the strategy.
+// The scheduler images built on the vendored plugin release can't score the nodes: the caller must check
+// the image supports the strategy.
+func UpdateSchedulerConfigScoringStrategy(logger tlog.Logger, cm *corev1.ConfigMap, strategy string) *corev1.ConfigMap {
+	return updateSchedulerConfig(logger, cm, func(kc *kubeschedulerconfigv1beta1.KubeSchedulerConfiguration) {
+		profile := &kc.Profiles[0]
+		for idx := 0; idx < len(profile.PluginConfig); idx++ {
+			if profile.PluginConfig[idx].Name != PluginNodeResourceTopologyMatch {
+				continue
+			}
+			args := make(map[string]interface{})
+			if raw := profile.PluginConfig[idx].Args.Raw; len(raw) > 0 {
+				if err := json.Unmarshal(raw, &args); err != nil {
+					logger.Debugf("failed to decode NodeResourceTopologyMatchArgs: %v", err)
+					return
+				}
+			}
+			args["scoringStrategy"] = map[string]interface{}{
+				"type": strategy,
+			}
+			blob, err := json.Marshal(args)
 			if err != nil {
 				logger.Debugf("failed to re-encode NodeResourceTopologyMatchArgs: %v", err)
-				continue
+				return
+			}
+			profile.PluginConfig[idx].Args.Raw = blob
+		}
+
+		if profile.Plugins == nil {
+			profile.Plugins = &kubeschedulerconfigv1beta1.Plugins{}
+		}
+		if profile.Plugins.Score == nil {
+			profile.Plugins.Score = &kubeschedulerconfigv1beta1.PluginSet{}
+		}
+		for _, plugin := range profile.Plugins.Score.Enabled {
+			if plugin.Name == PluginNodeResourceTopologyMatch {
+				return
+			}
+		}
+		profile.Plugins.Score.Enabled = append(profile.Plugins.Score.Enabled, kubeschedulerconfigv1beta1.Plugin{
+			Name: PluginNodeResourceTopologyMatch,
+		})
+		logger.Debugf("new scoring strategy: %q", strategy)
+	})
+}
+
+// UpdateSchedulerConfigNodeSelector restricts the scheduler profile to the nodes matching all the given labels,
+// using the `addedAffinity` argument of the NodeAffinity plugin. An empty label value matches any value.
+func UpdateSchedulerConfigNodeSelector(logger tlog.Logger, cm *corev1.ConfigMap, nodeSelector map[string]string) *corev1.ConfigMap {
+	return updateSchedulerConfig(logger, cm, func(kc *kubeschedulerconfigv1beta1.KubeSchedulerConfiguration) {
+		var reqs []corev1.NodeSelectorRequirement
+		for _, key := range sets.StringKeySet(nodeSelector).List() {
+			req := corev1.NodeSelectorRequirement{
+				Key:      key,
+				Operator: corev1.NodeSelectorOpExists,
+			}
+			if val := nodeSelector[key]; val != "" {
+				req.Operator = corev1.NodeSelectorOpIn
+				req.Values = []string{val}
+			}
+			reqs = append(reqs, req)
+		}
+		args := kubeschedulerconfigv1beta1.NodeAffinityArgs{
+			AddedAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{
+						{MatchExpressions: reqs},
+					},
+				},
+			},
+		}
+		blob, err := json.Marshal(args)
+		if err != nil {
+			logger.Debugf("failed to encode NodeAffinityArgs: %v", err)
+			return
+		}
+
+		profile := &kc.Profiles[0]
+		for idx := 0; idx < len(profile.PluginConfig); idx++ {
+			if profile.PluginConfig[idx].Name == PluginNodeAffinity {
+				profile.PluginConfig[idx].Args.Raw = blob
+				return
 			}
-			kc.Profiles[0].PluginConfig[idx].Args.Raw = blob
 		}
+		profile.PluginConfig = append(profile.PluginConfig, kubeschedulerconfigv1beta1.PluginConfig{
+			Name: PluginNodeAffinity,
+			Args: runtime.RawExtension{Raw: blob},
+		})
+		logger.Debugf("new node selector: %v", nodeSelector)
+	})
+}
+
+// UpdateSchedulerConfigLeaderElection enables the leader election of the scheduler replicas, using the lease
+// with the given name and namespace, so different scheduler instances do not contend for the same lease.
+func UpdateSchedulerConfigLeaderElection(logger tlog.Logger, cm *corev1.ConfigMap, leaseName, leaseNamespace string) *corev1.ConfigMap {
+	return updateSchedulerConfig(logger, cm, func(kc *kubeschedulerconfigv1beta1.KubeSchedulerConfiguration) {
+		kc.LeaderElection.LeaderElect = newBool(true)
+		kc.LeaderElection.ResourceLock = "leases"
+		kc.LeaderElection.ResourceName = leaseName
+		kc.LeaderElection.ResourceNamespace = leaseNamespace
+		logger.Debugf("leader election enabled using lease %s/%s", leaseNamespace, leaseName)
+	})
+}
+
+func UpdateSchedulerPluginSchedulerDeploymentName(dp *appsv1.Deployment, schedulerName string) *appsv1.Deployment {
+	cnt := &dp.Spec.Template.Spec.Containers[0]
+	for idx, arg := range cnt.Command {
+		if strings.HasPrefix(arg, "--scheduler-name=") {
+			cnt.Command[idx] = "--scheduler-name=" + schedulerName
+		}
+	}
+	return dp
+}
+
+// UpdateSchedulerPluginSchedulerDeploymentFeatureGates sets the `--feature-gates` argument of the scheduler, replacing any previous value.
+func UpdateSchedulerPluginSchedulerDeploymentFeatureGates(dp *appsv1.Deployment, featureGates map[string]bool) *appsv1.Deployment {
+	var gates []string
+	for _, name := range sortedGateNames(featureGates) {
+		gates = append(gates, fmt.Sprintf("%s=%t", name, featureGates[name]))
+	}
+	arg := "--feature-gates=" + strings.Join(gates, ",")
+
+	cnt := &dp.Spec.Template.Spec.Containers[0]
+	for idx := range cnt.Command {
+		if strings.HasPrefix(cnt.Command[idx], "--feature-gates=") {
+			cnt.Command[idx] = arg
+			return dp
+		}
+	}
+	cnt.Command = append(cnt.Command, arg)
+	return dp
+}
+
+// UpdateDeploymentProjectedServiceAccountToken replaces the auto-mounted service account token of the pods
+// with a projected token, bound to the given expiration and to the apiserver audience.
+// The projected volume provides the same files of the auto-mounted one, so the in-cluster clients work unchanged.
+// A token for the apiserver must have its audience: a not empty audience gets an extra token, in
+// ServiceAccountAudienceTokenPath, for the other consumers.
+func UpdateDeploymentProjectedServiceAccountToken(dp *appsv1.Deployment, expirationSeconds int64, audience string) *appsv1.Deployment {
+	podSpec := &dp.Spec.Template.Spec
+	podSpec.AutomountServiceAccountToken = newBool(false)
+	tokens := []corev1.VolumeProjection{
+		{
+			ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+				ExpirationSeconds: &expirationSeconds,
+				Path:              "token",
+			},
+		},
+	}
+	if audience != "" {
+		tokens = append(tokens, corev1.VolumeProjection{
+			ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+				Audience:          audience,
+				ExpirationSeconds: &expirationSeconds,
+				Path:              ServiceAccountAudienceTokenPath,
+			},
+		})
+	}
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: ServiceAccountTokenVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{
+				Sources: append(tokens, []corev1.VolumeProjection{
+					{
+						ConfigMap: &corev1.ConfigMapProjection{
+							LocalObjectReference: corev1.LocalObjectReference{
+								Name: "kube-root-ca.crt",
+							},
+							Items: []corev1.KeyToPath{
+								{Key: "ca.crt", Path: "ca.crt"},
+							},
+						},
+					},
+					{
+						DownwardAPI: &corev1.DownwardAPIProjection{
+							Items: []corev1.DownwardAPIVolumeFile{
+								{
+									Path:     "namespace",
+									FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"},
+								},
+							},
+						},
+					},
+				}...),
+			},
+		},
+	})
+	for idx := range podSpec.Containers {
+		podSpec.Containers[idx].VolumeMounts = append(podSpec.Containers[idx].VolumeMounts, corev1.VolumeMount{
+			Name:      ServiceAccountTokenVolumeName,
+			MountPath: ServiceAccountTokenMountPath,
+			ReadOnly:  true,
+		})
+	}
+	return dp
+}
+
+func sortedGateNames(featureGates map[string]bool) []string {
+	names := make([]string, 0, len(featureGates))
+	for name := range featureGates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func updateSchedulerConfig(logger tlog.Logger, cm *corev1.ConfigMap, update func(kc *kubeschedulerconfigv1beta1.KubeSchedulerConfiguration)) *corev1.ConfigMap {
+	confData, ok := cm.Data[SchedulerConfigFileName]
+	if !ok {
+		logger.Debugf("missing data for %s", SchedulerConfigFileName)
+		return cm
+	}
+	kc, err := KubeSchedulerConfigurationFromData([]byte(confData))
+	if err != nil {
+		logger.Debugf("cannot decode the KubeSchedulerConfiguration: %v", err)
+		return cm
+	}
+	if len(kc.Profiles) == 0 {
+		logger.Debugf("no profiles in the KubeSchedulerConfiguration")
+		return cm
 	}
 
+	update(kc)
+
 	binData, err := KubeSchedulerConfigurationToData(kc)
 	if err != nil {
 		logger.Debugf("cannot encode the KubeSchedulerConfiguration: %v", err)
 		return cm
 	}
-	cm.Data["scheduler-config.yaml"] = string(binData)
+	cm.Data[SchedulerConfigFileName] = string(binData)
 	return cm
 }
 
+// UpdateResourceTopologyExporterVerbosity sets the `--v` log level argument of the RTE container, replacing any previous value.
+func UpdateResourceTopologyExporterVerbosity(ds *appsv1.DaemonSet, verbosity int) *appsv1.DaemonSet {
+	arg := fmt.Sprintf("--v=%d", verbosity)
+
+	// TODO: better match by name than assume container#0 is RTE proper (not minion)
+	cnt := &ds.Spec.Template.Spec.Containers[0]
+	for idx := range cnt.Command {
+		if strings.HasPrefix(cnt.Command[idx], "--v=") {
+			cnt.Command[idx] = arg
+			return ds
+		}
+	}
+	cnt.Command = append(cnt.Command, arg)
+	return ds
+}
+
 func UpdateResourceTopologyExporterDaemonSet(plat platform.Platform, ds *appsv1.DaemonSet, cm *corev1.ConfigMap, pullIfNotPresent bool) *appsv1.DaemonSet {
 	// TODO: better match by name than assume container#0 is RTE proper (not minion)
 	ds.Spec.Template.Spec.Containers[0].Image = images.ResourceTopologyExporterImage
@@ -111,19 +537,24 @@ func UpdateResourceTopologyExporterDaemonSet(plat platform.Platform, ds *appsv1.
 		ds.Spec.Template.Spec.Containers[0].SecurityContext.Privileged = newBool(true)
 	}
 	if cm != nil {
+		if ds.Spec.Template.Annotations == nil {
+			ds.Spec.Template.Annotations = make(map[string]string)
+		}
+		ds.Spec.Template.Annotations[AnnotationConfigHash] = ConfigMapDataHash(cm)
+
 		ds.Spec.Template.Spec.Containers[0].VolumeMounts = append(ds.Spec.Template.Spec.Containers[0].VolumeMounts,
 			corev1.VolumeMount{
-				Name:      "rte-config",
+				Name:      RTEConfigVolumeName,
 				MountPath: "/etc/resource-topology-exporter/",
 			},
 		)
 		ds.Spec.Template.Spec.Volumes = append(ds.Spec.Template.Spec.Volumes,
 			corev1.Volume{
-				Name: "rte-config",
+				Name: RTEConfigVolumeName,
 				VolumeSource: corev1.VolumeSource{
 					ConfigMap: &corev1.ConfigMapVolumeSource{
 						LocalObjectReference: corev1.LocalObjectReference{
-							Name: "rte-config",
+							Name: cm.Name,
 						},
 						Optional: newBool(true),
 					},
@@ -134,6 +565,345 @@ func UpdateResourceTopologyExporterDaemonSet(plat platform.Platform, ds *appsv1.
 	return ds
 }
 
+// UpdateContainerStartupProbe adds a startup probe mirroring the liveness probe of the container, so the
+// liveness checks begin only once the container completed its startup. Containers without liveness probe
+// get a probe checking they listen on the given port instead. Zero values keep the kubernetes defaults.
+func UpdateContainerStartupProbe(cnt *corev1.Container, port, failureThreshold, periodSeconds int32) *corev1.Container {
+	cnt.StartupProbe = &corev1.Probe{
+		Handler: corev1.Handler{
+			TCPSocket: &corev1.TCPSocketAction{
+				Port: intstr.FromInt(int(port)),
+			},
+		},
+		FailureThreshold: failureThreshold,
+		PeriodSeconds:    periodSeconds,
+	}
+	if cnt.LivenessProbe != nil {
+		cnt.StartupProbe.Handler = *cnt.LivenessProbe.Handler.DeepCopy()
+		cnt.StartupProbe.TimeoutSeconds = cnt.LivenessProbe.TimeoutSeconds
+	}
+	return cnt
+}
+
+// UpdateContainerResources sets the requests and the limits of the container, overriding the existing ones
+// only on resource name collision. Empty requirements leave the container untouched.
+func UpdateContainerResources(cnt *corev1.Container, res corev1.ResourceRequirements) *corev1.Container {
+	cnt.Resources.Requests = mergeResourceList(cnt.Resources.Requests, res.Requests)
+	cnt.Resources.Limits = mergeResourceList(cnt.Resources.Limits, res.Limits)
+	return cnt
+}
+
+// UpdateContainerEnv merges the environment variables into the ones of the container, overriding the existing
+// ones only on name collision, in place. The new variables are appended in the given order.
+func UpdateContainerEnv(cnt *corev1.Container, env []corev1.EnvVar) *corev1.Container {
+	for _, ev := range env {
+		found := false
+		for idx := range cnt.Env {
+			if cnt.Env[idx].Name == ev.Name {
+				cnt.Env[idx] = *ev.DeepCopy()
+				found = true
+				break
+			}
+		}
+		if !found {
+			cnt.Env = append(cnt.Env, *ev.DeepCopy())
+		}
+	}
+	return cnt
+}
+
+func mergeResourceList(cur, res corev1.ResourceList) corev1.ResourceList {
+	if len(res) == 0 {
+		return cur
+	}
+	if cur == nil {
+		cur = make(corev1.ResourceList, len(res))
+	}
+	for name, qty := range res {
+		cur[name] = qty.DeepCopy()
+	}
+	return cur
+}
+
+// UpdateFinalizers adds the given finalizers to the object, skipping the ones already present.
+func UpdateFinalizers(obj metav1.Object, finalizers []string) metav1.Object {
+	cur := sets.NewString(obj.GetFinalizers()...)
+	for _, finalizer := range finalizers {
+		if cur.Has(finalizer) {
+			continue
+		}
+		obj.SetFinalizers(append(obj.GetFinalizers(), finalizer))
+		cur.Insert(finalizer)
+	}
+	return obj
+}
+
+// UpdateMetadata adds the labels and the annotations to the object, overriding the existing ones only on key collision.
+func UpdateMetadata(obj metav1.Object, labels, annotations map[string]string) metav1.Object {
+	if len(labels) > 0 {
+		objLabels := obj.GetLabels()
+		if objLabels == nil {
+			objLabels = make(map[string]string, len(labels))
+		}
+		for key, val := range labels {
+			objLabels[key] = val
+		}
+		obj.SetLabels(objLabels)
+	}
+	if len(annotations) > 0 {
+		objAnnotations := obj.GetAnnotations()
+		if objAnnotations == nil {
+			objAnnotations = make(map[string]string, len(annotations))
+		}
+		for key, val := range annotations {
+			objAnnotations[key] = val
+		}
+		obj.SetAnnotations(objAnnotations)
+	}
+	return obj
+}
+
+// ValidateMetadata checks the labels and the annotations can be set on the objects.
+func ValidateMetadata(labels, annotations map[string]string) error {
+	for key, val := range labels {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid label key %q: %s", key, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(val); len(errs) > 0 {
+			return fmt.Errorf("invalid label value %q for key %q: %s", val, key, strings.Join(errs, "; "))
+		}
+	}
+	for key := range annotations {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid annotation key %q: %s", key, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+// UpdatePodSpecImagePullSecrets adds the secrets to the ones the pods use to pull their images, skipping the ones already present.
+func UpdatePodSpecImagePullSecrets(podSpec *corev1.PodSpec, names []string) *corev1.PodSpec {
+	podSpec.ImagePullSecrets = mergeImagePullSecrets(podSpec.ImagePullSecrets, names)
+	return podSpec
+}
+
+// UpdateServiceAccountImagePullSecrets adds the secrets to the ones of the service account, skipping the ones already present.
+func UpdateServiceAccountImagePullSecrets(sa *corev1.ServiceAccount, names []string) *corev1.ServiceAccount {
+	sa.ImagePullSecrets = mergeImagePullSecrets(sa.ImagePullSecrets, names)
+	return sa
+}
+
+func mergeImagePullSecrets(refs []corev1.LocalObjectReference, names []string) []corev1.LocalObjectReference {
+	cur := sets.NewString()
+	for _, ref := range refs {
+		cur.Insert(ref.Name)
+	}
+	for _, name := range names {
+		if cur.Has(name) {
+			continue
+		}
+		refs = append(refs, corev1.LocalObjectReference{Name: name})
+		cur.Insert(name)
+	}
+	return refs
+}
+
+// ValidateImagePullSecrets checks the names can be used as secret names.
+func ValidateImagePullSecrets(names []string) error {
+	for _, name := range names {
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return fmt.Errorf("invalid image pull secret name %q: %s", name, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+// ValidatePriorityClassName checks the name can be used as a priority class name. Empty is allowed.
+func ValidatePriorityClassName(name string) error {
+	if name == "" {
+		return nil
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return fmt.Errorf("invalid priority class name %q: %s", name, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// ValidateAPIGroup checks the group can be used as the NodeResourceTopology API group.
+// Like the apiserver, requires a DNS subdomain with at least one dot.
+func ValidateAPIGroup(group string) error {
+	if errs := validation.IsDNS1123Subdomain(group); len(errs) > 0 {
+		return fmt.Errorf("invalid API group %q: %s", group, strings.Join(errs, "; "))
+	}
+	if !strings.Contains(group, ".") {
+		return fmt.Errorf("invalid API group %q: must contain at least one dot", group)
+	}
+	return nil
+}
+
+// UpdateCRDAPIGroup moves the CRD to the given API group. The CRD name must match the group, so it changes too.
+func UpdateCRDAPIGroup(crd *apiextensionv1.CustomResourceDefinition, group string) *apiextensionv1.CustomResourceDefinition {
+	crd.Spec.Group = group
+	crd.Name = crd.Spec.Names.Plural + "." + group
+	return crd
+}
+
+// UpdatePolicyRulesAPIGroup makes the rules granting access to the upstream NodeResourceTopology API group
+// reference the given group instead, to keep them in sync with the CRD.
+func UpdatePolicyRulesAPIGroup(rules []rbacv1.PolicyRule, group string) []rbacv1.PolicyRule {
+	for idx := range rules {
+		for jdx := range rules[idx].APIGroups {
+			if rules[idx].APIGroups[jdx] == NRTAPIGroup {
+				rules[idx].APIGroups[jdx] = group
+			}
+		}
+	}
+	return rules
+}
+
+// LeasePolicyRule grants the access to the named lease needed by the leader election.
+// The lease creation is granted separately, as it cannot be restricted by name.
+func LeasePolicyRule(leaseName string) rbacv1.PolicyRule {
+	return rbacv1.PolicyRule{
+		APIGroups:     []string{"coordination.k8s.io"},
+		Resources:     []string{"leases"},
+		ResourceNames: []string{leaseName},
+		Verbs:         []string{"get", "update"},
+	}
+}
+
+// UpdateDaemonSetMaxUnavailable makes the DaemonSet roll its pods out gradually, replacing at most maxUnavailable
+// of them, a number or a percentage of the nodes, at a time. Nil keeps the existing update strategy.
+func UpdateDaemonSetMaxUnavailable(ds *appsv1.DaemonSet, maxUnavailable *intstr.IntOrString) *appsv1.DaemonSet {
+	if maxUnavailable == nil {
+		return ds
+	}
+	val := *maxUnavailable
+	ds.Spec.UpdateStrategy = appsv1.DaemonSetUpdateStrategy{
+		Type: appsv1.RollingUpdateDaemonSetStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateDaemonSet{
+			MaxUnavailable: &val,
+		},
+	}
+	return ds
+}
+
+// ValidateMaxUnavailable checks the value can be used as the maxUnavailable of a rolling update:
+// a positive number, or a percentage between 1% and 100%.
+func ValidateMaxUnavailable(maxUnavailable intstr.IntOrString) error {
+	if maxUnavailable.Type == intstr.Int {
+		if maxUnavailable.IntVal < 1 {
+			return fmt.Errorf("invalid max unavailable %d: must be at least 1", maxUnavailable.IntVal)
+		}
+		return nil
+	}
+	pct := maxUnavailable.StrVal
+	val, err := strconv.Atoi(strings.TrimSuffix(pct, "%"))
+	if !strings.HasSuffix(pct, "%") || err != nil || val < 1 || val > 100 {
+		return fmt.Errorf("invalid max unavailable %q: must be a number, or a percentage between 1%% and 100%%", maxUnavailable.StrVal)
+	}
+	return nil
+}
+
+// ValidateDNSPolicy checks the DNS policy can be set on the pods. Empty is allowed, meaning the manifest policy.
+// "None" is rejected, because it requires a DNS config the deployer does not set.
+func ValidateDNSPolicy(dnsPolicy corev1.DNSPolicy) error {
+	switch dnsPolicy {
+	case "", corev1.DNSClusterFirst, corev1.DNSClusterFirstWithHostNet, corev1.DNSDefault:
+		return nil
+	default:
+		return fmt.Errorf("invalid DNS policy %q: must be %q, %q or %q", dnsPolicy, corev1.DNSClusterFirst, corev1.DNSClusterFirstWithHostNet, corev1.DNSDefault)
+	}
+}
+
+// UpdatePodSpecNetworking makes the pods use the host network or not, and sets their DNS policy.
+// Nil hostNetwork and empty dnsPolicy keep the existing settings.
+func UpdatePodSpecNetworking(podSpec *corev1.PodSpec, hostNetwork *bool, dnsPolicy corev1.DNSPolicy) *corev1.PodSpec {
+	if hostNetwork != nil {
+		podSpec.HostNetwork = *hostNetwork
+	}
+	if dnsPolicy != "" {
+		podSpec.DNSPolicy = dnsPolicy
+	}
+	return podSpec
+}
+
+// UpdateDaemonSetTolerations adds the given tolerations to the DaemonSet pod template, skipping the ones already present.
+func UpdateDaemonSetTolerations(ds *appsv1.DaemonSet, tolerations []corev1.Toleration) *appsv1.DaemonSet {
+	UpdatePodSpecTolerations(&ds.Spec.Template.Spec, tolerations)
+	return ds
+}
+
+// UpdatePodSpecTolerations adds the given tolerations to the pod spec, skipping the ones already present.
+func UpdatePodSpecTolerations(podSpec *corev1.PodSpec, tolerations []corev1.Toleration) *corev1.PodSpec {
+	for _, tol := range tolerations {
+		if !hasToleration(podSpec.Tolerations, tol) {
+			podSpec.Tolerations = append(podSpec.Tolerations, tol)
+		}
+	}
+	return podSpec
+}
+
+// UpdateDaemonSetNodeSelector adds the labels to the DaemonSet node selector, overriding the existing ones only on key collision.
+func UpdateDaemonSetNodeSelector(ds *appsv1.DaemonSet, nodeSelector map[string]string) *appsv1.DaemonSet {
+	UpdatePodSpecNodeSelector(&ds.Spec.Template.Spec, nodeSelector)
+	return ds
+}
+
+// UpdatePodSpecNodeSelector adds the labels to the pod spec node selector, overriding the existing ones only on key collision.
+func UpdatePodSpecNodeSelector(podSpec *corev1.PodSpec, nodeSelector map[string]string) *corev1.PodSpec {
+	if len(nodeSelector) == 0 {
+		return podSpec
+	}
+	if podSpec.NodeSelector == nil {
+		podSpec.NodeSelector = make(map[string]string, len(nodeSelector))
+	}
+	for key, val := range nodeSelector {
+		podSpec.NodeSelector[key] = val
+	}
+	return podSpec
+}
+
+// ParseToleration parses a toleration in the "key[=value][:effect]" form. Without value the key can have
+// any value, without effect all the effects are tolerated.
+func ParseToleration(spec string) (corev1.Toleration, error) {
+	tol := corev1.Toleration{
+		Operator: corev1.TolerationOpExists,
+	}
+	keyVal := spec
+	if idx := strings.LastIndex(spec, ":"); idx >= 0 {
+		keyVal = spec[:idx]
+		tol.Effect = corev1.TaintEffect(spec[idx+1:])
+		switch tol.Effect {
+		case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+		default:
+			return tol, fmt.Errorf("invalid toleration %q: unknown effect %q", spec, tol.Effect)
+		}
+	}
+	tol.Key = keyVal
+	if idx := strings.Index(keyVal, "="); idx >= 0 {
+		tol.Key = keyVal[:idx]
+		tol.Value = keyVal[idx+1:]
+		tol.Operator = corev1.TolerationOpEqual
+		if errs := validation.IsValidLabelValue(tol.Value); len(errs) > 0 {
+			return tol, fmt.Errorf("invalid toleration %q value: %s", spec, strings.Join(errs, "; "))
+		}
+	}
+	if errs := validation.IsQualifiedName(tol.Key); len(errs) > 0 {
+		return tol, fmt.Errorf("invalid toleration %q key: %s", spec, strings.Join(errs, "; "))
+	}
+	return tol, nil
+}
+
+func hasToleration(tolerations []corev1.Toleration, tol corev1.Toleration) bool {
+	for _, cur := range tolerations {
+		if cur.MatchToleration(&tol) {
+			return true
+		}
+	}
+	return false
+}
+
 func UpdateResourceTopologyExporterCommand(args []string, vars map[string]string, plat platform.Platform) []string {
 	res := []string{}
 	for _, arg := range args {
@@ -0,0 +1,109 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package manifests
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+)
+
+const (
+	// VersionDefault names the manifests the deployer is built with.
+	VersionDefault = "default"
+
+	// defaultRoot holds the default manifests. The pinned versions are laid out
+	// the same way, each under versionsRoot/<version>.
+	defaultRoot  = "yaml"
+	versionsRoot = "yaml/versions"
+)
+
+// Source is a set of embedded manifests the loaders read from. The zero value is the VersionDefault manifests.
+type Source struct {
+	fsys fs.FS
+	root string
+}
+
+// Versions returns the names of the embedded manifests versions, VersionDefault first.
+func Versions() []string {
+	return versions(src)
+}
+
+// NewSource returns the source of the given embedded manifests version. Empty selects VersionDefault.
+// Returns error if the version is unknown.
+func NewSource(version string) (Source, error) {
+	dir, err := resolveVersion(src, version)
+	if err != nil {
+		return Source{}, err
+	}
+	return Source{fsys: src, root: dir}, nil
+}
+
+// Version returns the name of the manifests version of the source.
+func (s Source) Version() string {
+	if s.dir() == defaultRoot {
+		return VersionDefault
+	}
+	return path.Base(s.root)
+}
+
+func (s Source) fs() fs.FS {
+	if s.fsys == nil {
+		return src
+	}
+	return s.fsys
+}
+
+func (s Source) dir() string {
+	if s.root == "" {
+		return defaultRoot
+	}
+	return s.root
+}
+
+func versions(fsys fs.FS) []string {
+	ret := []string{VersionDefault}
+	entries, err := fs.ReadDir(fsys, versionsRoot)
+	if err != nil {
+		return ret
+	}
+	var pinned []string
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != VersionDefault {
+			pinned = append(pinned, entry.Name())
+		}
+	}
+	sort.Strings(pinned)
+	return append(ret, pinned...)
+}
+
+func resolveVersion(fsys fs.FS, version string) (string, error) {
+	if version == "" || version == VersionDefault {
+		return defaultRoot, nil
+	}
+	dir := path.Join(versionsRoot, version)
+	info, err := fs.Stat(fsys, dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	if err != nil || !info.IsDir() || path.Base(dir) != version {
+		return "", fmt.Errorf("unknown manifests version %q: must be one of %v", version, versions(fsys))
+	}
+	return dir, nil
+}
@@ -0,0 +1,130 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package manifests
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestResolveVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"yaml/rte/daemonset.yaml":                 {},
+		"yaml/versions/v0.2/rte/daemonset.yaml":   {},
+		"yaml/versions/v0.1/rte/daemonset.yaml":   {},
+		"yaml/versions/notes.txt":                 {},
+		"yaml/versions/v0.1/sched/configmap.yaml": {},
+	}
+
+	expectedVersions := []string{VersionDefault, "v0.1", "v0.2"}
+	if got := versions(fsys); !reflect.DeepEqual(got, expectedVersions) {
+		t.Errorf("expected versions %v, got %v", expectedVersions, got)
+	}
+
+	testCases := []struct {
+		name          string
+		version       string
+		expected      string
+		expectedError bool
+	}{
+		{
+			name:     "empty",
+			expected: "yaml",
+		},
+		{
+			name:     "default",
+			version:  VersionDefault,
+			expected: "yaml",
+		},
+		{
+			name:     "pinned",
+			version:  "v0.1",
+			expected: "yaml/versions/v0.1",
+		},
+		{
+			name:          "unknown",
+			version:       "v0.3",
+			expectedError: true,
+		},
+		{
+			name:          "not a directory",
+			version:       "notes.txt",
+			expectedError: true,
+		},
+		{
+			name:          "not a version name",
+			version:       "../versions/v0.1",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := resolveVersion(fsys, tc.version)
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestNewSource(t *testing.T) {
+	if _, err := NewSource("missing"); err == nil {
+		t.Fatalf("expected error for an unknown version")
+	}
+
+	for _, version := range Versions() {
+		src, err := NewSource(version)
+		if err != nil {
+			t.Fatalf("unexpected error for version %q: %v", version, err)
+		}
+		if got := src.Version(); got != version {
+			t.Errorf("expected version %q, got %q", version, got)
+		}
+		if _, err := src.DaemonSet(ComponentResourceTopologyExporter); err != nil {
+			t.Errorf("cannot load the manifests of version %q: %v", version, err)
+		}
+	}
+
+	if got := (Source{}).Version(); got != VersionDefault {
+		t.Errorf("expected the zero source to be %q, got %q", VersionDefault, got)
+	}
+}
+
+func TestSourceFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"yaml/rte/daemonset.yaml":               {Data: []byte("apiVersion: apps/v1\nkind: DaemonSet\nmetadata:\n  name: default\n")},
+		"yaml/versions/v0.1/rte/daemonset.yaml": {Data: []byte("apiVersion: apps/v1\nkind: DaemonSet\nmetadata:\n  name: pinned\n")},
+	}
+	for version, expected := range map[string]string{VersionDefault: "default", "v0.1": "pinned"} {
+		dir, err := resolveVersion(fsys, version)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ds, err := Source{fsys: fsys, root: dir}.DaemonSet(ComponentResourceTopologyExporter)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ds.Name != expected {
+			t.Errorf("version %q: expected daemonset %q, got %q", version, expected, ds.Name)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+// Package metrics collects metrics about the deployer operations, exposing them
+// in the prometheus text format.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	OperationCreate = "create"
+	OperationUpdate = "update"
+	OperationDelete = "delete"
+	OperationWait   = "wait"
+)
+
+// waitBuckets are the upper bounds, in seconds, of the wait duration histogram buckets.
+// The waits poll for up to few minutes.
+var waitBuckets = []float64{1, 5, 10, 30, 60, 120, 180, 300}
+
+// Default collects the metrics of the process. The deployer runs a single operation
+// per invocation, so there is no need for more registries.
+var Default = NewRegistry()
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+type Registry struct {
+	mu       sync.Mutex
+	created  map[string]uint64
+	failures map[string]uint64
+	waits    map[string]*histogram
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		created:  make(map[string]uint64),
+		failures: make(map[string]uint64),
+		waits:    make(map[string]*histogram),
+	}
+}
+
+// ObjectCreated records the creation of an object of the given kind.
+func (r *Registry) ObjectCreated(kind string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.created[kind]++
+}
+
+// OperationFailed records the failure of the given operation.
+func (r *Registry) OperationFailed(operation string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failures[operation]++
+}
+
+// ObserveWait records how long it took to wait for the given condition.
+func (r *Registry) ObserveWait(condition string, elapsed time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h, ok := r.waits[condition]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(waitBuckets))}
+		r.waits[condition] = h
+	}
+	secs := elapsed.Seconds()
+	for idx, le := range waitBuckets {
+		if secs <= le {
+			h.counts[idx]++
+			break
+		}
+	}
+	h.sum += secs
+	h.count++
+}
+
+// Write writes all the metrics in the prometheus text exposition format.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var sb strings.Builder
+	sb.WriteString("# HELP deployer_objects_created_total Number of objects created, by kind.\n")
+	sb.WriteString("# TYPE deployer_objects_created_total counter\n")
+	for _, kind := range sortedKeys(r.created) {
+		fmt.Fprintf(&sb, "deployer_objects_created_total{kind=%q} %d\n", kind, r.created[kind])
+	}
+
+	sb.WriteString("# HELP deployer_operation_failures_total Number of failed operations, by operation.\n")
+	sb.WriteString("# TYPE deployer_operation_failures_total counter\n")
+	for _, op := range sortedKeys(r.failures) {
+		fmt.Fprintf(&sb, "deployer_operation_failures_total{operation=%q} %d\n", op, r.failures[op])
+	}
+
+	sb.WriteString("# HELP deployer_wait_duration_seconds Time spent waiting for the objects to reach the expected state, by condition.\n")
+	sb.WriteString("# TYPE deployer_wait_duration_seconds histogram\n")
+	conditions := make([]string, 0, len(r.waits))
+	for cond := range r.waits {
+		conditions = append(conditions, cond)
+	}
+	sort.Strings(conditions)
+	for _, cond := range conditions {
+		h := r.waits[cond]
+		var cumulative uint64
+		for idx, le := range waitBuckets {
+			cumulative += h.counts[idx]
+			fmt.Fprintf(&sb, "deployer_wait_duration_seconds_bucket{condition=%q,le=\"%g\"} %d\n", cond, le, cumulative)
+		}
+		fmt.Fprintf(&sb, "deployer_wait_duration_seconds_bucket{condition=%q,le=\"+Inf\"} %d\n", cond, h.count)
+		fmt.Fprintf(&sb, "deployer_wait_duration_seconds_sum{condition=%q} %g\n", cond, h.sum)
+		fmt.Fprintf(&sb, "deployer_wait_duration_seconds_count{condition=%q} %d\n", cond, h.count)
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.Write(w)
+}
+
+// Serve exposes the Default registry on the given address, under /metrics, in the background.
+// The returned function stops the server.
+func Serve(addr string) (func() error, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Default)
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(ln)
+
+	return func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(ctx)
+	}, nil
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,72 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	reg := NewRegistry()
+	reg.ObjectCreated("DaemonSet")
+	reg.ObjectCreated("Role")
+	reg.ObjectCreated("Role")
+	reg.OperationFailed(OperationWait)
+	reg.ObserveWait("daemonset_running", 3*time.Second)
+	reg.ObserveWait("daemonset_running", 500*time.Second)
+
+	var sb strings.Builder
+	if err := reg.Write(&sb); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := sb.String()
+
+	expected := []string{
+		`deployer_objects_created_total{kind="DaemonSet"} 1`,
+		`deployer_objects_created_total{kind="Role"} 2`,
+		`deployer_operation_failures_total{operation="wait"} 1`,
+		`deployer_wait_duration_seconds_bucket{condition="daemonset_running",le="1"} 0`,
+		`deployer_wait_duration_seconds_bucket{condition="daemonset_running",le="5"} 1`,
+		`deployer_wait_duration_seconds_bucket{condition="daemonset_running",le="300"} 1`,
+		`deployer_wait_duration_seconds_bucket{condition="daemonset_running",le="+Inf"} 2`,
+		`deployer_wait_duration_seconds_sum{condition="daemonset_running"} 503`,
+		`deployer_wait_duration_seconds_count{condition="daemonset_running"} 2`,
+	}
+	for _, exp := range expected {
+		if !strings.Contains(out, exp+"\n") {
+			t.Errorf("missing %q in:\n%s", exp, out)
+		}
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	reg := NewRegistry()
+	reg.ObjectCreated("Namespace")
+
+	rec := httptest.NewRecorder()
+	reg.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("unexpected content type: %q", rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Body.String(), `deployer_objects_created_total{kind="Namespace"} 1`) {
+		t.Errorf("unexpected body: %s", rec.Body.String())
+	}
+}
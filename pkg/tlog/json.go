@@ -0,0 +1,114 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package tlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	LevelInfo  = "info"
+	LevelDebug = "debug"
+)
+
+type jsonRecord struct {
+	Time  string `json:"time"`
+	Level string `json:"level"`
+	Msg   string `json:"msg"`
+	Fields
+}
+
+// JSONWriter emits each line written to it as a JSON record of the given level, one per line,
+// so it can back the standard loggers too.
+type JSONWriter struct {
+	lock  sync.Mutex
+	out   io.Writer
+	level string
+}
+
+func NewJSONWriter(out io.Writer, level string) *JSONWriter {
+	return &JSONWriter{
+		out:   out,
+		level: level,
+	}
+}
+
+func (jw *JSONWriter) Write(data []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		if err := jw.WriteRecord(Fields{}, line); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+// WriteRecord emits the message as JSON record, along with its fields.
+func (jw *JSONWriter) WriteRecord(fields Fields, msg string) error {
+	data, err := json.Marshal(jsonRecord{
+		Time:   time.Now().UTC().Format(time.RFC3339),
+		Level:  jw.level,
+		Msg:    msg,
+		Fields: fields,
+	})
+	if err != nil {
+		return err
+	}
+	jw.lock.Lock()
+	defer jw.lock.Unlock()
+	_, err = jw.out.Write(append(data, '\n'))
+	return err
+}
+
+// JSONLogAdapter logs JSON records instead of human-oriented text, to be ingested by the log pipelines.
+type JSONLogAdapter struct {
+	log      *JSONWriter
+	debugLog *JSONWriter
+}
+
+// NewJSONLogAdapter returns a JSONLogAdapter writing to the same destinations of the given loggers.
+func NewJSONLogAdapter(log, debugLog *log.Logger) JSONLogAdapter {
+	return JSONLogAdapter{
+		log:      jsonWriterFor(log.Writer(), LevelInfo),
+		debugLog: jsonWriterFor(debugLog.Writer(), LevelDebug),
+	}
+}
+
+func (la JSONLogAdapter) Printf(format string, v ...interface{}) {
+	la.log.WriteRecord(Fields{}, fmt.Sprintf(format, v...))
+}
+
+func (la JSONLogAdapter) Debugf(format string, v ...interface{}) {
+	la.debugLog.WriteRecord(Fields{}, fmt.Sprintf(format, v...))
+}
+
+func (la JSONLogAdapter) PrintfFields(fields Fields, format string, v ...interface{}) {
+	la.log.WriteRecord(fields, fmt.Sprintf(format, v...))
+}
+
+// jsonWriterFor avoids to encode twice the loggers already emitting JSON records.
+func jsonWriterFor(out io.Writer, level string) *JSONWriter {
+	if jw, ok := out.(*JSONWriter); ok {
+		return jw
+	}
+	return NewJSONWriter(out, level)
+}
@@ -0,0 +1,64 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package tlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestJSONLogAdapter(t *testing.T) {
+	var out, debugOut bytes.Buffer
+	logger := log.New(NewJSONWriter(&out, LevelInfo), "", 0)
+	la := NewJSONLogAdapter(logger, log.New(&debugOut, "", 0))
+
+	la.Printf("deploying %d objects", 3)
+	PrintfFields(la, Fields{Component: "RTE", Action: "created", Kind: "DaemonSet", Name: "rte"}, "created %s", "rte")
+	logger.Printf("plain")
+	la.Debugf("details")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 records, got %d: %q", len(lines), out.String())
+	}
+	var recs []jsonRecord
+	for _, line := range lines {
+		var rec jsonRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("cannot decode %q: %v", line, err)
+		}
+		recs = append(recs, rec)
+	}
+	if recs[0].Msg != "deploying 3 objects" || recs[0].Level != LevelInfo {
+		t.Errorf("unexpected record: %+v", recs[0])
+	}
+	expected := Fields{Component: "RTE", Action: "created", Kind: "DaemonSet", Name: "rte"}
+	if recs[1].Fields != expected || recs[1].Msg != "created rte" {
+		t.Errorf("unexpected record: %+v", recs[1])
+	}
+	if recs[2].Msg != "plain" {
+		t.Errorf("the standard logger records were encoded twice: %+v", recs[2])
+	}
+
+	var rec jsonRecord
+	if err := json.Unmarshal(debugOut.Bytes(), &rec); err != nil || rec.Level != LevelDebug || rec.Msg != "details" {
+		t.Errorf("unexpected debug record %q: %v", debugOut.String(), err)
+	}
+}
@@ -26,6 +26,29 @@ type Logger interface {
 	Debugf(format string, v ...interface{})
 }
 
+// Fields are the structured context of a log message about an operation on an object.
+type Fields struct {
+	Component string `json:"component,omitempty"`
+	Action    string `json:"action,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Name      string `json:"name,omitempty"`
+}
+
+// FieldsLogger is implemented by the loggers which can record the Fields along with the messages.
+type FieldsLogger interface {
+	Logger
+	PrintfFields(fields Fields, format string, v ...interface{})
+}
+
+// PrintfFields logs the message along with its fields if the logger supports them, the message only otherwise.
+func PrintfFields(log Logger, fields Fields, format string, v ...interface{}) {
+	if fl, ok := log.(FieldsLogger); ok {
+		fl.PrintfFields(fields, format, v...)
+		return
+	}
+	log.Printf(format, v...)
+}
+
 type LogAdapter struct {
 	log      *log.Logger
 	debugLog *log.Logger
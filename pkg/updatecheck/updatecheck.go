@@ -0,0 +1,134 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+// Package updatecheck compares the component versions bundled in the
+// deployer against a remote index, to tell if newer ones are available.
+package updatecheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-version"
+)
+
+const DeployerName = "deployer"
+
+// Index describes a deployer release and the components it bundles.
+// The remote index is expected to be a JSON document like:
+//
+//	{"deployer": "v0.6.0", "components": {"topology_updater": "v0.2.4"}}
+type Index struct {
+	Deployer   string            `json:"deployer"`
+	Components map[string]string `json:"components,omitempty"`
+}
+
+type Result struct {
+	Name      string
+	Current   string
+	Available string
+	Newer     bool
+}
+
+func (res Result) String() string {
+	if !res.Newer {
+		return fmt.Sprintf("%s: %s is up to date", res.Name, res.Current)
+	}
+	return fmt.Sprintf("%s: %s available (current %s)", res.Name, res.Available, res.Current)
+}
+
+// VersionFromImage returns the tag of the given image pull spec, or empty if it has none.
+// The images pinned by digest are pulled regardless of their tag, so their version is unknown: empty.
+func VersionFromImage(image string) string {
+	if strings.Contains(image, "@") {
+		return ""
+	}
+	idx := strings.LastIndex(image, ":")
+	if idx == -1 || strings.Contains(image[idx:], "/") {
+		return ""
+	}
+	return image[idx+1:]
+}
+
+func Fetch(url string, timeout time.Duration) (Index, error) {
+	var idx Index
+	cli := http.Client{Timeout: timeout}
+	resp, err := cli.Get(url)
+	if err != nil {
+		return idx, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return idx, fmt.Errorf("cannot fetch %q: %s", url, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&idx); err != nil {
+		return idx, fmt.Errorf("cannot decode %q: %w", url, err)
+	}
+	return idx, nil
+}
+
+// Compare checks the current versions against the remote ones. Entries
+// missing from the remote index, or whose current version is unknown (empty), are not reported.
+func Compare(current, remote Index) ([]Result, error) {
+	var ret []Result
+	if remote.Deployer != "" {
+		res, err := compareVersion(DeployerName, current.Deployer, remote.Deployer)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, res)
+	}
+
+	var names []string
+	for name := range current.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		available, ok := remote.Components[name]
+		if !ok || current.Components[name] == "" {
+			continue
+		}
+		res, err := compareVersion(name, current.Components[name], available)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, res)
+	}
+	return ret, nil
+}
+
+func compareVersion(name, current, available string) (Result, error) {
+	res := Result{
+		Name:      name,
+		Current:   current,
+		Available: available,
+	}
+	curVer, err := version.NewVersion(current)
+	if err != nil {
+		return res, fmt.Errorf("invalid current version %q for %s: %w", current, name, err)
+	}
+	availVer, err := version.NewVersion(available)
+	if err != nil {
+		return res, fmt.Errorf("invalid available version %q for %s: %w", available, name, err)
+	}
+	res.Newer = availVer.GreaterThan(curVer)
+	return res, nil
+}
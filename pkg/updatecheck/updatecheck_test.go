@@ -0,0 +1,83 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package updatecheck
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestVersionFromImage(t *testing.T) {
+	type testCase struct {
+		image    string
+		expected string
+	}
+
+	testCases := []testCase{
+		{image: "quay.io/foo/bar:v0.2.3", expected: "v0.2.3"},
+		{image: "localhost:5000/foo/bar", expected: ""},
+		{image: "localhost:5000/foo/bar:v1.0.0", expected: "v1.0.0"},
+		{image: "bar", expected: ""},
+		{image: "quay.io/foo/bar@sha256:0123456789abcdef", expected: ""},
+		{image: "quay.io/foo/bar:v0.2.3@sha256:0123456789abcdef", expected: ""},
+	}
+
+	for _, tc := range testCases {
+		if got := VersionFromImage(tc.image); got != tc.expected {
+			t.Errorf("image %q: got %q expected %q", tc.image, got, tc.expected)
+		}
+	}
+}
+
+func TestFetchAndCompare(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"deployer": "v0.6.0", "components": {"topology_updater": "v0.2.3", "scheduler_plugin": "v0.0.2021110100", "scheduler_controller": "v0.0.2021110100"}}`)
+	}))
+	defer srv.Close()
+
+	remote, err := Fetch(srv.URL, 5*time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	current := Index{
+		Deployer: "v0.5.1",
+		Components: map[string]string{
+			"scheduler_plugin": "v0.0.2021101805",
+			"topology_updater": "v0.2.3",
+			"unknown":          "v1.0.0",
+			// untagged or pinned by digest
+			"scheduler_controller": "",
+		},
+	}
+	results, err := Compare(current, remote)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []Result{
+		{Name: DeployerName, Current: "v0.5.1", Available: "v0.6.0", Newer: true},
+		{Name: "scheduler_plugin", Current: "v0.0.2021101805", Available: "v0.0.2021110100", Newer: true},
+		{Name: "topology_updater", Current: "v0.2.3", Available: "v0.2.3", Newer: false},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("unexpected results: %v expected %v", results, expected)
+	}
+}
@@ -0,0 +1,135 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+// Package upgrade compares the deployer version which installed the components,
+// stamped on their objects, with the version of the running deployer.
+package upgrade
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/hashicorp/go-version"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests"
+)
+
+// Direction is how the installed components move to the target version.
+type Direction string
+
+const (
+	// DirectionUnknown means the objects carry no version, e.g. they were created by a deployer which did not stamp it.
+	DirectionUnknown   Direction = "unknown"
+	DirectionUpgrade   Direction = "upgrade"
+	DirectionSame      Direction = "same"
+	DirectionDowngrade Direction = "downgrade"
+)
+
+// describeSuffix matches the suffix `git describe --tags` adds to the tag on the commits after it,
+// e.g. "-12-gabc1234" in "v0.5.0-12-gabc1234", with the count of commits since the tag.
+var describeSuffix = regexp.MustCompile(`-([0-9]+)-g[0-9a-f]+(-dirty)?$`)
+
+// describedVersion is a deployer version as produced by `git describe --tags`: a tag, and the commits after it.
+type describedVersion struct {
+	tag     *version.Version
+	commits int
+}
+
+// parseVersion parses the deployer version. The describe suffix is split off the tag: taken as is,
+// it would be a prerelease, making the builds after a tag older than the tag itself.
+func parseVersion(val string) (describedVersion, error) {
+	dv := describedVersion{}
+	tag := val
+	if match := describeSuffix.FindStringSubmatchIndex(val); match != nil {
+		commits, err := strconv.Atoi(val[match[2]:match[3]])
+		if err != nil {
+			return dv, err
+		}
+		dv.commits = commits
+		tag = val[:match[0]]
+	}
+	ver, err := version.NewVersion(tag)
+	if err != nil {
+		return dv, err
+	}
+	dv.tag = ver
+	return dv, nil
+}
+
+// compare returns -1, 0 or 1 if dv is older, the same or newer than other.
+// The builds on the same tag are ordered by the commits after it.
+func (dv describedVersion) compare(other describedVersion) int {
+	if ret := dv.tag.Compare(other.tag); ret != 0 {
+		return ret
+	}
+	switch {
+	case dv.commits < other.commits:
+		return -1
+	case dv.commits > other.commits:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// InstalledVersion returns the newest deployer version stamped on the objects, empty if none is.
+// The objects not changed by an upgrade keep the version of the deployer which last changed them,
+// so the newest version is the one the components were last upgraded to.
+func InstalledVersion(objs []client.Object) (string, error) {
+	var newest describedVersion
+	ret := ""
+	for _, obj := range objs {
+		val, ok := obj.GetAnnotations()[manifests.AnnotationDeployerVersion]
+		if !ok {
+			continue
+		}
+		ver, err := parseVersion(val)
+		if err != nil {
+			return "", fmt.Errorf("invalid deployer version %q on %s: %w", val, manifests.ObjectName(obj), err)
+		}
+		if ret == "" || ver.compare(newest) > 0 {
+			newest = ver
+			ret = val
+		}
+	}
+	return ret, nil
+}
+
+// Compare tells how the components installed at the installed version move to the target version.
+// Empty installed version means DirectionUnknown.
+func Compare(installed, target string) (Direction, error) {
+	if installed == "" {
+		return DirectionUnknown, nil
+	}
+	installedVer, err := parseVersion(installed)
+	if err != nil {
+		return DirectionUnknown, fmt.Errorf("invalid installed version %q: %w", installed, err)
+	}
+	targetVer, err := parseVersion(target)
+	if err != nil {
+		return DirectionUnknown, fmt.Errorf("invalid target version %q: %w", target, err)
+	}
+	switch targetVer.compare(installedVer) {
+	case 1:
+		return DirectionUpgrade, nil
+	case -1:
+		return DirectionDowngrade, nil
+	default:
+		return DirectionSame, nil
+	}
+}
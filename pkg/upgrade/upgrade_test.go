@@ -0,0 +1,104 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package upgrade
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests"
+)
+
+func stampedObject(name, ver string) client.Object {
+	cm := &corev1.ConfigMap{}
+	cm.Name = name
+	if ver != "" {
+		cm.Annotations = map[string]string{manifests.AnnotationDeployerVersion: ver}
+	}
+	return cm
+}
+
+func TestInstalledVersion(t *testing.T) {
+	testCases := []struct {
+		name        string
+		objs        []client.Object
+		expected    string
+		expectedErr bool
+	}{
+		{name: "no objects"},
+		{name: "not stamped", objs: []client.Object{stampedObject("a", "")}},
+		{name: "single", objs: []client.Object{stampedObject("a", "v0.5.0")}, expected: "v0.5.0"},
+		{
+			name:     "partially upgraded",
+			objs:     []client.Object{stampedObject("a", "v0.5.0"), stampedObject("b", ""), stampedObject("c", "v0.10.1"), stampedObject("d", "v0.6.0")},
+			expected: "v0.10.1",
+		},
+		{
+			name:     "builds after the tag",
+			objs:     []client.Object{stampedObject("a", "v0.5.0-12-gabc1234"), stampedObject("b", "v0.5.0"), stampedObject("c", "v0.5.0-3-g1234abc")},
+			expected: "v0.5.0-12-gabc1234",
+		},
+		{name: "invalid", objs: []client.Object{stampedObject("a", "v0.5.0"), stampedObject("b", "latest")}, expectedErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := InstalledVersion(tc.objs)
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.expected {
+				t.Errorf("got %q expected %q", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	testCases := []struct {
+		installed   string
+		target      string
+		expected    Direction
+		expectedErr bool
+	}{
+		{installed: "", target: "v0.5.0", expected: DirectionUnknown},
+		{installed: "v0.5.0", target: "v0.6.0", expected: DirectionUpgrade},
+		{installed: "v0.5.0", target: "v0.5.0", expected: DirectionSame},
+		{installed: "v0.10.0", target: "v0.9.2", expected: DirectionDowngrade},
+		{installed: "v0.5.0", target: "v0.0.0-dev", expected: DirectionDowngrade},
+		{installed: "v0.5.0-rc1", target: "v0.5.0", expected: DirectionUpgrade},
+		{installed: "v0.5.0", target: "v0.5.0-12-gabc1234", expected: DirectionUpgrade},
+		{installed: "v0.5.0-3-g1234abc", target: "v0.5.0-12-gabc1234", expected: DirectionUpgrade},
+		{installed: "v0.5.0-12-gabc1234", target: "v0.5.0-3-g1234abc", expected: DirectionDowngrade},
+		{installed: "v0.5.0-12-gabc1234", target: "v0.5.0-12-gabc1234-dirty", expected: DirectionSame},
+		{installed: "v0.5.0-12-gabc1234", target: "v0.5.1", expected: DirectionUpgrade},
+		{installed: "v0.5.1", target: "v0.5.0-12-gabc1234", expected: DirectionDowngrade},
+		{installed: "v0.6.0-rc1-2-gabc1234", target: "v0.6.0", expected: DirectionUpgrade},
+		{installed: "latest", target: "v0.5.0", expected: DirectionUnknown, expectedErr: true},
+		{installed: "v0.5.0", target: "dev", expected: DirectionUnknown, expectedErr: true},
+	}
+	for _, tc := range testCases {
+		got, err := Compare(tc.installed, tc.target)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("%q -> %q: unexpected error: %v", tc.installed, tc.target, err)
+		}
+		if got != tc.expected {
+			t.Errorf("%q -> %q: got %q expected %q", tc.installed, tc.target, got, tc.expected)
+		}
+	}
+}
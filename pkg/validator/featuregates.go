@@ -0,0 +1,121 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package validator
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/clientutil"
+)
+
+const (
+	AreaFeatureGates = "featuregates"
+
+	ComponentAPIServer = "apiserver"
+)
+
+// RequiredFeatureGates are the feature gates the topology-aware-scheduling components depend on.
+// The topology updater needs the kubelet podresources API, including the allocatable resources endpoint.
+var RequiredFeatureGates = []string{
+	"KubeletPodResources",
+	"KubeletPodResourcesGetAllocatable",
+}
+
+// the apiserver exposes the state of its feature gates since kubernetes 1.26, like
+// kubernetes_feature_enabled{name="KubeletPodResources",stage="BETA"} 1
+var featureEnabledMetric = regexp.MustCompile(`^kubernetes_feature_enabled\{(.*)\}\s+(\S+)$`)
+var featureNameLabel = regexp.MustCompile(`(?:^|,)name="([^"]*)"`)
+
+// ValidateAPIServerFeatureGates checks the required feature gates are enabled in the apiserver.
+// The feature gates are discovered from the apiserver metrics; if they are not exposed
+// (older apiserver, or missing permissions) nothing can be checked, and no result is reported.
+// The required gates are kubelet gates: the apiserver state only hints at the kubelet one, which can
+// be set differently, so the results are advisory. The kubelet configuration check is authoritative.
+func (vd Validator) ValidateAPIServerFeatureGates() ([]ValidationResult, error) {
+	cs, err := clientutil.NewK8s()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := cs.Discovery().RESTClient().Get().AbsPath("/metrics").DoRaw(context.TODO())
+	if err != nil {
+		vd.Log.Printf("cannot get the apiserver metrics, feature gates not discoverable: %v", err)
+		return []ValidationResult{}, nil
+	}
+
+	enabled, err := ParseFeatureGatesMetrics(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return vd.ValidateFeatureGates(enabled, RequiredFeatureGates), nil
+}
+
+// ParseFeatureGatesMetrics extracts the feature gates state from the prometheus text exposition of the metrics.
+func ParseFeatureGatesMetrics(r io.Reader) (map[string]bool, error) {
+	enabled := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	// some metrics lines can be pretty long
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		match := featureEnabledMetric.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		name := featureNameLabel.FindStringSubmatch(match[1])
+		if name == nil {
+			continue
+		}
+		enabled[name[1]] = (match[2] == "1")
+	}
+	return enabled, scanner.Err()
+}
+
+// ValidateFeatureGates checks the required feature gates are enabled. The gates whose state is
+// unknown are not reported: if no gate is known at all, we can't tell anything about the cluster.
+func (vd Validator) ValidateFeatureGates(enabled map[string]bool, required []string) []ValidationResult {
+	vrs := []ValidationResult{}
+	if len(enabled) == 0 {
+		vd.Log.Printf("feature gates not discoverable, skipped the check")
+		return vrs
+	}
+	for _, name := range required {
+		val, ok := enabled[name]
+		if !ok {
+			vd.Log.Printf("feature gate %q state unknown", name)
+			continue
+		}
+		vd.Log.Printf("feature gate %q enabled=%v", name, val)
+		if val {
+			continue
+		}
+		vrs = append(vrs, ValidationResult{
+			Area:      AreaFeatureGates,
+			Component: ComponentAPIServer,
+			Setting:   name,
+			Expected:  fmt.Sprintf("%v", true),
+			Detected:  fmt.Sprintf("%v", val),
+		})
+	}
+	return vrs
+}
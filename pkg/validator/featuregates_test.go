@@ -0,0 +1,59 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package validator
+
+import (
+	"io/ioutil"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestParseFeatureGatesMetrics(t *testing.T) {
+	data := `# HELP kubernetes_feature_enabled [BETA] This metric records the data about the stage and enablement of a k8s feature.
+# TYPE kubernetes_feature_enabled gauge
+kubernetes_feature_enabled{name="KubeletPodResources",stage=""} 1
+kubernetes_feature_enabled{name="KubeletPodResourcesGetAllocatable",stage="BETA"} 0
+apiserver_request_total{code="200",verb="GET"} 42
+`
+	enabled, err := ParseFeatureGatesMetrics(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(enabled) != 2 {
+		t.Fatalf("unexpected feature gates: %v", enabled)
+	}
+	if !enabled["KubeletPodResources"] || enabled["KubeletPodResourcesGetAllocatable"] {
+		t.Errorf("unexpected feature gates: %v", enabled)
+	}
+}
+
+func TestValidateFeatureGates(t *testing.T) {
+	vd := Validator{
+		Log: log.New(ioutil.Discard, "", 0),
+	}
+
+	vrs := vd.ValidateFeatureGates(map[string]bool{"Foo": true, "Bar": false}, []string{"Foo", "Bar", "Baz"})
+	if len(vrs) != 1 || vrs[0].Setting != "Bar" || vrs[0].Area != AreaFeatureGates {
+		t.Errorf("unexpected results: %v", vrs)
+	}
+
+	vrs = vd.ValidateFeatureGates(nil, []string{"Foo"})
+	if len(vrs) != 0 {
+		t.Errorf("unexpected results with undiscoverable feature gates: %v", vrs)
+	}
+}
@@ -0,0 +1,97 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package validator
+
+import (
+	"fmt"
+
+	kubeletconfigv1beta1 "k8s.io/kubelet/config/v1beta1"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/clientutil"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/kubeletconfig"
+)
+
+const (
+	AreaKubeletConfig = "kubeletconfig"
+)
+
+// ValidateMachineConfig validates the cluster configuration on OpenShift, where
+// the kubelet settings are driven by the KubeletConfig objects rather than
+// being read from the nodes.
+func (vd Validator) ValidateMachineConfig() ([]ValidationResult, error) {
+	cli, err := clientutil.New()
+	if err != nil {
+		return nil, err
+	}
+
+	kubeConfs, err := kubeletconfig.GetKubeletConfigFromMachineConfig(cli, vd.Log)
+	if err != nil {
+		return nil, err
+	}
+
+	vrs := []ValidationResult{}
+	if len(kubeConfs) == 0 {
+		vd.Log.Printf("no KubeletConfig found")
+		vrs = append(vrs, ValidationResult{
+			Area:      AreaCluster,
+			Component: ComponentTopologyManager,
+			Expected:  "KubeletConfig objects",
+			Detected:  "none",
+		})
+		return vrs, nil
+	}
+
+	for name, kubeletConf := range kubeConfs {
+		vrs = append(vrs, vd.ValidateKubeletConfigObject(name, kubeletConf)...)
+	}
+	return vrs, nil
+}
+
+// ValidateKubeletConfigObject validates the kubelet configuration fragment
+// of the named KubeletConfig object. Settings not managed through the
+// KubeletConfig objects, like the feature gates, are not checked.
+func (vd Validator) ValidateKubeletConfigObject(name string, kubeletConf *kubeletconfigv1beta1.KubeletConfiguration) []ValidationResult {
+	vrs := []ValidationResult{}
+	area := fmt.Sprintf("%s/%s", AreaKubeletConfig, name)
+
+	if kubeletConf.CPUManagerPolicy != ExpectedCPUManagerPolicy {
+		vrs = append(vrs, ValidationResult{
+			Area:      area,
+			Component: ComponentCPUManager,
+			Setting:   "policy",
+			Expected:  ExpectedCPUManagerPolicy,
+			Detected:  kubeletConf.CPUManagerPolicy,
+		})
+	}
+
+	if kubeletConf.TopologyManagerPolicy != ExpectedTopologyManagerPolicy {
+		vrs = append(vrs, ValidationResult{
+			Area:      area,
+			Component: ComponentTopologyManager,
+			Setting:   "policy",
+			Expected:  ExpectedTopologyManagerPolicy,
+			Detected:  kubeletConf.TopologyManagerPolicy,
+		})
+	}
+
+	result := "OK"
+	if len(vrs) > 0 {
+		result = fmt.Sprintf("%d issues found", len(vrs))
+	}
+	vd.Log.Printf("validated KubeletConfig %q: %s", name, result)
+	return vrs
+}
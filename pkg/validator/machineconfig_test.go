@@ -0,0 +1,89 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package validator
+
+import (
+	"log"
+	"os"
+	"testing"
+
+	kubeletconfigv1beta1 "k8s.io/kubelet/config/v1beta1"
+)
+
+func TestKubeletConfigObjectValidations(t *testing.T) {
+	kcName := "worker-tuning"
+	area := AreaKubeletConfig + "/" + kcName
+
+	type testCase struct {
+		name        string
+		kubeletConf *kubeletconfigv1beta1.KubeletConfiguration
+		expected    []ValidationResult
+	}
+
+	testCases := []testCase{
+		{
+			name:        "empty",
+			kubeletConf: &kubeletconfigv1beta1.KubeletConfiguration{},
+			expected: []ValidationResult{
+				{
+					Area:      area,
+					Component: ComponentCPUManager,
+					Setting:   "policy",
+				},
+				{
+					Area:      area,
+					Component: ComponentTopologyManager,
+					Setting:   "policy",
+				},
+			},
+		},
+		{
+			name: "correct",
+			kubeletConf: &kubeletconfigv1beta1.KubeletConfiguration{
+				CPUManagerPolicy:      ExpectedCPUManagerPolicy,
+				TopologyManagerPolicy: ExpectedTopologyManagerPolicy,
+			},
+			expected: []ValidationResult{},
+		},
+		{
+			name: "wrong topology manager policy",
+			kubeletConf: &kubeletconfigv1beta1.KubeletConfiguration{
+				CPUManagerPolicy:      ExpectedCPUManagerPolicy,
+				TopologyManagerPolicy: "best-effort",
+			},
+			expected: []ValidationResult{
+				{
+					Area:      area,
+					Component: ComponentTopologyManager,
+					Setting:   "policy",
+				},
+			},
+		},
+	}
+
+	vd := Validator{
+		Log: log.New(os.Stderr, "testing ", log.LstdFlags),
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := vd.ValidateKubeletConfigObject(kcName, tc.kubeletConf)
+			if !matchValidationResults(tc.expected, got) {
+				t.Fatalf("validation failed:\nexpected=%#v\ngot=%#v", tc.expected, got)
+			}
+		})
+	}
+}
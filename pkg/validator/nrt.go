@@ -0,0 +1,150 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package validator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/clientutil"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests"
+)
+
+const (
+	AreaNRT = "noderesourcetopology"
+
+	ComponentTopologyUpdater = "topology updater"
+	ComponentAPI             = "API"
+)
+
+// nrtPlural is the plural name of the NodeResourceTopology resource, which prefixes the CRD name.
+const nrtPlural = "noderesourcetopologies"
+
+var (
+	NRTListGVK = schema.GroupVersionKind{
+		Group:   manifests.NRTAPIGroup,
+		Version: "v1alpha1",
+		Kind:    "NodeResourceTopologyList",
+	}
+)
+
+// ValidateNRTCRD checks the NodeResourceTopology CRD of the given API group is installed.
+// Empty group means the upstream one.
+func (vd Validator) ValidateNRTCRD(group string) ([]ValidationResult, error) {
+	if group == "" {
+		group = manifests.NRTAPIGroup
+	}
+	cs, err := clientutil.NewK8sExt()
+	if err != nil {
+		return nil, err
+	}
+
+	name := nrtPlural + "." + group
+	vrs := []ValidationResult{}
+	_, err = cs.ApiextensionsV1().CustomResourceDefinitions().Get(context.TODO(), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		vd.Log.Printf("CRD %q not found", name)
+		vrs = append(vrs, ValidationResult{
+			Area:      AreaNRT,
+			Component: ComponentAPI,
+			Setting:   name,
+			Expected:  "installed",
+			Detected:  "missing",
+		})
+		return vrs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the CRD %q: %w", name, err)
+	}
+	return vrs, nil
+}
+
+// ValidateNRTFreshness checks the NodeResourceTopology objects were all updated
+// within maxAge, so the topology updaters are actively publishing. The results are
+// advisory: see NRTLastUpdate.
+func (vd Validator) ValidateNRTFreshness(maxAge time.Duration) ([]ValidationResult, error) {
+	cli, err := clientutil.New()
+	if err != nil {
+		return nil, err
+	}
+
+	nrtList := &unstructured.UnstructuredList{}
+	nrtList.SetGroupVersionKind(NRTListGVK)
+	if err := cli.List(context.TODO(), nrtList); err != nil {
+		return nil, fmt.Errorf("cannot list the NodeResourceTopology objects: %w", err)
+	}
+
+	objs := make([]metav1.Object, 0, len(nrtList.Items))
+	for idx := range nrtList.Items {
+		objs = append(objs, &nrtList.Items[idx])
+	}
+	return vd.ValidateNRTObjectsFreshness(objs, maxAge, time.Now()), nil
+}
+
+// ValidateNRTObjectsFreshness checks the given NodeResourceTopology objects were all
+// updated within maxAge from now. The objects are named after the nodes.
+func (vd Validator) ValidateNRTObjectsFreshness(objs []metav1.Object, maxAge time.Duration, now time.Time) []ValidationResult {
+	vrs := []ValidationResult{}
+	if len(objs) == 0 {
+		vrs = append(vrs, ValidationResult{
+			Area:      AreaCluster,
+			Component: ComponentTopologyUpdater,
+			Expected:  "NodeResourceTopology objects",
+			Detected:  "none",
+		})
+		return vrs
+	}
+
+	for _, obj := range objs {
+		lastUpdate := NRTLastUpdate(obj)
+		age := now.Sub(lastUpdate)
+		vd.Log.Printf("NRT %q last updated %v ago", obj.GetName(), age)
+		if age <= maxAge {
+			continue
+		}
+		vrs = append(vrs, ValidationResult{
+			Node:      obj.GetName(),
+			Area:      AreaNRT,
+			Component: ComponentTopologyUpdater,
+			Setting:   "last update",
+			Expected:  fmt.Sprintf("within %v", maxAge),
+			Detected:  fmt.Sprintf("%v ago", age.Truncate(time.Second)),
+		})
+	}
+	return vrs
+}
+
+// NRTLastUpdate returns when the object was last written. The NRT API carries no
+// update timestamp, so we use the most recent managed fields entry, falling back
+// to the creation time. The managed fields time moves only when the content changes:
+// a topology updater publishing the same topology over and over looks idle, so a
+// stale object hints at an issue but does not prove it.
+func NRTLastUpdate(obj metav1.Object) time.Time {
+	lastUpdate := obj.GetCreationTimestamp().Time
+	for _, mf := range obj.GetManagedFields() {
+		if mf.Time != nil && mf.Time.After(lastUpdate) {
+			lastUpdate = mf.Time.Time
+		}
+	}
+	return lastUpdate
+}
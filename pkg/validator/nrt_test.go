@@ -0,0 +1,66 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package validator
+
+import (
+	"io/ioutil"
+	"log"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateNRTObjectsFreshness(t *testing.T) {
+	now := time.Now()
+	vd := Validator{
+		Log: log.New(ioutil.Discard, "", 0),
+	}
+
+	fresh := &metav1.ObjectMeta{
+		Name:              "fresh",
+		CreationTimestamp: metav1.NewTime(now.Add(-time.Hour)),
+		ManagedFields: []metav1.ManagedFieldsEntry{
+			{Manager: "resource-topology-exporter", Time: timePtr(now.Add(-10 * time.Second))},
+		},
+	}
+	stale := &metav1.ObjectMeta{
+		Name:              "stale",
+		CreationTimestamp: metav1.NewTime(now.Add(-time.Hour)),
+		ManagedFields: []metav1.ManagedFieldsEntry{
+			{Manager: "resource-topology-exporter", Time: timePtr(now.Add(-5 * time.Minute))},
+		},
+	}
+
+	vrs := vd.ValidateNRTObjectsFreshness([]metav1.Object{fresh, stale}, time.Minute, now)
+	if len(vrs) != 1 {
+		t.Fatalf("unexpected results: %v", vrs)
+	}
+	if vrs[0].Node != "stale" || vrs[0].Area != AreaNRT {
+		t.Errorf("unexpected result: %v", vrs[0])
+	}
+
+	vrs = vd.ValidateNRTObjectsFreshness(nil, time.Minute, now)
+	if len(vrs) != 1 || vrs[0].Area != AreaCluster {
+		t.Errorf("unexpected results with no objects: %v", vrs)
+	}
+}
+
+func timePtr(t time.Time) *metav1.Time {
+	ret := metav1.NewTime(t)
+	return &ret
+}